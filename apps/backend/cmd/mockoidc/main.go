@@ -0,0 +1,302 @@
+// Mock OpenID Connect issuer untuk development & pengujian login SSO.
+//
+// Jalankan:
+//
+//	go run ./cmd/mockoidc
+//
+// lalu set OIDC_ISSUER=http://localhost:9000, OIDC_CLIENT_ID=unispace,
+// OIDC_REDIRECT_URL=http://localhost:3000/auth/oidc/callback di .env backend.
+// Halaman /authorize menampilkan form untuk mengisi claim (sub, email, jurusan, NIM/NIP) secara bebas.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "mock-1"
+
+type authCode struct {
+	ClientID      string
+	RedirectURI   string
+	CodeChallenge string
+	Nonce         string
+	Claims        map[string]interface{}
+	ExpiresAt     time.Time
+}
+
+type server struct {
+	issuer   string
+	clientID string
+	secret   string
+	key      *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]authCode
+	tokens map[string]map[string]interface{} // access_token -> claims (userinfo)
+}
+
+func main() {
+	addr := envOr("MOCK_OIDC_ADDR", ":9000")
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal("Gagal membuat RSA key:", err)
+	}
+
+	s := &server{
+		issuer:   envOr("MOCK_OIDC_ISSUER", "http://localhost:9000"),
+		clientID: envOr("MOCK_OIDC_CLIENT_ID", "unispace"),
+		secret:   os.Getenv("MOCK_OIDC_CLIENT_SECRET"),
+		key:      key,
+		codes:    make(map[string]authCode),
+		tokens:   make(map[string]map[string]interface{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/userinfo", s.userinfo)
+
+	log.Printf("Mock OIDC issuer %s berjalan di %s (client_id=%s)", s.issuer, addr, s.clientID)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+func (s *server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"userinfo_endpoint":                     s.issuer + "/userinfo",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+	})
+}
+
+func (s *server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<html><head><title>Mock SSO Kampus</title></head>
+<body style="font-family:sans-serif;max-width:420px;margin:40px auto">
+<h2>Mock SSO Kampus</h2>
+<form method="post" action="/authorize">
+{{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{$v}}">
+{{end}}
+<p><label>Subject (sub)<br><input name="sub" value="mhs-001" required></label></p>
+<p><label>Email<br><input name="email" value="mahasiswa@kampus.ac.id" required></label></p>
+<p><label>Nama<br><input name="name" value="Mahasiswa Uji"></label></p>
+<p><label>Username<br><input name="preferred_username" value="mahasiswa.uji"></label></p>
+<p><label>Jurusan<br><input name="department" value="Teknik Informatika"></label></p>
+<p><label>NIM / NIP<br><input name="identity_number" value="2201001"></label></p>
+<p><label><input type="checkbox" name="email_verified" value="true" checked> Email terverifikasi</label></p>
+<button type="submit">Login</button>
+</form>
+</body></html>`))
+
+// authorize: GET menampilkan form, POST menerbitkan authorization code
+func (s *server) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	q := r.Form
+	if q.Get("response_type") != "code" || q.Get("client_id") != s.clientID || q.Get("redirect_uri") == "" {
+		http.Error(w, "response_type, client_id, atau redirect_uri tidak valid", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE (S256) wajib", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		params := map[string]string{}
+		for _, k := range []string{"response_type", "client_id", "redirect_uri", "scope", "state", "nonce", "code_challenge", "code_challenge_method"} {
+			params[k] = q.Get(k)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = loginPage.Execute(w, map[string]interface{}{"Params": params})
+		return
+	}
+
+	claims := map[string]interface{}{
+		"sub":            q.Get("sub"),
+		"email":          q.Get("email"),
+		"email_verified": q.Get("email_verified") == "true",
+	}
+	for _, k := range []string{"name", "preferred_username", "department", "identity_number"} {
+		if v := q.Get(k); v != "" {
+			claims[k] = v
+		}
+	}
+
+	code := randomString()
+	s.mu.Lock()
+	s.codes[code] = authCode{
+		ClientID:      q.Get("client_id"),
+		RedirectURI:   q.Get("redirect_uri"),
+		CodeChallenge: q.Get("code_challenge"),
+		Nonce:         q.Get("nonce"),
+		Claims:        claims,
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+	s.mu.Unlock()
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "redirect_uri tidak valid", http.StatusBadRequest)
+		return
+	}
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token: tukar code + code_verifier dengan id_token (RS256)
+func (s *server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	if s.secret != "" {
+		id, secret, ok := r.BasicAuth()
+		if !ok {
+			id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+		if id != s.clientID || secret != s.secret {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+			return
+		}
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	ac, ok := s.codes[code]
+	delete(s.codes, code) // code hanya sekali pakai
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(ac.ExpiresAt) || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	if r.PostForm.Get("redirect_uri") != ac.RedirectURI || r.PostForm.Get("client_id") != ac.ClientID {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "redirect_uri / client_id tidak cocok"})
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != ac.CodeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "code_verifier salah"})
+		return
+	}
+
+	now := time.Now()
+	idClaims := jwt.MapClaims{
+		"iss": s.issuer,
+		"aud": ac.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	if ac.Nonce != "" {
+		idClaims["nonce"] = ac.Nonce
+	}
+	for k, v := range ac.Claims {
+		idClaims[k] = v
+	}
+
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, idClaims)
+	tok.Header["kid"] = keyID
+	idToken, err := tok.SignedString(s.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	accessToken := randomString()
+	s.mu.Lock()
+	s.tokens[accessToken] = ac.Claims
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (s *server) userinfo(w http.ResponseWriter, r *http.Request) {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(prefix) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+
+	s.mu.Lock()
+	claims, ok := s.tokens[auth[len(prefix):]]
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+	writeJSON(w, http.StatusOK, claims)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	buf := make([]byte, 24)
+	_, _ = rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"log"
+	"os"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/joho/godotenv"
 
 	// Import Library Swagger
@@ -14,12 +16,21 @@ import (
 	"github.com/gofiber/swagger"
 
 	"campus-reservation-backend/internal/auth"
+	"campus-reservation-backend/internal/billing"
 	"campus-reservation-backend/internal/booking"
+	"campus-reservation-backend/internal/campus"
 	"campus-reservation-backend/internal/dashboard"
 	"campus-reservation-backend/internal/database"
+	"campus-reservation-backend/internal/document"
+	"campus-reservation-backend/internal/equipment"
 	"campus-reservation-backend/internal/facility"
+	"campus-reservation-backend/internal/media"
+	"campus-reservation-backend/internal/payment"
 	"campus-reservation-backend/internal/profile"
+	"campus-reservation-backend/internal/realtime"
+	"campus-reservation-backend/internal/storage"
 	"campus-reservation-backend/internal/user"
+	"campus-reservation-backend/internal/webhook"
 )
 
 // @title           Campus Reservation API
@@ -52,6 +63,24 @@ func main() {
 	db := database.Connect()
 	defer db.Close()
 
+	if err := database.Migrate(db); err != nil {
+		log.Fatal("Gagal menjalankan migrasi database:", err)
+	}
+
+	// Teruskan perubahan booking (LISTEN/NOTIFY) ke client SSE
+	realtime.Start(os.Getenv("DATABASE_URL"))
+
+	// Rate limit OTP default disimpan di Postgres (aman untuk multi instance).
+	// Set RATE_LIMIT_STORE=memory untuk menyimpan di memori (single instance / development).
+	if os.Getenv("RATE_LIMIT_STORE") == "memory" {
+		auth.SetLimitStore(auth.NewMemoryLimitStore())
+	}
+
+	// Penyimpanan file upload (lokal / S3-compatible); konfigurasi salah dihentikan sejak awal
+	if _, err := storage.Active(); err != nil {
+		log.Fatal("Konfigurasi storage tidak valid:", err)
+	}
+
 	// ==========================
 	// 3. INIT FIBER APP
 	// ==========================
@@ -64,8 +93,10 @@ func main() {
 	// ==========================
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "http://localhost:3001, http://localhost:3000",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-API-Key",
 		AllowMethods: "GET, POST, HEAD, PUT, DELETE, PATCH",
+		// Header paginasi daftar fasilitas harus bisa dibaca JavaScript di browser
+		ExposeHeaders: "X-Total-Count, X-Total-Pages, X-Page",
 	}))
 
 	// ==========================
@@ -74,9 +105,12 @@ func main() {
 	app.Get("/swagger/*", swagger.HandlerDefault)
 
 	// ==========================
-	// 3.3. SETUP STATIC FOLDER
+	// 3.3. SETUP STATIC FOLDER & STORAGE FILE
 	// ==========================
+	// ./uploads hanya untuk file lama; upload baru disimpan lewat storage (STORAGE_DRIVER=local|s3)
 	app.Static("/uploads", "./uploads")
+	// Backend lokal: file publik & signed URL file privat
+	app.Get("/files/*", storage.FileHandler())
 
 	// ==========================
 	// 4. PUBLIC ROUTES
@@ -95,12 +129,42 @@ func main() {
 	app.Post("/auth/register/request-otp", auth.RequestRegisterOTPHandler(db))
 	app.Post("/auth/register/verify-otp", auth.VerifyRegisterOTPHandler(db))
 
+	// SSO KAMPUS (OPENID CONNECT)
+	app.Get("/auth/oidc/login", auth.OIDCLoginHandler(db))
+	app.Get("/auth/oidc/callback", auth.OIDCCallbackHandler(db))
+
+	// LOGIN LANGKAH KEDUA (2FA)
+	app.Post("/auth/2fa/verify", auth.VerifyTwoFactorLoginHandler(db))
+
+	// LUPA PASSWORD (OTP WA / LINK EMAIL)
+	app.Post("/auth/reset-password/request-otp", auth.RequestResetOTPHandler(db))
+	app.Post("/auth/reset-password/verify-otp", auth.VerifyResetOTPHandler(db))
+	app.Post("/auth/reset-password/request-link", auth.RequestResetLinkHandler(db))
+	app.Post("/auth/reset-password/confirm", auth.ConfirmResetLinkHandler(db))
+
+	// CALLBACK PAYMENT GATEWAY (diverifikasi lewat signature) & HALAMAN SIMULATOR PEMBAYARAN
+	app.Post("/payments/callback/:provider", payment.CallbackHandler(db))
+	if payment.SimulatorEnabled() {
+		app.Get("/payments/simulator/:order_id", payment.SimulatorPageHandler())
+		app.Post("/payments/simulator/:order_id", payment.SimulatorActionHandler())
+	}
+
+	// BOOKING TAMU (ORGANISASI LUAR KAMPUS, TANPA LOGIN). Pengajuan dibatasi per IP.
+	app.Post("/guest-bookings", limiter.New(limiter.Config{Max: 5, Expiration: time.Hour}), booking.CreateGuestBookingHandler(db))
+	app.Get("/guest-bookings/:token", booking.GuestBookingStatusHandler(db))
+	app.Post("/guest-bookings/:token/payments", booking.GuestBookingPaymentHandler(db))
+	app.Post("/guest-bookings/:token/documents", booking.GuestBookingDocumentHandler(db))
+	app.Post("/guest-bookings/:token/cancel", booking.CancelGuestBookingHandler(db))
+
+	// VERIFIKASI TIKET & SURAT KONFIRMASI (DARI QR / LINK DI DOKUMEN). Dibatasi per IP agar kode tidak ditebak massal.
+	app.Get("/verify/:code", limiter.New(limiter.Config{Max: 30, Expiration: time.Minute}), booking.VerifyTicketHandler(db))
+
 	// ==========================
 	// 5. PROTECTED ROUTES (JWT)
 	// ==========================
 
 	// Endpoint /me (Profile)
-	app.Get("/me", auth.JWTProtected(), func(c *fiber.Ctx) error {
+	app.Get("/me", auth.JWTProtected(db), func(c *fiber.Ctx) error {
 		userVal := c.Locals("user_id")
 
 		if userVal == nil {
@@ -128,65 +192,175 @@ func main() {
 			"name":       userData.Name,
 			"email":      userData.Email,
 			"role":       userData.Role,
+			"campus_id":  userData.AdminCampusID, // kosong = admin pusat / user biasa
 			"avatar_url": avatarURL,
 		})
 	})
 
 	// Change Password
-	app.Post("/users/change-password", auth.JWTProtected(), user.ChangePasswordHandler(db))
+	app.Post("/users/change-password", auth.JWTProtected(db), user.ChangePasswordHandler(db))
 	// Change Email (BARU)
-	app.Patch("/users/change-email", auth.JWTProtected(), user.ChangeEmailHandler(db))
+	app.Patch("/users/change-email", auth.JWTProtected(db), user.ChangeEmailHandler(db))
 	// Change Phone (OTP)
-	app.Post("/users/change-phone/request-otp", auth.JWTProtected(), auth.RequestChangePhoneOTPHandler(db))
-	app.Post("/users/change-phone/verify-otp", auth.JWTProtected(), auth.VerifyChangePhoneOTPHandler(db))
+	app.Post("/users/change-phone/request-otp", auth.JWTProtected(db), auth.RequestChangePhoneOTPHandler(db))
+	app.Post("/users/change-phone/verify-otp", auth.JWTProtected(db), auth.VerifyChangePhoneOTPHandler(db))
+
+	// Two-Factor Authentication (TOTP)
+	app.Get("/auth/2fa", auth.JWTProtected(db), auth.TwoFactorStatusHandler(db))
+	app.Post("/auth/2fa/setup", auth.JWTProtected(db), auth.SetupTwoFactorHandler(db))
+	app.Post("/auth/2fa/enable", auth.JWTProtected(db), auth.EnableTwoFactorHandler(db))
+	app.Post("/auth/2fa/disable", auth.JWTProtected(db), auth.DisableTwoFactorHandler(db))
+	app.Post("/auth/2fa/recovery-codes", auth.JWTProtected(db), auth.RegenerateRecoveryCodesHandler(db))
 
 	// ==========================
 	// 6. FACILITY ROUTES
 	// ==========================
-	app.Post("/facilities", auth.JWTProtected(), auth.RequireRole("admin"), facility.CreateHandler(db))
-	app.Put("/facilities/:id", auth.JWTProtected(), auth.RequireRole("admin"), facility.UpdateHandler(db))
-	app.Patch("/facilities/:id/status", auth.JWTProtected(), auth.RequireRole("admin"), facility.ToggleStatusHandler(db))
-	app.Delete("/facilities/:id", auth.JWTProtected(), auth.RequireRole("admin"), facility.DeleteHandler(db))
-	app.Get("/facilities", auth.JWTProtected(), facility.ListHandler(db))
-	app.Get("/facilities/:id", auth.JWTProtected(), facility.GetOneHandler(db))
+	app.Post("/facilities", auth.JWTProtected(db), auth.RequireRole("admin"), facility.CreateHandler(db))
+	app.Put("/facilities/:id", auth.JWTProtected(db), auth.RequireRole("admin"), facility.UpdateHandler(db))
+	app.Patch("/facilities/:id/walk-up-policy", auth.JWTProtected(db), auth.RequireRole("admin"), facility.UpdateWalkUpPolicyHandler(db))
+	app.Patch("/facilities/:id/status", auth.JWTProtected(db), auth.RequireRole("admin"), facility.ToggleStatusHandler(db))
+	app.Delete("/facilities/:id", auth.JWTProtected(db), auth.RequireRole("admin"), facility.DeleteHandler(db))
+	app.Get("/facilities", auth.JWTProtected(db), facility.ListHandler(db))
+	app.Get("/facilities/filters", auth.JWTProtected(db), facility.FiltersHandler(db))
+	app.Get("/facilities/:id", auth.JWTProtected(db), facility.GetOneHandler(db))
+	app.Get("/facilities/:id/opening-hours", auth.JWTProtected(db), campus.GetFacilityHoursHandler(db))
+	app.Put("/facilities/:id/opening-hours", auth.JWTProtected(db), auth.RequireRole("admin"), campus.UpdateFacilityHoursHandler(db))
+
+	// Hierarki Kampus -> Gedung -> Lantai
+	app.Get("/campuses", auth.JWTProtected(db), campus.ListCampusesHandler(db))
+	app.Get("/campuses/:id", auth.JWTProtected(db), campus.GetCampusHandler(db))
+	app.Get("/campuses/:id/buildings", auth.JWTProtected(db), campus.ListBuildingsHandler(db))
+	app.Post("/campuses", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), campus.CreateCampusHandler(db))
+	app.Put("/campuses/:id", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), campus.UpdateCampusHandler(db))
+	app.Delete("/campuses/:id", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), campus.DeleteCampusHandler(db))
+	app.Get("/buildings", auth.JWTProtected(db), campus.ListBuildingsHandler(db))
+	app.Get("/buildings/:id", auth.JWTProtected(db), campus.GetBuildingHandler(db))
+	app.Get("/buildings/:id/floors", auth.JWTProtected(db), campus.ListFloorsHandler(db))
+	app.Post("/buildings", auth.JWTProtected(db), auth.RequireRole("admin"), campus.CreateBuildingHandler(db))
+	app.Put("/buildings/:id", auth.JWTProtected(db), auth.RequireRole("admin"), campus.UpdateBuildingHandler(db))
+	app.Delete("/buildings/:id", auth.JWTProtected(db), auth.RequireRole("admin"), campus.DeleteBuildingHandler(db))
+	app.Get("/floors/:id/facilities", auth.JWTProtected(db), campus.ListFloorFacilitiesHandler(db))
+	app.Post("/floors", auth.JWTProtected(db), auth.RequireRole("admin"), campus.CreateFloorHandler(db))
+	app.Put("/floors/:id", auth.JWTProtected(db), auth.RequireRole("admin"), campus.UpdateFloorHandler(db))
+	app.Delete("/floors/:id", auth.JWTProtected(db), auth.RequireRole("admin"), campus.DeleteFloorHandler(db))
 
 	// ==========================
 	// 7. BOOKING ROUTES
 	// ==========================
-	app.Post("/bookings", auth.JWTProtected(), auth.RequireRole("user"), booking.CreateHandler(db))
-	app.Delete("/bookings/:id", auth.JWTProtected(), auth.RequireRole("user"), booking.CancelHandler(db))
-	app.Get("/bookings/:id/ticket", auth.JWTProtected(), booking.DownloadTicketHandler(db))
-	app.Get("/facilities/:id/schedule", auth.JWTProtected(), booking.GetFacilityScheduleHandler(db))
-	app.Get("/bookings/me", auth.JWTProtected(), auth.RequireRole("user"), booking.MyBookingsHandler(db))
-	app.Post("/bookings/:id/review", auth.JWTProtected(), auth.RequireRole("user"), booking.SubmitReviewHandler(db))
+	app.Post("/bookings", auth.JWTProtected(db), auth.RequireRole("user"), booking.CreateHandler(db))
+	app.Delete("/bookings/:id", auth.JWTProtected(db), auth.RequireRole("user"), booking.CancelHandler(db))
+	app.Get("/bookings/:id/ticket", auth.JWTProtected(db), booking.DownloadTicketHandler(db))
+	app.Get("/facilities/:id/schedule", auth.JWTOrAPIKey(db, auth.ScopeScheduleRead), booking.GetFacilityScheduleHandler(db))
+	app.Get("/facilities/:id/stream", auth.TokenFromQuery(), auth.JWTOrAPIKey(db, auth.ScopeScheduleRead), realtime.FacilityStreamHandler())
+	app.Post("/facilities/:id/walk-up", auth.JWTProtected(db), auth.RequireRole("user"), booking.WalkUpHandler(db))
+	app.Get("/bookings/:id/attendees", auth.JWTProtected(db), booking.ListAttendeesHandler(db))
+	app.Post("/bookings/:id/attendees", auth.JWTProtected(db), booking.AddAttendeesHandler(db))
+	app.Post("/bookings/:id/attendees/import", auth.JWTProtected(db), booking.ImportAttendeesHandler(db))
+	app.Delete("/bookings/:id/attendees/:attendeeId", auth.JWTProtected(db), booking.DeleteAttendeeHandler(db))
+	app.Get("/attendee-tickets/:code", booking.DownloadAttendeeTicketHandler(db))
+	app.Get("/bookings/me", auth.JWTProtected(db), auth.RequireRole("user"), booking.MyBookingsHandler(db))
+	app.Post("/bookings/:id/review", auth.JWTProtected(db), auth.RequireRole("user"), booking.SubmitReviewHandler(db))
 
 	// Admin Routes for Bookings
-	app.Get("/bookings", auth.JWTProtected(), auth.RequireRole("admin"), booking.ListAllHandler(db))
-	app.Patch("/bookings/:id/status", auth.JWTProtected(), auth.RequireRole("admin"), booking.UpdateStatusHandler(db))
-	app.Get("/admin/reviews", auth.JWTProtected(), auth.RequireRole("admin"), booking.GetAdminReviewsHandler(db))
-	app.Post("/bookings/verify-ticket", auth.JWTProtected(), auth.RequireRole("admin"), booking.CheckInHandler(db))
-	app.Get("/admin/attendance", auth.JWTProtected(), auth.RequireRole("admin"), booking.GetAttendanceLogsHandler(db))
-	app.Get("/admin/attendance/export", auth.JWTProtected(), auth.RequireRole("admin"), booking.ExportAttendanceHandler(db))
+	app.Get("/bookings", auth.JWTProtected(db), auth.RequireRole("admin"), booking.ListAllHandler(db))
+	app.Patch("/bookings/:id/status", auth.JWTProtected(db), auth.RequireRole("admin"), booking.UpdateStatusHandler(db))
+	app.Get("/admin/guest-bookings", auth.JWTProtected(db), auth.RequireRole("admin"), booking.ListGuestBookingsHandler(db))
+	app.Get("/admin/guest-bookings/:id", auth.JWTProtected(db), auth.RequireRole("admin"), booking.GetGuestBookingHandler(db))
+	app.Get("/admin/guest-bookings/:id/document", auth.JWTProtected(db), auth.RequireRole("admin"), booking.GuestDocumentHandler(db))
+	app.Get("/admin/bookings/stream", auth.TokenFromQuery(), auth.JWTProtected(db), auth.RequireRole("admin"), realtime.AdminStreamHandler())
+	app.Get("/admin/reviews", auth.JWTProtected(db), auth.RequireRole("admin"), booking.GetAdminReviewsHandler(db))
+	app.Post("/bookings/verify-ticket", auth.JWTOrAPIKey(db, auth.ScopeTicketsScan), auth.RequireRole("admin"), booking.CheckInHandler(db))
+	app.Get("/admin/attendance", auth.JWTProtected(db), auth.RequireRole("admin"), booking.GetAttendanceLogsHandler(db))
+	app.Get("/admin/attendance/export", auth.JWTProtected(db), auth.RequireRole("admin"), booking.ExportAttendanceHandler(db))
+
+	// Layar Pintu Ruangan (Token Display)
+	app.Get("/display/facilities/:id", auth.TokenFromQuery(), auth.JWTOrAPIKey(db, auth.ScopeDisplayRead), booking.FacilityDisplayHandler(db))
+	app.Get("/display/facilities/:id/stream", auth.TokenFromQuery(), auth.JWTOrAPIKey(db, auth.ScopeDisplayRead), booking.FacilityDisplayStreamHandler(db))
+	app.Get("/display/facilities/:id/walk-up-qr", auth.TokenFromQuery(), auth.JWTOrAPIKey(db, auth.ScopeDisplayRead), booking.WalkUpQRHandler(db))
+
+	// Peminjaman Peralatan (bersama booking ruangan atau berdiri sendiri)
+	app.Get("/equipment", auth.JWTProtected(db), equipment.ListHandler(db))
+	app.Post("/equipment", auth.JWTProtected(db), auth.RequireRole("admin"), equipment.CreateHandler(db))
+	app.Put("/equipment/:id", auth.JWTProtected(db), auth.RequireRole("admin"), equipment.UpdateHandler(db))
+	app.Delete("/equipment/:id", auth.JWTProtected(db), auth.RequireRole("admin"), equipment.DeleteHandler(db))
+	app.Post("/bookings/:id/equipment", auth.JWTProtected(db), equipment.AttachToBookingHandler(db))
+	app.Post("/equipment-reservations", auth.JWTProtected(db), equipment.CreateReservationHandler(db))
+	app.Get("/equipment-reservations", auth.JWTProtected(db), equipment.ListReservationsHandler(db))
+	app.Get("/equipment-reservations/:id", auth.JWTProtected(db), equipment.GetReservationHandler(db))
+	app.Delete("/equipment-reservations/:id", auth.JWTProtected(db), equipment.CancelReservationHandler(db))
+	app.Patch("/equipment-reservations/:id/status", auth.JWTProtected(db), auth.RequireRole("admin"), equipment.UpdateReservationStatusHandler(db))
+	app.Post("/equipment-reservations/:id/checkout", auth.JWTProtected(db), auth.RequireRole("admin"), equipment.CheckoutReservationHandler(db))
+	app.Post("/equipment-reservations/:id/return", auth.JWTProtected(db), auth.RequireRole("admin"), equipment.ReturnReservationHandler(db))
+
+	// Harga & Invoice (Booking Berbayar)
+	app.Get("/facilities/:id/quote", auth.JWTProtected(db), billing.QuoteHandler(db))
+	app.Get("/bookings/:id/invoice", auth.JWTProtected(db), billing.BookingBillingHandler(db))
+	app.Get("/invoices/me", auth.JWTProtected(db), billing.MyInvoicesHandler(db))
+	app.Get("/invoices/:id", auth.JWTProtected(db), billing.GetInvoiceHandler(db))
+	app.Post("/invoices/:id/payments", auth.JWTProtected(db), auth.RequireRole("user"), payment.CreateLinkHandler(db))
+	app.Get("/invoices/:id/payments", auth.JWTProtected(db), payment.ListInvoicePaymentsHandler(db))
+	app.Get("/payments/:id", auth.JWTProtected(db), payment.GetPaymentHandler(db))
+	app.Get("/admin/invoices", auth.JWTProtected(db), auth.RequireRole("admin"), billing.ListInvoicesHandler(db))
+	app.Post("/admin/invoices/:id/pay", auth.JWTProtected(db), auth.RequireRole("admin"), billing.PayInvoiceHandler(db))
+	app.Get("/admin/pricing-rules", auth.JWTProtected(db), auth.RequireRole("admin"), billing.ListRulesHandler(db))
+	app.Post("/admin/pricing-rules", auth.JWTProtected(db), auth.RequireRole("admin"), billing.CreateRuleHandler(db))
+	app.Put("/admin/pricing-rules/:id", auth.JWTProtected(db), auth.RequireRole("admin"), billing.UpdateRuleHandler(db))
+	app.Delete("/admin/pricing-rules/:id", auth.JWTProtected(db), auth.RequireRole("admin"), billing.DeleteRuleHandler(db))
+
+	// Dokumen Pendukung Booking (surat izin, proposal) & dokumen wajib per fasilitas
+	app.Get("/facilities/:id/document-requirements", auth.JWTProtected(db), document.ListRequirementsHandler(db))
+	app.Post("/facilities/:id/document-requirements", auth.JWTProtected(db), auth.RequireRole("admin"), document.CreateRequirementHandler(db))
+	app.Delete("/facilities/:id/document-requirements/:reqId", auth.JWTProtected(db), auth.RequireRole("admin"), document.DeleteRequirementHandler(db))
+	app.Get("/bookings/:id/documents", auth.JWTProtected(db), document.ListHandler(db))
+	app.Post("/bookings/:id/documents", auth.JWTProtected(db), document.UploadHandler(db))
+	app.Get("/bookings/:id/documents/:docId", auth.JWTProtected(db), document.DownloadHandler(db))
+	app.Delete("/bookings/:id/documents/:docId", auth.JWTProtected(db), document.DeleteHandler(db))
 
 	// ==========================
 	// 8. USER ROUTES (ADMIN)
 	// ==========================
-	app.Get("/users", auth.JWTProtected(), auth.RequireRole("admin"), user.ListHandler(db))
-	app.Get("/users/:id", auth.JWTProtected(), auth.RequireRole("admin"), user.GetOneHandler(db))
-	app.Patch("/users/:id/role", auth.JWTProtected(), auth.RequireRole("admin"), user.UpdateRoleHandler(db))
-	app.Delete("/users/:id", auth.JWTProtected(), auth.RequireRole("admin"), user.DeleteUserHandler(db))
+	app.Get("/users", auth.JWTProtected(db), auth.RequireRole("admin"), user.ListHandler(db))
+	app.Get("/users/:id", auth.JWTProtected(db), auth.RequireRole("admin"), user.GetOneHandler(db))
+	// Mengubah role / kampus / menghapus user berlaku lintas kampus: khusus admin pusat
+	app.Patch("/users/:id/role", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), user.UpdateRoleHandler(db))
+	app.Patch("/users/:id/campus", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), user.UpdateAdminCampusHandler(db))
+	app.Patch("/users/:id/type", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), user.UpdateUserTypeHandler(db))
+	app.Delete("/users/:id", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), user.DeleteUserHandler(db))
+
+	// Keamanan Login (Lockout & Riwayat)
+	app.Get("/admin/lockouts", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), auth.ListLockoutsHandler(db))
+	app.Delete("/admin/lockouts", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), auth.ClearLockoutHandler(db))
+	app.Get("/admin/login-attempts", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), auth.ListLoginAttemptsHandler(db))
+	app.Delete("/admin/users/:id/2fa", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), auth.ResetUserTwoFactorHandler(db))
+
+	// API Key (Kiosk Scanner & Integrasi)
+	app.Post("/admin/api-keys", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), auth.CreateAPIKeyHandler(db))
+	app.Get("/admin/api-keys", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), auth.ListAPIKeysHandler(db))
+	app.Delete("/admin/api-keys/:id", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), auth.RevokeAPIKeyHandler(db))
+
+	// Webhook Keluar (Integrasi Portal Jurusan / Keamanan)
+	app.Post("/admin/webhooks", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), webhook.CreateHandler(db))
+	app.Get("/admin/webhooks", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), webhook.ListHandler(db))
+	app.Put("/admin/webhooks/:id", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), webhook.UpdateHandler(db))
+	app.Delete("/admin/webhooks/:id", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), webhook.DeleteHandler(db))
+	app.Post("/admin/webhooks/:id/rotate-secret", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), webhook.RotateSecretHandler(db))
+	app.Post("/admin/webhooks/:id/ping", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), webhook.PingHandler(db))
+	app.Get("/admin/webhooks/:id/deliveries", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), webhook.ListDeliveriesHandler(db))
+	app.Get("/admin/webhook-deliveries/:id", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), webhook.GetDeliveryHandler(db))
+	app.Post("/admin/webhook-deliveries/:id/redeliver", auth.JWTProtected(db), auth.RequireRole("admin"), auth.RequireCentralAdmin(), webhook.RedeliverHandler(db))
 
 	// ==========================
 	// 9. DASHBOARD STATS (ADMIN)
 	// ==========================
-	app.Get("/dashboard/stats", auth.JWTProtected(), auth.RequireRole("admin"), dashboard.DashboardHandler(db))
+	app.Get("/dashboard/stats", auth.JWTProtected(db), auth.RequireRole("admin"), dashboard.DashboardHandler(db))
+	app.Get("/admin/reports/buildings", auth.JWTProtected(db), auth.RequireRole("admin"), campus.BuildingReportHandler(db))
 
 	// ==========================
 	// 10. PROFILE ROUTES
 	// ==========================
-	app.Get("/profile", auth.JWTProtected(), profile.GetHandler(db))
-	app.Put("/profile", auth.JWTProtected(), profile.UpdateHandler(db))
-	app.Post("/profile/avatar", auth.JWTProtected(), profile.UploadAvatarHandler)
+	app.Get("/profile", auth.JWTProtected(db), profile.GetHandler(db))
+	app.Put("/profile", auth.JWTProtected(db), profile.UpdateHandler(db))
+	app.Post("/profile/avatar", auth.JWTProtected(db), profile.UploadAvatarHandler)
 
 	// ==========================
 	// 11. WORKER: AUTO CHECK-OUT
@@ -204,6 +378,69 @@ func main() {
 		}
 	}()
 
+	// ==========================
+	// 12. WORKER: PENGIRIMAN WEBHOOK
+	// ==========================
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+
+		log.Println("Worker Webhook Started...")
+
+		for range ticker.C {
+			if err := webhook.ProcessQueue(db); err != nil {
+				log.Printf("Error processing webhook queue: %v\n", err)
+			}
+		}
+	}()
+
+	// ==========================
+	// 13. WORKER: CEK STATUS PEMBAYARAN
+	// ==========================
+	go func() {
+		ticker := time.NewTicker(1 * time.Minute)
+		defer ticker.Stop()
+
+		log.Println("Worker Payment Polling Started...")
+
+		for range ticker.C {
+			if err := payment.PollPending(db); err != nil {
+				log.Printf("Error polling payments: %v\n", err)
+			}
+		}
+	}()
+
+	// ==========================
+	// 14. WORKER: PEMBERSIHAN FILE YATIM
+	// ==========================
+	go func() {
+		ticker := time.NewTicker(6 * time.Hour)
+		defer ticker.Stop()
+
+		log.Println("Worker Storage Cleanup Started...")
+
+		for range ticker.C {
+			removed, err := storage.CleanupOrphans(db)
+			if err != nil {
+				log.Printf("Error cleaning up orphaned files: %v\n", err)
+			} else if removed > 0 {
+				log.Printf("Storage cleanup: %d file yatim dihapus\n", removed)
+			}
+		}
+	}()
+
+	// ==========================
+	// 15. KONVERSI FOTO LAMA KE VARIAN WEBP (SEKALI SAAT STARTUP)
+	// ==========================
+	go func() {
+		converted, err := media.BackfillVariants(db)
+		if err != nil {
+			log.Printf("Error converting legacy images: %v\n", err)
+		} else if converted > 0 {
+			log.Printf("Konversi gambar: %d foto/avatar lama dibuatkan varian WebP\n", converted)
+		}
+	}()
+
 	// ==========================
 	// RUN SERVER
 	// ==========================
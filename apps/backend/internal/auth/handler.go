@@ -58,6 +58,7 @@ func RegisterHandler(db *sql.DB) fiber.Handler {
 // @Success      200  {object}  LoginResponse
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
 // @Router       /auth/login [post]
 func LoginHandler(db *sql.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -70,12 +71,13 @@ func LoginHandler(db *sql.DB) fiber.Handler {
 			})
 		}
 
-		// 2. Panggil service login
+		// 2. Panggil service login (IP & User-Agent untuk lockout dan deteksi perangkat baru)
+		req.IP = c.IP()
+		req.UserAgent = c.Get("User-Agent")
+
 		res, err := Login(db, req)
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": err.Error(),
-			})
+			return rateLimitAwareError(c, err, fiber.StatusUnauthorized)
 		}
 
 		// 3. Response token
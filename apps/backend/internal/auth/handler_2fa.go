@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ==========================================
+// HANDLER FUNCTIONS (2FA / TOTP)
+// ==========================================
+
+// TwoFactorStatusHandler menampilkan status 2FA user yang login
+// @Summary      Status 2FA
+// @Description  Menampilkan apakah 2FA aktif, wajib, dan sisa kode pemulihan.
+// @Tags         Auth 2FA
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  TwoFactorStatus
+// @Failure      401  {object}  map[string]string
+// @Router       /auth/2fa [get]
+func TwoFactorStatusHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		status, err := GetTwoFactorStatus(db, userID)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": "user tidak ditemukan"})
+		}
+		return c.JSON(status)
+	}
+}
+
+// SetupTwoFactorHandler memulai enrollment 2FA
+// @Summary      Setup 2FA
+// @Description  Membuat secret TOTP baru dan QR code untuk dipindai aplikasi authenticator.
+// @Tags         Auth 2FA
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  TwoFactorSetupResponse
+// @Failure      400  {object}  map[string]string
+// @Router       /auth/2fa/setup [post]
+func SetupTwoFactorHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		res, err := SetupTwoFactor(db, userID)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(res)
+	}
+}
+
+// EnableTwoFactorHandler mengonfirmasi enrollment 2FA
+// @Summary      Aktifkan 2FA
+// @Description  Konfirmasi kode pertama dari aplikasi authenticator. Mengembalikan kode pemulihan (hanya ditampilkan sekali) dan token baru.
+// @Tags         Auth 2FA
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body TwoFactorCodeRequest true "Kode TOTP"
+// @Success      200  {object}  TwoFactorEnableResponse
+// @Failure      400  {object}  map[string]string
+// @Router       /auth/2fa/enable [post]
+func EnableTwoFactorHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		var req TwoFactorCodeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		res, err := EnableTwoFactor(db, userID, req.Code)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(res)
+	}
+}
+
+// DisableTwoFactorHandler mematikan 2FA
+// @Summary      Nonaktifkan 2FA
+// @Description  Mematikan 2FA dengan konfirmasi password dan kode TOTP / kode pemulihan.
+// @Tags         Auth 2FA
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body TwoFactorDisableRequest true "Password & Kode"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Router       /auth/2fa/disable [post]
+func DisableTwoFactorHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		var req TwoFactorDisableRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		if err := DisableTwoFactor(db, userID, req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"message": "2FA berhasil dinonaktifkan"})
+	}
+}
+
+// RegenerateRecoveryCodesHandler membuat ulang kode pemulihan
+// @Summary      Buat Ulang Kode Pemulihan
+// @Description  Mengganti semua kode pemulihan lama dengan 10 kode baru.
+// @Tags         Auth 2FA
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body TwoFactorCodeRequest true "Kode TOTP"
+// @Success      200  {object}  map[string][]string
+// @Failure      400  {object}  map[string]string
+// @Router       /auth/2fa/recovery-codes [post]
+func RegenerateRecoveryCodesHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		var req TwoFactorCodeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		codes, err := RegenerateRecoveryCodes(db, userID, req.Code)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"recovery_codes": codes})
+	}
+}
+
+// VerifyTwoFactorLoginHandler adalah langkah kedua login
+// @Summary      Verifikasi 2FA Login
+// @Description  Tukar challenge_token dari /auth/login (atau /auth/login/verify-otp) + kode TOTP / kode pemulihan dengan Token JWT.
+// @Tags         Auth 2FA
+// @Accept       json
+// @Produce      json
+// @Param        request body TwoFactorVerifyRequest true "Challenge & Kode"
+// @Success      200  {object}  LoginResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /auth/2fa/verify [post]
+func VerifyTwoFactorLoginHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req TwoFactorVerifyRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		req.IP = c.IP()
+		req.UserAgent = c.Get("User-Agent")
+
+		res, err := VerifyTwoFactorLogin(db, req)
+		if err != nil {
+			return rateLimitAwareError(c, err, fiber.StatusUnauthorized)
+		}
+		return c.JSON(res)
+	}
+}
+
+// ResetUserTwoFactorHandler dipakai admin untuk mereset 2FA user
+// @Summary      Reset 2FA User (Admin)
+// @Description  Menghapus 2FA & kode pemulihan user (misal HP hilang). User akan diberi notifikasi.
+// @Tags         Admin Security
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "ID User"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Router       /admin/users/{id}/2fa [delete]
+func ResetUserTwoFactorHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := ResetTwoFactor(db, c.Params("id")); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"message": "2FA user berhasil direset"})
+	}
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ==========================================
+// HANDLER FUNCTIONS (API KEY - ADMIN)
+// ==========================================
+
+// CreateAPIKeyHandler membuat API key baru untuk kiosk / integrasi
+// @Summary      Buat API Key
+// @Description  Membuat API key dengan scope terbatas (tickets:scan, schedule:read), opsional dibatasi ke satu fasilitas. Key hanya ditampilkan sekali.
+// @Tags         Admin API Keys
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body CreateAPIKeyRequest true "Data API Key"
+// @Success      201  {object}  CreateAPIKeyResponse
+// @Failure      400  {object}  map[string]string
+// @Router       /admin/api-keys [post]
+func CreateAPIKeyHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID, _ := c.Locals("user_id").(string)
+
+		var req CreateAPIKeyRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		res, err := CreateAPIKey(db, adminID, req)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusCreated).JSON(res)
+	}
+}
+
+// ListAPIKeysHandler menampilkan semua API key
+// @Summary      Daftar API Key
+// @Description  Menampilkan semua API key beserta scope, masa berlaku, dan waktu terakhir dipakai.
+// @Tags         Admin API Keys
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   APIKey
+// @Router       /admin/api-keys [get]
+func ListAPIKeysHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		keys, err := ListAPIKeys(db)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Gagal memuat API key"})
+		}
+		return c.JSON(keys)
+	}
+}
+
+// RevokeAPIKeyHandler mencabut API key
+// @Summary      Cabut API Key
+// @Tags         Admin API Keys
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "ID API Key"
+// @Success      200  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/api-keys/{id} [delete]
+func RevokeAPIKeyHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID, _ := c.Locals("user_id").(string)
+
+		if err := RevokeAPIKey(db, adminID, c.Params("id")); err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"message": "API key berhasil dicabut"})
+	}
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ==========================================
+// HANDLER ADMIN: LOCKOUT & RIWAYAT LOGIN
+// ==========================================
+
+// ListLockoutsHandler menampilkan akun / IP yang sedang dikunci
+// @Summary      Daftar Lockout Aktif
+// @Description  Menampilkan key yang sedang dikunci (login:account:*, login:ip:*, otp_request:*). Filter dengan query prefix.
+// @Tags         Admin Security
+// @Produce      json
+// @Security     BearerAuth
+// @Param        prefix  query     string  false  "Awalan key, contoh: login:"
+// @Success      200     {array}   LimitEntry
+// @Failure      500     {object}  map[string]string
+// @Router       /admin/lockouts [get]
+func ListLockoutsHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		entries, err := GetActiveLockouts(db, c.Query("prefix"))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Gagal memuat data lockout"})
+		}
+		return c.JSON(entries)
+	}
+}
+
+// ClearLockoutHandler membuka kunci akun / IP
+// @Summary      Hapus Lockout
+// @Description  Membuka kunci berdasarkan key, atau berdasarkan user_id untuk lockout akun.
+// @Tags         Admin Security
+// @Produce      json
+// @Security     BearerAuth
+// @Param        key      query     string  false  "Key lockout"
+// @Param        user_id  query     string  false  "ID User"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Router       /admin/lockouts [delete]
+func ClearLockoutHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Query("key")
+		if userID := c.Query("user_id"); userID != "" {
+			key = loginAccountKey(userID, "")
+		}
+
+		if key == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "key atau user_id wajib diisi"})
+		}
+
+		if err := ClearLockout(db, key); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Gagal menghapus lockout"})
+		}
+
+		return c.JSON(fiber.Map{"message": "Lockout berhasil dihapus"})
+	}
+}
+
+// ListLoginAttemptsHandler menampilkan riwayat percobaan login
+// @Summary      Riwayat Percobaan Login
+// @Description  Menampilkan 200 percobaan login terakhir, bisa difilter per user atau IP.
+// @Tags         Admin Security
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  query     string  false  "ID User"
+// @Param        ip       query     string  false  "Alamat IP"
+// @Success      200      {array}   LoginAttempt
+// @Failure      500      {object}  map[string]string
+// @Router       /admin/login-attempts [get]
+func ListLoginAttemptsHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		attempts, err := GetLoginAttempts(db, c.Query("user_id"), c.Query("ip"))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Gagal memuat riwayat login"})
+		}
+		return c.JSON(attempts)
+	}
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"database/sql"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ==========================================
+// HANDLER FUNCTIONS (SSO KAMPUS / OIDC)
+// ==========================================
+
+// OIDCLoginHandler mengarahkan browser ke halaman login SSO kampus
+// @Summary      Login SSO (OIDC)
+// @Description  Redirect ke Identity Provider kampus (Authorization Code + PKCE). Gunakan ?mode=json untuk mendapatkan URL-nya saja.
+// @Tags         Auth SSO
+// @Produce      json
+// @Param        mode  query     string  false  "json = kembalikan URL tanpa redirect"
+// @Success      302
+// @Success      200  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /auth/oidc/login [get]
+func OIDCLoginHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authURL, state, err := StartOIDCLogin(db)
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		// Mode json: frontend wajib memanggil dengan credentials agar cookie state tersimpan
+		c.Cookie(&fiber.Cookie{
+			Name:     OIDCStateCookie,
+			Value:    state,
+			Path:     "/auth/oidc",
+			MaxAge:   int(oidcStateTTL.Seconds()),
+			Secure:   c.Protocol() == "https",
+			HTTPOnly: true,
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+
+		if c.Query("mode") == "json" {
+			return c.JSON(fiber.Map{"authorization_url": authURL})
+		}
+		return c.Redirect(authURL, fiber.StatusFound)
+	}
+}
+
+// OIDCCallbackHandler menerima redirect dari Identity Provider
+// @Summary      Callback SSO (OIDC)
+// @Description  Tukar authorization code dengan token, lalu redirect ke frontend ({FRONTEND_URL}/auth/sso/callback) dengan token / challenge_token / error di fragment URL.
+// @Tags         Auth SSO
+// @Param        code   query  string  true  "Authorization code"
+// @Param        state  query  string  true  "State"
+// @Success      302
+// @Router       /auth/oidc/callback [get]
+func OIDCCallbackHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req OIDCCallbackRequest
+		if err := c.QueryParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		req.BrowserState = c.Cookies(OIDCStateCookie)
+		req.IP = c.IP()
+		req.UserAgent = c.Get("User-Agent")
+		c.ClearCookie(OIDCStateCookie)
+
+		// Token dikirim lewat fragment (#) agar tidak tercatat di log server / header Referer
+		fragment := url.Values{}
+		res, err := CompleteOIDCLogin(db, req)
+		switch {
+		case err != nil:
+			fragment.Set("error", err.Error())
+		case res.TwoFactorRequired:
+			fragment.Set("two_factor_required", "true")
+			fragment.Set("challenge_token", res.ChallengeToken)
+		default:
+			fragment.Set("token", res.Token)
+		}
+
+		return c.Redirect(frontendURL()+"/auth/sso/callback#"+fragment.Encode(), fiber.StatusFound)
+	}
+}
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"database/sql"
+	"errors"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -42,6 +44,7 @@ type VerifyRegisterReq struct {
 // @Param        request body RequestOTPReq true "Nomor WhatsApp"
 // @Success      200  {object} map[string]string "OTP Terkirim"
 // @Failure      400  {object} map[string]string "Error Validasi"
+// @Failure      429  {object} map[string]string "Terlalu Banyak Permintaan"
 // @Router       /auth/login/request-otp [post]
 func RequestLoginOTPHandler(db *sql.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -50,10 +53,10 @@ func RequestLoginOTPHandler(db *sql.DB) fiber.Handler {
 			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 		}
 
-		serviceReq := RequestOTPRequest{Phone: req.Phone}
+		serviceReq := RequestOTPRequest{Phone: req.Phone, IP: c.IP()}
 
 		if err := RequestOTP(db, serviceReq, "login"); err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+			return rateLimitAwareError(c, err, fiber.StatusBadRequest)
 		}
 
 		return c.JSON(fiber.Map{"message": "Kode OTP telah dikirim ke WhatsApp"})
@@ -70,6 +73,7 @@ func RequestLoginOTPHandler(db *sql.DB) fiber.Handler {
 // @Success      200  {object} map[string]string "Token JWT"
 // @Failure      400  {object} map[string]string "Invalid Request"
 // @Failure      401  {object} map[string]string "OTP Salah/Expired"
+// @Failure      429  {object} map[string]string "Akun/IP Dikunci Sementara"
 // @Router       /auth/login/verify-otp [post]
 func VerifyLoginOTPHandler(db *sql.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -79,11 +83,11 @@ func VerifyLoginOTPHandler(db *sql.DB) fiber.Handler {
 		}
 
 		// Mapping ke struct service
-		serviceReq := VerifyOTPRequest{Phone: req.Phone, Code: req.Code}
+		serviceReq := VerifyOTPRequest{Phone: req.Phone, Code: req.Code, IP: c.IP(), UserAgent: c.Get("User-Agent")}
 
 		res, err := VerifyLoginOTP(db, serviceReq)
 		if err != nil {
-			return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+			return rateLimitAwareError(c, err, fiber.StatusUnauthorized)
 		}
 
 		return c.JSON(res)
@@ -108,10 +112,10 @@ func RequestRegisterOTPHandler(db *sql.DB) fiber.Handler {
 		}
 
 		// Mapping ke struct service
-		serviceReq := RequestOTPRequest{Phone: req.Phone}
+		serviceReq := RequestOTPRequest{Phone: req.Phone, IP: c.IP()}
 
 		if err := RequestOTP(db, serviceReq, "register"); err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+			return rateLimitAwareError(c, err, fiber.StatusBadRequest)
 		}
 
 		return c.JSON(fiber.Map{"message": "Kode OTP registrasi telah dikirim ke WhatsApp"})
@@ -179,10 +183,10 @@ func RequestChangePhoneOTPHandler(db *sql.DB) fiber.Handler {
 		// Validasi input nomor (misal hanya angka) bisa ditambahkan di sini atau di service
 		// Service RequestOTP akan melakukan validasi WA dan duplikasi
 
-		serviceReq := RequestOTPRequest{Phone: req.Phone}
+		serviceReq := RequestOTPRequest{Phone: req.Phone, IP: c.IP()}
 
 		if err := RequestOTP(db, serviceReq, "change_phone"); err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+			return rateLimitAwareError(c, err, fiber.StatusBadRequest)
 		}
 
 		return c.JSON(fiber.Map{"message": "Kode OTP dikirim ke nomor baru"})
@@ -220,3 +224,14 @@ func VerifyChangePhoneOTPHandler(db *sql.DB) fiber.Handler {
 		return c.JSON(fiber.Map{"message": "Nomor WhatsApp berhasil diperbarui"})
 	}
 }
+
+// rateLimitAwareError mengembalikan 429 (Too Many Requests) jika terkena rate limit / lockout,
+// selain itu memakai status fallback
+func rateLimitAwareError(c *fiber.Ctx, err error, fallback int) error {
+	var rlErr *RateLimitError
+	if errors.As(err, &rlErr) {
+		c.Set("Retry-After", strconv.Itoa(int(rlErr.RetryAfter.Seconds())+1))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fallback).JSON(fiber.Map{"error": err.Error()})
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ==========================================
+// HANDLER FUNCTIONS (LUPA PASSWORD)
+// ==========================================
+
+// RequestResetOTPHandler meminta OTP untuk reset password
+// @Summary      Request OTP Reset Password (WA)
+// @Description  Mengirimkan kode OTP reset password ke WhatsApp user yang nomornya sudah terverifikasi.
+// @Tags         Auth Reset Password
+// @Accept       json
+// @Produce      json
+// @Param        request body RequestOTPReq true "Nomor WhatsApp"
+// @Success      200  {object} map[string]string "OTP Terkirim"
+// @Failure      400  {object} map[string]string "Error Validasi"
+// @Router       /auth/reset-password/request-otp [post]
+func RequestResetOTPHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req RequestOTPReq
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		serviceReq := RequestOTPRequest{Phone: req.Phone, IP: c.IP()}
+
+		if err := RequestOTP(db, serviceReq, "reset_password"); err != nil {
+			return rateLimitAwareError(c, err, fiber.StatusBadRequest)
+		}
+
+		return c.JSON(fiber.Map{"message": "Kode OTP reset password telah dikirim ke WhatsApp"})
+	}
+}
+
+// VerifyResetOTPHandler memverifikasi OTP & mengganti password
+// @Summary      Reset Password dengan OTP (WA)
+// @Description  Verifikasi kode OTP lalu ganti password. Semua sesi login lama akan dicabut.
+// @Tags         Auth Reset Password
+// @Accept       json
+// @Produce      json
+// @Param        request body ResetPasswordOTPRequest true "Nomor, Kode & Password Baru"
+// @Success      200  {object} map[string]string "Password Diubah"
+// @Failure      400  {object} map[string]string "OTP Salah/Expired"
+// @Router       /auth/reset-password/verify-otp [post]
+func VerifyResetOTPHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req ResetPasswordOTPRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		if err := ResetPasswordWithOTP(db, req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"message": "Password berhasil direset, silakan login kembali"})
+	}
+}
+
+// RequestResetLinkHandler mengirim link reset password ke email
+// @Summary      Request Link Reset Password (Email)
+// @Description  Mengirimkan link reset password sekali pakai ke email (khusus akun tanpa WhatsApp terverifikasi; akun WhatsApp menerima petunjuk OTP). Response selalu sama untuk email apa pun.
+// @Tags         Auth Reset Password
+// @Accept       json
+// @Produce      json
+// @Param        request body ResetPasswordLinkRequest true "Email"
+// @Success      200  {object} map[string]string "Link Terkirim"
+// @Failure      400  {object} map[string]string "Error Validasi"
+// @Router       /auth/reset-password/request-link [post]
+func RequestResetLinkHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req ResetPasswordLinkRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		if err := RequestPasswordResetLink(db, req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"message": "Jika email terdaftar, link reset password telah dikirim"})
+	}
+}
+
+// ConfirmResetLinkHandler mengganti password menggunakan token dari link email
+// @Summary      Reset Password dengan Link (Email)
+// @Description  Ganti password menggunakan token dari link email. Token hanya bisa dipakai sekali dan semua sesi lama dicabut.
+// @Tags         Auth Reset Password
+// @Accept       json
+// @Produce      json
+// @Param        request body ResetPasswordConfirmRequest true "Token & Password Baru"
+// @Success      200  {object} map[string]string "Password Diubah"
+// @Failure      400  {object} map[string]string "Token Tidak Valid"
+// @Router       /auth/reset-password/confirm [post]
+func ConfirmResetLinkHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req ResetPasswordConfirmRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		if err := ResetPasswordWithToken(db, req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"message": "Password berhasil direset, silakan login kembali"})
+	}
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ==========================================
+// IDENTITAS EKSTERNAL (SSO / LDAP)
+// ==========================================
+
+// externalIdentity adalah hasil pemetaan data IdP / direktori kampus ke data user lokal
+type externalIdentity struct {
+	Issuer         string // Issuer OIDC atau URL server LDAP
+	Subject        string // ID unik & stabil di sumber identitas
+	Email          string
+	EmailVerified  bool
+	Name           string
+	Username       string
+	Department     string
+	IdentityNumber string
+	Position       string
+	Role           string // Kosong = role tidak disinkronkan
+}
+
+// resolveExternalUser mencari user berdasarkan (issuer, subject), lalu email terverifikasi, lalu membuat user baru.
+// Profil selalu disinkronkan; role ikut disinkronkan jika ident.Role diisi.
+func resolveExternalUser(db *sql.DB, ident externalIdentity) (string, string, int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", "", 0, err
+	}
+	defer tx.Rollback()
+
+	var (
+		userID         string
+		role           string
+		sessionVersion int
+		deletedAt      sql.NullTime
+	)
+
+	// 1. Identitas yang sudah pernah login
+	err = tx.QueryRow(`
+		SELECT u.id, u.role, u.session_version, u.deleted_at
+		FROM user_identities ui
+		JOIN users u ON u.id = ui.user_id
+		WHERE ui.issuer = $1 AND ui.subject = $2
+	`, ident.Issuer, ident.Subject).Scan(&userID, &role, &sessionVersion, &deletedAt)
+
+	switch {
+	case err == nil:
+		if deletedAt.Valid {
+			return "", "", 0, errors.New("akun sudah dinonaktifkan")
+		}
+		_, err = tx.Exec(`
+			UPDATE user_identities SET email = $3, last_login_at = NOW()
+			WHERE issuer = $1 AND subject = $2
+		`, ident.Issuer, ident.Subject, ident.Email)
+		if err != nil {
+			return "", "", 0, err
+		}
+
+	case err == sql.ErrNoRows:
+		if ident.Email == "" {
+			return "", "", 0, errors.New("akun kampus tidak memiliki email")
+		}
+
+		// 2. Tautkan ke akun lokal dengan email yang sama (hanya jika email diverifikasi sumber identitas)
+		err = tx.QueryRow(`
+			SELECT id, role, session_version, deleted_at FROM users WHERE LOWER(email) = $1
+		`, ident.Email).Scan(&userID, &role, &sessionVersion, &deletedAt)
+
+		if err == nil {
+			if deletedAt.Valid {
+				return "", "", 0, errors.New("akun sudah dinonaktifkan")
+			}
+			if !ident.EmailVerified {
+				return "", "", 0, errors.New("email akun kampus belum terverifikasi, tidak dapat ditautkan ke akun yang sudah ada")
+			}
+		} else if err == sql.ErrNoRows {
+			// 3. Auto-provision user baru (tanpa password, login hanya via SSO / LDAP / OTP)
+			userID, err = provisionExternalUser(tx, ident)
+			if err != nil {
+				return "", "", 0, err
+			}
+			role = "user"
+			sessionVersion = 0
+		} else {
+			return "", "", 0, err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO user_identities (user_id, issuer, subject, email)
+			VALUES ($1, $2, $3, $4)
+		`, userID, ident.Issuer, ident.Subject, ident.Email)
+		if err != nil {
+			return "", "", 0, errors.New("gagal menautkan akun kampus")
+		}
+
+	default:
+		return "", "", 0, err
+	}
+
+	if err := syncExternalProfile(tx, userID, ident); err != nil {
+		return "", "", 0, err
+	}
+
+	// Role dari grup direktori. Perubahan role mencabut sesi lama agar token dengan role lama tidak berlaku.
+	// Admin lokal (punya password) dikelola manual dan tidak pernah diturunkan oleh sinkronisasi.
+	if ident.Role != "" && ident.Role != role {
+		err = tx.QueryRow(`
+			UPDATE users SET role = $2, session_version = session_version + 1, updated_at = NOW()
+			WHERE id = $1 AND NOT (role = 'admin' AND password_hash IS NOT NULL)
+			RETURNING session_version
+		`, userID, ident.Role).Scan(&sessionVersion)
+		if err == nil {
+			role = ident.Role
+		} else if err != sql.ErrNoRows {
+			return "", "", 0, errors.New("gagal menyinkronkan role")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", "", 0, err
+	}
+	return userID, role, sessionVersion, nil
+}
+
+var usernameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9._ -]+`)
+
+// provisionExternalUser membuat baris users baru. Nama harus unik, jadi diberi akhiran angka jika bentrok.
+func provisionExternalUser(tx *sql.Tx, ident externalIdentity) (string, error) {
+	base := ident.Username
+	if base == "" {
+		base = ident.Name
+	}
+	if base == "" {
+		base = strings.Split(ident.Email, "@")[0]
+	}
+	base = strings.TrimSpace(usernameSanitizer.ReplaceAllString(base, ""))
+	if base == "" {
+		base = "user"
+	}
+	if len(base) > 90 {
+		base = base[:90]
+	}
+
+	name := base
+	for i := 2; ; i++ {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE name = $1)", name).Scan(&exists); err != nil {
+			return "", err
+		}
+		if !exists {
+			break
+		}
+		if i > 50 {
+			return "", errors.New("gagal membuat username untuk akun kampus")
+		}
+		name = base + "-" + strconv.Itoa(i)
+	}
+
+	var userID string
+	err := tx.QueryRow(`
+		INSERT INTO users (name, email, password_hash)
+		VALUES ($1, $2, NULL)
+		RETURNING id
+	`, name, ident.Email).Scan(&userID)
+	if err != nil {
+		return "", errors.New("gagal membuat akun dari data kampus")
+	}
+	return userID, nil
+}
+
+// syncExternalProfile mengisi profil dari data kampus (claim IdP / atribut LDAP).
+// Data kampus dianggap sumber utama; field yang kosong di sumber tidak menimpa isian user.
+func syncExternalProfile(tx *sql.Tx, userID string, ident externalIdentity) error {
+	_, err := tx.Exec(`
+		INSERT INTO profiles (user_id, full_name, department, identity_number, "position", updated_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NOW())
+		ON CONFLICT (user_id)
+		DO UPDATE SET
+			full_name = COALESCE(EXCLUDED.full_name, profiles.full_name),
+			department = COALESCE(EXCLUDED.department, profiles.department),
+			identity_number = COALESCE(EXCLUDED.identity_number, profiles.identity_number),
+			"position" = COALESCE(EXCLUDED."position", profiles."position"),
+			updated_at = NOW()
+	`, userID, ident.Name, ident.Department, ident.IdentityNumber, ident.Position)
+	if err != nil {
+		return errors.New("gagal menyimpan profil dari data kampus")
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ==========================================
+// LDAP / ACTIVE DIRECTORY CLIENT
+// ==========================================
+// Alur: bind akun layanan -> cari entry berdasarkan NIM/NIP/email -> bind ulang sebagai user (cek password).
+
+var (
+	errLDAPInvalidCredentials = errors.New("ldap: kredensial salah")
+	errLDAPUnavailable        = errors.New("ldap: server tidak dapat dihubungi")
+)
+
+const ldapTimeout = 10 * time.Second
+
+type ldapConfig struct {
+	URL          string
+	StartTLS     bool
+	SkipVerify   bool
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	UserFilter   string // {login} diganti dengan input user (sudah di-escape)
+
+	AttrID         string
+	AttrName       string
+	AttrEmail      string
+	AttrIdentity   string
+	AttrDepartment string
+	AttrPosition   string
+	AttrGroups     string
+
+	GroupRoles []ldapGroupRole
+}
+
+// ldapGroupRole memetakan satu grup direktori (DN lengkap atau CN) ke role backend
+type ldapGroupRole struct {
+	Role  string
+	Group string
+}
+
+type ldapEntry struct {
+	DN             string
+	ID             string
+	Name           string
+	Email          string
+	IdentityNumber string
+	Department     string
+	Position       string
+	Groups         []string
+}
+
+// loadLDAPConfig membaca konfigurasi LDAP. ok = false jika LDAP tidak diaktifkan (LDAP_URL kosong).
+func loadLDAPConfig() (ldapConfig, bool) {
+	cfg := ldapConfig{
+		URL:          os.Getenv("LDAP_URL"),
+		StartTLS:     os.Getenv("LDAP_STARTTLS") == "true",
+		SkipVerify:   os.Getenv("LDAP_INSECURE_SKIP_VERIFY") == "true",
+		BindDN:       os.Getenv("LDAP_BIND_DN"),
+		BindPassword: os.Getenv("LDAP_BIND_PASSWORD"),
+		BaseDN:       os.Getenv("LDAP_BASE_DN"),
+		UserFilter:   envOrDefault("LDAP_USER_FILTER", "(|(uid={login})(mail={login})(employeeNumber={login}))"),
+
+		AttrID:         envOrDefault("LDAP_ATTR_ID", "uid"),
+		AttrName:       envOrDefault("LDAP_ATTR_NAME", "cn"),
+		AttrEmail:      envOrDefault("LDAP_ATTR_EMAIL", "mail"),
+		AttrIdentity:   envOrDefault("LDAP_ATTR_IDENTITY_NUMBER", "employeeNumber"),
+		AttrDepartment: envOrDefault("LDAP_ATTR_DEPARTMENT", "departmentNumber"),
+		AttrPosition:   envOrDefault("LDAP_ATTR_POSITION", "title"),
+		AttrGroups:     envOrDefault("LDAP_ATTR_GROUPS", "memberOf"),
+	}
+
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return cfg, false
+	}
+
+	cfg.GroupRoles = parseLDAPGroupRoles(os.Getenv("LDAP_GROUP_ROLES"))
+	return cfg, true
+}
+
+// parseLDAPGroupRoles mem-parsing format "role:grup;role:grup", contoh:
+// admin:cn=sarpras,ou=groups,dc=kampus,dc=ac,dc=id;admin:it-staff
+func parseLDAPGroupRoles(raw string) []ldapGroupRole {
+	var out []ldapGroupRole
+	for _, item := range strings.Split(raw, ";") {
+		role, group, ok := strings.Cut(strings.TrimSpace(item), ":")
+		role = strings.TrimSpace(role)
+		group = strings.TrimSpace(group)
+		if !ok || group == "" || (role != "admin" && role != "user") {
+			continue
+		}
+		out = append(out, ldapGroupRole{Role: role, Group: group})
+	}
+	return out
+}
+
+// mapRole mengembalikan role dari grup user. "admin" diutamakan; "" jika pemetaan tidak dikonfigurasi.
+func (cfg ldapConfig) mapRole(groups []string) string {
+	if len(cfg.GroupRoles) == 0 {
+		return ""
+	}
+
+	role := "user"
+	for _, gr := range cfg.GroupRoles {
+		for _, g := range groups {
+			if !ldapGroupMatches(g, gr.Group) {
+				continue
+			}
+			if gr.Role == "admin" {
+				return "admin"
+			}
+			role = gr.Role
+		}
+	}
+	return role
+}
+
+// ldapGroupMatches mencocokkan DN grup dengan DN lengkap atau nama CN-nya saja (tidak case-sensitive)
+func ldapGroupMatches(groupDN, want string) bool {
+	if strings.EqualFold(groupDN, want) {
+		return true
+	}
+	dn, err := ldap.ParseDN(groupDN)
+	if err != nil || len(dn.RDNs) == 0 {
+		return false
+	}
+	for _, attr := range dn.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Type, "cn") && strings.EqualFold(attr.Value, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cfg ldapConfig) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.SkipVerify}
+
+	conn, err := ldap.DialURL(cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errLDAPUnavailable, err)
+	}
+	conn.SetTimeout(ldapTimeout)
+
+	if cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: StartTLS gagal: %v", errLDAPUnavailable, err)
+		}
+	}
+	return conn, nil
+}
+
+// authenticate memverifikasi login + password ke direktori dan mengembalikan atribut user
+func (cfg ldapConfig) authenticate(login, password string) (*ldapEntry, error) {
+	login = strings.TrimSpace(login)
+	// Password kosong = "unauthenticated bind" yang di banyak server dianggap sukses. Wajib ditolak.
+	if login == "" || password == "" {
+		return nil, errLDAPInvalidCredentials
+	}
+
+	conn, err := cfg.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// 1. Bind akun layanan (atau anonymous jika LDAP_BIND_DN kosong)
+	if cfg.BindDN != "" {
+		if err := conn.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("%w: bind akun layanan gagal: %v", errLDAPUnavailable, err)
+		}
+	}
+
+	// 2. Cari entry user
+	filter := strings.ReplaceAll(cfg.UserFilter, "{login}", ldap.EscapeFilter(login))
+	attrs := []string{cfg.AttrID, cfg.AttrName, cfg.AttrEmail, cfg.AttrIdentity, cfg.AttrDepartment, cfg.AttrPosition, cfg.AttrGroups}
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(ldapTimeout/time.Second), false,
+		filter, attrs, nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, errLDAPInvalidCredentials
+		}
+		return nil, fmt.Errorf("%w: pencarian gagal: %v", errLDAPUnavailable, err)
+	}
+	// Tidak ditemukan atau ambigu (lebih dari satu entry) -> anggap kredensial salah
+	if len(res.Entries) != 1 {
+		return nil, errLDAPInvalidCredentials
+	}
+	e := res.Entries[0]
+
+	// 3. Bind sebagai user untuk memeriksa password
+	if err := conn.Bind(e.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, errLDAPInvalidCredentials
+		}
+		return nil, fmt.Errorf("%w: bind user gagal: %v", errLDAPUnavailable, err)
+	}
+
+	entry := &ldapEntry{
+		DN:             e.DN,
+		ID:             e.GetAttributeValue(cfg.AttrID),
+		Name:           e.GetAttributeValue(cfg.AttrName),
+		Email:          strings.ToLower(e.GetAttributeValue(cfg.AttrEmail)),
+		IdentityNumber: e.GetAttributeValue(cfg.AttrIdentity),
+		Department:     e.GetAttributeValue(cfg.AttrDepartment),
+		Position:       e.GetAttributeValue(cfg.AttrPosition),
+		Groups:         e.GetAttributeValues(cfg.AttrGroups),
+	}
+	if entry.ID == "" {
+		// Atribut ID tidak tersedia, pakai DN (kurang stabil jika user dipindah OU)
+		entry.ID = e.DN
+	}
+	return entry, nil
+}
+
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"database/sql"
 	"os"
 	"strings"
 
@@ -14,7 +15,8 @@ import (
 // Digunakan untuk:
 // - memastikan user sudah login
 // - mengambil user_id & role dari token
-func JWTProtected() fiber.Handler {
+// - menolak token yang sesinya sudah dicabut (misal setelah reset password)
+func JWTProtected(db *sql.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 
@@ -58,10 +60,122 @@ func JWTProtected() fiber.Handler {
 			})
 		}
 
-		// 5. Simpan data user ke context
+		// 5. Cek versi sesi ke database
+		userID, _ := claims["user_id"].(string)
+		campusID, active := isSessionActive(db, userID, claims)
+		if !active {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "sesi sudah berakhir, silakan login kembali",
+			})
+		}
+
+		// 6. Simpan data user ke context
 		c.Locals("user_id", claims["user_id"])
 		c.Locals("role", claims["role"])
+		c.Locals("mfa", claims["mfa"] == true)
+		c.Locals("campus_id", campusID)
+
+		return c.Next()
+	}
+}
+
+// ==========================
+// API KEY ATAU JWT
+// ==========================
+// Untuk route yang juga boleh diakses kiosk / integrasi. API key dikirim lewat
+// "Authorization: Bearer usk_..." atau header "X-API-Key" dan wajib memiliki scope yang diminta.
+// Selain itu request diteruskan ke JWTProtected seperti biasa.
+func JWTOrAPIKey(db *sql.DB, scope string) fiber.Handler {
+	jwtProtected := JWTProtected(db)
+
+	return func(c *fiber.Ctx) error {
+		key := c.Get("X-API-Key")
+		if key == "" {
+			if bearer, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer "); ok && strings.HasPrefix(bearer, apiKeyPrefix) {
+				key = bearer
+			}
+		}
+
+		if key == "" {
+			return jwtProtected(c)
+		}
+
+		principal, err := authenticateAPIKey(db, key, c.IP())
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if !principal.hasScope(scope) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "API key tidak memiliki scope " + scope,
+			})
+		}
+
+		c.Locals("api_key", principal)
+		return c.Next()
+	}
+}
+
+// APIKeyFacilityAllowed dipakai handler untuk memastikan API key yang dibatasi ke satu fasilitas
+// hanya mengakses fasilitas tersebut. Selalu true untuk request dengan JWT.
+func APIKeyFacilityAllowed(c *fiber.Ctx, facilityID string) bool {
+	principal, ok := c.Locals("api_key").(*apiKeyPrincipal)
+	if !ok || principal.FacilityID == "" {
+		return true
+	}
+	return principal.FacilityID == facilityID
+}
+
+// ==========================
+// CAKUPAN KAMPUS ADMIN
+// ==========================
+
+// AdminCampusID mengembalikan kampus yang dikelola admin yang sedang login.
+// String kosong berarti admin pusat (semua kampus), user biasa, atau request API key.
+func AdminCampusID(c *fiber.Ctx) string {
+	campusID, _ := c.Locals("campus_id").(string)
+	return campusID
+}
+
+// CampusAllowed memastikan admin kampus hanya mengakses data kampusnya sendiri
+func CampusAllowed(c *fiber.Ctx, campusID string) bool {
+	scope := AdminCampusID(c)
+	return scope == "" || scope == campusID
+}
+
+// CampusFilter menentukan filter kampus untuk daftar data admin: admin kampus selalu dibatasi ke kampusnya,
+// admin pusat boleh memilih lewat ?campus_id= (kosong = semua kampus).
+func CampusFilter(c *fiber.Ctx) string {
+	if scope := AdminCampusID(c); scope != "" {
+		return scope
+	}
+	return c.Query("campus_id")
+}
+
+// RequireCentralAdmin dipasang setelah RequireRole("admin") untuk aksi yang berlaku lintas kampus
+func RequireCentralAdmin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if AdminCampusID(c) != "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "hanya admin pusat yang dapat melakukan aksi ini",
+			})
+		}
+		return c.Next()
+	}
+}
 
+// ==========================
+// TOKEN DARI QUERY STRING
+// ==========================
+// EventSource di browser tidak bisa mengirim header Authorization, sehingga route stream SSE
+// menerima token lewat "?access_token=". Dipasang sebelum JWTProtected / JWTOrAPIKey.
+func TokenFromQuery() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token := c.Query("access_token"); token != "" && c.Get("Authorization") == "" {
+			c.Request().Header.Set("Authorization", "Bearer "+token)
+		}
 		return c.Next()
 	}
 }
@@ -72,6 +186,11 @@ func JWTProtected() fiber.Handler {
 
 func RequireRole(requiredRole string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		// Request dengan API key sudah divalidasi scope-nya di JWTOrAPIKey
+		if _, ok := c.Locals("api_key").(*apiKeyPrincipal); ok {
+			return c.Next()
+		}
+
 		role := c.Locals("role")
 
 		if role == nil {
@@ -86,6 +205,14 @@ func RequireRole(requiredRole string) fiber.Handler {
 			})
 		}
 
+		// Jika 2FA diwajibkan untuk admin, token harus berasal dari login yang lolos 2FA
+		if requiredRole == "admin" && adminTwoFactorRequired() && c.Locals("mfa") != true {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "akun admin wajib mengaktifkan dan menggunakan 2FA",
+				"code":  "2fa_required",
+			})
+		}
+
 		return c.Next()
 	}
 }
@@ -0,0 +1,362 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ==========================================
+// OPENID CONNECT CLIENT (Authorization Code + PKCE)
+// ==========================================
+// Cukup memakai stdlib + golang-jwt: discovery, JWKS (RSA), tukar code, verifikasi ID token.
+
+const jwksRefreshInterval = time.Minute
+
+var oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+type oidcConfig struct {
+	Issuer          string
+	ClientID        string
+	ClientSecret    string
+	RedirectURL     string
+	Scopes          []string
+	ClaimDepartment string
+	ClaimIdentity   string
+}
+
+// loadOIDCConfig membaca konfigurasi SSO dari environment
+func loadOIDCConfig() (oidcConfig, error) {
+	cfg := oidcConfig{
+		Issuer:          strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/"),
+		ClientID:        os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:    os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:     os.Getenv("OIDC_REDIRECT_URL"),
+		ClaimDepartment: os.Getenv("OIDC_CLAIM_DEPARTMENT"),
+		ClaimIdentity:   os.Getenv("OIDC_CLAIM_IDENTITY_NUMBER"),
+	}
+
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return cfg, errors.New("login SSO belum dikonfigurasi")
+	}
+
+	scopes := os.Getenv("OIDC_SCOPES")
+	if scopes == "" {
+		scopes = "openid profile email"
+	}
+	cfg.Scopes = strings.Fields(scopes)
+
+	if cfg.ClaimDepartment == "" {
+		cfg.ClaimDepartment = "department"
+	}
+	if cfg.ClaimIdentity == "" {
+		cfg.ClaimIdentity = "identity_number"
+	}
+
+	return cfg, nil
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcProvider menyimpan cache discovery & public key milik issuer
+type oidcProvider struct {
+	mu          sync.Mutex
+	issuer      string
+	discovery   *oidcDiscovery
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+var oidcCache = &oidcProvider{}
+
+// getOIDCProvider mengambil dokumen discovery (sekali per issuer, lalu di-cache)
+func getOIDCProvider(cfg oidcConfig) (*oidcProvider, error) {
+	oidcCache.mu.Lock()
+	defer oidcCache.mu.Unlock()
+
+	if oidcCache.discovery != nil && oidcCache.issuer == cfg.Issuer {
+		return oidcCache, nil
+	}
+
+	var disc oidcDiscovery
+	if err := getJSON(cfg.Issuer+"/.well-known/openid-configuration", &disc); err != nil {
+		return nil, fmt.Errorf("gagal menghubungi server SSO: %w", err)
+	}
+	if strings.TrimSuffix(disc.Issuer, "/") != cfg.Issuer {
+		return nil, errors.New("issuer SSO tidak cocok dengan konfigurasi")
+	}
+	if disc.AuthorizationEndpoint == "" || disc.TokenEndpoint == "" || disc.JWKSURI == "" {
+		return nil, errors.New("dokumen discovery SSO tidak lengkap")
+	}
+
+	oidcCache.issuer = cfg.Issuer
+	oidcCache.discovery = &disc
+	oidcCache.keys = nil
+	oidcCache.keysFetched = time.Time{}
+	return oidcCache, nil
+}
+
+// publicKey mencari key berdasarkan kid. JWKS diambil ulang jika kid belum dikenal (rotasi key).
+func (p *oidcProvider) publicKey(kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+
+	if time.Since(p.keysFetched) < jwksRefreshInterval {
+		return nil, errors.New("key ID token tidak dikenal")
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := getJSON(p.discovery.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("gagal mengambil JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	p.keys = keys
+	p.keysFetched = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, errors.New("key ID token tidak dikenal")
+}
+
+func (p *oidcProvider) lookupKey(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// authCodeURL menyusun URL redirect ke halaman login SSO
+func (p *oidcProvider) authCodeURL(cfg oidcConfig, state, nonce, verifier string) string {
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", cfg.ClientID)
+	q.Set("redirect_uri", cfg.RedirectURL)
+	q.Set("scope", strings.Join(cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", pkceChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.discovery.AuthorizationEndpoint + sep + q.Encode()
+}
+
+type oidcTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchangeCode menukar authorization code (+ PKCE verifier) dengan token di token endpoint
+func (p *oidcProvider) exchangeCode(cfg oidcConfig, code, verifier string) (oidcTokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", cfg.RedirectURL)
+	form.Set("client_id", cfg.ClientID)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequest(http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return oidcTokenResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if cfg.ClientSecret != "" {
+		// client_secret_basic
+		req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return oidcTokenResponse{}, fmt.Errorf("gagal menghubungi server SSO: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tok oidcTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return oidcTokenResponse{}, errors.New("respon token SSO tidak valid")
+	}
+	if resp.StatusCode != http.StatusOK || tok.Error != "" {
+		msg := tok.Error
+		if tok.ErrorDescription != "" {
+			msg += ": " + tok.ErrorDescription
+		}
+		return oidcTokenResponse{}, fmt.Errorf("server SSO menolak login (%s)", msg)
+	}
+	if tok.IDToken == "" {
+		return oidcTokenResponse{}, errors.New("server SSO tidak mengirim id_token")
+	}
+	return tok, nil
+}
+
+// verifyIDToken memeriksa tanda tangan, issuer, audience, masa berlaku, dan nonce ID token
+func (p *oidcProvider) verifyIDToken(cfg oidcConfig, raw, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.publicKey(kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}),
+		jwt.WithIssuer(p.discovery.Issuer),
+		jwt.WithAudience(cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("id_token tidak valid: %w", err)
+	}
+
+	if n, _ := claims["nonce"].(string); n == "" || n != nonce {
+		return nil, errors.New("id_token tidak valid: nonce tidak cocok")
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, errors.New("id_token tidak valid: sub kosong")
+	}
+
+	return claims, nil
+}
+
+// userinfo melengkapi claim yang tidak ada di ID token (beberapa IdP hanya mengirim sub)
+func (p *oidcProvider) userinfo(accessToken string, claims jwt.MapClaims) {
+	if p.discovery.UserinfoEndpoint == "" || accessToken == "" {
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodGet, p.discovery.UserinfoEndpoint, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	var info map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return
+	}
+	// Spesifikasi OIDC: sub userinfo WAJIB sama dengan sub ID token
+	if info["sub"] != claims["sub"] {
+		return
+	}
+	for k, v := range info {
+		if _, exists := claims[k]; !exists {
+			claims[k] = v
+		}
+	}
+}
+
+// ==========================================
+// HELPERS
+// ==========================================
+
+func getJSON(u string, out interface{}) error {
+	resp, err := oidcHTTPClient.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d dari %s", resp.StatusCode, u)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// randomURLToken menghasilkan string acak base64url (dipakai untuk state, nonce, PKCE verifier)
+func randomURLToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// pkceChallenge = BASE64URL(SHA256(verifier)) sesuai RFC 7636 (metode S256)
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// claimString mengambil claim string; mendukung claim bersarang dengan titik, misal "campus.department"
+func claimString(claims jwt.MapClaims, name string) string {
+	var cur interface{} = map[string]interface{}(claims)
+	for _, part := range strings.Split(name, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		cur = m[part]
+	}
+
+	switch v := cur.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
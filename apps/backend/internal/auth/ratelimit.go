@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==========================
+// RATE LIMIT STORE
+// ==========================
+// Penyimpanan counter rate limit dibuat pluggable.
+// Default memakai Postgres (tabel rate_limits) agar konsisten di banyak instance backend.
+// Untuk development / single instance bisa diganti MemoryLimitStore lewat SetLimitStore.
+
+type LimitStore interface {
+	// Hit mencatat satu percobaan pada key dan mengembalikan jumlah percobaan di window berjalan
+	Hit(key string, window time.Duration) (int, error)
+	// BlockedFor mengembalikan sisa waktu cooldown (0 jika tidak sedang diblokir)
+	BlockedFor(key string) (time.Duration, error)
+	// Block memblokir key dengan cooldown eksponensial dan mengembalikan durasi blokir
+	Block(key string, base time.Duration, max time.Duration) (time.Duration, error)
+	// Reset menghapus counter & cooldown key
+	Reset(key string) error
+	// Active mengembalikan semua key yang sedang diblokir dengan awalan prefix
+	Active(prefix string) ([]LimitEntry, error)
+}
+
+// LimitEntry adalah data blokir yang ditampilkan ke admin
+type LimitEntry struct {
+	Key          string    `json:"key"`
+	Hits         int       `json:"hits"`
+	Strikes      int       `json:"strikes"`
+	BlockedUntil time.Time `json:"blocked_until"`
+}
+
+var (
+	limitStore   LimitStore
+	limitStoreMu sync.RWMutex
+)
+
+// SetLimitStore mengganti store default (Postgres)
+func SetLimitStore(s LimitStore) {
+	limitStoreMu.Lock()
+	defer limitStoreMu.Unlock()
+	limitStore = s
+}
+
+func getLimitStore(db *sql.DB) LimitStore {
+	limitStoreMu.RLock()
+	defer limitStoreMu.RUnlock()
+	if limitStore != nil {
+		return limitStore
+	}
+	return PostgresLimitStore{DB: db}
+}
+
+// Strike dianggap kedaluwarsa jika blokir terakhir sudah lewat lebih dari 24 jam
+const strikeDecay = 24 * time.Hour
+
+// cooldownFor menghitung durasi blokir: base * 2^(strikes-1), maksimal max
+func cooldownFor(strikes int, base, max time.Duration) time.Duration {
+	if strikes < 1 {
+		strikes = 1
+	}
+	d := time.Duration(float64(base) * math.Pow(2, float64(strikes-1)))
+	if d > max || d <= 0 {
+		return max
+	}
+	return d
+}
+
+// ==========================
+// RATE LIMIT ERROR
+// ==========================
+
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	minutes := int(math.Ceil(e.RetryAfter.Minutes()))
+	if minutes < 1 {
+		minutes = 1
+	}
+	return fmt.Sprintf("terlalu banyak percobaan, silakan coba lagi dalam %d menit", minutes)
+}
+
+// ==========================
+// POSTGRES STORE
+// ==========================
+
+type PostgresLimitStore struct {
+	DB *sql.DB
+}
+
+func (s PostgresLimitStore) Hit(key string, window time.Duration) (int, error) {
+	var hits int
+	err := s.DB.QueryRow(`
+		INSERT INTO rate_limits (key, hits, window_start, updated_at)
+		VALUES ($1, 1, NOW(), NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			hits = CASE
+				WHEN rate_limits.window_start < NOW() - $2 * INTERVAL '1 millisecond' THEN 1
+				ELSE rate_limits.hits + 1
+			END,
+			window_start = CASE
+				WHEN rate_limits.window_start < NOW() - $2 * INTERVAL '1 millisecond' THEN NOW()
+				ELSE rate_limits.window_start
+			END,
+			updated_at = NOW()
+		RETURNING hits
+	`, key, window.Milliseconds()).Scan(&hits)
+	return hits, err
+}
+
+func (s PostgresLimitStore) BlockedFor(key string) (time.Duration, error) {
+	var blockedUntil sql.NullTime
+	err := s.DB.QueryRow(`SELECT blocked_until FROM rate_limits WHERE key = $1`, key).Scan(&blockedUntil)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !blockedUntil.Valid || time.Now().After(blockedUntil.Time) {
+		return 0, nil
+	}
+	return time.Until(blockedUntil.Time), nil
+}
+
+func (s PostgresLimitStore) Block(key string, base time.Duration, max time.Duration) (time.Duration, error) {
+	var strikes int
+	err := s.DB.QueryRow(`
+		INSERT INTO rate_limits (key, strikes, updated_at)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			strikes = CASE
+				WHEN rate_limits.blocked_until IS NULL OR rate_limits.blocked_until < NOW() - $2 * INTERVAL '1 millisecond' THEN 1
+				ELSE rate_limits.strikes + 1
+			END,
+			updated_at = NOW()
+		RETURNING strikes
+	`, key, strikeDecay.Milliseconds()).Scan(&strikes)
+	if err != nil {
+		return 0, err
+	}
+
+	d := cooldownFor(strikes, base, max)
+	_, err = s.DB.Exec(`
+		UPDATE rate_limits
+		SET blocked_until = NOW() + $2 * INTERVAL '1 millisecond', hits = 0, window_start = NOW()
+		WHERE key = $1
+	`, key, d.Milliseconds())
+	return d, err
+}
+
+func (s PostgresLimitStore) Reset(key string) error {
+	_, err := s.DB.Exec(`DELETE FROM rate_limits WHERE key = $1`, key)
+	return err
+}
+
+func (s PostgresLimitStore) Active(prefix string) ([]LimitEntry, error) {
+	rows, err := s.DB.Query(`
+		SELECT key, hits, strikes, blocked_until
+		FROM rate_limits
+		WHERE key LIKE $1 || '%' AND blocked_until > NOW()
+		ORDER BY blocked_until DESC
+	`, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []LimitEntry{}
+	for rows.Next() {
+		var e LimitEntry
+		if err := rows.Scan(&e.Key, &e.Hits, &e.Strikes, &e.BlockedUntil); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// ==========================
+// MEMORY STORE
+// ==========================
+
+type memoryLimitEntry struct {
+	hits         int
+	windowStart  time.Time
+	strikes      int
+	blockedUntil time.Time
+}
+
+type MemoryLimitStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryLimitEntry
+}
+
+func NewMemoryLimitStore() *MemoryLimitStore {
+	return &MemoryLimitStore{entries: make(map[string]*memoryLimitEntry)}
+}
+
+func (s *MemoryLimitStore) entry(key string) *memoryLimitEntry {
+	e, ok := s.entries[key]
+	if !ok {
+		e = &memoryLimitEntry{windowStart: time.Now()}
+		s.entries[key] = e
+	}
+	return e
+}
+
+func (s *MemoryLimitStore) Hit(key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entry(key)
+	if time.Since(e.windowStart) > window {
+		e.hits = 0
+		e.windowStart = time.Now()
+	}
+	e.hits++
+	return e.hits, nil
+}
+
+func (s *MemoryLimitStore) BlockedFor(key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || time.Now().After(e.blockedUntil) {
+		return 0, nil
+	}
+	return time.Until(e.blockedUntil), nil
+}
+
+func (s *MemoryLimitStore) Block(key string, base time.Duration, max time.Duration) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entry(key)
+	if e.blockedUntil.IsZero() || time.Since(e.blockedUntil) > strikeDecay {
+		e.strikes = 0
+	}
+	e.strikes++
+
+	d := cooldownFor(e.strikes, base, max)
+	e.blockedUntil = time.Now().Add(d)
+	e.hits = 0
+	e.windowStart = time.Now()
+	return d, nil
+}
+
+func (s *MemoryLimitStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryLimitStore) Active(prefix string) ([]LimitEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := []LimitEntry{}
+	for key, e := range s.entries {
+		if strings.HasPrefix(key, prefix) && time.Now().Before(e.blockedUntil) {
+			entries = append(entries, LimitEntry{Key: key, Hits: e.hits, Strikes: e.strikes, BlockedUntil: e.blockedUntil})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].BlockedUntil.After(entries[j].BlockedUntil) })
+	return entries, nil
+}
@@ -3,11 +3,11 @@ package auth
 import (
 	"database/sql"
 	"errors"
-	"os"
+	"log"
 	"strings"
-	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"campus-reservation-backend/internal/media"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -110,64 +110,91 @@ func Register(db *sql.DB, req RegisterRequest) error {
 //
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token string `json:"token,omitempty"`
+	// Diisi jika user mengaktifkan 2FA: token baru diterbitkan setelah /auth/2fa/verify
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
 func Login(db *sql.DB, req LoginRequest) (LoginResponse, error) {
 	var (
-		userID       string
-		passwordHash string
-		role         string
+		userID         string
+		passwordHash   sql.NullString
+		role           string
+		sessionVersion int
 	)
 
 	// 1. Ambil user berdasarkan email
 	err := db.QueryRow(`
-		SELECT id, password_hash, role
+		SELECT id, password_hash, role, session_version
 		FROM users
 		WHERE email = $1
-	`, req.Email).Scan(&userID, &passwordHash, &role)
+	`, req.Email).Scan(&userID, &passwordHash, &role, &sessionVersion)
 
-	if err != nil {
-		return LoginResponse{}, errors.New("email atau password salah")
+	userFound := err == nil
+	if !userFound {
+		userID = ""
 	}
 
-	// 2. Cocokkan password
-	err = bcrypt.CompareHashAndPassword(
-		[]byte(passwordHash),
-		[]byte(req.Password),
-	)
-	if err != nil {
-		return LoginResponse{}, errors.New("email atau password salah")
+	// 2. Cek apakah akun / IP sedang dikunci
+	if err := checkLoginLock(getLimitStore(db), loginAccountKey(userID, req.Email), req.IP); err != nil {
+		return LoginResponse{}, err
 	}
 
-	// 3. Buat JWT token
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"role":    role,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-		"iat":     time.Now().Unix(),
-	}
+	// 3. Autentikasi ke LDAP kampus (jika diaktifkan). Field email boleh diisi NIM / NIP.
+	if cfg, ok := loadLDAPConfig(); ok {
+		entry, ldapErr := cfg.authenticate(req.Email, req.Password)
+		if ldapErr == nil {
+			ldapUserID, ldapRole, ldapSessionVersion, err := syncLDAPUser(db, cfg, entry)
+			if err != nil {
+				return LoginResponse{}, err
+			}
+			return issueLoginToken(db, ldapUserID, ldapRole, ldapSessionVersion,
+				loginAttempt{Identifier: req.Email, Method: "ldap", IP: req.IP, UserAgent: req.UserAgent})
+		}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		// Password lokal hanya dipakai sebagai cadangan untuk akun admin
+		if !userFound || role != "admin" || !passwordHash.Valid {
+			if errors.Is(ldapErr, errLDAPUnavailable) {
+				log.Println("LDAP:", ldapErr)
+				return LoginResponse{}, errors.New("server direktori kampus tidak dapat dihubungi, coba lagi nanti")
+			}
+			if err := recordLoginFailure(db, userID, req.Email, "ldap", req.IP, req.UserAgent); err != nil {
+				return LoginResponse{}, err
+			}
+			return LoginResponse{}, errors.New("email atau password salah")
+		}
+	}
 
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return LoginResponse{}, errors.New("JWT_SECRET belum diset")
+	if !userFound || !passwordHash.Valid {
+		if err := recordLoginFailure(db, userID, req.Email, "password", req.IP, req.UserAgent); err != nil {
+			return LoginResponse{}, err
+		}
+		return LoginResponse{}, errors.New("email atau password salah")
 	}
 
-	signedToken, err := token.SignedString([]byte(secret))
+	// 4. Cocokkan password
+	err = bcrypt.CompareHashAndPassword(
+		[]byte(passwordHash.String),
+		[]byte(req.Password),
+	)
 	if err != nil {
-		return LoginResponse{}, errors.New("gagal membuat token")
+		if err := recordLoginFailure(db, userID, req.Email, "password", req.IP, req.UserAgent); err != nil {
+			return LoginResponse{}, err
+		}
+		return LoginResponse{}, errors.New("email atau password salah")
 	}
 
-	return LoginResponse{
-		Token: signedToken,
-	}, nil
+	// 5. Buat JWT token (atau challenge 2FA jika aktif)
+	return issueLoginToken(db, userID, role, sessionVersion,
+		loginAttempt{Identifier: req.Email, Method: "password", IP: req.IP, UserAgent: req.UserAgent})
 }
 
 //
@@ -181,6 +208,8 @@ type UserResponse struct {
 	Email     string `json:"email"`
 	Role      string `json:"role"`
 	AvatarURL string `json:"avatar_url"`
+	// URL thumb/medium/large avatar (diturunkan dari avatar_url)
+	AvatarVariants media.Variants `json:"avatar_variants"`
 }
 
 func GetMe(db *sql.DB, userID string) (UserResponse, error) {
@@ -214,6 +243,7 @@ func GetMe(db *sql.DB, userID string) (UserResponse, error) {
 	if avatarURL.Valid {
 		user.AvatarURL = avatarURL.String
 	}
+	user.AvatarVariants = media.VariantsOf(user.AvatarURL)
 
 	return user, nil
 }
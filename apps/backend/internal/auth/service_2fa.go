@@ -0,0 +1,514 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	twoFactorChallengeTTL = 5 * time.Minute
+	recoveryCodeCount     = 10
+)
+
+// ==========================
+// REQUEST / RESPONSE OBJECTS
+// ==========================
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code" example:"123456"`
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code" example:"123456"`
+	RecoveryCode   string `json:"recovery_code" example:"ABCD-EFGH"`
+	IP             string `json:"-"`
+	UserAgent      string `json:"-"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	QRCode     string `json:"qr_code"` // data:image/png;base64,...
+}
+
+type TwoFactorEnableResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+	Token         string   `json:"token"` // Token baru dengan status 2FA terverifikasi
+}
+
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// adminTwoFactorRequired: jika REQUIRE_ADMIN_2FA=true, route admin hanya bisa diakses dengan token yang sudah lolos 2FA
+func adminTwoFactorRequired() bool {
+	return os.Getenv("REQUIRE_ADMIN_2FA") == "true"
+}
+
+// ==========================
+// LOGIN STEP 2
+// ==========================
+
+// loginAttempt: data faktor pertama yang dicatat sebagai login berhasil saat JWT benar-benar diterbitkan
+type loginAttempt struct {
+	Identifier string
+	Method     string
+	IP         string
+	UserAgent  string
+}
+
+// issueLoginToken dipanggil setelah faktor pertama (password / OTP WA / SSO) berhasil.
+// Jika user mengaktifkan 2FA, token belum diterbitkan dan diganti challenge token; counter lockout, perangkat
+// dikenal dan notifikasi perangkat baru baru diproses setelah kode 2FA valid (VerifyTwoFactorLogin).
+func issueLoginToken(db *sql.DB, userID, role string, sessionVersion int, attempt loginAttempt) (LoginResponse, error) {
+	enabled, err := isTwoFactorEnabled(db, userID)
+	if err != nil {
+		return LoginResponse{}, errors.New("gagal memeriksa status 2FA")
+	}
+
+	if enabled {
+		challenge, err := signChallengeToken(userID)
+		if err != nil {
+			return LoginResponse{}, err
+		}
+		return LoginResponse{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	recordLoginSuccess(db, userID, attempt.Identifier, attempt.Method, attempt.IP, attempt.UserAgent)
+	return generateJWT(userID, role, sessionVersion, false)
+}
+
+// VerifyTwoFactorLogin menukar challenge token + kode TOTP / kode pemulihan dengan token JWT
+func VerifyTwoFactorLogin(db *sql.DB, req TwoFactorVerifyRequest) (LoginResponse, error) {
+	userID, err := parseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		return LoginResponse{}, errors.New("sesi login tidak valid atau kadaluarsa, silakan login ulang")
+	}
+
+	if err := checkLoginLock(getLimitStore(db), loginAccountKey(userID, ""), req.IP); err != nil {
+		return LoginResponse{}, err
+	}
+
+	var valid bool
+	if req.RecoveryCode != "" {
+		valid, err = useRecoveryCode(db, userID, req.RecoveryCode)
+	} else {
+		valid, err = verifyUserTOTP(db, userID, req.Code)
+	}
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	if !valid {
+		if lockErr := recordLoginFailure(db, userID, userID, "totp", req.IP, req.UserAgent); lockErr != nil {
+			return LoginResponse{}, lockErr
+		}
+		return LoginResponse{}, errors.New("kode 2FA salah")
+	}
+
+	var role string
+	var sessionVersion int
+	err = db.QueryRow(`
+		SELECT role, session_version FROM users WHERE id = $1 AND deleted_at IS NULL
+	`, userID).Scan(&role, &sessionVersion)
+	if err != nil {
+		return LoginResponse{}, errors.New("user tidak ditemukan")
+	}
+
+	recordLoginSuccess(db, userID, userID, "totp", req.IP, req.UserAgent)
+
+	return generateJWT(userID, role, sessionVersion, true)
+}
+
+// ==========================
+// ENROLLMENT
+// ==========================
+
+// SetupTwoFactor membuat secret baru (belum aktif sampai dikonfirmasi lewat EnableTwoFactor)
+func SetupTwoFactor(db *sql.DB, userID string) (TwoFactorSetupResponse, error) {
+	enabled, err := isTwoFactorEnabled(db, userID)
+	if err != nil {
+		return TwoFactorSetupResponse{}, err
+	}
+	if enabled {
+		return TwoFactorSetupResponse{}, errors.New("2FA sudah aktif, nonaktifkan terlebih dahulu untuk mendaftar ulang")
+	}
+
+	var email string
+	if err := db.QueryRow("SELECT email FROM users WHERE id = $1 AND deleted_at IS NULL", userID).Scan(&email); err != nil {
+		return TwoFactorSetupResponse{}, errors.New("user tidak ditemukan")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return TwoFactorSetupResponse{}, errors.New("gagal membuat secret 2FA")
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO user_two_factor (user_id, secret, enabled, last_used_counter)
+		VALUES ($1, $2, false, 0)
+		ON CONFLICT (user_id)
+		DO UPDATE SET secret = EXCLUDED.secret, enabled = false, last_used_counter = 0, updated_at = NOW()
+	`, userID, secret)
+	if err != nil {
+		return TwoFactorSetupResponse{}, errors.New("gagal menyimpan secret 2FA")
+	}
+
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "UniSpace"
+	}
+	uri := totpURI(issuer, email, secret)
+
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return TwoFactorSetupResponse{}, errors.New("gagal membuat QR code")
+	}
+
+	return TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURL: uri,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// EnableTwoFactor mengonfirmasi enrollment dengan kode pertama dari aplikasi authenticator
+func EnableTwoFactor(db *sql.DB, userID string, code string) (TwoFactorEnableResponse, error) {
+	var secret string
+	err := db.QueryRow(`
+		SELECT secret FROM user_two_factor WHERE user_id = $1 AND enabled = false
+	`, userID).Scan(&secret)
+	if err == sql.ErrNoRows {
+		return TwoFactorEnableResponse{}, errors.New("lakukan setup 2FA terlebih dahulu")
+	} else if err != nil {
+		return TwoFactorEnableResponse{}, err
+	}
+
+	counter := verifyTOTP(secret, code, time.Now())
+	if counter < 0 {
+		return TwoFactorEnableResponse{}, errors.New("kode 2FA salah")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return TwoFactorEnableResponse{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE user_two_factor
+		SET enabled = true, enabled_at = NOW(), last_used_counter = $2, updated_at = NOW()
+		WHERE user_id = $1
+	`, userID, counter)
+	if err != nil {
+		return TwoFactorEnableResponse{}, errors.New("gagal mengaktifkan 2FA")
+	}
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return TwoFactorEnableResponse{}, err
+	}
+
+	var role string
+	var sessionVersion int
+	if err := tx.QueryRow("SELECT role, session_version FROM users WHERE id = $1", userID).Scan(&role, &sessionVersion); err != nil {
+		return TwoFactorEnableResponse{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return TwoFactorEnableResponse{}, err
+	}
+
+	token, err := generateJWT(userID, role, sessionVersion, true)
+	if err != nil {
+		return TwoFactorEnableResponse{}, err
+	}
+
+	return TwoFactorEnableResponse{RecoveryCodes: codes, Token: token.Token}, nil
+}
+
+// DisableTwoFactor mematikan 2FA (butuh password + kode 2FA / kode pemulihan)
+func DisableTwoFactor(db *sql.DB, userID string, req TwoFactorDisableRequest) error {
+	var passwordHash, role string
+	err := db.QueryRow(`
+		SELECT COALESCE(password_hash, ''), role FROM users WHERE id = $1 AND deleted_at IS NULL
+	`, userID).Scan(&passwordHash, &role)
+	if err != nil {
+		return errors.New("user tidak ditemukan")
+	}
+
+	if role == "admin" && adminTwoFactorRequired() {
+		return errors.New("2FA wajib untuk akun admin dan tidak dapat dinonaktifkan")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)); err != nil {
+		return errors.New("password salah")
+	}
+
+	valid, err := verifyUserTOTP(db, userID, req.Code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		if valid, err = useRecoveryCode(db, userID, req.Code); err != nil || !valid {
+			return errors.New("kode 2FA salah")
+		}
+	}
+
+	return deleteTwoFactor(db, userID)
+}
+
+// RegenerateRecoveryCodes mengganti semua kode pemulihan lama
+func RegenerateRecoveryCodes(db *sql.DB, userID string, code string) ([]string, error) {
+	valid, err := verifyUserTOTP(db, userID, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, errors.New("kode 2FA salah")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, tx.Commit()
+}
+
+func GetTwoFactorStatus(db *sql.DB, userID string) (TwoFactorStatus, error) {
+	var status TwoFactorStatus
+	var role string
+
+	err := db.QueryRow(`
+		SELECT u.role, COALESCE(t.enabled, false),
+			(SELECT COUNT(*) FROM two_factor_recovery_codes r WHERE r.user_id = u.id AND r.used_at IS NULL)
+		FROM users u
+		LEFT JOIN user_two_factor t ON t.user_id = u.id
+		WHERE u.id = $1 AND u.deleted_at IS NULL
+	`, userID).Scan(&role, &status.Enabled, &status.RecoveryCodesLeft)
+	if err != nil {
+		return status, err
+	}
+
+	status.Required = role == "admin" && adminTwoFactorRequired()
+	return status, nil
+}
+
+// ==========================
+// ADMIN: RESET 2FA USER
+// ==========================
+// Dipakai jika user kehilangan HP sekaligus kode pemulihannya
+func ResetTwoFactor(db *sql.DB, userID string) error {
+	enabled, err := isTwoFactorEnabled(db, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return errors.New("2FA user ini tidak aktif")
+	}
+
+	if err := deleteTwoFactor(db, userID); err != nil {
+		return err
+	}
+
+	notifyUser(db, userID, "2FA Akun UniSpace Direset",
+		"Peringatan Keamanan UniSpace\n\n"+
+			"Autentikasi dua faktor (2FA) akun Anda telah direset oleh admin. "+
+			"Silakan login dan aktifkan kembali 2FA dari halaman profil.\n\n"+
+			"Jika Anda tidak meminta reset ini, segera hubungi admin.")
+	return nil
+}
+
+// ==========================
+// HELPER FUNCTIONS
+// ==========================
+
+func isTwoFactorEnabled(db *sql.DB, userID string) (bool, error) {
+	var enabled bool
+	err := db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM user_two_factor WHERE user_id = $1 AND enabled = true)
+	`, userID).Scan(&enabled)
+	return enabled, err
+}
+
+// verifyUserTOTP memvalidasi kode dan menolak kode yang sudah pernah dipakai (replay)
+func verifyUserTOTP(db *sql.DB, userID, code string) (bool, error) {
+	var secret string
+	err := db.QueryRow(`
+		SELECT secret FROM user_two_factor WHERE user_id = $1 AND enabled = true
+	`, userID).Scan(&secret)
+	if err == sql.ErrNoRows {
+		return false, errors.New("2FA belum aktif")
+	} else if err != nil {
+		return false, err
+	}
+
+	counter := verifyTOTP(secret, code, time.Now())
+	if counter < 0 {
+		return false, nil
+	}
+
+	res, err := db.Exec(`
+		UPDATE user_two_factor SET last_used_counter = $2, updated_at = NOW()
+		WHERE user_id = $1 AND last_used_counter < $2
+	`, userID, counter)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func useRecoveryCode(db *sql.DB, userID, code string) (bool, error) {
+	res, err := db.Exec(`
+		UPDATE two_factor_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, hashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID string) ([]string, error) {
+	if _, err := tx.Exec("DELETE FROM two_factor_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, errors.New("gagal membuat kode pemulihan")
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO two_factor_recovery_codes (user_id, code_hash) VALUES ($1, $2)
+		`, userID, hashRecoveryCode(code))
+		if err != nil {
+			return nil, errors.New("gagal menyimpan kode pemulihan")
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func deleteTwoFactor(db *sql.DB, userID string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM two_factor_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_two_factor WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// generateRecoveryCode menghasilkan kode seperti: K7QM-2XPA
+func generateRecoveryCode() (string, error) {
+	const charset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	result := make([]byte, 8)
+	for i := range result {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			return "", err
+		}
+		result[i] = charset[num.Int64()]
+	}
+	return string(result[:4]) + "-" + string(result[4:]), nil
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeRecoveryCode(code string) string {
+	out := make([]rune, 0, len(code))
+	for _, r := range code {
+		if r == '-' || r == ' ' {
+			continue
+		}
+		if r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+func signChallengeToken(userID string) (string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return "", errors.New("JWT_SECRET belum diset")
+	}
+
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"purpose": "2fa_challenge",
+		"exp":     time.Now().Add(twoFactorChallengeTTL).Unix(),
+		"iat":     time.Now().Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(purposeSigningKey(secret, "2fa_challenge"))
+	if err != nil {
+		return "", errors.New("gagal membuat token")
+	}
+	return signed, nil
+}
+
+func parseChallengeToken(tokenString string) (string, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("metode signing tidak valid")
+		}
+		return purposeSigningKey(os.Getenv("JWT_SECRET"), "2fa_challenge"), nil
+	})
+	if err != nil || !token.Valid {
+		return "", errors.New("token tidak valid")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != "2fa_challenge" {
+		return "", errors.New("token tidak valid")
+	}
+
+	userID, _ := claims["user_id"].(string)
+	if userID == "" {
+		return "", errors.New("token tidak valid")
+	}
+	return userID, nil
+}
@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Format key: usk_<prefix>_<secret>. Prefix disimpan apa adanya untuk pencarian, key utuh disimpan sebagai hash.
+const apiKeyPrefix = "usk_"
+
+// Scope yang bisa diberikan ke API key
+const (
+	ScopeTicketsScan  = "tickets:scan"
+	ScopeScheduleRead = "schedule:read"
+	ScopeDisplayRead  = "display:read"
+)
+
+var apiKeyScopes = map[string]string{
+	ScopeTicketsScan:  "Scan tiket check-in / check-out (POST /bookings/verify-ticket)",
+	ScopeScheduleRead: "Membaca jadwal fasilitas (GET /facilities/:id/schedule)",
+	ScopeDisplayRead:  "Token layar pintu ruangan (GET /display/facilities/:id)",
+}
+
+// ==========================
+// REQUEST / RESPONSE OBJECTS
+// ==========================
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" example:"Kiosk Gedung A"`
+	Scopes        []string `json:"scopes" example:"tickets:scan"`
+	FacilityID    string   `json:"facility_id"`     // Opsional: batasi key ke satu fasilitas
+	ExpiresInDays int      `json:"expires_in_days"` // 0 = tidak kadaluarsa
+}
+
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	FacilityID *string    `json:"facility_id"`
+	CreatedBy  *string    `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP *string    `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"` // Hanya ditampilkan sekali
+}
+
+// apiKeyPrincipal adalah identitas hasil autentikasi API key (disimpan di Locals)
+type apiKeyPrincipal struct {
+	ID         string
+	Scopes     []string
+	FacilityID string
+}
+
+func (p *apiKeyPrincipal) hasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ==========================
+// ADMIN: KELOLA API KEY
+// ==========================
+
+// CreateAPIKey membuat key baru. Key utuh dikembalikan sekali saja dan tidak bisa dilihat lagi.
+func CreateAPIKey(db *sql.DB, adminID string, req CreateAPIKeyRequest) (CreateAPIKeyResponse, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return CreateAPIKeyResponse{}, errors.New("nama API key wajib diisi")
+	}
+	if len(req.Scopes) == 0 {
+		return CreateAPIKeyResponse{}, errors.New("minimal satu scope wajib dipilih")
+	}
+	for _, s := range req.Scopes {
+		if _, ok := apiKeyScopes[s]; !ok {
+			return CreateAPIKeyResponse{}, errors.New("scope tidak dikenal: " + s)
+		}
+	}
+	// Token layar pintu selalu terikat ke satu ruangan
+	if req.FacilityID == "" {
+		for _, s := range req.Scopes {
+			if s == ScopeDisplayRead {
+				return CreateAPIKeyResponse{}, errors.New("scope " + ScopeDisplayRead + " wajib dibatasi ke satu fasilitas")
+			}
+		}
+	}
+	if req.ExpiresInDays < 0 {
+		return CreateAPIKeyResponse{}, errors.New("masa berlaku tidak valid")
+	}
+
+	var facilityID interface{}
+	if req.FacilityID != "" {
+		var exists bool
+		err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM facilities WHERE id = $1)", req.FacilityID).Scan(&exists)
+		if err != nil || !exists {
+			return CreateAPIKeyResponse{}, errors.New("fasilitas tidak ditemukan")
+		}
+		facilityID = req.FacilityID
+	}
+
+	var expiresAt interface{}
+	if req.ExpiresInDays > 0 {
+		expiresAt = time.Now().AddDate(0, 0, req.ExpiresInDays)
+	}
+
+	prefix, err := randomURLToken(6)
+	if err != nil {
+		return CreateAPIKeyResponse{}, errors.New("gagal membuat API key")
+	}
+	secret, err := randomURLToken(32)
+	if err != nil {
+		return CreateAPIKeyResponse{}, errors.New("gagal membuat API key")
+	}
+	// Karakter "_" dipakai sebagai pemisah, jadi tidak boleh ada di prefix
+	prefix = strings.NewReplacer("_", "x", "-", "y").Replace(prefix)
+	key := apiKeyPrefix + prefix + "_" + secret
+
+	var id string
+	err = db.QueryRow(`
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, facility_id, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, req.Name, prefix, hashAPIKey(key), pq.Array(req.Scopes), facilityID, adminID, expiresAt).Scan(&id)
+	if err != nil {
+		return CreateAPIKeyResponse{}, errors.New("gagal menyimpan API key")
+	}
+
+	created, err := getAPIKey(db, id)
+	if err != nil {
+		return CreateAPIKeyResponse{}, err
+	}
+	return CreateAPIKeyResponse{APIKey: created, Key: key}, nil
+}
+
+// ListAPIKeys menampilkan semua key (tanpa hash), terbaru di atas
+func ListAPIKeys(db *sql.DB) ([]APIKey, error) {
+	rows, err := db.Query(apiKeySelect + ` ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey mencabut key; berlaku seketika untuk request berikutnya
+func RevokeAPIKey(db *sql.DB, adminID, keyID string) error {
+	res, err := db.Exec(`
+		UPDATE api_keys SET revoked_at = NOW(), revoked_by = $2
+		WHERE id = $1 AND revoked_at IS NULL
+	`, keyID, adminID)
+	if err != nil {
+		return errors.New("gagal mencabut API key")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("API key tidak ditemukan atau sudah dicabut")
+	}
+	return nil
+}
+
+// ==========================
+// AUTENTIKASI
+// ==========================
+
+// authenticateAPIKey memvalidasi key dan mencatat waktu terakhir dipakai
+func authenticateAPIKey(db *sql.DB, key, ip string) (*apiKeyPrincipal, error) {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	if !ok {
+		return nil, errors.New("API key tidak valid")
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" {
+		return nil, errors.New("API key tidak valid")
+	}
+
+	var (
+		p          apiKeyPrincipal
+		keyHash    string
+		facilityID sql.NullString
+		expiresAt  sql.NullTime
+		revokedAt  sql.NullTime
+	)
+	err := db.QueryRow(`
+		SELECT id, key_hash, scopes, facility_id, expires_at, revoked_at
+		FROM api_keys WHERE prefix = $1
+	`, prefix).Scan(&p.ID, &keyHash, pq.Array(&p.Scopes), &facilityID, &expiresAt, &revokedAt)
+	if err != nil {
+		return nil, errors.New("API key tidak valid")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(keyHash), []byte(hashAPIKey(key))) != 1 {
+		return nil, errors.New("API key tidak valid")
+	}
+	if revokedAt.Valid {
+		return nil, errors.New("API key sudah dicabut")
+	}
+	if expiresAt.Valid && time.Now().After(expiresAt.Time) {
+		return nil, errors.New("API key sudah kadaluarsa")
+	}
+	p.FacilityID = facilityID.String
+
+	// Cukup dicatat maksimal sekali per menit agar kiosk yang sibuk tidak membebani DB
+	_, _ = db.Exec(`
+		UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, p.ID, ip)
+
+	return &p, nil
+}
+
+// ==========================
+// HELPERS
+// ==========================
+
+const apiKeySelect = `
+	SELECT id, name, prefix, scopes, facility_id, created_by, expires_at, last_used_at, last_used_ip, revoked_at, created_at
+	FROM api_keys`
+
+func getAPIKey(db *sql.DB, id string) (APIKey, error) {
+	row := db.QueryRow(apiKeySelect+` WHERE id = $1`, id)
+	k, err := scanAPIKey(row)
+	if err != nil {
+		return APIKey{}, errors.New("API key tidak ditemukan")
+	}
+	return k, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (APIKey, error) {
+	var (
+		k          APIKey
+		facilityID sql.NullString
+		createdBy  sql.NullString
+		expiresAt  sql.NullTime
+		lastUsedAt sql.NullTime
+		lastUsedIP sql.NullString
+		revokedAt  sql.NullTime
+	)
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &facilityID, &createdBy,
+		&expiresAt, &lastUsedAt, &lastUsedIP, &revokedAt, &k.CreatedAt)
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	if facilityID.Valid {
+		k.FacilityID = &facilityID.String
+	}
+	if createdBy.Valid {
+		k.CreatedBy = &createdBy.String
+	}
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	if lastUsedIP.Valid {
+		k.LastUsedIP = &lastUsedIP.String
+	}
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+	return k, nil
+}
+
+// hashAPIKey: SHA-256 cukup karena key berentropi tinggi (bukan password pilihan manusia)
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
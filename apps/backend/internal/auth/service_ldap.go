@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// ==========================
+// LOGIN VIA LDAP
+// ==========================
+
+// ldapPlaceholderDomain dipakai jika entry direktori tidak punya email (kolom users.email wajib diisi)
+const ldapPlaceholderDomain = "@ldap.users"
+
+// syncLDAPUser memetakan entry direktori ke user lokal (auto-provision), menyinkronkan profil dan role
+func syncLDAPUser(db *sql.DB, cfg ldapConfig, entry *ldapEntry) (string, string, int, error) {
+	email := entry.Email
+	if email == "" {
+		email = strings.ToLower(entry.ID) + ldapPlaceholderDomain
+	}
+
+	ident := externalIdentity{
+		Issuer:         cfg.URL,
+		Subject:        entry.ID,
+		Email:          email,
+		EmailVerified:  entry.Email != "", // Email dari direktori kampus dianggap terverifikasi
+		Name:           entry.Name,
+		Username:       entry.ID,
+		Department:     entry.Department,
+		IdentityNumber: entry.IdentityNumber,
+		Position:       entry.Position,
+		Role:           cfg.mapRole(entry.Groups),
+	}
+
+	return resolveExternalUser(db, ident)
+}
+
+// isPlaceholderEmail: email buatan sistem (user OTP / LDAP tanpa email) tidak bisa menerima surat
+func isPlaceholderEmail(email string) bool {
+	return strings.HasSuffix(email, "@phone.users") || strings.HasSuffix(email, ldapPlaceholderDomain)
+}
@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"campus-reservation-backend/internal/mailer"
+	"campus-reservation-backend/internal/whatsapp"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ==========================
+// BATAS LOGIN
+// ==========================
+const (
+	loginMaxFailPerAccount = 5  // Akun dikunci setelah 5 kali gagal dalam satu window
+	loginMaxFailPerIP      = 20 // IP dikunci setelah 20 kali gagal dalam satu window
+	loginFailWindow        = 15 * time.Minute
+	loginCooldownBase      = 5 * time.Minute // Berlipat ganda setiap kali terkunci lagi
+	loginCooldownMax       = 24 * time.Hour
+)
+
+// LoginAttempt adalah satu baris riwayat login untuk admin
+type LoginAttempt struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id,omitempty"`
+	UserName   string    `json:"user_name,omitempty"`
+	Identifier string    `json:"identifier"`
+	Method     string    `json:"method"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	Success    bool      `json:"success"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ==========================
+// LOCKOUT CHECK
+// ==========================
+
+// loginAccountKey memakai user ID jika user ditemukan, agar login via email & OTP berbagi counter yang sama
+func loginAccountKey(userID, identifier string) string {
+	if userID != "" {
+		return "login:account:" + userID
+	}
+	return "login:account:" + strings.ToLower(strings.TrimSpace(identifier))
+}
+
+func loginIPKey(ip string) string {
+	return "login:ip:" + ip
+}
+
+func checkLoginLock(store LimitStore, accountKey, ip string) error {
+	keys := []string{accountKey}
+	if ip != "" {
+		keys = append(keys, loginIPKey(ip))
+	}
+
+	for _, key := range keys {
+		remaining, err := store.BlockedFor(key)
+		if err != nil {
+			return fmt.Errorf("gagal memeriksa status login")
+		}
+		if remaining > 0 {
+			return &RateLimitError{RetryAfter: remaining}
+		}
+	}
+	return nil
+}
+
+// ==========================
+// RECORD ATTEMPTS
+// ==========================
+
+// recordLoginFailure mencatat login gagal dan mengunci akun / IP jika melewati batas.
+// Mengembalikan RateLimitError jika percobaan ini memicu penguncian.
+func recordLoginFailure(db *sql.DB, userID, identifier, method, ip, userAgent string) error {
+	insertLoginAttempt(db, userID, identifier, method, ip, userAgent, false)
+
+	store := getLimitStore(db)
+
+	if ip != "" {
+		hits, err := store.Hit(loginIPKey(ip), loginFailWindow)
+		if err == nil && hits > loginMaxFailPerIP {
+			if d, err := store.Block(loginIPKey(ip), loginCooldownBase, loginCooldownMax); err == nil {
+				return &RateLimitError{RetryAfter: d}
+			}
+		}
+	}
+
+	accountKey := loginAccountKey(userID, identifier)
+	hits, err := store.Hit(accountKey, loginFailWindow)
+	if err != nil || hits < loginMaxFailPerAccount {
+		return nil
+	}
+
+	d, err := store.Block(accountKey, loginCooldownBase, loginCooldownMax)
+	if err != nil {
+		return nil
+	}
+
+	if userID != "" {
+		msg := fmt.Sprintf("Peringatan Keamanan UniSpace\n\n"+
+			"Terdeteksi %d kali percobaan login gagal pada akun Anda (IP: %s). "+
+			"Login dikunci sementara selama %d menit.\n\n"+
+			"Jika ini bukan Anda, segera reset password akun Anda.", hits, ip, int(d.Minutes()))
+		notifyUser(db, userID, "Akun UniSpace Dikunci Sementara", msg)
+	}
+
+	return &RateLimitError{RetryAfter: d}
+}
+
+// recordLoginSuccess mencatat login berhasil, mereset counter akun, dan memberi notifikasi jika perangkat baru
+func recordLoginSuccess(db *sql.DB, userID, identifier, method, ip, userAgent string) {
+	insertLoginAttempt(db, userID, identifier, method, ip, userAgent, true)
+
+	_ = getLimitStore(db).Reset(loginAccountKey(userID, identifier))
+
+	deviceHash := hashDevice(userAgent)
+
+	var knownDevices int
+	var isKnown bool
+	err := db.QueryRow(`
+		SELECT COUNT(*), COALESCE(BOOL_OR(device_hash = $2), false)
+		FROM user_devices WHERE user_id = $1
+	`, userID, deviceHash).Scan(&knownDevices, &isKnown)
+	if err != nil {
+		return
+	}
+
+	_, _ = db.Exec(`
+		INSERT INTO user_devices (user_id, device_hash, user_agent, last_ip)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, device_hash)
+		DO UPDATE SET last_ip = EXCLUDED.last_ip, last_seen_at = NOW()
+	`, userID, deviceHash, userAgent, ip)
+
+	// Login pertama kali tidak dianggap mencurigakan
+	if knownDevices > 0 && !isKnown {
+		loc, err := time.LoadLocation("Asia/Jakarta")
+		if err != nil {
+			loc = time.Local
+		}
+
+		msg := fmt.Sprintf("Peringatan Keamanan UniSpace\n\n"+
+			"Akun Anda baru saja login dari perangkat baru.\n"+
+			"Waktu: %s WIB\nIP: %s\nPerangkat: %s\n\n"+
+			"Jika ini bukan Anda, segera reset password akun Anda.",
+			time.Now().In(loc).Format("02 Jan 2006 15:04"), ip, userAgent)
+		notifyUser(db, userID, "Login dari Perangkat Baru", msg)
+	}
+}
+
+func insertLoginAttempt(db *sql.DB, userID, identifier, method, ip, userAgent string, success bool) {
+	var userIDVal interface{} = userID
+	if userID == "" {
+		userIDVal = nil
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO login_attempts (user_id, identifier, method, ip_address, user_agent, success)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, userIDVal, identifier, method, ip, userAgent, success)
+	if err != nil {
+		fmt.Printf("Gagal mencatat login attempt: %v\n", err)
+	}
+}
+
+func hashDevice(userAgent string) string {
+	if userAgent == "" {
+		userAgent = "unknown"
+	}
+	sum := sha256.Sum256([]byte(userAgent))
+	return hex.EncodeToString(sum[:])
+}
+
+// ==========================
+// NOTIFIKASI USER
+// ==========================
+
+// notifyUser mengirim pesan ke WhatsApp (jika nomor terverifikasi), jika tidak ke email
+func notifyUser(db *sql.DB, userID, subject, msg string) {
+	var email string
+	var phone sql.NullString
+	var phoneVerified bool
+	err := db.QueryRow(`
+		SELECT email, phone, COALESCE(is_phone_verified, false) FROM users WHERE id = $1
+	`, userID).Scan(&email, &phone, &phoneVerified)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		if phoneVerified && phone.Valid {
+			if err := whatsapp.SendMessage(phone.String, msg); err != nil {
+				fmt.Printf("Gagal mengirim notifikasi WA ke %s: %v\n", phone.String, err)
+			}
+			return
+		}
+
+		if !isPlaceholderEmail(email) {
+			if err := mailer.Send(email, subject, msg); err != nil {
+				fmt.Printf("Gagal mengirim notifikasi email ke %s: %v\n", email, err)
+			}
+		}
+	}()
+}
+
+// ==========================
+// ADMIN: LOCKOUT & RIWAYAT LOGIN
+// ==========================
+
+func GetActiveLockouts(db *sql.DB, prefix string) ([]LimitEntry, error) {
+	return getLimitStore(db).Active(prefix)
+}
+
+func ClearLockout(db *sql.DB, key string) error {
+	return getLimitStore(db).Reset(key)
+}
+
+func GetLoginAttempts(db *sql.DB, userID, ip string) ([]LoginAttempt, error) {
+	query := `
+		SELECT
+			la.id, COALESCE(la.user_id::text, ''), COALESCE(u.name, ''),
+			la.identifier, la.method, COALESCE(la.ip_address, ''), COALESCE(la.user_agent, ''),
+			la.success, la.created_at
+		FROM login_attempts la
+		LEFT JOIN users u ON la.user_id = u.id
+		WHERE 1=1
+	`
+
+	var args []interface{}
+	argCounter := 1
+
+	if userID != "" {
+		query += fmt.Sprintf(" AND la.user_id = $%d", argCounter)
+		args = append(args, userID)
+		argCounter++
+	}
+
+	if ip != "" {
+		query += fmt.Sprintf(" AND la.ip_address = $%d", argCounter)
+		args = append(args, ip)
+		argCounter++
+	}
+
+	query += " ORDER BY la.created_at DESC LIMIT 200"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []LoginAttempt{}
+	for rows.Next() {
+		var a LoginAttempt
+		if err := rows.Scan(
+			&a.ID, &a.UserID, &a.UserName,
+			&a.Identifier, &a.Method, &a.IPAddress, &a.UserAgent,
+			&a.Success, &a.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, nil
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+// OIDCStateCookie menyimpan state di browser yang memulai login, agar callback milik sesi lain
+// (login CSRF: korban dibuat login sebagai akun penyerang) ditolak
+const OIDCStateCookie = "oidc_state"
+
+// ==========================
+// REQUEST / RESPONSE OBJECTS
+// ==========================
+
+type OIDCCallbackRequest struct {
+	Code  string `query:"code"`
+	State string `query:"state"`
+	Error string `query:"error"`
+	// BrowserState: nilai cookie OIDCStateCookie dari browser yang membuka callback
+	BrowserState string `json:"-"`
+	IP           string `json:"-"`
+	UserAgent    string `json:"-"`
+}
+
+// ==========================
+// LOGIN FLOW
+// ==========================
+
+// StartOIDCLogin membuat state + nonce + PKCE verifier lalu mengembalikan URL login SSO beserta state
+// (disimpan handler di cookie browser)
+func StartOIDCLogin(db *sql.DB) (string, string, error) {
+	cfg, err := loadOIDCConfig()
+	if err != nil {
+		return "", "", err
+	}
+
+	provider, err := getOIDCProvider(cfg)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomURLToken(24)
+	if err != nil {
+		return "", "", errors.New("gagal membuat state login")
+	}
+	nonce, err := randomURLToken(24)
+	if err != nil {
+		return "", "", errors.New("gagal membuat state login")
+	}
+	verifier, err := randomURLToken(48)
+	if err != nil {
+		return "", "", errors.New("gagal membuat state login")
+	}
+
+	// Bersihkan state lama yang tidak pernah diselesaikan
+	_, _ = db.Exec("DELETE FROM oidc_login_states WHERE expires_at < NOW()")
+
+	_, err = db.Exec(`
+		INSERT INTO oidc_login_states (state, code_verifier, nonce, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`, state, verifier, nonce, time.Now().Add(oidcStateTTL))
+	if err != nil {
+		return "", "", errors.New("gagal menyimpan state login")
+	}
+
+	return provider.authCodeURL(cfg, state, nonce, verifier), state, nil
+}
+
+// CompleteOIDCLogin memproses callback dari IdP: validasi state, tukar code, verifikasi ID token,
+// petakan ke user lokal (auto-provision jika belum ada), lalu terbitkan token seperti login biasa.
+func CompleteOIDCLogin(db *sql.DB, req OIDCCallbackRequest) (LoginResponse, error) {
+	if req.Error != "" {
+		return LoginResponse{}, errors.New("login SSO dibatalkan atau ditolak")
+	}
+	if req.Code == "" || req.State == "" {
+		return LoginResponse{}, errors.New("parameter callback SSO tidak lengkap")
+	}
+	// State wajib sama dengan cookie browser yang memulai login
+	if req.BrowserState == "" || subtle.ConstantTimeCompare([]byte(req.BrowserState), []byte(req.State)) != 1 {
+		return LoginResponse{}, errors.New("sesi login SSO tidak valid atau kadaluarsa, silakan ulangi")
+	}
+
+	cfg, err := loadOIDCConfig()
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	// State hanya bisa dipakai sekali
+	var verifier, nonce string
+	err = db.QueryRow(`
+		DELETE FROM oidc_login_states
+		WHERE state = $1 AND expires_at > NOW()
+		RETURNING code_verifier, nonce
+	`, req.State).Scan(&verifier, &nonce)
+	if err != nil {
+		return LoginResponse{}, errors.New("sesi login SSO tidak valid atau kadaluarsa, silakan ulangi")
+	}
+
+	provider, err := getOIDCProvider(cfg)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	tok, err := provider.exchangeCode(cfg, req.Code, verifier)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	claims, err := provider.verifyIDToken(cfg, tok.IDToken, nonce)
+	if err != nil {
+		log.Println("OIDC:", err)
+		return LoginResponse{}, errors.New("token SSO tidak valid")
+	}
+	provider.userinfo(tok.AccessToken, claims)
+
+	ident := mapOIDCClaims(cfg, provider.discovery.Issuer, claims)
+
+	userID, role, sessionVersion, err := resolveExternalUser(db, ident)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	if err := checkLoginLock(getLimitStore(db), loginAccountKey(userID, ""), req.IP); err != nil {
+		return LoginResponse{}, err
+	}
+
+	return issueLoginToken(db, userID, role, sessionVersion,
+		loginAttempt{Identifier: ident.Email, Method: "oidc", IP: req.IP, UserAgent: req.UserAgent})
+}
+
+// mapOIDCClaims memetakan claim standar + claim kampus (nama claim bisa diatur lewat env)
+func mapOIDCClaims(cfg oidcConfig, issuer string, claims jwt.MapClaims) externalIdentity {
+	ident := externalIdentity{
+		Issuer:         issuer,
+		Subject:        claimString(claims, "sub"),
+		Email:          strings.ToLower(claimString(claims, "email")),
+		Name:           claimString(claims, "name"),
+		Username:       claimString(claims, "preferred_username"),
+		Department:     claimString(claims, cfg.ClaimDepartment),
+		IdentityNumber: claimString(claims, cfg.ClaimIdentity),
+	}
+
+	// email_verified bisa berupa bool atau string "true" tergantung IdP
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		ident.EmailVerified = v
+	case string:
+		ident.EmailVerified = v == "true"
+	}
+
+	return ident
+}
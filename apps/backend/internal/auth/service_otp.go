@@ -2,10 +2,12 @@ package auth
 
 import (
 	"campus-reservation-backend/internal/whatsapp"
+	"crypto/rand"
+	"crypto/subtle"
 	"database/sql"
 	"errors"
 	"fmt"
-	"math/rand"
+	"math/big"
 	"os"
 	"strings"
 	"time"
@@ -21,11 +23,26 @@ import (
 type RequestOTPRequest struct {
 	Phone string `json:"phone"`
 	Name  string `json:"name"`
+	IP    string `json:"-"` // Diisi handler dari c.IP(), untuk rate limit per IP
 }
 
+// ==========================
+// BATAS OTP
+// ==========================
+const (
+	otpRequestsPerPhone = 3  // Maksimal request OTP per nomor dalam satu window
+	otpRequestsPerIP    = 10 // Maksimal request OTP per IP dalam satu window
+	otpRequestWindow    = 15 * time.Minute
+	otpCooldownBase     = 15 * time.Minute // Cooldown pertama, berlipat ganda setiap pelanggaran berikutnya
+	otpCooldownMax      = 24 * time.Hour
+	otpMaxVerifyAttempt = 5 // Kode OTP dinonaktifkan setelah 5 kali salah
+)
+
 type VerifyOTPRequest struct {
-	Phone string `json:"phone"`
-	Code  string `json:"code"`
+	Phone     string `json:"phone"`
+	Code      string `json:"code"`
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 // ==========================
@@ -40,6 +57,13 @@ func RequestOTP(db *sql.DB, req RequestOTPRequest, flowType string) error {
 		return errors.New("nomor telepon tidak valid")
 	}
 
+	// -------------------------------------------------------------
+	// 0. RATE LIMIT (sebelum memanggil WA gateway agar gateway tidak di-spam)
+	// -------------------------------------------------------------
+	if err := checkOTPRequestLimit(getLimitStore(db), cleanPhone, req.IP); err != nil {
+		return err
+	}
+
 	// -------------------------------------------------------------
 	// 1. VALIDASI KE WHATSAPP GATEWAY
 	// -------------------------------------------------------------
@@ -65,6 +89,17 @@ func RequestOTP(db *sql.DB, req RequestOTPRequest, flowType string) error {
 	if flowType == "login" && !exists {
 		return errors.New("nomor HP belum terdaftar, silahkan register")
 	}
+	// Reset password hanya untuk nomor yang sudah terverifikasi
+	if flowType == "reset_password" {
+		var verified bool
+		err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE phone = $1 AND is_phone_verified = true AND deleted_at IS NULL)", cleanPhone).Scan(&verified)
+		if err != nil {
+			return err
+		}
+		if !verified {
+			return errors.New("nomor HP belum terverifikasi, gunakan reset password via email")
+		}
+	}
 	if flowType == "register" && exists {
 		return errors.New("nomor HP sudah terdaftar, silahkan login")
 	}
@@ -76,7 +111,10 @@ func RequestOTP(db *sql.DB, req RequestOTPRequest, flowType string) error {
 	// -------------------------------------------------------------
 	// 3. GENERATE & SIMPAN OTP
 	// -------------------------------------------------------------
-	otpCode := fmt.Sprintf("%06d", rand.Intn(1000000))
+	otpCode, err := generateOTPCode()
+	if err != nil {
+		return errors.New("gagal membuat kode OTP")
+	}
 
 	// Hapus kode lama
 	_, _ = db.Exec("DELETE FROM verification_codes WHERE phone_number = $1", cleanPhone)
@@ -130,26 +168,36 @@ func VerifyRegisterOTP(db *sql.DB, req VerifyOTPRequest, name string, password s
 	_, _ = db.Exec(`INSERT INTO profiles (user_id, phone_number) VALUES ($1, $2)`, userID, cleanPhone)
 	_, _ = db.Exec("DELETE FROM verification_codes WHERE phone_number = $1", cleanPhone)
 
-	return generateJWT(userID, "user")
+	return generateJWT(userID, "user", 0, false)
 }
 
 // 3. Verify Login OTP
 func VerifyLoginOTP(db *sql.DB, req VerifyOTPRequest) (LoginResponse, error) {
 	cleanPhone := cleanPhoneNumber(req.Phone)
 
-	if err := validateOTP(db, cleanPhone, req.Code, "login"); err != nil {
-		return LoginResponse{}, err
-	}
-
 	var userID, role string
-	err := db.QueryRow("SELECT id, role FROM users WHERE phone = $1", cleanPhone).Scan(&userID, &role)
+	var sessionVersion int
+	err := db.QueryRow("SELECT id, role, session_version FROM users WHERE phone = $1", cleanPhone).Scan(&userID, &role, &sessionVersion)
 	if err != nil {
 		return LoginResponse{}, errors.New("user tidak ditemukan")
 	}
 
+	// Lockout berlaku sama seperti login password (counter per akun & per IP)
+	if err := checkLoginLock(getLimitStore(db), loginAccountKey(userID, cleanPhone), req.IP); err != nil {
+		return LoginResponse{}, err
+	}
+
+	if err := validateOTP(db, cleanPhone, req.Code, "login"); err != nil {
+		if lockErr := recordLoginFailure(db, userID, cleanPhone, "otp", req.IP, req.UserAgent); lockErr != nil {
+			return LoginResponse{}, lockErr
+		}
+		return LoginResponse{}, err
+	}
+
 	_, _ = db.Exec("DELETE FROM verification_codes WHERE phone_number = $1", cleanPhone)
 
-	return generateJWT(userID, role)
+	return issueLoginToken(db, userID, role, sessionVersion,
+		loginAttempt{Identifier: cleanPhone, Method: "otp", IP: req.IP, UserAgent: req.UserAgent})
 }
 
 // 4. [BARU] Verify Change Phone OTP
@@ -203,13 +251,29 @@ func VerifyChangePhoneOTP(db *sql.DB, userID string, req VerifyOTPRequest) error
 // HELPER FUNCTIONS
 // ==========================
 
+// validateOTP memeriksa kode dalam satu transaksi: baris kode dikunci (FOR UPDATE) sehingga tebakan paralel
+// diproses bergantian dan batas percobaan tidak bisa dilewati. Kode yang benar langsung dihapus (sekali pakai).
 func validateOTP(db *sql.DB, phone, code, typeFlow string) error {
-	var expirationTime time.Time
-	err := db.QueryRow(`
-		SELECT expiration_time 
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		codeID         string
+		storedCode     string
+		expirationTime time.Time
+		attempts       int
+	)
+	err = tx.QueryRow(`
+		SELECT id, code, expiration_time, attempts
 		FROM verification_codes 
-		WHERE phone_number = $1 AND code = $2 AND type = $3
-	`, phone, code, typeFlow).Scan(&expirationTime)
+		WHERE phone_number = $1 AND type = $2
+		ORDER BY created_at DESC
+		LIMIT 1
+		FOR UPDATE
+	`, phone, typeFlow).Scan(&codeID, &storedCode, &expirationTime, &attempts)
 
 	if err == sql.ErrNoRows {
 		return errors.New("kode OTP salah atau tidak ditemukan")
@@ -220,20 +284,108 @@ func validateOTP(db *sql.DB, phone, code, typeFlow string) error {
 	if time.Now().After(expirationTime) {
 		return errors.New("kode OTP sudah kadaluarsa")
 	}
+	if attempts >= otpMaxVerifyAttempt {
+		return errors.New("kode OTP salah terlalu banyak, kode dinonaktifkan. Silakan minta kode baru nanti")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(storedCode), []byte(code)) == 1 {
+		if _, err := tx.Exec("DELETE FROM verification_codes WHERE id = $1", codeID); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	attempts++
+	if attempts >= otpMaxVerifyAttempt {
+		// Kode dinonaktifkan & nomor masuk cooldown sebelum boleh minta kode baru
+		if _, err := tx.Exec("DELETE FROM verification_codes WHERE id = $1", codeID); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		_, _ = getLimitStore(db).Block(otpPhoneKey(phone), otpCooldownBase, otpCooldownMax)
+		return errors.New("kode OTP salah terlalu banyak, kode dinonaktifkan. Silakan minta kode baru nanti")
+	}
+
+	if _, err := tx.Exec("UPDATE verification_codes SET attempts = $2 WHERE id = $1", codeID, attempts); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return fmt.Errorf("kode OTP salah, sisa percobaan %d kali", otpMaxVerifyAttempt-attempts)
+}
+
+func checkOTPRequestLimit(store LimitStore, phone, ip string) error {
+	type rule struct {
+		key string
+		max int
+	}
+
+	rules := []rule{{key: otpPhoneKey(phone), max: otpRequestsPerPhone}}
+	if ip != "" {
+		rules = append(rules, rule{key: "otp_request:ip:" + ip, max: otpRequestsPerIP})
+	}
+
+	// Cek cooldown yang masih aktif terlebih dahulu
+	for _, r := range rules {
+		remaining, err := store.BlockedFor(r.key)
+		if err != nil {
+			return errors.New("gagal memeriksa batas permintaan OTP")
+		}
+		if remaining > 0 {
+			return &RateLimitError{RetryAfter: remaining}
+		}
+	}
+
+	for _, r := range rules {
+		hits, err := store.Hit(r.key, otpRequestWindow)
+		if err != nil {
+			return errors.New("gagal memeriksa batas permintaan OTP")
+		}
+		if hits > r.max {
+			d, err := store.Block(r.key, otpCooldownBase, otpCooldownMax)
+			if err != nil {
+				return errors.New("gagal memeriksa batas permintaan OTP")
+			}
+			return &RateLimitError{RetryAfter: d}
+		}
+	}
 
 	return nil
 }
 
-func generateJWT(userID, role string) (LoginResponse, error) {
+func otpPhoneKey(phone string) string {
+	return "otp_request:phone:" + phone
+}
+
+// generateOTPCode membuat 6 digit angka acak menggunakan crypto/rand
+func generateOTPCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// mfa = true jika token diterbitkan setelah lolos verifikasi 2FA
+func generateJWT(userID, role string, sessionVersion int, mfa bool) (LoginResponse, error) {
 	claims := jwt.MapClaims{
-		"user_id": userID,
-		"role":    role,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-		"iat":     time.Now().Unix(),
+		"user_id":         userID,
+		"role":            role,
+		"session_version": sessionVersion,
+		"mfa":             mfa,
+		"exp":             time.Now().Add(24 * time.Hour).Unix(),
+		"iat":             time.Now().Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return LoginResponse{}, errors.New("JWT_SECRET belum diset")
+	}
+
 	signedToken, err := token.SignedString([]byte(secret))
 	if err != nil {
 		return LoginResponse{}, errors.New("gagal membuat token")
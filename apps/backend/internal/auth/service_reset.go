@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"campus-reservation-backend/internal/mailer"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Masa berlaku link reset password via email
+const resetLinkTTL = 30 * time.Minute
+
+// Masa berlaku link aktivasi akun tamu (membuat password pertama)
+const activationLinkTTL = 72 * time.Hour
+
+// ==========================
+// REQUEST OBJECTS
+// ==========================
+
+type ResetPasswordOTPRequest struct {
+	Phone       string `json:"phone"`
+	Code        string `json:"code"`
+	NewPassword string `json:"new_password"`
+}
+
+type ResetPasswordLinkRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordConfirmRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// ==========================
+// 1. RESET VIA OTP WHATSAPP
+// ==========================
+// Kode OTP diminta lewat RequestOTP dengan flowType "reset_password"
+func ResetPasswordWithOTP(db *sql.DB, req ResetPasswordOTPRequest) error {
+	if len(req.NewPassword) < 6 {
+		return errors.New("password minimal 6 karakter")
+	}
+
+	cleanPhone := cleanPhoneNumber(req.Phone)
+
+	if err := validateOTP(db, cleanPhone, req.Code, "reset_password"); err != nil {
+		return err
+	}
+
+	var userID string
+	err := db.QueryRow(`
+		SELECT id FROM users WHERE phone = $1 AND is_phone_verified = true AND deleted_at IS NULL
+	`, cleanPhone).Scan(&userID)
+	if err != nil {
+		return errors.New("user tidak ditemukan")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updatePasswordAndRevoke(tx, userID, req.NewPassword); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM verification_codes WHERE phone_number = $1", cleanPhone); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ==========================
+// 2. REQUEST LINK RESET (EMAIL)
+// ==========================
+// Hanya untuk akun tanpa nomor WhatsApp terverifikasi.
+// Response selalu sama (tanpa error) untuk email apa pun agar email terdaftar / jenis akunnya tidak bisa ditebak.
+func RequestPasswordResetLink(db *sql.DB, req ResetPasswordLinkRequest) error {
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		return errors.New("email wajib diisi")
+	}
+
+	// Email dummy milik user hasil register via WA tidak bisa menerima email
+	if isPlaceholderEmail(email) {
+		return nil
+	}
+
+	var userID string
+	var phoneVerified bool
+	err := db.QueryRow(`
+		SELECT id, COALESCE(is_phone_verified, false) FROM users WHERE email = $1 AND deleted_at IS NULL AND NOT is_guest
+	`, email).Scan(&userID, &phoneVerified)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Petunjuk memakai OTP hanya dikirim ke email pemilik akun, bukan ditampilkan di response
+	if phoneVerified {
+		go func() {
+			body := "Kami menerima permintaan reset password akun UniSpace Anda.\n\n" +
+				"Akun ini terhubung dengan WhatsApp, silakan reset password via OTP WhatsApp di halaman lupa password.\n\n" +
+				"Abaikan email ini jika Anda tidak merasa meminta reset password."
+			if err := mailer.Send(email, "Reset Password UniSpace", body); err != nil {
+				fmt.Printf("Gagal mengirim petunjuk reset ke %s: %v\n", email, err)
+			}
+		}()
+		return nil
+	}
+
+	jti := uuid.New().String()
+	expiresAt := time.Now().Add(resetLinkTTL)
+
+	_, err = db.Exec(`
+		INSERT INTO password_reset_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+	`, jti, userID, expiresAt)
+	if err != nil {
+		return errors.New("gagal membuat token reset password")
+	}
+
+	token, err := signResetToken(userID, jti, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", frontendURL(), token)
+	body := fmt.Sprintf("Kami menerima permintaan reset password akun UniSpace Anda.\n\n"+
+		"Buka link berikut untuk membuat password baru (berlaku %d menit, hanya bisa digunakan sekali):\n%s\n\n"+
+		"Abaikan email ini jika Anda tidak merasa meminta reset password.", int(resetLinkTTL.Minutes()), link)
+
+	go func() {
+		if err := mailer.Send(email, "Reset Password UniSpace", body); err != nil {
+			fmt.Printf("Gagal mengirim link reset ke %s: %v\n", email, err)
+		}
+	}()
+
+	return nil
+}
+
+// ==========================
+// 2.1 LINK AKTIVASI AKUN TAMU
+// ==========================
+// Akun tamu (booking organisasi luar) tidak punya password. Setelah booking disetujui, tamu membuat
+// password pertama lewat halaman reset password dengan token sekali pakai yang berlaku lebih lama.
+func CreateActivationLink(db *sql.DB, userID string) (string, error) {
+	jti := uuid.New().String()
+	expiresAt := time.Now().Add(activationLinkTTL)
+
+	_, err := db.Exec(`
+		INSERT INTO password_reset_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+	`, jti, userID, expiresAt)
+	if err != nil {
+		return "", errors.New("gagal membuat token aktivasi akun")
+	}
+
+	token, err := signResetToken(userID, jti, expiresAt)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/reset-password?token=%s", frontendURL(), token), nil
+}
+
+// ==========================
+// 3. KONFIRMASI RESET (EMAIL)
+// ==========================
+func ResetPasswordWithToken(db *sql.DB, req ResetPasswordConfirmRequest) error {
+	if len(req.NewPassword) < 6 {
+		return errors.New("password minimal 6 karakter")
+	}
+
+	userID, jti, err := parseResetToken(req.Token)
+	if err != nil {
+		return errors.New("link reset password tidak valid atau kadaluarsa")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Tandai token sudah dipakai (sekali pakai). Jika 0 baris terupdate, berarti token sudah dipakai / kadaluarsa.
+	res, err := tx.Exec(`
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE jti = $1 AND user_id = $2 AND used_at IS NULL AND expires_at > NOW()
+	`, jti, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("link reset password sudah digunakan atau kadaluarsa")
+	}
+
+	if err := updatePasswordAndRevoke(tx, userID, req.NewPassword); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ==========================
+// HELPER FUNCTIONS
+// ==========================
+
+func updatePasswordAndRevoke(tx *sql.Tx, userID string, newPassword string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("gagal mengenkripsi password")
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL
+	`, string(hashedPassword), userID)
+	if err != nil {
+		return errors.New("gagal menyimpan password baru")
+	}
+
+	// Semua sesi login lama dicabut
+	if err := revokeSessions(tx, userID); err != nil {
+		return errors.New("gagal mencabut sesi lama")
+	}
+
+	// Token reset lain yang masih aktif ikut dinonaktifkan
+	_, err = tx.Exec(`
+		UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL
+	`, userID)
+	return err
+}
+
+func signResetToken(userID, jti string, expiresAt time.Time) (string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return "", errors.New("JWT_SECRET belum diset")
+	}
+
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"purpose": "reset_password",
+		"jti":     jti,
+		"exp":     expiresAt.Unix(),
+		"iat":     time.Now().Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(purposeSigningKey(secret, "reset_password"))
+	if err != nil {
+		return "", errors.New("gagal membuat token")
+	}
+	return signed, nil
+}
+
+func parseResetToken(tokenString string) (userID string, jti string, err error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("metode signing tidak valid")
+		}
+		return purposeSigningKey(os.Getenv("JWT_SECRET"), "reset_password"), nil
+	})
+	if err != nil || !token.Valid {
+		return "", "", errors.New("token tidak valid")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != "reset_password" {
+		return "", "", errors.New("token tidak valid")
+	}
+
+	userID, _ = claims["user_id"].(string)
+	jti, _ = claims["jti"].(string)
+	if userID == "" || jti == "" {
+		return "", "", errors.New("token tidak valid")
+	}
+
+	return userID, jti, nil
+}
+
+// Key dibedakan per tujuan (reset password, 2FA, dll) agar token khusus tidak bisa dipakai sebagai Bearer token
+func purposeSigningKey(secret string, purpose string) []byte {
+	return []byte(secret + ":" + purpose)
+}
+
+func frontendURL() string {
+	if u := os.Getenv("FRONTEND_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	return "http://localhost:3001"
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"database/sql"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ==========================
+// SESSION VERSION
+// ==========================
+// Setiap token JWT membawa claim "session_version".
+// Jika versi di tabel users sudah dinaikkan (revokeSessions), token lama otomatis tidak berlaku.
+
+func isSessionActive(db *sql.DB, userID string, claims jwt.MapClaims) (adminCampusID string, ok bool) {
+	if userID == "" {
+		return "", false
+	}
+
+	// Token lama (sebelum fitur ini ada) dianggap versi 0
+	tokenVersion := 0
+	if v, ok := claims["session_version"].(float64); ok {
+		tokenVersion = int(v)
+	}
+
+	// Kampus admin ikut dibaca di sini (bukan dari claim) agar perubahan penugasan langsung berlaku
+	var currentVersion int
+	var campusID sql.NullString
+	err := db.QueryRow(`
+		SELECT session_version, CASE WHEN role = 'admin' THEN admin_campus_id::text END
+		FROM users WHERE id = $1 AND deleted_at IS NULL
+	`, userID).Scan(&currentVersion, &campusID)
+	if err != nil {
+		return "", false
+	}
+
+	return campusID.String, tokenVersion == currentVersion
+}
+
+// revokeSessions mencabut semua token yang sudah pernah diterbitkan untuk user
+func revokeSessions(tx *sql.Tx, userID string) error {
+	_, err := tx.Exec(`
+		UPDATE users SET session_version = session_version + 1, updated_at = NOW() WHERE id = $1
+	`, userID)
+	return err
+}
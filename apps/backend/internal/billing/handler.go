@@ -0,0 +1,335 @@
+package billing
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"campus-reservation-backend/internal/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ========================================================
+// REQUEST DTO
+// ========================================================
+
+type PricingRuleRequest struct {
+	Name                string   `json:"name" example:"Tarif Mahasiswa"`
+	FacilityID          *string  `json:"facility_id"`                 // kosong = semua fasilitas
+	UserType            string   `json:"user_type" example:"student"` // all | student | staff | external
+	HourlyRate          *float64 `json:"hourly_rate" example:"50000"` // kosong = harga fasilitas
+	WeekendSurchargePct float64  `json:"weekend_surcharge_pct" example:"25"`
+	FreeHoursPerMonth   float64  `json:"free_hours_per_month" example:"4"`
+	IsActive            *bool    `json:"is_active"`
+}
+
+func (r PricingRuleRequest) toRule() PricingRule {
+	active := true
+	if r.IsActive != nil {
+		active = *r.IsActive
+	}
+	return PricingRule{
+		Name:                r.Name,
+		FacilityID:          r.FacilityID,
+		UserType:            r.UserType,
+		HourlyRate:          r.HourlyRate,
+		WeekendSurchargePct: r.WeekendSurchargePct,
+		FreeHoursPerMonth:   r.FreeHoursPerMonth,
+		IsActive:            active,
+	}
+}
+
+type PayInvoiceRequest struct {
+	PaymentRef string `json:"payment_ref" example:"TRF-BNI-0012"`
+}
+
+const timeLayout = "2006-01-02T15:04:05"
+
+func errorStatus(err error) int {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "tidak ditemukan"):
+		return 404
+	case strings.Contains(msg, "sudah ada"), strings.Contains(msg, "sudah lunas"),
+		strings.Contains(msg, "sudah dibatalkan"), strings.Contains(msg, "sudah diproses"):
+		return 409
+	case strings.Contains(msg, "kampus lain"), strings.Contains(msg, "admin pusat"):
+		return 403
+	}
+	return 400
+}
+
+func respondError(c *fiber.Ctx, err error) error {
+	return c.Status(errorStatus(err)).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+func isAdmin(c *fiber.Ctx) bool {
+	role, _ := c.Locals("role").(string)
+	return role == "admin"
+}
+
+// ========================================================
+// HANDLER: QUOTE (PREVIEW HARGA)
+// ========================================================
+
+// QuoteHandler: GET /facilities/:id/quote?start_time=YYYY-MM-DDTHH:MM:SS&end_time=...
+// Menghitung harga untuk user yang login (tarif, biaya akhir pekan & sisa kuota gratis bulan itu)
+func QuoteHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(string)
+
+		loc := jakarta()
+		start, err1 := time.ParseInLocation(timeLayout, c.Query("start_time"), loc)
+		end, err2 := time.ParseInLocation(timeLayout, c.Query("end_time"), loc)
+		if err1 != nil || err2 != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format tanggal salah (YYYY-MM-DDTHH:MM:SS)",
+			})
+		}
+
+		quote, err := GetQuote(db, c.Params("id"), userID, start, end)
+		if err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(quote)
+	}
+}
+
+// ========================================================
+// HANDLER: INVOICE
+// ========================================================
+
+// MyInvoicesHandler: daftar invoice milik user yang login
+func MyInvoicesHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(string)
+
+		invoices, err := FindInvoices(db, InvoiceFilter{UserID: userID, Status: c.Query("status")})
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal memuat invoice",
+			})
+		}
+		return c.JSON(invoices)
+	}
+}
+
+// ListInvoicesHandler (admin): ?status=unpaid|paid|void, ?user_id=, ?campus_id= (admin pusat)
+func ListInvoicesHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		invoices, err := FindInvoices(db, InvoiceFilter{
+			UserID:   c.Query("user_id"),
+			CampusID: auth.CampusFilter(c),
+			Status:   c.Query("status"),
+		})
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal memuat invoice",
+			})
+		}
+		return c.JSON(invoices)
+	}
+}
+
+// canViewInvoice: pemilik invoice atau admin (admin kampus hanya kampusnya)
+func canViewInvoice(c *fiber.Ctx, inv Invoice) bool {
+	if isAdmin(c) {
+		return auth.CampusAllowed(c, inv.CampusID)
+	}
+	userID, _ := c.Locals("user_id").(string)
+	return inv.UserID == userID
+}
+
+func GetInvoiceHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		inv, err := FindInvoiceByID(db, c.Params("id"))
+		if err != nil || !canViewInvoice(c, inv) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Invoice tidak ditemukan",
+			})
+		}
+		return c.JSON(inv)
+	}
+}
+
+// BookingBillingHandler: GET /bookings/:id/invoice -> quote & invoice sebuah booking
+func BookingBillingHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		bookingID := c.Params("id")
+
+		b, err := findBookingInfo(db, bookingID)
+		userID, _ := c.Locals("user_id").(string)
+		if err != nil || (!isAdmin(c) && b.UserID != userID) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Booking tidak ditemukan",
+			})
+		}
+
+		quote, inv, err := GetBookingBilling(db, bookingID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return c.Status(404).JSON(fiber.Map{
+					"error": "Booking ini belum memiliki rincian harga",
+				})
+			}
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal memuat rincian harga",
+			})
+		}
+		if inv != nil && !canViewInvoice(c, *inv) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Booking tidak ditemukan",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"quote":   quote,
+			"invoice": inv,
+		})
+	}
+}
+
+// PayInvoiceHandler (admin): tandai invoice lunas setelah pembayaran tunai / transfer dicek
+func PayInvoiceHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID := c.Locals("user_id").(string)
+
+		var req PayInvoiceRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		existing, err := FindInvoiceByID(db, c.Params("id"))
+		if err != nil || !auth.CampusAllowed(c, existing.CampusID) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Invoice tidak ditemukan",
+			})
+		}
+
+		inv, err := PayInvoice(db, existing.ID, adminID, req.PaymentRef)
+		if err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"message": "Invoice " + inv.Number + " ditandai lunas",
+			"invoice": inv,
+		})
+	}
+}
+
+// ========================================================
+// HANDLER: ATURAN HARGA (ADMIN)
+// ========================================================
+
+// checkRuleScope: aturan global hanya boleh diatur admin pusat, aturan fasilitas mengikuti kampus fasilitas
+func checkRuleScope(c *fiber.Ctx, db *sql.DB, facilityID *string) error {
+	if auth.AdminCampusID(c) == "" {
+		return nil
+	}
+	if facilityID == nil || strings.TrimSpace(*facilityID) == "" {
+		return errors.New("aturan harga global hanya dapat diatur admin pusat")
+	}
+	pc, err := findPricingContext(db, *facilityID, c.Locals("user_id").(string))
+	if err != nil {
+		return errors.New("fasilitas tidak ditemukan")
+	}
+	if !auth.CampusAllowed(c, pc.CampusID) {
+		return errors.New("fasilitas ini milik kampus lain")
+	}
+	return nil
+}
+
+func ListRulesHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		rules, err := FindRules(db, c.Query("facility_id"), auth.CampusFilter(c))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal memuat aturan harga",
+			})
+		}
+		return c.JSON(rules)
+	}
+}
+
+func CreateRuleHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(string)
+
+		var req PricingRuleRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+		if err := checkRuleScope(c, db, req.FacilityID); err != nil {
+			return respondError(c, err)
+		}
+
+		id, err := CreateRule(db, req.toRule(), userID)
+		if err != nil {
+			return respondError(c, err)
+		}
+		return c.Status(201).JSON(fiber.Map{
+			"message": "Aturan harga berhasil dibuat",
+			"id":      id,
+		})
+	}
+}
+
+func UpdateRuleHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req PricingRuleRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		existing, err := FindRuleByID(db, c.Params("id"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Aturan harga tidak ditemukan",
+			})
+		}
+		if err := checkRuleScope(c, db, existing.FacilityID); err != nil {
+			return respondError(c, err)
+		}
+		if err := checkRuleScope(c, db, req.FacilityID); err != nil {
+			return respondError(c, err)
+		}
+
+		if err := EditRule(db, existing.ID, req.toRule()); err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"message": "Aturan harga berhasil diperbarui",
+		})
+	}
+}
+
+func DeleteRuleHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		existing, err := FindRuleByID(db, c.Params("id"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Aturan harga tidak ditemukan",
+			})
+		}
+		if err := checkRuleScope(c, db, existing.FacilityID); err != nil {
+			return respondError(c, err)
+		}
+
+		if err := RemoveRule(db, existing.ID); err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"message": "Aturan harga berhasil dihapus",
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package billing
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"campus-reservation-backend/internal/database"
+)
+
+// ==========================
+// MODEL
+// ==========================
+
+type PricingRule struct {
+	ID                  string    `json:"id"`
+	Name                string    `json:"name"`
+	FacilityID          *string   `json:"facility_id"` // nil = semua fasilitas
+	FacilityName        string    `json:"facility_name,omitempty"`
+	UserType            string    `json:"user_type"`   // all | student | staff | external
+	HourlyRate          *float64  `json:"hourly_rate"` // nil = pakai harga fasilitas
+	WeekendSurchargePct float64   `json:"weekend_surcharge_pct"`
+	FreeHoursPerMonth   float64   `json:"free_hours_per_month"`
+	IsActive            bool      `json:"is_active"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// Quote adalah rincian harga satu booking
+type Quote struct {
+	FacilityID          string  `json:"facility_id"`
+	RuleID              *string `json:"rule_id"`
+	RuleName            string  `json:"rule_name,omitempty"`
+	UserType            string  `json:"user_type"`
+	HourlyRate          float64 `json:"hourly_rate"`
+	Hours               float64 `json:"hours"`
+	WeekendHours        float64 `json:"weekend_hours"`
+	FreeHours           float64 `json:"free_hours"`
+	FreeHoursRemaining  float64 `json:"free_hours_remaining"` // sisa kuota bulan ini setelah booking ini
+	WeekendSurchargePct float64 `json:"weekend_surcharge_pct"`
+	Subtotal            float64 `json:"subtotal"`
+	Surcharge           float64 `json:"surcharge"`
+	Discount            float64 `json:"discount"`
+	Total               float64 `json:"total"`
+	RequiresPayment     bool    `json:"requires_payment"`
+}
+
+type Invoice struct {
+	ID            string     `json:"id"`
+	Number        string     `json:"number"`
+	BookingID     *string    `json:"booking_id"`
+	UserID        string     `json:"user_id"`
+	CampusID      string     `json:"campus_id,omitempty"`
+	CustomerName  string     `json:"customer_name"`
+	CustomerEmail string     `json:"customer_email"`
+	FacilityName  string     `json:"facility_name"`
+	Description   string     `json:"description"`
+	Amount        float64    `json:"amount"`
+	Status        string     `json:"status"` // unpaid | paid | void | refunded
+	IssuedAt      time.Time  `json:"issued_at"`
+	DueAt         *time.Time `json:"due_at"`
+	PaidAt        *time.Time `json:"paid_at"`
+	PaymentRef    string     `json:"payment_ref,omitempty"`
+	VoidedAt      *time.Time `json:"voided_at,omitempty"`
+	RefundedAt    *time.Time `json:"refunded_at,omitempty"`
+}
+
+// ==========================
+// ATURAN HARGA
+// ==========================
+
+func InsertRule(db *sql.DB, r PricingRule, userID string) (string, error) {
+	var id string
+	err := db.QueryRow(`
+		INSERT INTO pricing_rules (name, facility_id, user_type, hourly_rate, weekend_surcharge_pct,
+			free_hours_per_month, is_active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, r.Name, r.FacilityID, r.UserType, r.HourlyRate, r.WeekendSurchargePct, r.FreeHoursPerMonth, r.IsActive, userID).Scan(&id)
+	return id, err
+}
+
+func UpdateRule(db *sql.DB, id string, r PricingRule) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE pricing_rules
+		SET name = $1, facility_id = $2, user_type = $3, hourly_rate = $4, weekend_surcharge_pct = $5,
+			free_hours_per_month = $6, is_active = $7, updated_at = NOW()
+		WHERE id = $8
+	`, r.Name, r.FacilityID, r.UserType, r.HourlyRate, r.WeekendSurchargePct, r.FreeHoursPerMonth, r.IsActive, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func DeleteRule(db *sql.DB, id string) (int64, error) {
+	res, err := db.Exec(`DELETE FROM pricing_rules WHERE id = $1`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const ruleSelect = `
+	SELECT r.id, r.name, r.facility_id::text, COALESCE(f.name, ''), r.user_type, r.hourly_rate::float8,
+		r.weekend_surcharge_pct::float8, r.free_hours_per_month::float8, r.is_active, r.created_at
+	FROM pricing_rules r
+	LEFT JOIN facilities f ON f.id = r.facility_id`
+
+// FindRules menampilkan aturan harga; facilityID terisi = aturan fasilitas tsb + aturan global,
+// campusID terisi = aturan global + aturan fasilitas di kampus tsb
+func FindRules(db *sql.DB, facilityID, campusID string) ([]PricingRule, error) {
+	rows, err := db.Query(ruleSelect+`
+		WHERE ($1::text = '' OR r.facility_id IS NULL OR r.facility_id::text = $1::text)
+		  AND ($2::text = '' OR r.facility_id IS NULL OR f.campus_id::text = $2::text)
+		ORDER BY r.facility_id NULLS FIRST, f.name, r.user_type
+	`, facilityID, campusID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []PricingRule{}
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+func FindRuleByID(db *sql.DB, id string) (PricingRule, error) {
+	return scanRule(db.QueryRow(ruleSelect+` WHERE r.id = $1`, id))
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRule(row rowScanner) (PricingRule, error) {
+	var r PricingRule
+	var facilityID sql.NullString
+	var rate sql.NullFloat64
+	err := row.Scan(&r.ID, &r.Name, &facilityID, &r.FacilityName, &r.UserType, &rate,
+		&r.WeekendSurchargePct, &r.FreeHoursPerMonth, &r.IsActive, &r.CreatedAt)
+	if facilityID.Valid {
+		r.FacilityID = &facilityID.String
+	}
+	if rate.Valid {
+		r.HourlyRate = &rate.Float64
+	}
+	return r, err
+}
+
+// findApplicableRule memilih aturan aktif paling spesifik. sql.ErrNoRows = tidak ada aturan
+func findApplicableRule(q database.Querier, facilityID, userType string) (PricingRule, error) {
+	return scanRule(q.QueryRow(ruleSelect+`
+		WHERE r.is_active
+		  AND (r.facility_id IS NULL OR r.facility_id = $1)
+		  AND r.user_type IN ($2, 'all')
+		ORDER BY (r.facility_id IS NULL), (r.user_type = 'all')
+		LIMIT 1
+	`, facilityID, userType))
+}
+
+// ==========================
+// DATA PERHITUNGAN HARGA
+// ==========================
+
+type pricingContext struct {
+	FacilityName    string
+	FacilityPrice   float64
+	RequiresPayment bool
+	CampusID        string
+	UserType        string
+	CustomerName    string
+	CustomerEmail   string
+}
+
+func findPricingContext(q database.Querier, facilityID, userID string) (pricingContext, error) {
+	var pc pricingContext
+	err := q.QueryRow(`
+		SELECT f.name, COALESCE(f.price, 0)::float8, f.requires_payment, COALESCE(f.campus_id::text, ''),
+			u.user_type, COALESCE(NULLIF(p.full_name, ''), u.name), u.email
+		FROM facilities f, users u
+		LEFT JOIN profiles p ON p.user_id = u.id
+		WHERE f.id::text = $1 AND f.deleted_at IS NULL AND u.id::text = $2
+	`, facilityID, userID).Scan(&pc.FacilityName, &pc.FacilityPrice, &pc.RequiresPayment, &pc.CampusID,
+		&pc.UserType, &pc.CustomerName, &pc.CustomerEmail)
+	return pc, err
+}
+
+// freeHoursUsed menjumlahkan jam gratis dari aturan yang sama pada bulan [monthStart, monthEnd).
+// Booking yang dibatalkan / ditolak mengembalikan kuotanya.
+func freeHoursUsed(q database.Querier, userID, ruleID string, monthStart, monthEnd time.Time) (float64, error) {
+	var used float64
+	err := q.QueryRow(`
+		SELECT COALESCE(SUM(bq.free_hours), 0)::float8
+		FROM booking_quotes bq
+		JOIN bookings b ON b.id = bq.booking_id
+		WHERE bq.rule_id = $1
+		  AND b.user_id::text = $2
+		  AND b.deleted_at IS NULL
+		  AND b.status::text IN ('pending', 'approved', 'completed')
+		  AND b.start_time >= $3 AND b.start_time < $4
+	`, ruleID, userID, monthStart, monthEnd).Scan(&used)
+	return used, err
+}
+
+// lockUserQuota mencegah dua booking bersamaan memakai kuota gratis yang sama
+func lockUserQuota(tx *sql.Tx, userID string) error {
+	_, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('billing:' || $1::text))`, userID)
+	return err
+}
+
+func insertQuote(q database.Querier, bookingID string, qt Quote) error {
+	_, err := q.Exec(`
+		INSERT INTO booking_quotes (booking_id, rule_id, user_type, hourly_rate, hours, weekend_hours, free_hours,
+			weekend_surcharge_pct, subtotal, surcharge, discount, total)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`, bookingID, qt.RuleID, qt.UserType, qt.HourlyRate, qt.Hours, qt.WeekendHours, qt.FreeHours,
+		qt.WeekendSurchargePct, qt.Subtotal, qt.Surcharge, qt.Discount, qt.Total)
+	return err
+}
+
+func FindQuote(q database.Querier, bookingID string) (Quote, error) {
+	var qt Quote
+	var ruleID sql.NullString
+	err := q.QueryRow(`
+		SELECT b.facility_id::text, bq.rule_id::text, COALESCE(r.name, ''), bq.user_type, bq.hourly_rate::float8,
+			bq.hours::float8, bq.weekend_hours::float8, bq.free_hours::float8, bq.weekend_surcharge_pct::float8,
+			bq.subtotal::float8, bq.surcharge::float8, bq.discount::float8, bq.total::float8, f.requires_payment
+		FROM booking_quotes bq
+		JOIN bookings b ON b.id = bq.booking_id
+		JOIN facilities f ON f.id = b.facility_id
+		LEFT JOIN pricing_rules r ON r.id = bq.rule_id
+		WHERE bq.booking_id::text = $1
+	`, bookingID).Scan(&qt.FacilityID, &ruleID, &qt.RuleName, &qt.UserType, &qt.HourlyRate, &qt.Hours, &qt.WeekendHours,
+		&qt.FreeHours, &qt.WeekendSurchargePct, &qt.Subtotal, &qt.Surcharge, &qt.Discount, &qt.Total, &qt.RequiresPayment)
+	if ruleID.Valid {
+		qt.RuleID = &ruleID.String
+	}
+	qt.RequiresPayment = qt.RequiresPayment && qt.Total > 0
+	return qt, err
+}
+
+type bookingInfo struct {
+	FacilityID string
+	UserID     string
+	StartTime  time.Time
+	EndTime    time.Time
+	Status     string
+}
+
+func findBookingInfo(q database.Querier, bookingID string) (bookingInfo, error) {
+	var b bookingInfo
+	err := q.QueryRow(`
+		SELECT facility_id::text, user_id::text, start_time, end_time, status::text
+		FROM bookings WHERE id::text = $1 AND deleted_at IS NULL
+	`, bookingID).Scan(&b.FacilityID, &b.UserID, &b.StartTime, &b.EndTime, &b.Status)
+	return b, err
+}
+
+// ==========================
+// INVOICE
+// ==========================
+
+// nextInvoiceNumber mengambil nomor berikutnya untuk tahun tsb. Baris sequence terkunci sampai transaksi selesai,
+// sehingga nomor selalu berurutan tanpa duplikat.
+func nextInvoiceNumber(tx *sql.Tx, year int) (string, error) {
+	var n int
+	err := tx.QueryRow(`
+		INSERT INTO invoice_sequences (year, last_number) VALUES ($1, 1)
+		ON CONFLICT (year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+		RETURNING last_number
+	`, year).Scan(&n)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("INV/%d/%06d", year, n), nil
+}
+
+func insertInvoice(tx *sql.Tx, inv *Invoice) error {
+	return tx.QueryRow(`
+		INSERT INTO invoices (number, booking_id, user_id, campus_id, customer_name, customer_email, facility_name,
+			description, amount, due_at)
+		VALUES ($1, $2, $3, NULLIF($4, '')::uuid, $5, $6, $7, $8, $9, $10)
+		RETURNING id, status, issued_at
+	`, inv.Number, inv.BookingID, inv.UserID, inv.CampusID, inv.CustomerName, inv.CustomerEmail, inv.FacilityName,
+		inv.Description, inv.Amount, inv.DueAt).Scan(&inv.ID, &inv.Status, &inv.IssuedAt)
+}
+
+const invoiceSelect = `
+	SELECT id, number, booking_id::text, COALESCE(user_id::text, ''), COALESCE(campus_id::text, ''),
+		customer_name, customer_email, facility_name, description, amount::float8, status,
+		issued_at, due_at, paid_at, COALESCE(payment_ref, ''), voided_at, refunded_at
+	FROM invoices`
+
+func scanInvoice(row rowScanner) (Invoice, error) {
+	var inv Invoice
+	var bookingID sql.NullString
+	var dueAt, paidAt, voidedAt, refundedAt sql.NullTime
+	err := row.Scan(&inv.ID, &inv.Number, &bookingID, &inv.UserID, &inv.CampusID,
+		&inv.CustomerName, &inv.CustomerEmail, &inv.FacilityName, &inv.Description, &inv.Amount, &inv.Status,
+		&inv.IssuedAt, &dueAt, &paidAt, &inv.PaymentRef, &voidedAt, &refundedAt)
+	if bookingID.Valid {
+		inv.BookingID = &bookingID.String
+	}
+	if dueAt.Valid {
+		inv.DueAt = &dueAt.Time
+	}
+	if paidAt.Valid {
+		inv.PaidAt = &paidAt.Time
+	}
+	if voidedAt.Valid {
+		inv.VoidedAt = &voidedAt.Time
+	}
+	if refundedAt.Valid {
+		inv.RefundedAt = &refundedAt.Time
+	}
+	return inv, err
+}
+
+func FindInvoiceByID(q database.Querier, id string) (Invoice, error) {
+	return scanInvoice(q.QueryRow(invoiceSelect+` WHERE id::text = $1`, id))
+}
+
+func FindInvoiceByBooking(q database.Querier, bookingID string) (Invoice, error) {
+	return scanInvoice(q.QueryRow(invoiceSelect+` WHERE booking_id::text = $1`, bookingID))
+}
+
+type InvoiceFilter struct {
+	UserID   string
+	CampusID string
+	Status   string
+}
+
+func FindInvoices(db *sql.DB, f InvoiceFilter) ([]Invoice, error) {
+	rows, err := db.Query(invoiceSelect+`
+		WHERE ($1::text = '' OR user_id::text = $1::text)
+		  AND ($2::text = '' OR campus_id::text = $2::text)
+		  AND ($3::text = '' OR status = $3::text)
+		ORDER BY issued_at DESC
+	`, f.UserID, f.CampusID, f.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []Invoice{}
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
+
+// MarkPaid hanya mengubah invoice yang masih unpaid
+func MarkPaid(q database.Querier, id, paidBy, paymentRef string) (int64, error) {
+	res, err := q.Exec(`
+		UPDATE invoices
+		SET status = 'paid', paid_at = NOW(), paid_by = NULLIF($2, '')::uuid, payment_ref = NULLIF($3, '')
+		WHERE id::text = $1 AND status = 'unpaid'
+	`, id, paidBy, paymentRef)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// MarkRefunded: invoice lunas yang dananya dikembalikan (booking batal / ditolak)
+func MarkRefunded(q database.Querier, id string) (int64, error) {
+	res, err := q.Exec(`
+		UPDATE invoices SET status = 'refunded', refunded_at = NOW()
+		WHERE id::text = $1 AND status = 'paid'
+	`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// voidByBooking membatalkan invoice yang belum dibayar (booking dibatalkan / ditolak)
+func voidByBooking(q database.Querier, bookingID string) (int64, error) {
+	res, err := q.Exec(`
+		UPDATE invoices SET status = 'void', voided_at = NOW()
+		WHERE booking_id::text = $1 AND status = 'unpaid'
+	`, bookingID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
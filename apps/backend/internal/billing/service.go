@@ -0,0 +1,381 @@
+package billing
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"campus-reservation-backend/internal/database"
+)
+
+// UserTypes adalah jenis user yang valid untuk tarif (sama dengan CHECK di tabel users)
+var UserTypes = []string{"student", "staff", "external"}
+
+func jakarta() *time.Location {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// roundMoney membulatkan ke rupiah penuh
+func roundMoney(v float64) float64 {
+	return math.Round(v)
+}
+
+func roundHours(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// ==========================
+// PERHITUNGAN HARGA
+// ==========================
+
+// splitWeekendHours membagi durasi [start, end) menjadi jam hari kerja dan jam akhir pekan (Sabtu & Minggu WIB)
+func splitWeekendHours(start, end time.Time) (weekday, weekend float64) {
+	loc := jakarta()
+	start, end = start.In(loc), end.In(loc)
+	for cur := start; cur.Before(end); {
+		next := time.Date(cur.Year(), cur.Month(), cur.Day()+1, 0, 0, 0, 0, loc)
+		if next.After(end) {
+			next = end
+		}
+		h := next.Sub(cur).Hours()
+		if d := cur.Weekday(); d == time.Saturday || d == time.Sunday {
+			weekend += h
+		} else {
+			weekday += h
+		}
+		cur = next
+	}
+	return weekday, weekend
+}
+
+// calculate menghitung quote dari tarif & durasi. Jam gratis dipakai untuk jam hari kerja lebih dulu.
+func calculate(rate, surchargePct, freeAvailable float64, start, end time.Time) Quote {
+	weekday, weekend := splitWeekendHours(start, end)
+	hours := weekday + weekend
+
+	free := math.Max(0, math.Min(freeAvailable, hours))
+	freeWeekday := math.Min(free, weekday)
+	freeWeekend := free - freeWeekday
+
+	weekendRate := rate * (1 + surchargePct/100)
+
+	subtotal := roundMoney(rate * hours)
+	surcharge := roundMoney(rate * weekend * surchargePct / 100)
+	discount := roundMoney(freeWeekday*rate + freeWeekend*weekendRate)
+
+	return Quote{
+		HourlyRate:          rate,
+		Hours:               roundHours(hours),
+		WeekendHours:        roundHours(weekend),
+		FreeHours:           roundHours(free),
+		WeekendSurchargePct: surchargePct,
+		Subtotal:            subtotal,
+		Surcharge:           surcharge,
+		Discount:            discount,
+		Total:               math.Max(0, subtotal+surcharge-discount),
+	}
+}
+
+// monthRange mengembalikan awal & akhir bulan (WIB) tempat t berada
+func monthRange(t time.Time) (time.Time, time.Time) {
+	t = t.In(jakarta())
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return start, start.AddDate(0, 1, 0)
+}
+
+// buildQuote mencari aturan harga yang berlaku lalu menghitung quote untuk user & rentang waktu tsb
+func buildQuote(q database.Querier, facilityID, userID string, start, end time.Time) (Quote, pricingContext, error) {
+	pc, err := findPricingContext(q, facilityID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Quote{}, pc, errors.New("fasilitas tidak ditemukan")
+		}
+		return Quote{}, pc, err
+	}
+
+	rate := pc.FacilityPrice
+	var surchargePct, freeAvailable float64
+	var ruleID *string
+	var ruleName string
+
+	rule, err := findApplicableRule(q, facilityID, pc.UserType)
+	switch {
+	case err == nil:
+		ruleID, ruleName = &rule.ID, rule.Name
+		if rule.HourlyRate != nil {
+			rate = *rule.HourlyRate
+		}
+		surchargePct = rule.WeekendSurchargePct
+		if rule.FreeHoursPerMonth > 0 {
+			monthStart, monthEnd := monthRange(start)
+			used, err := freeHoursUsed(q, userID, rule.ID, monthStart, monthEnd)
+			if err != nil {
+				return Quote{}, pc, err
+			}
+			freeAvailable = math.Max(0, rule.FreeHoursPerMonth-used)
+		}
+	case err != sql.ErrNoRows:
+		return Quote{}, pc, err
+	}
+
+	qt := calculate(rate, surchargePct, freeAvailable, start, end)
+	qt.FacilityID = facilityID
+	qt.RuleID, qt.RuleName = ruleID, ruleName
+	qt.UserType = pc.UserType
+	qt.FreeHoursRemaining = roundHours(freeAvailable - qt.FreeHours)
+	qt.RequiresPayment = pc.RequiresPayment && qt.Total > 0
+	return qt, pc, nil
+}
+
+// GetQuote menghitung harga tanpa menyimpan apa pun (preview sebelum booking)
+func GetQuote(db *sql.DB, facilityID, userID string, start, end time.Time) (Quote, error) {
+	if !start.Before(end) {
+		return Quote{}, errors.New("waktu mulai harus sebelum waktu selesai")
+	}
+	qt, _, err := buildQuote(db, facilityID, userID, start, end)
+	return qt, err
+}
+
+// ==========================
+// QUOTE & INVOICE BOOKING
+// ==========================
+
+// BookingEndBuffer: jeda yang ditambahkan package booking ke end_time tersimpan (di luar jam yang ditagih).
+// Didefinisikan di sini karena billing tidak bisa mengimpor booking.
+const BookingEndBuffer = 10 * time.Minute
+
+// IssueForBooking menyimpan quote booking dan menerbitkan invoice jika totalnya > 0.
+// start & end adalah jam yang diminta user (tanpa buffer). Aman dipanggil ulang: quote yang sudah ada dikembalikan apa adanya.
+func IssueForBooking(db *sql.DB, bookingID string, start, end time.Time) (Quote, *Invoice, error) {
+	b, err := findBookingInfo(db, bookingID)
+	if err != nil {
+		return Quote{}, nil, errors.New("booking tidak ditemukan")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return Quote{}, nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockUserQuota(tx, b.UserID); err != nil {
+		return Quote{}, nil, err
+	}
+
+	if existing, err := FindQuote(tx, bookingID); err == nil {
+		inv, err := FindInvoiceByBooking(tx, bookingID)
+		if err != nil {
+			return existing, nil, nil
+		}
+		return existing, &inv, nil
+	}
+
+	qt, pc, err := buildQuote(tx, b.FacilityID, b.UserID, start, end)
+	if err != nil {
+		return Quote{}, nil, err
+	}
+	if err := insertQuote(tx, bookingID, qt); err != nil {
+		return Quote{}, nil, err
+	}
+
+	var inv *Invoice
+	if qt.Total > 0 {
+		loc := jakarta()
+		number, err := nextInvoiceNumber(tx, time.Now().In(loc).Year())
+		if err != nil {
+			return Quote{}, nil, err
+		}
+		// Jatuh tempo: saat booking dimulai
+		due := b.StartTime
+		inv = &Invoice{
+			Number:        number,
+			BookingID:     &bookingID,
+			UserID:        b.UserID,
+			CampusID:      pc.CampusID,
+			CustomerName:  pc.CustomerName,
+			CustomerEmail: pc.CustomerEmail,
+			FacilityName:  pc.FacilityName,
+			Description: fmt.Sprintf("Sewa %s, %s - %s WIB (%.2f jam)", pc.FacilityName,
+				start.In(loc).Format("02 Jan 2006 15:04"), end.In(loc).Format("15:04"), qt.Hours),
+			Amount: qt.Total,
+			DueAt:  &due,
+		}
+		if err := insertInvoice(tx, inv); err != nil {
+			return Quote{}, nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Quote{}, nil, err
+	}
+	return qt, inv, nil
+}
+
+// GetBookingBilling mengembalikan quote & invoice booking (invoice nil jika gratis)
+func GetBookingBilling(db *sql.DB, bookingID string) (Quote, *Invoice, error) {
+	qt, err := FindQuote(db, bookingID)
+	if err != nil {
+		return Quote{}, nil, err
+	}
+	inv, err := FindInvoiceByBooking(db, bookingID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return qt, nil, nil
+		}
+		return qt, nil, err
+	}
+	return qt, &inv, nil
+}
+
+// CheckPaymentForApproval dipanggil sebelum admin menyetujui booking.
+// Untuk fasilitas yang mewajibkan pembayaran, invoice harus sudah lunas.
+func CheckPaymentForApproval(db *sql.DB, bookingID string) error {
+	b, err := findBookingInfo(db, bookingID)
+	if err != nil {
+		return errors.New("booking tidak ditemukan")
+	}
+
+	qt, inv, err := GetBookingBilling(db, bookingID)
+	if err == sql.ErrNoRows {
+		// Booking lama (sebelum fitur harga) dihitung sekarang dari jam yang tersimpan, tanpa buffer
+		qt, inv, err = IssueForBooking(db, bookingID, b.StartTime, b.EndTime.Add(-BookingEndBuffer))
+	}
+	if err != nil {
+		return errors.New("gagal memeriksa pembayaran booking")
+	}
+
+	if !qt.RequiresPayment || inv == nil || inv.Status == "paid" {
+		return nil
+	}
+	return fmt.Errorf("booking belum dibayar, invoice %s sebesar Rp %s harus dilunasi sebelum disetujui",
+		inv.Number, FormatRupiah(inv.Amount))
+}
+
+// VoidForBooking membatalkan invoice yang belum dibayar saat booking dibatalkan / ditolak
+func VoidForBooking(db *sql.DB, bookingID string) {
+	if _, err := voidByBooking(db, bookingID); err != nil {
+		log.Printf("Gagal membatalkan invoice booking %s: %v\n", bookingID, err)
+	}
+}
+
+// PayInvoice menandai invoice lunas (pembayaran tunai / transfer yang dicek admin)
+func PayInvoice(db *sql.DB, id, adminID, paymentRef string) (Invoice, error) {
+	inv, err := FindInvoiceByID(db, id)
+	if err != nil {
+		return inv, errors.New("invoice tidak ditemukan")
+	}
+	switch inv.Status {
+	case "paid":
+		return inv, errors.New("invoice sudah lunas")
+	case "void", "refunded":
+		return inv, errors.New("invoice sudah dibatalkan")
+	}
+
+	n, err := MarkPaid(db, id, adminID, strings.TrimSpace(paymentRef))
+	if err != nil {
+		return inv, err
+	}
+	if n == 0 {
+		return inv, errors.New("invoice sudah diproses")
+	}
+	return FindInvoiceByID(db, id)
+}
+
+// FormatRupiah: 150000 -> "150.000"
+func FormatRupiah(v float64) string {
+	s := fmt.Sprintf("%.0f", v)
+	var out []byte
+	for i := range s {
+		if i > 0 && (len(s)-i)%3 == 0 && s[i-1] != '-' {
+			out = append(out, '.')
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
+
+// ==========================
+// ATURAN HARGA
+// ==========================
+
+func validateRule(r *PricingRule) error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return errors.New("nama aturan harga wajib diisi")
+	}
+	if r.UserType == "" {
+		r.UserType = "all"
+	}
+	if r.UserType != "all" && !slices.Contains(UserTypes, r.UserType) {
+		return errors.New("jenis user tidak valid (all, student, staff, external)")
+	}
+	if r.FacilityID != nil && strings.TrimSpace(*r.FacilityID) == "" {
+		r.FacilityID = nil
+	}
+	if r.HourlyRate != nil && *r.HourlyRate < 0 {
+		return errors.New("tarif per jam tidak boleh negatif")
+	}
+	if r.WeekendSurchargePct < 0 || r.WeekendSurchargePct > 500 {
+		return errors.New("biaya tambahan akhir pekan harus antara 0 - 500%")
+	}
+	if r.FreeHoursPerMonth < 0 || r.FreeHoursPerMonth > 744 {
+		return errors.New("kuota jam gratis tidak valid")
+	}
+	return nil
+}
+
+func mapRuleError(err error) error {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "idx_pricing_rules_scope"), strings.Contains(msg, "duplicate key"):
+		return errors.New("aturan harga untuk fasilitas & jenis user ini sudah ada")
+	case strings.Contains(msg, "violates foreign key"), strings.Contains(msg, "invalid input syntax for type uuid"):
+		return errors.New("fasilitas tidak ditemukan")
+	}
+	return err
+}
+
+func CreateRule(db *sql.DB, r PricingRule, userID string) (string, error) {
+	if err := validateRule(&r); err != nil {
+		return "", err
+	}
+	id, err := InsertRule(db, r, userID)
+	if err != nil {
+		return "", mapRuleError(err)
+	}
+	return id, nil
+}
+
+func EditRule(db *sql.DB, id string, r PricingRule) error {
+	if err := validateRule(&r); err != nil {
+		return err
+	}
+	n, err := UpdateRule(db, id, r)
+	if err != nil {
+		return mapRuleError(err)
+	}
+	if n == 0 {
+		return errors.New("aturan harga tidak ditemukan")
+	}
+	return nil
+}
+
+func RemoveRule(db *sql.DB, id string) error {
+	n, err := DeleteRule(db, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New("aturan harga tidak ditemukan")
+	}
+	return nil
+}
@@ -7,6 +7,11 @@ import (
 	"strings"
 	"time"
 
+	"campus-reservation-backend/internal/auth"
+	"campus-reservation-backend/internal/billing"
+	"campus-reservation-backend/internal/document"
+	"campus-reservation-backend/internal/equipment"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
@@ -15,11 +20,14 @@ import (
 // REQUEST DTO
 // ========================================================
 
+// CreateBookingRequest bisa dikirim sebagai JSON atau multipart (jika sekaligus melampirkan dokumen)
 type CreateBookingRequest struct {
-	FacilityID string `json:"facility_id"`
-	StartTime  string `json:"start_time"` // YYYY-MM-DDTHH:MM:SS
-	EndTime    string `json:"end_time"`
-	Purpose    string `json:"purpose"`
+	FacilityID string `json:"facility_id" form:"facility_id"`
+	StartTime  string `json:"start_time" form:"start_time"` // YYYY-MM-DDTHH:MM:SS
+	EndTime    string `json:"end_time" form:"end_time"`
+	Purpose    string `json:"purpose" form:"purpose"`
+	// Opsional: peralatan yang dipinjam bersama ruangan, disetujui bersamaan dengan booking (hanya JSON)
+	Equipment []equipment.ItemRequest `json:"equipment" form:"-"`
 }
 
 type UpdateStatusRequest struct {
@@ -30,6 +38,9 @@ type UpdateStatusRequest struct {
 // Request untuk Scan QR (Digunakan untuk In dan Out)
 type CheckInRequest struct {
 	TicketCode string `json:"ticket_code"`
+	// Opsional: jumlah orang yang diperkirakan / benar-benar hadir
+	ExpectedHeadcount *int `json:"expected_headcount" example:"40"`
+	ActualHeadcount   *int `json:"actual_headcount" example:"38"`
 }
 
 // Struct untuk input ulasan
@@ -94,6 +105,15 @@ func CreateHandler(db *sql.DB) fiber.Handler {
 			Status:     "pending",
 		}
 
+		// Cek stok peralatan lebih dulu (rentang waktu termasuk buffer 10 menit seperti booking)
+		if len(req.Equipment) > 0 {
+			if err := equipment.CheckAvailability(db, req.Equipment, start, end.Add(bookingEndBuffer)); err != nil {
+				return c.Status(equipmentErrorStatus(err)).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+		}
+
 		if err := CreateBooking(db, newBooking); err != nil {
 			status, msg := mapBookingError(err)
 			return c.Status(status).JSON(fiber.Map{
@@ -101,9 +121,30 @@ func CreateHandler(db *sql.DB) fiber.Handler {
 			})
 		}
 
-		return c.Status(201).JSON(fiber.Map{
+		resp := fiber.Map{
 			"message": "Booking berhasil dibuat, menunggu persetujuan admin",
-		})
+			"id":      newBooking.ID,
+		}
+
+		// Rincian harga & invoice (invoice null jika gratis)
+		if quote, inv, err := billing.GetBookingBilling(db, newBooking.ID); err == nil {
+			resp["quote"] = quote
+			resp["invoice"] = inv
+			if quote.RequiresPayment && inv != nil {
+				resp["message"] = "Booking berhasil dibuat. Lunasi invoice " + inv.Number + " agar booking dapat disetujui admin"
+			}
+		}
+
+		if len(req.Equipment) > 0 {
+			// Stok bisa saja diambil reservasi lain di sela pengecekan; booking ruangan tetap dibuat
+			if _, err := equipment.ReserveForBooking(db, newBooking.ID, userID, false, req.Equipment); err != nil {
+				resp["equipment_error"] = err.Error()
+			}
+		}
+
+		attachCreateDocuments(c, db, newBooking.ID, userID, resp)
+
+		return c.Status(201).JSON(resp)
 	}
 }
 
@@ -126,18 +167,41 @@ func CheckInHandler(db *sql.DB) fiber.Handler {
 			})
 		}
 
+		// Tiket peserta acara (AT-...) diproses terpisah: hanya check-in per orang
+		if IsAttendeeTicketCode(req.TicketCode) {
+			return checkInAttendee(c, db, req.TicketCode)
+		}
+
+		// API key kiosk yang dibatasi ke satu fasilitas hanya boleh memindai tiket fasilitas tersebut
+		if target, err := FindByTicketCode(db, req.TicketCode); err == nil {
+			if !auth.APIKeyFacilityAllowed(c, target.FacilityID) {
+				return c.Status(403).JSON(fiber.Map{
+					"error": "Tiket ini bukan untuk fasilitas kiosk ini",
+				})
+			}
+			if !facilityCampusAllowed(c, db, target.FacilityID) {
+				return c.Status(403).JSON(fiber.Map{
+					"error": "Tiket ini untuk fasilitas di kampus lain",
+				})
+			}
+		}
+
 		// Jalankan logika Service
-		err := CheckInTicket(db, req.TicketCode)
+		err := CheckInTicket(db, req.TicketCode, Headcount{Expected: req.ExpectedHeadcount, Actual: req.ActualHeadcount})
 
 		if err != nil {
 			msg := err.Error()
 
 			// Jika sukses Check-out tapi Telat, Service melempar error string khusus
 			if strings.Contains(msg, "Check-Out Berhasil!") {
-				return c.Status(200).JSON(fiber.Map{
+				resp := fiber.Map{
 					"message": msg,
 					"type":    "checkout_late",
-				})
+				}
+				if booking, err := FindByTicketCode(db, req.TicketCode); err == nil {
+					addCapacityInfo(resp, booking)
+				}
+				return c.Status(200).JSON(resp)
 			}
 
 			// Error validasi sungguhan
@@ -147,7 +211,13 @@ func CheckInHandler(db *sql.DB) fiber.Handler {
 		}
 
 		// Ambil data booking untuk menentukan pesan Check-In atau Check-Out
-		booking, _ := FindByTicketCode(db, req.TicketCode)
+		booking, err := FindByTicketCode(db, req.TicketCode)
+		if err != nil {
+			return c.JSON(fiber.Map{
+				"message": "Check-In Berhasil! Silakan masuk ke ruangan.",
+				"type":    "checkin",
+			})
+		}
 
 		message := "Check-In Berhasil! Silakan masuk ke ruangan."
 		respType := "checkin"
@@ -157,10 +227,58 @@ func CheckInHandler(db *sql.DB) fiber.Handler {
 			respType = "checkout_on_time"
 		}
 
-		return c.JSON(fiber.Map{
+		resp := fiber.Map{
 			"message": message,
 			"type":    respType,
-		})
+		}
+		addCapacityInfo(resp, booking)
+		return c.JSON(resp)
+	}
+}
+
+// attachCreateDocuments menyimpan dokumen yang dikirim bersama form booking (multipart: documents[] dengan
+// document_requirement_ids[] sejajar urutannya). Booking tetap dibuat walau ada dokumen yang gagal;
+// dokumen bisa dilengkapi lewat POST /bookings/:id/documents sebelum disetujui admin.
+func attachCreateDocuments(c *fiber.Ctx, db *sql.DB, bookingID, userID string, resp fiber.Map) {
+	if form, err := c.MultipartForm(); err == nil {
+		requirementIDs := form.Value["document_requirement_ids"]
+		var docErrors []string
+		for i, file := range form.File["documents"] {
+			requirementID := ""
+			if i < len(requirementIDs) {
+				requirementID = requirementIDs[i]
+			}
+			if _, err := document.Attach(db, bookingID, requirementID, userID, file); err != nil {
+				docErrors = append(docErrors, file.Filename+": "+err.Error())
+			}
+		}
+		if len(docErrors) > 0 {
+			resp["document_errors"] = docErrors
+		}
+	}
+
+	if missing, err := document.MissingForBooking(db, bookingID); err == nil && len(missing) > 0 {
+		resp["missing_documents"] = missing
+	}
+}
+
+// facilityCampusAllowed memastikan admin kampus hanya memproses booking fasilitas di kampusnya
+func facilityCampusAllowed(c *fiber.Ctx, db *sql.DB, facilityID string) bool {
+	if auth.AdminCampusID(c) == "" {
+		return true
+	}
+	campusID, err := FindFacilityCampusID(db, facilityID)
+	return err == nil && auth.CampusAllowed(c, campusID)
+}
+
+// addCapacityInfo menambahkan data jumlah orang & peringatan kapasitas ke response scanner
+func addCapacityInfo(resp fiber.Map, b *BookingResponse) {
+	resp["capacity"] = b.FacilityCapacity
+	resp["expected_headcount"] = b.ExpectedHeadcount
+	resp["actual_headcount"] = b.ActualHeadcount
+	resp["over_capacity"] = b.OverCapacity
+	if b.OverCapacity {
+		resp["warning"] = fmt.Sprintf("Jumlah orang melebihi kapasitas ruangan (%d orang)", b.FacilityCapacity)
 	}
 }
 
@@ -193,7 +311,8 @@ func ListAllHandler(db *sql.DB) fiber.Handler {
 		facilityID := c.Query("facility_id")
 		userID := c.Query("user_id")
 
-		bookings, err := GetAll(db, statusFilter, facilityID, userID)
+		// Admin kampus hanya melihat booking fasilitas di kampusnya
+		bookings, err := GetAll(db, statusFilter, facilityID, userID, auth.CampusFilter(c))
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{
 				"error": "Gagal memuat data booking",
@@ -220,9 +339,28 @@ func UpdateStatusHandler(db *sql.DB) fiber.Handler {
 			})
 		}
 
+		target, err := FindDetailByID(db, bookingID)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Booking tidak ditemukan",
+			})
+		}
+		if !facilityCampusAllowed(c, db, target.FacilityID) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Booking ini milik kampus lain",
+			})
+		}
+
 		// [DIPERBARUI] Mengirimkan req.RejectionReason ke fungsi service
 		if err := UpdateBookingStatus(db, bookingID, req.Status, req.RejectionReason, adminID); err != nil {
-			return c.Status(400).JSON(fiber.Map{
+			status := 400
+			switch {
+			case strings.Contains(err.Error(), "belum dibayar"):
+				status = 402
+			case strings.Contains(err.Error(), "dokumen wajib belum lengkap"):
+				status = 422
+			}
+			return c.Status(status).JSON(fiber.Map{
 				"error": err.Error(),
 			})
 		}
@@ -254,6 +392,12 @@ func GetFacilityScheduleHandler(db *sql.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		facilityID := c.Params("id")
 
+		if !auth.APIKeyFacilityAllowed(c, facilityID) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "API key tidak memiliki akses ke fasilitas ini",
+			})
+		}
+
 		schedules, err := GetScheduleByFacility(db, facilityID)
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{
@@ -270,35 +414,76 @@ func GetFacilityScheduleHandler(db *sql.DB) fiber.Handler {
 }
 
 // ========================================================
-// HANDLER: DOWNLOAD TICKET IMAGE
+// HANDLER: DOWNLOAD TIKET (PNG / PDF / SURAT KONFIRMASI)
 // ========================================================
+
+// DownloadTicketHandler: GET /bookings/:id/ticket?format=png|pdf|letter (default png).
+// Hanya pemesan atau admin (kampus fasilitas tersebut) yang boleh mengunduh.
 func DownloadTicketHandler(db *sql.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		bookingID := c.Params("id")
+		format := strings.ToLower(c.Query("format", TicketFormatPNG))
+		if format != TicketFormatPNG && format != TicketFormatPDF && format != TicketFormatLetter {
+			return c.Status(400).JSON(fiber.Map{"error": "format harus png, pdf atau letter"})
+		}
 
 		// 1. Ambil Data Booking Lengkap
 		bookingData, err := FindDetailByID(db, bookingID)
 		if err != nil {
 			return c.Status(404).JSON(fiber.Map{"error": "Booking tidak ditemukan"})
 		}
+		userID, _ := c.Locals("user_id").(string)
+		if isAdmin(c) {
+			if !facilityCampusAllowed(c, db, bookingData.FacilityID) {
+				return c.Status(404).JSON(fiber.Map{"error": "Booking tidak ditemukan"})
+			}
+		} else if bookingData.User.ID != userID {
+			return c.Status(404).JSON(fiber.Map{"error": "Booking tidak ditemukan"})
+		}
 
 		// Validasi: Hanya booking approved/completed yang punya tiket
 		if bookingData.Status != "approved" && bookingData.Status != "completed" {
 			return c.Status(400).JSON(fiber.Map{"error": "Tiket belum tersedia (Status: " + bookingData.Status + ")"})
 		}
 
-		// 2. Generate Gambar Tiket (Memanggil Service)
-		imgBytes, err := GenerateTicketImage(*bookingData)
+		// 2. Generate dokumen sesuai format (Memanggil Service)
+		var content []byte
+		contentType, filename := "image/png", ""
+		switch format {
+		case TicketFormatPDF:
+			content, err = GenerateTicketPDF(*bookingData)
+			contentType, filename = "application/pdf", "ticket-"+bookingData.TicketCode+".pdf"
+		case TicketFormatLetter:
+			var letter *BookingLetter
+			if letter, err = FindLetterData(db, bookingID); err == nil {
+				content, err = GenerateConfirmationLetter(*letter)
+			}
+			contentType, filename = "application/pdf", "surat-konfirmasi-"+bookingData.TicketCode+".pdf"
+		default:
+			content, err = GenerateTicketImage(*bookingData)
+		}
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": "Gagal membuat tiket: " + err.Error()})
 		}
 
-		// 3. Kirim Response Gambar (PNG)
-		c.Set("Content-Type", "image/png")
-		// c.Set("Content-Disposition", "attachment; filename=ticket-"+bookingData.TicketCode+".png") // Opsional: Force download
+		// 3. Kirim Response (PNG tampil inline, PDF diunduh)
+		c.Set("Content-Type", contentType)
+		if contentType == "application/pdf" {
+			c.Attachment(filename)
+		}
+
+		return c.SendStream(bytes.NewReader(content))
+	}
+}
 
-		return c.SendStream(bytes.NewReader(imgBytes))
+func equipmentErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "hanya tersedia"):
+		return 409
+	case strings.Contains(err.Error(), "tidak ditemukan"):
+		return 404
 	}
+	return 400
 }
 
 func mapBookingError(err error) (int, string) {
@@ -330,7 +515,7 @@ func GetAttendanceLogsHandler(db *sql.DB) fiber.Handler {
 		endDate := c.Query("end_date")
 		status := c.Query("status")
 
-		logs, err := GetAttendanceLogs(db, startDate, endDate, status)
+		logs, err := GetAttendanceLogs(db, startDate, endDate, status, auth.CampusFilter(c))
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": "Gagal memuat log kehadiran"})
 		}
@@ -350,8 +535,8 @@ func ExportAttendanceHandler(db *sql.DB) fiber.Handler {
 		endDate := c.Query("end_date")
 		status := c.Query("status")
 
-		// 1. Ambil data
-		logs, err := GetAttendanceLogs(db, startDate, endDate, status)
+		// 1. Ambil data (admin kampus otomatis hanya kampusnya, admin pusat bisa ?campus_id=)
+		logs, err := GetAttendanceLogs(db, startDate, endDate, status, auth.CampusFilter(c))
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil data untuk export"})
 		}
@@ -403,7 +588,7 @@ func SubmitReviewHandler(db *sql.DB) fiber.Handler {
 // GetAdminReviewsHandler - Admin melihat semua ulasan
 func GetAdminReviewsHandler(db *sql.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		reviews, err := GetAllReviews(db)
+		reviews, err := GetAllReviews(db, auth.CampusFilter(c))
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{
 				"error": "Gagal memuat data ulasan",
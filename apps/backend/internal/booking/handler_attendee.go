@@ -0,0 +1,180 @@
+package booking
+
+import (
+	"bytes"
+	"database/sql"
+
+	"campus-reservation-backend/internal/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ========================================================
+// HANDLER: PESERTA BOOKING (PEMESAN & ADMIN)
+// ========================================================
+
+func isAdmin(c *fiber.Ctx) bool {
+	role, _ := c.Locals("role").(string)
+	return role == "admin"
+}
+
+func ListAttendeesHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+
+		attendees, err := GetAttendees(db, c.Params("id"), userID, isAdmin(c))
+		if err != nil {
+			return c.Status(attendeeErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(attendees)
+	}
+}
+
+// AddAttendeesHandler menambah / mengundang peserta lewat JSON
+func AddAttendeesHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+
+		var req AddAttendeesRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		res, err := AddAttendees(db, c.Params("id"), userID, isAdmin(c), req.Attendees)
+		if err != nil {
+			return c.Status(attendeeErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(201).JSON(res)
+	}
+}
+
+// ImportAttendeesHandler menerima file CSV (field "file") dengan kolom name,email,phone
+func ImportAttendeesHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "File CSV wajib diunggah (field: file)",
+			})
+		}
+		if fileHeader.Size > 2*1024*1024 {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Ukuran file CSV maksimal 2MB",
+			})
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Gagal membaca file CSV",
+			})
+		}
+		defer file.Close()
+
+		inputs, err := ParseAttendeeCSV(file)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		res, err := AddAttendees(db, c.Params("id"), userID, isAdmin(c), inputs)
+		if err != nil {
+			return c.Status(attendeeErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(201).JSON(res)
+	}
+}
+
+func DeleteAttendeeHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+
+		if err := RemoveAttendee(db, c.Params("id"), c.Params("attendeeId"), userID, isAdmin(c)); err != nil {
+			return c.Status(attendeeErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"message": "Peserta berhasil dihapus",
+		})
+	}
+}
+
+// ========================================================
+// HANDLER: TIKET PESERTA (PUBLIK, BERBASIS KODE TIKET)
+// ========================================================
+// Link dikirim ke peserta lewat WhatsApp / email, sehingga peserta tidak wajib punya akun.
+func DownloadAttendeeTicketHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		code := c.Params("code")
+		if !IsAttendeeTicketCode(code) {
+			return c.Status(404).JSON(fiber.Map{"error": "Tiket peserta tidak ditemukan"})
+		}
+
+		b, a, err := GetAttendeeTicket(db, code)
+		if err != nil {
+			return c.Status(attendeeErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		imgBytes, err := GenerateAttendeeTicketImage(*b, a)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Gagal membuat tiket: " + err.Error()})
+		}
+
+		c.Set("Content-Type", "image/png")
+		c.Set("Cache-Control", "no-store")
+		return c.SendStream(bytes.NewReader(imgBytes))
+	}
+}
+
+// checkInAttendee dipanggil CheckInHandler untuk kode tiket peserta
+func checkInAttendee(c *fiber.Ctx, db *sql.DB, code string) error {
+	if target, err := FindAttendeeByTicketCode(db, code); err == nil {
+		if b, err := FindDetailByID(db, target.BookingID); err == nil {
+			if !auth.APIKeyFacilityAllowed(c, b.FacilityID) {
+				return c.Status(403).JSON(fiber.Map{
+					"error": "Tiket ini bukan untuk fasilitas kiosk ini",
+				})
+			}
+			if !facilityCampusAllowed(c, db, b.FacilityID) {
+				return c.Status(403).JSON(fiber.Map{
+					"error": "Tiket ini untuk fasilitas di kampus lain",
+				})
+			}
+		}
+	}
+
+	a, err := CheckInAttendee(db, code)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Check-In Peserta Berhasil! Selamat datang, " + a.Name + ".",
+		"type":     "attendee_checkin",
+		"attendee": a,
+	})
+}
+
+func attendeeErrorStatus(err error) int {
+	switch err.Error() {
+	case "booking tidak ditemukan", "peserta tidak ditemukan", "tiket peserta tidak ditemukan":
+		return 404
+	case "anda tidak memiliki akses ke booking ini":
+		return 403
+	}
+	return 400
+}
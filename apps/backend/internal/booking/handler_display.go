@@ -0,0 +1,133 @@
+package booking
+
+import (
+	"database/sql"
+	"time"
+
+	"campus-reservation-backend/internal/auth"
+	"campus-reservation-backend/internal/realtime"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ========================================================
+// HANDLER: LAYAR PINTU RUANGAN
+// ========================================================
+// Diakses tablet dengan token display (API key scope display:read yang terikat ke fasilitas),
+// dikirim lewat header X-API-Key atau "?access_token=".
+
+// Snapshot tetap dikirim ulang tiap menit agar sisa waktu & pergantian booking ikut terbarui
+const displayRefreshInterval = time.Minute
+
+func FacilityDisplayHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		facilityID := c.Params("id")
+
+		if !auth.APIKeyFacilityAllowed(c, facilityID) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Token display tidak terdaftar untuk fasilitas ini",
+			})
+		}
+
+		display, err := GetFacilityDisplay(db, facilityID)
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Fasilitas tidak ditemukan",
+			})
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal memuat data layar fasilitas",
+			})
+		}
+
+		return c.JSON(display)
+	}
+}
+
+// FacilityDisplayStreamHandler mengirim snapshot layar (event "display") setiap ada perubahan booking
+func FacilityDisplayStreamHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		facilityID := c.Params("id")
+
+		if !auth.APIKeyFacilityAllowed(c, facilityID) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Token display tidak terdaftar untuk fasilitas ini",
+			})
+		}
+
+		if _, err := FindDisplayFacility(db, facilityID); err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Fasilitas tidak ditemukan",
+			})
+		}
+
+		return realtime.StreamSnapshots(c, realtime.TopicFacility(facilityID), "display", displayRefreshInterval, func() (interface{}, error) {
+			return GetFacilityDisplay(db, facilityID)
+		})
+	}
+}
+
+// WalkUpQRHandler mengembalikan gambar QR untuk ditampilkan di layar pintu
+func WalkUpQRHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		facilityID := c.Params("id")
+
+		if !auth.APIKeyFacilityAllowed(c, facilityID) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Token display tidak terdaftar untuk fasilitas ini",
+			})
+		}
+
+		facility, err := FindDisplayFacility(db, facilityID)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Fasilitas tidak ditemukan",
+			})
+		}
+		if !facility.AllowWalkUp {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Fasilitas ini tidak menerima booking walk-up",
+			})
+		}
+
+		png, err := GenerateWalkUpQR(facilityID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		c.Set("Content-Type", "image/png")
+		return c.Send(png)
+	}
+}
+
+// ========================================================
+// HANDLER: BOOKING WALK-UP (USER)
+// ========================================================
+func WalkUpHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(string)
+
+		var req WalkUpRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		booking, err := CreateWalkUpBooking(db, userID, c.Params("id"), req)
+		if err != nil {
+			status, msg := mapBookingError(err)
+			return c.Status(status).JSON(fiber.Map{
+				"error": msg,
+			})
+		}
+
+		return c.Status(201).JSON(fiber.Map{
+			"message": "Ruangan berhasil dipakai, Anda sudah tercatat check-in",
+			"booking": booking,
+		})
+	}
+}
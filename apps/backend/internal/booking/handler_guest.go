@@ -0,0 +1,261 @@
+package booking
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"campus-reservation-backend/internal/auth"
+	"campus-reservation-backend/internal/billing"
+	"campus-reservation-backend/internal/document"
+	"campus-reservation-backend/internal/payment"
+	"campus-reservation-backend/internal/storage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ========================================================
+// HANDLER: BOOKING TAMU (PUBLIK & ADMIN)
+// ========================================================
+
+func guestErrorStatus(err error) (int, string) {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "tidak ditemukan"):
+		return 404, msg
+	case strings.Contains(msg, "sudah terdaftar"):
+		return 409, msg
+	case strings.Contains(msg, "tidak menerima booking tamu"):
+		return 403, msg
+	case strings.Contains(msg, "sudah lunas"), strings.Contains(msg, "sudah dibatalkan"), strings.Contains(msg, "jatuh tempo"):
+		return 409, msg
+	case strings.Contains(msg, "belum diaktifkan"):
+		return 503, msg
+	}
+	return mapBookingError(err)
+}
+
+// CreateGuestBookingHandler godoc
+// @Summary      Ajukan booking tamu (organisasi luar kampus)
+// @Description  Tanpa login. Membuat akun tamu & booking pending dengan tarif external. access_token di response (juga dikirim ke email) dipakai untuk memantau status & membayar.
+// @Tags         Guest Bookings
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        facility_id        formData  string  true  "ID Fasilitas"
+// @Param        start_time         formData  string  true  "YYYY-MM-DDTHH:MM:SS"
+// @Param        end_time           formData  string  true  "YYYY-MM-DDTHH:MM:SS"
+// @Param        organization_name  formData  string  true  "Nama organisasi"
+// @Param        contact_name       formData  string  true  "Nama narahubung"
+// @Param        contact_email      formData  string  true  "Email narahubung"
+// @Param        contact_phone      formData  string  true  "Telepon narahubung"
+// @Param        purpose            formData  string  true  "Keperluan acara"
+// @Param        document           formData  file    true  "Surat permohonan / proposal (PDF/JPG/PNG, maks 10MB)"
+// @Success      201  {object}  GuestBookingResult
+// @Router       /guest-bookings [post]
+func CreateGuestBookingHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		loc, _ := time.LoadLocation("Asia/Jakarta")
+		layout := "2006-01-02T15:04:05"
+		start, err1 := time.ParseInLocation(layout, c.FormValue("start_time"), loc)
+		end, err2 := time.ParseInLocation(layout, c.FormValue("end_time"), loc)
+		if err1 != nil || err2 != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format tanggal salah (YYYY-MM-DDTHH:MM:SS)",
+			})
+		}
+
+		file, err := c.FormFile("document")
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Surat permohonan (document) wajib diunggah",
+			})
+		}
+		// Jenis file dicek dari isinya, bukan hanya ekstensi (sama seperti upload dokumen booking)
+		if _, err := document.ValidateFile(file); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		obj, err := storage.SaveFile(file, storage.PrefixGuestDocuments)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal menyimpan dokumen",
+			})
+		}
+
+		res, err := CreateGuestBooking(db, GuestBookingInput{
+			FacilityID:       c.FormValue("facility_id"),
+			StartTime:        start,
+			EndTime:          end,
+			OrganizationName: c.FormValue("organization_name"),
+			ContactName:      c.FormValue("contact_name"),
+			ContactEmail:     c.FormValue("contact_email"),
+			ContactPhone:     c.FormValue("contact_phone"),
+			Purpose:          c.FormValue("purpose"),
+			DocumentPath:     obj.Key,
+			DocumentName:     filepath.Base(file.Filename),
+		})
+		if err != nil {
+			storage.Release(db, obj.Key)
+			status, msg := guestErrorStatus(err)
+			return c.Status(status).JSON(fiber.Map{
+				"error": msg,
+			})
+		}
+
+		return c.Status(201).JSON(fiber.Map{
+			"message": "Pengajuan booking terkirim, menunggu persetujuan admin. Link status dikirim ke email narahubung.",
+			"data":    res,
+		})
+	}
+}
+
+// GuestBookingStatusHandler: GET /guest-bookings/:token -> status pengajuan & invoice
+func GuestBookingStatusHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		g, err := FindGuestBookingByToken(db, c.Params("token"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		var inv *billing.Invoice
+		if found, err := billing.FindInvoiceByBooking(db, g.BookingID); err == nil {
+			inv = &found
+		}
+		reqs, err := document.Checklist(db, g.BookingID)
+		if err != nil {
+			reqs = []document.RequirementStatus{}
+		}
+		return c.JSON(fiber.Map{
+			"booking":            g,
+			"invoice":            inv,
+			"document_checklist": reqs,
+		})
+	}
+}
+
+// GuestBookingPaymentHandler: POST /guest-bookings/:token/payments -> link pembayaran invoice booking tamu
+func GuestBookingPaymentHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		g, err := FindGuestBookingByToken(db, c.Params("token"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		inv, err := billing.FindInvoiceByBooking(db, g.BookingID)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Booking ini tidak memiliki tagihan",
+			})
+		}
+
+		p, err := payment.CreateLink(db, inv.ID, g.GuestUserID)
+		if err != nil {
+			status, msg := guestErrorStatus(err)
+			return c.Status(status).JSON(fiber.Map{
+				"error": msg,
+			})
+		}
+		return c.Status(201).JSON(p)
+	}
+}
+
+// GuestBookingDocumentHandler: POST /guest-bookings/:token/documents (multipart: file, requirement_id)
+// untuk melengkapi dokumen wajib fasilitas selama pengajuan masih pending
+func GuestBookingDocumentHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		g, err := FindGuestBookingByToken(db, c.Params("token"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if g.Status != "pending" {
+			return c.Status(409).JSON(fiber.Map{
+				"error": "Dokumen hanya bisa ditambahkan selama pengajuan masih pending",
+			})
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "File dokumen (file) wajib diunggah",
+			})
+		}
+
+		d, err := document.Attach(db, g.BookingID, c.FormValue("requirement_id"), g.GuestUserID, file)
+		if err != nil {
+			status, msg := guestErrorStatus(err)
+			return c.Status(status).JSON(fiber.Map{
+				"error": msg,
+			})
+		}
+		return c.Status(201).JSON(d)
+	}
+}
+
+// CancelGuestBookingHandler: POST /guest-bookings/:token/cancel (hanya selama masih pending)
+func CancelGuestBookingHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := CancelGuestBooking(db, c.Params("token")); err != nil {
+			status, msg := guestErrorStatus(err)
+			return c.Status(status).JSON(fiber.Map{
+				"error": msg,
+			})
+		}
+		return c.JSON(fiber.Map{
+			"message": "Pengajuan booking dibatalkan",
+		})
+	}
+}
+
+// ListGuestBookingsHandler (admin): ?status=pending|approved|rejected|canceled, ?campus_id= (admin pusat)
+func ListGuestBookingsHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		list, err := FindGuestBookings(db, c.Query("status"), auth.CampusFilter(c))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal memuat booking tamu",
+			})
+		}
+		return c.JSON(list)
+	}
+}
+
+func GetGuestBookingHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		g, err := FindGuestBookingByID(db, c.Params("id"))
+		if err != nil || !auth.CampusAllowed(c, g.CampusID) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Pengajuan tidak ditemukan",
+			})
+		}
+		return c.JSON(g)
+	}
+}
+
+// GuestDocumentHandler (admin): unduh surat permohonan tamu
+func GuestDocumentHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		g, err := FindGuestBookingByID(db, c.Params("id"))
+		if err != nil || !auth.CampusAllowed(c, g.CampusID) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Pengajuan tidak ditemukan",
+			})
+		}
+		body, err := storage.Open(g.DocumentPath)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Dokumen tidak ditemukan",
+			})
+		}
+		c.Attachment(g.DocumentName)
+		return c.SendStream(body)
+	}
+}
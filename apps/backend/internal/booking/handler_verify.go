@@ -0,0 +1,33 @@
+package booking
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ========================================================
+// HANDLER: VERIFIKASI PUBLIK TIKET & SURAT (TANPA LOGIN)
+// ========================================================
+
+// VerifyTicketHandler: GET /verify/:code?sig= (link / QR pada tiket PDF & surat konfirmasi).
+// Selalu 200 dengan field result kecuali kode tidak terdaftar (404); response tidak berisi data pribadi pemesan.
+func VerifyTicketHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		code := strings.ToUpper(strings.TrimSpace(c.Params("code")))
+
+		v, err := VerifyTicket(db, code, c.Query("sig"))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal memverifikasi tiket",
+			})
+		}
+
+		c.Set("Cache-Control", "no-store")
+		if v.Result == VerifyNotFound {
+			return c.Status(404).JSON(v)
+		}
+		return c.JSON(v)
+	}
+}
@@ -4,6 +4,10 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"campus-reservation-backend/internal/database"
+	"campus-reservation-backend/internal/equipment"
+	"campus-reservation-backend/internal/webhook"
 )
 
 // ========================================================
@@ -77,6 +81,11 @@ type BookingResponse struct {
 	// Response field baru untuk ulasan
 	ReviewComment string     `json:"review_comment,omitempty"`
 	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+	// Jumlah orang (dicatat saat scan tiket)
+	FacilityCapacity  int  `json:"facility_capacity,omitempty"`
+	ExpectedHeadcount *int `json:"expected_headcount,omitempty"`
+	ActualHeadcount   *int `json:"actual_headcount,omitempty"`
+	OverCapacity      bool `json:"over_capacity"`
 }
 
 // Struct khusus untuk respon jadwal publik/user
@@ -133,8 +142,8 @@ func GetScheduleByFacility(db *sql.DB, facilityID string) ([]ScheduleResponse, e
 }
 
 // Func to insert new booking
-func Insert(db *sql.DB, b Booking) error {
-	_, err := db.Exec(`
+func Insert(q database.Querier, b Booking) error {
+	_, err := q.Exec(`
 		INSERT INTO bookings (id, user_id, facility_id, start_time, end_time, purpose, status, created_by, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, 'pending', $2, NOW())
 	`, b.ID, b.UserID, b.FacilityID, b.StartTime, b.EndTime, b.Purpose)
@@ -190,7 +199,7 @@ func FindDetailByID(db *sql.DB, bookingID string) (*BookingResponse, error) {
 }
 
 // UpdateStatus memperbarui status booking beserta alasan penolakan dan kode tiket jika ada
-func UpdateStatus(db *sql.DB, bookingID string, status string, rejectionReason string, adminID string, ticketCode string) error {
+func UpdateStatus(q database.Querier, bookingID string, status string, rejectionReason string, adminID string, ticketCode string) error {
 	var ticketCodeVal interface{} = ticketCode
 	if ticketCode == "" {
 		ticketCodeVal = nil
@@ -201,21 +210,24 @@ func UpdateStatus(db *sql.DB, bookingID string, status string, rejectionReason s
 		reasonVal = nil
 	}
 
-	_, err := db.Exec(`
+	// approved_by / approved_at dicatat untuk surat konfirmasi
+	_, err := q.Exec(`
 		UPDATE bookings 
 		SET status = $1, 
 			rejection_reason = $2, 
 			updated_at = NOW(), 
 			updated_by = $3, 
-			ticket_code = $4
+			ticket_code = $4,
+			approved_by = CASE WHEN $6::boolean THEN $3 ELSE approved_by END,
+			approved_at = CASE WHEN $6::boolean THEN NOW() ELSE approved_at END
 		WHERE id = $5 AND deleted_at IS NULL
-	`, status, reasonVal, adminID, ticketCodeVal, bookingID)
+	`, status, reasonVal, adminID, ticketCodeVal, bookingID, status == "approved")
 	return err
 }
 
 // UpdateStatusCancel memperbarui status booking menjadi 'canceled' oleh user
-func UpdateStatusCancel(db *sql.DB, bookingID string, userID string) error {
-	_, err := db.Exec(`UPDATE bookings SET status = 'canceled', updated_at = NOW(), updated_by = $1 WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL`, userID, bookingID)
+func UpdateStatusCancel(q database.Querier, bookingID string, userID string) error {
+	_, err := q.Exec(`UPDATE bookings SET status = 'canceled', updated_at = NOW(), updated_by = $1 WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL`, userID, bookingID)
 	return err
 }
 
@@ -262,8 +274,9 @@ func FindByUserID(db *sql.DB, userID string) ([]BookingResponse, error) {
 	return scanBookings(rows)
 }
 
-// Menambahkan kolom review_comment dan reviewed_at pada SELECT
-func GetAll(db *sql.DB, statusFilter, facilityID, userID string) ([]BookingResponse, error) {
+// Menambahkan kolom review_comment dan reviewed_at pada SELECT.
+// campusID kosong = semua kampus
+func GetAll(db *sql.DB, statusFilter, facilityID, userID, campusID string) ([]BookingResponse, error) {
 	query := `
 		SELECT
 			b.id, u.id, u.name, u.email,
@@ -305,6 +318,12 @@ func GetAll(db *sql.DB, statusFilter, facilityID, userID string) ([]BookingRespo
 		argCounter++
 	}
 
+	if campusID != "" {
+		query += fmt.Sprintf(" AND f.campus_id::text = $%d", argCounter)
+		args = append(args, campusID)
+		argCounter++
+	}
+
 	query += " ORDER BY b.start_time DESC"
 
 	rows, err := db.Query(query, args...)
@@ -352,7 +371,8 @@ func FindByTicketCode(db *sql.DB, code string) (*BookingResponse, error) {
 		SELECT 
 			b.id, b.status, b.start_time, b.end_time, b.is_checked_in, b.checked_in_at, 
 			b.is_checked_out, b.checked_out_at, b.attendance_status,
-			u.name, f.name, b.ticket_code, b.actual_end_time
+			u.name, b.facility_id, f.name, b.ticket_code, b.actual_end_time,
+			f.capacity, b.expected_headcount, b.actual_headcount, b.over_capacity
 		FROM bookings b
 		JOIN users u ON b.user_id = u.id
 		JOIN facilities f ON b.facility_id = f.id
@@ -360,7 +380,8 @@ func FindByTicketCode(db *sql.DB, code string) (*BookingResponse, error) {
 	`, code).Scan(
 		&b.ID, &b.Status, &b.StartTime, &b.EndTime, &b.IsCheckedIn, &checkedInAt,
 		&b.IsCheckedOut, &checkedOutAt, &attendanceStatus,
-		&b.UserName, &b.FacilityName, &ticketCode, &actualEndTime,
+		&b.UserName, &b.FacilityID, &b.FacilityName, &ticketCode, &actualEndTime,
+		&b.FacilityCapacity, &b.ExpectedHeadcount, &b.ActualHeadcount, &b.OverCapacity,
 	)
 	if err != nil {
 		return nil, err
@@ -386,14 +407,32 @@ func FindByTicketCode(db *sql.DB, code string) (*BookingResponse, error) {
 }
 
 // UpdateCheckIn memperbarui status check-in booking
-func UpdateCheckIn(db *sql.DB, bookingID string) error {
-	_, err := db.Exec(`UPDATE bookings SET is_checked_in = true, checked_in_at = NOW() WHERE id = $1`, bookingID)
+func UpdateCheckIn(q database.Querier, bookingID string) error {
+	_, err := q.Exec(`UPDATE bookings SET is_checked_in = true, checked_in_at = NOW() WHERE id = $1`, bookingID)
 	return err
 }
 
+// UpdateHeadcount menyimpan jumlah orang (nilai nil tidak mengubah data lama) lalu menandai
+// booking yang melebihi kapasitas fasilitas: jumlah hadir dipakai begitu dicatat, sebelumnya perkiraan
+// (sama dengan perhitungan utilisasi). Mengembalikan status over_capacity dan kapasitasnya.
+func UpdateHeadcount(q database.Querier, bookingID string, expected, actual *int) (bool, int, error) {
+	var overCapacity bool
+	var capacity int
+	err := q.QueryRow(`
+		UPDATE bookings b
+		SET expected_headcount = COALESCE($2::int, b.expected_headcount),
+			actual_headcount = COALESCE($3::int, b.actual_headcount),
+			over_capacity = COALESCE($3::int, b.actual_headcount, $2::int, b.expected_headcount, 0) > f.capacity
+		FROM facilities f
+		WHERE b.id = $1 AND f.id = b.facility_id
+		RETURNING b.over_capacity, f.capacity
+	`, bookingID, expected, actual).Scan(&overCapacity, &capacity)
+	return overCapacity, capacity, err
+}
+
 // UpdateCheckOut memperbarui status check-out booking beserta status kehadiran
-func UpdateCheckOut(db *sql.DB, bookingID string, attendanceStatus string) error {
-	_, err := db.Exec(`
+func UpdateCheckOut(q database.Querier, bookingID string, attendanceStatus string) error {
+	_, err := q.Exec(`
 		UPDATE bookings 
 		SET is_checked_out = true, 
 			checked_out_at = NOW(), 
@@ -427,9 +466,10 @@ func GetConflictingBooking(db *sql.DB, facilityID string, start, end time.Time)
 	return &conflictStart, &conflictEnd, nil
 }
 
-// ProcessExpiredBookings memperbarui booking yang sudah lewat end_time
-func ProcessExpiredBookings(db *sql.DB) error {
-	_, err := db.Exec(`
+// ProcessExpiredBookings memperbarui booking yang sudah lewat end_time.
+// Mengembalikan ID booking yang ditandai mangkir (no-show) dan yang di-check-out otomatis.
+func ProcessExpiredBookings(q database.Querier) (noShowIDs []string, checkedOutIDs []string, err error) {
+	noShowIDs, err = queryIDs(q, `
 		UPDATE bookings 
 		SET status = 'completed', 
 			attendance_status = 'no_show',
@@ -438,12 +478,13 @@ func ProcessExpiredBookings(db *sql.DB) error {
 		  AND is_checked_in = false 
 		  AND end_time < NOW()
 		  AND deleted_at IS NULL
+		RETURNING id
 	`)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	_, err = db.Exec(`
+	checkedOutIDs, err = queryIDs(q, `
 		UPDATE bookings 
 		SET status = 'completed', 
 			is_checked_out = true,
@@ -455,8 +496,81 @@ func ProcessExpiredBookings(db *sql.DB) error {
 		  AND is_checked_out = false
 		  AND end_time < NOW()
 		  AND deleted_at IS NULL
+		RETURNING id
 	`)
-	return err
+	return noShowIDs, checkedOutIDs, err
+}
+
+// queryIDs menjalankan query yang mengembalikan satu kolom id (misal UPDATE ... RETURNING id)
+func queryIDs(q database.Querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// BookingEvent adalah data booking yang dikirim lewat webhook (tanpa email / nomor HP pemesan)
+type BookingEvent struct {
+	ID               string     `json:"id"`
+	FacilityID       string     `json:"facility_id"`
+	FacilityName     string     `json:"facility_name"`
+	UserID           string     `json:"user_id"`
+	UserName         string     `json:"user_name"`
+	StartTime        time.Time  `json:"start_time"`
+	EndTime          time.Time  `json:"end_time"`
+	Status           string     `json:"status"`
+	Purpose          string     `json:"purpose"`
+	AttendanceStatus string     `json:"attendance_status,omitempty"`
+	RejectionReason  string     `json:"rejection_reason,omitempty"`
+	CheckedInAt      *time.Time `json:"checked_in_at,omitempty"`
+	CheckedOutAt     *time.Time `json:"checked_out_at,omitempty"`
+}
+
+// FindEventData mengambil data booking untuk payload webhook (bisa dipanggil di dalam transaksi)
+func FindEventData(q database.Querier, bookingID string) (*BookingEvent, error) {
+	var e BookingEvent
+	var checkedInAt, checkedOutAt sql.NullTime
+
+	err := q.QueryRow(`
+		SELECT 
+			b.id, b.facility_id, f.name, b.user_id,
+			COALESCE(NULLIF(p.full_name, ''), u.name),
+			b.start_time, b.end_time, b.status::text, COALESCE(b.purpose, ''),
+			COALESCE(b.attendance_status, ''), COALESCE(b.rejection_reason, ''),
+			b.checked_in_at, b.checked_out_at
+		FROM bookings b
+		JOIN users u ON b.user_id = u.id
+		LEFT JOIN profiles p ON u.id = p.user_id
+		JOIN facilities f ON b.facility_id = f.id
+		WHERE b.id = $1
+	`, bookingID).Scan(
+		&e.ID, &e.FacilityID, &e.FacilityName, &e.UserID, &e.UserName,
+		&e.StartTime, &e.EndTime, &e.Status, &e.Purpose,
+		&e.AttendanceStatus, &e.RejectionReason,
+		&checkedInAt, &checkedOutAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkedInAt.Valid {
+		e.CheckedInAt = &checkedInAt.Time
+	}
+	if checkedOutAt.Valid {
+		e.CheckedOutAt = &checkedOutAt.Time
+	}
+	return &e, nil
 }
 
 // Helper function untuk scan multiple bookings
@@ -490,8 +604,17 @@ func scanBookings(rows *sql.Rows) ([]BookingResponse, error) {
 	return bookings, nil
 }
 
-// GetAttendanceLogs mengambil log kehadiran dalam rentang tanggal tertentu dengan filter status
-func GetAttendanceLogs(db *sql.DB, startDate, endDate, status string) ([]BookingResponse, error) {
+// FindFacilityCampusID mengembalikan kampus pemilik fasilitas (untuk cakupan admin kampus)
+func FindFacilityCampusID(db *sql.DB, facilityID string) (string, error) {
+	var campusID string
+	err := db.QueryRow(`
+		SELECT COALESCE(campus_id::text, '') FROM facilities WHERE id::text = $1
+	`, facilityID).Scan(&campusID)
+	return campusID, err
+}
+
+// GetAttendanceLogs mengambil log kehadiran dalam rentang tanggal tertentu dengan filter status & kampus
+func GetAttendanceLogs(db *sql.DB, startDate, endDate, status, campusID string) ([]BookingResponse, error) {
 	if startDate == "" {
 		startDate = time.Now().AddDate(0, 0, -30).Format("2006-01-02")
 	}
@@ -515,7 +638,8 @@ func GetAttendanceLogs(db *sql.DB, startDate, endDate, status string) ([]Booking
 			b.is_checked_out, 
 			b.checked_out_at, 
 			COALESCE(b.attendance_status, ''),
-			b.actual_end_time
+			b.actual_end_time,
+			f.capacity, b.expected_headcount, b.actual_headcount, b.over_capacity
 		FROM bookings b
 		JOIN users u ON b.user_id = u.id
 		LEFT JOIN profiles p ON u.id = p.user_id
@@ -530,8 +654,13 @@ func GetAttendanceLogs(db *sql.DB, startDate, endDate, status string) ([]Booking
 	args = append(args, startQuery, endQuery)
 
 	if status != "" && status != "all" {
-		query += " AND b.attendance_status = $3"
 		args = append(args, status)
+		query += fmt.Sprintf(" AND b.attendance_status = $%d", len(args))
+	}
+
+	if campusID != "" {
+		args = append(args, campusID)
+		query += fmt.Sprintf(" AND f.campus_id::text = $%d", len(args))
 	}
 
 	query += " ORDER BY b.start_time DESC"
@@ -557,6 +686,7 @@ func GetAttendanceLogs(db *sql.DB, startDate, endDate, status string) ([]Booking
 			&b.IsCheckedOut,
 			&checkedOutAt,
 			&b.AttendanceStatus, &b.ActualEndTime,
+			&b.FacilityCapacity, &b.ExpectedHeadcount, &b.ActualHeadcount, &b.OverCapacity,
 		); err != nil {
 			return nil, err
 		}
@@ -579,9 +709,10 @@ func GetAttendanceLogs(db *sql.DB, startDate, endDate, status string) ([]Booking
 	return bookings, nil
 }
 
-// CancelFutureBookingsTx membatalkan booking masa depan milik user yang akan dihapus (Support Transaction)
-func CancelFutureBookingsTx(tx *sql.Tx, userID string, adminID string) error {
-	_, err := tx.Exec(`
+// CancelFutureBookingsTx membatalkan booking masa depan milik user yang akan dihapus (Support Transaction).
+// ID booking yang dibatalkan dikembalikan untuk SettleCanceledBookings setelah transaksi di-commit.
+func CancelFutureBookingsTx(tx *sql.Tx, userID string, adminID string) ([]string, error) {
+	ids, err := queryIDs(tx, `
 		UPDATE bookings 
 		SET status = 'canceled', 
 			rejection_reason = 'User Account Deleted', 
@@ -591,6 +722,478 @@ func CancelFutureBookingsTx(tx *sql.Tx, userID string, adminID string) error {
 		  AND status IN ('pending', 'approved') 
 		  AND start_time > NOW() 
 		  AND deleted_at IS NULL
+		RETURNING id
 	`, userID, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Peralatan & event ikut diproses di transaksi yang sama (batal jika penghapusan user di-rollback)
+	for _, id := range ids {
+		if err := equipment.SyncBookingStatus(tx, id, "canceled", adminID); err != nil {
+			return nil, err
+		}
+		publishBookingEvent(tx, webhook.EventBookingCanceled, id)
+	}
+	return ids, nil
+}
+
+// DisplayFacility adalah data ruangan yang ditampilkan di layar pintu
+type DisplayFacility struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	Location         string `json:"location"`
+	Capacity         int    `json:"capacity"`
+	IsActive         bool   `json:"is_active"`
+	AllowWalkUp      bool   `json:"allow_walk_up"`
+	WalkUpMaxMinutes int    `json:"walk_up_max_minutes"`
+}
+
+// FindDisplayFacility mengambil data ringkas fasilitas untuk layar pintu
+func FindDisplayFacility(db *sql.DB, facilityID string) (DisplayFacility, error) {
+	var f DisplayFacility
+	err := db.QueryRow(`
+		SELECT id, name, COALESCE(location, ''), capacity, is_active, allow_walk_up, walk_up_max_minutes
+		FROM facilities
+		WHERE id = $1 AND deleted_at IS NULL
+	`, facilityID).Scan(&f.ID, &f.Name, &f.Location, &f.Capacity, &f.IsActive, &f.AllowWalkUp, &f.WalkUpMaxMinutes)
+	return f, err
+}
+
+// InsertWalkUp menyimpan booking walk-up yang langsung disetujui dan sudah check-in
+func InsertWalkUp(q database.Querier, b Booking) error {
+	_, err := q.Exec(`
+		INSERT INTO bookings (
+			id, user_id, facility_id, start_time, end_time, purpose, status, created_by, created_at,
+			ticket_code, is_checked_in, checked_in_at, is_walk_up, approved_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, 'approved', $2, NOW(), $7, true, NOW(), true, NOW())
+	`, b.ID, b.UserID, b.FacilityID, b.StartTime, b.EndTime, b.Purpose, b.TicketCode)
 	return err
 }
+
+// ========================================================
+// PESERTA BOOKING (ATTENDEES)
+// ========================================================
+
+type Attendee struct {
+	ID          string     `json:"id"`
+	BookingID   string     `json:"booking_id"`
+	UserID      *string    `json:"user_id"`
+	Name        string     `json:"name"`
+	Email       *string    `json:"email"`
+	Phone       *string    `json:"phone"`
+	TicketCode  string     `json:"ticket_code"`
+	IsCheckedIn bool       `json:"is_checked_in"`
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"`
+	InvitedAt   *time.Time `json:"invited_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// attendeeBooking adalah data booking yang dibutuhkan saat mengelola peserta
+type attendeeBooking struct {
+	ID           string
+	UserID       string
+	Status       string
+	FacilityID   string
+	FacilityName string
+	Capacity     int
+	StartTime    time.Time
+	EndTime      time.Time
+}
+
+// lockAttendeeBooking mengunci baris booking agar penambahan peserta paralel tidak melewati kapasitas
+func lockAttendeeBooking(tx *sql.Tx, bookingID string) (attendeeBooking, error) {
+	var b attendeeBooking
+	err := tx.QueryRow(`
+		SELECT b.id, b.user_id, b.status::text, b.facility_id, f.name, f.capacity, b.start_time, b.end_time
+		FROM bookings b
+		JOIN facilities f ON b.facility_id = f.id
+		WHERE b.id = $1 AND b.deleted_at IS NULL
+		FOR UPDATE OF b
+	`, bookingID).Scan(&b.ID, &b.UserID, &b.Status, &b.FacilityID, &b.FacilityName, &b.Capacity, &b.StartTime, &b.EndTime)
+	return b, err
+}
+
+func CountAttendees(q database.Querier, bookingID string) (int, error) {
+	var n int
+	err := q.QueryRow(`SELECT COUNT(*) FROM booking_attendees WHERE booking_id = $1`, bookingID).Scan(&n)
+	return n, err
+}
+
+// attendeeExists mengecek duplikasi email / nomor HP dalam satu booking
+func attendeeExists(q database.Querier, bookingID, email, phone string) (bool, error) {
+	var exists bool
+	err := q.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM booking_attendees
+			WHERE booking_id = $1
+			  AND (($2::text <> '' AND lower(email) = lower($2::text)) OR ($3::text <> '' AND phone = $3::text))
+		)
+	`, bookingID, email, phone).Scan(&exists)
+	return exists, err
+}
+
+// InsertAttendee menyimpan peserta. user_id diisi otomatis jika email / nomor HP cocok dengan akun terdaftar.
+func InsertAttendee(q database.Querier, bookingID string, a Attendee, createdBy string) (string, error) {
+	var id string
+	err := q.QueryRow(`
+		INSERT INTO booking_attendees (booking_id, user_id, name, email, phone, ticket_code, created_by)
+		VALUES (
+			$1, 
+			(SELECT id FROM users
+			 WHERE deleted_at IS NULL
+			   AND (($3::text IS NOT NULL AND lower(email) = lower($3::text)) OR ($4::text IS NOT NULL AND phone = $4::text))
+			 LIMIT 1),
+			$2, $3, $4, $5, $6
+		)
+		RETURNING id
+	`, bookingID, a.Name, a.Email, a.Phone, a.TicketCode, createdBy).Scan(&id)
+	return id, err
+}
+
+const attendeeSelect = `
+	SELECT id, booking_id, user_id, name, email, phone, ticket_code, is_checked_in, checked_in_at, invited_at, created_at
+	FROM booking_attendees`
+
+func FindAttendees(db *sql.DB, bookingID string) ([]Attendee, error) {
+	rows, err := db.Query(attendeeSelect+` WHERE booking_id = $1 ORDER BY created_at, name`, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attendees := []Attendee{}
+	for rows.Next() {
+		a, err := scanAttendee(rows)
+		if err != nil {
+			return nil, err
+		}
+		attendees = append(attendees, a)
+	}
+	return attendees, rows.Err()
+}
+
+func FindAttendeeByID(db *sql.DB, bookingID, attendeeID string) (Attendee, error) {
+	return scanAttendee(db.QueryRow(attendeeSelect+` WHERE booking_id = $1 AND id = $2`, bookingID, attendeeID))
+}
+
+func FindAttendeeByTicketCode(db *sql.DB, code string) (Attendee, error) {
+	return scanAttendee(db.QueryRow(attendeeSelect+` WHERE ticket_code = $1`, code))
+}
+
+func DeleteAttendee(db *sql.DB, bookingID, attendeeID string) (int64, error) {
+	res, err := db.Exec(`DELETE FROM booking_attendees WHERE booking_id = $1 AND id = $2`, bookingID, attendeeID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// UpdateAttendeeCheckIn hanya berhasil sekali (mencegah scan ganda bersamaan)
+func UpdateAttendeeCheckIn(db *sql.DB, attendeeID string) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE booking_attendees SET is_checked_in = true, checked_in_at = NOW()
+		WHERE id = $1 AND is_checked_in = false
+	`, attendeeID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ClaimUninvitedAttendees menandai peserta yang belum diundang lalu mengembalikannya untuk dikirimi undangan
+func ClaimUninvitedAttendees(db *sql.DB, bookingID string) ([]Attendee, error) {
+	rows, err := db.Query(`
+		UPDATE booking_attendees SET invited_at = NOW()
+		WHERE booking_id = $1 AND invited_at IS NULL
+		RETURNING id, booking_id, user_id, name, email, phone, ticket_code, is_checked_in, checked_in_at, invited_at, created_at
+	`, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attendees []Attendee
+	for rows.Next() {
+		a, err := scanAttendee(rows)
+		if err != nil {
+			return nil, err
+		}
+		attendees = append(attendees, a)
+	}
+	return attendees, rows.Err()
+}
+
+type attendeeScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAttendee(row attendeeScanner) (Attendee, error) {
+	var a Attendee
+	var userID, email, phone sql.NullString
+	var checkedInAt, invitedAt sql.NullTime
+
+	err := row.Scan(&a.ID, &a.BookingID, &userID, &a.Name, &email, &phone, &a.TicketCode,
+		&a.IsCheckedIn, &checkedInAt, &invitedAt, &a.CreatedAt)
+	if err != nil {
+		return a, err
+	}
+
+	if userID.Valid {
+		a.UserID = &userID.String
+	}
+	if email.Valid {
+		a.Email = &email.String
+	}
+	if phone.Valid {
+		a.Phone = &phone.String
+	}
+	if checkedInAt.Valid {
+		a.CheckedInAt = &checkedInAt.Time
+	}
+	if invitedAt.Valid {
+		a.InvitedAt = &invitedAt.Time
+	}
+	return a, nil
+}
+
+// ========================================================
+// BOOKING TAMU (ORGANISASI LUAR KAMPUS)
+// ========================================================
+
+type GuestBooking struct {
+	ID               string     `json:"id"`
+	BookingID        string     `json:"booking_id"`
+	GuestUserID      string     `json:"guest_user_id"`
+	OrganizationName string     `json:"organization_name"`
+	ContactName      string     `json:"contact_name"`
+	ContactEmail     string     `json:"contact_email"`
+	ContactPhone     string     `json:"contact_phone"`
+	Purpose          string     `json:"purpose"`
+	DocumentName     string     `json:"document_name"`
+	DocumentPath     string     `json:"-"` // key storage (private/guest-documents/...)
+	FacilityID       string     `json:"facility_id"`
+	FacilityName     string     `json:"facility_name"`
+	CampusID         string     `json:"campus_id,omitempty"`
+	StartTime        time.Time  `json:"start_time"`
+	EndTime          time.Time  `json:"end_time"`
+	Status           string     `json:"status"`
+	RejectionReason  string     `json:"rejection_reason,omitempty"`
+	TicketCode       string     `json:"ticket_code,omitempty"`
+	ConvertedAt      *time.Time `json:"converted_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// guestFacility adalah kebijakan fasilitas yang dicek saat tamu mengajukan booking
+type guestFacility struct {
+	Name              string
+	IsActive          bool
+	AllowGuestBooking bool
+}
+
+func findGuestFacility(db *sql.DB, facilityID string) (guestFacility, error) {
+	var f guestFacility
+	err := db.QueryRow(`
+		SELECT name, is_active, allow_guest_booking
+		FROM facilities
+		WHERE id::text = $1 AND deleted_at IS NULL
+	`, facilityID).Scan(&f.Name, &f.IsActive, &f.AllowGuestBooking)
+	return f, err
+}
+
+// findGuestUserByEmail: isGuest=false berarti email milik akun UniSpace biasa
+func findGuestUserByEmail(q database.Querier, email string) (userID string, isGuest bool, err error) {
+	err = q.QueryRow(`
+		SELECT id, is_guest FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL
+	`, email).Scan(&userID, &isGuest)
+	return
+}
+
+// insertGuestUser membuat akun tamu tanpa password dengan tarif external
+func insertGuestUser(tx *sql.Tx, name, email, contactName, phone, organization string) (string, error) {
+	var userID string
+	err := tx.QueryRow(`
+		INSERT INTO users (name, email, password_hash, role, user_type, is_guest)
+		VALUES ($1, $2, NULL, 'user', 'external', TRUE)
+		RETURNING id
+	`, name, email).Scan(&userID)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO profiles (user_id, full_name, phone_number, department, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`, userID, contactName, phone, organization)
+	return userID, err
+}
+
+func insertGuestRequest(db *sql.DB, g GuestBooking, tokenHash string) (string, error) {
+	var id string
+	err := db.QueryRow(`
+		INSERT INTO guest_booking_requests (
+			booking_id, guest_user_id, organization_name, contact_name, contact_email, contact_phone,
+			purpose, document_path, document_name, access_token_hash
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`, g.BookingID, g.GuestUserID, g.OrganizationName, g.ContactName, g.ContactEmail, g.ContactPhone,
+		g.Purpose, g.DocumentPath, g.DocumentName, tokenHash).Scan(&id)
+	return id, err
+}
+
+const guestBookingSelect = `
+	SELECT g.id, g.booking_id, g.guest_user_id, g.organization_name, g.contact_name, g.contact_email, g.contact_phone,
+		g.purpose, g.document_name, g.document_path,
+		b.facility_id, f.name, COALESCE(f.campus_id::text, ''),
+		b.start_time, b.end_time, b.status::text, COALESCE(b.rejection_reason, ''), COALESCE(b.ticket_code, ''),
+		g.converted_at, g.created_at
+	FROM guest_booking_requests g
+	JOIN bookings b ON b.id = g.booking_id
+	JOIN facilities f ON f.id = b.facility_id`
+
+func scanGuestBooking(row attendeeScanner) (GuestBooking, error) {
+	var g GuestBooking
+	var convertedAt sql.NullTime
+	err := row.Scan(&g.ID, &g.BookingID, &g.GuestUserID, &g.OrganizationName, &g.ContactName, &g.ContactEmail, &g.ContactPhone,
+		&g.Purpose, &g.DocumentName, &g.DocumentPath,
+		&g.FacilityID, &g.FacilityName, &g.CampusID,
+		&g.StartTime, &g.EndTime, &g.Status, &g.RejectionReason, &g.TicketCode,
+		&convertedAt, &g.CreatedAt)
+	if convertedAt.Valid {
+		g.ConvertedAt = &convertedAt.Time
+	}
+	return g, err
+}
+
+func FindGuestBookingByID(db *sql.DB, id string) (GuestBooking, error) {
+	return scanGuestBooking(db.QueryRow(guestBookingSelect+` WHERE g.id::text = $1`, id))
+}
+
+func findGuestBookingByBooking(db *sql.DB, bookingID string) (GuestBooking, error) {
+	return scanGuestBooking(db.QueryRow(guestBookingSelect+` WHERE g.booking_id::text = $1`, bookingID))
+}
+
+func findGuestBookingByTokenHash(db *sql.DB, tokenHash string) (GuestBooking, error) {
+	return scanGuestBooking(db.QueryRow(guestBookingSelect+` WHERE g.access_token_hash = $1`, tokenHash))
+}
+
+// FindGuestBookings (admin): ?status= status booking, campusID untuk admin kampus
+func FindGuestBookings(db *sql.DB, status, campusID string) ([]GuestBooking, error) {
+	rows, err := db.Query(guestBookingSelect+`
+		WHERE ($1::text = '' OR b.status::text = $1::text)
+			AND ($2::text = '' OR f.campus_id::text = $2::text)
+		ORDER BY g.created_at DESC
+	`, status, campusID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []GuestBooking{}
+	for rows.Next() {
+		g, err := scanGuestBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, g)
+	}
+	return list, rows.Err()
+}
+
+// markGuestConverted mengaktifkan akun tamu menjadi akun biasa (sekali saja)
+func markGuestConverted(db *sql.DB, requestID, userID string) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE guest_booking_requests SET converted_at = NOW() WHERE id::text = $1 AND converted_at IS NULL
+	`, requestID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET is_guest = FALSE, updated_at = NOW() WHERE id::text = $1`, userID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ========================================================
+// DATA TIKET PDF & SURAT KONFIRMASI
+// ========================================================
+
+// BookingLetter melengkapi detail booking dengan data kop surat & penyetuju
+type BookingLetter struct {
+	BookingResponse
+	FacilityLocation string
+	CampusName       string
+	CampusAddress    string
+	ApproverName     string // kosong = disetujui otomatis (walk-up)
+	ApprovedAt       *time.Time
+}
+
+func FindLetterData(db *sql.DB, bookingID string) (*BookingLetter, error) {
+	b, err := FindDetailByID(db, bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	l := BookingLetter{BookingResponse: *b}
+	var approvedAt sql.NullTime
+	err = db.QueryRow(`
+		SELECT COALESCE(b.purpose, ''), COALESCE(f.location, ''), COALESCE(cmp.name, ''), COALESCE(cmp.address, ''),
+			COALESCE(NULLIF(ap.full_name, ''), apu.name, ''), b.approved_at
+		FROM bookings b
+		JOIN facilities f ON b.facility_id = f.id
+		LEFT JOIN campuses cmp ON f.campus_id = cmp.id
+		LEFT JOIN users apu ON b.approved_by = apu.id
+		LEFT JOIN profiles ap ON ap.user_id = apu.id
+		WHERE b.id = $1
+	`, bookingID).Scan(&l.Purpose, &l.FacilityLocation, &l.CampusName, &l.CampusAddress, &l.ApproverName, &approvedAt)
+	if err != nil {
+		return nil, err
+	}
+	if approvedAt.Valid {
+		l.ApprovedAt = &approvedAt.Time
+	}
+	return &l, nil
+}
+
+// ========================================================
+// VERIFIKASI PUBLIK TIKET
+// ========================================================
+
+// ticketVerifyRow: data booking untuk verifikasi publik (tanpa data pemesan)
+type ticketVerifyRow struct {
+	FacilityID   string
+	FacilityName string
+	CampusName   string
+	StartTime    time.Time
+	EndTime      time.Time
+	Status       string
+	IsCheckedIn  bool
+	Deleted      bool
+}
+
+// findTicketVerifyRow mencari booking berdasarkan kode tiket, termasuk yang sudah dihapus
+func findTicketVerifyRow(db *sql.DB, code string) (ticketVerifyRow, error) {
+	var r ticketVerifyRow
+	err := db.QueryRow(`
+		SELECT b.facility_id, f.name, COALESCE(cmp.name, ''), b.start_time, b.end_time, b.status::text,
+			b.is_checked_in, b.deleted_at IS NOT NULL
+		FROM bookings b
+		JOIN facilities f ON b.facility_id = f.id
+		LEFT JOIN campuses cmp ON f.campus_id = cmp.id
+		WHERE b.ticket_code = $1::text
+	`, code).Scan(&r.FacilityID, &r.FacilityName, &r.CampusName, &r.StartTime, &r.EndTime, &r.Status,
+		&r.IsCheckedIn, &r.Deleted)
+	return r, err
+}
@@ -8,10 +8,20 @@ import (
 	"fmt"
 	"image"
 	"image/png"
+	"log"
+	"math"
 	"math/big"
 	"strings"
 	"time"
 
+	"campus-reservation-backend/internal/billing"
+	"campus-reservation-backend/internal/campus"
+	"campus-reservation-backend/internal/database"
+	"campus-reservation-backend/internal/document"
+	"campus-reservation-backend/internal/equipment"
+	"campus-reservation-backend/internal/payment"
+	"campus-reservation-backend/internal/webhook"
+
 	"github.com/fogleman/gg"
 	"github.com/skip2/go-qrcode"
 	"github.com/xuri/excelize/v2"
@@ -45,8 +55,16 @@ func CreateBooking(db *sql.DB, b Booking) error {
 		return errors.New("waktu mulai harus sebelum waktu selesai")
 	}
 
+	// Jam operasional diwariskan dari fasilitas -> gedung -> kampus (dicek sebelum buffer ditambahkan)
+	if err := campus.CheckBookingHours(db, b.FacilityID, b.StartTime, b.EndTime); err != nil {
+		return err
+	}
+
+	// Harga dihitung dari jam yang diminta user (tanpa buffer)
+	requestedEnd := b.EndTime
+
 	// Menambahkan batas akhir otomatis +10 menit dari input user sesuai kesepakatan
-	b.EndTime = b.EndTime.Add(10 * time.Minute)
+	b.EndTime = b.EndTime.Add(bookingEndBuffer)
 
 	// 1. CEK BENTROK
 	conflictStart, conflictEnd, err := GetConflictingBooking(db, b.FacilityID, b.StartTime, b.EndTime)
@@ -70,11 +88,22 @@ func CreateBooking(db *sql.DB, b Booking) error {
 	}
 	b.IsCheckedIn = false
 
-	// 3. INSERT KE DATABASE
-	if err := Insert(db, b); err != nil {
+	// 3. INSERT KE DATABASE (event webhook di-commit bersama booking)
+	err = webhook.WithTx(db, func(tx *sql.Tx) error {
+		if err := Insert(tx, b); err != nil {
+			return err
+		}
+		publishBookingEvent(tx, webhook.EventBookingCreated, b.ID)
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
+	// 4. QUOTE & INVOICE (jika gagal, dihitung ulang saat booking akan disetujui)
+	if _, _, err := billing.IssueForBooking(db, b.ID, b.StartTime, requestedEnd); err != nil {
+		log.Printf("Gagal membuat invoice booking %s: %v\n", b.ID, err)
+	}
 	return nil
 }
 
@@ -95,7 +124,29 @@ func CancelBooking(db *sql.DB, bookingID string, userID string) error {
 		return errors.New("hanya booking pending yang bisa dibatalkan")
 	}
 
-	return UpdateStatusCancel(db, bookingID, userID)
+	err = webhook.WithTx(db, func(tx *sql.Tx) error {
+		if err := UpdateStatusCancel(tx, bookingID, userID); err != nil {
+			return err
+		}
+		publishBookingEvent(tx, webhook.EventBookingCanceled, bookingID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	syncEquipmentStatus(db, bookingID, "canceled", userID)
+	billing.VoidForBooking(db, bookingID)
+	payment.RefundForBooking(db, bookingID, "booking dibatalkan pemesan")
+	return nil
+}
+
+// SettleCanceledBookings membatalkan invoice & me-refund pembayaran booking yang dibatalkan di dalam transaksi
+// (misal CancelFutureBookingsTx). Dipanggil setelah commit karena refund menghubungi payment gateway.
+func SettleCanceledBookings(db *sql.DB, bookingIDs []string, reason string) {
+	for _, id := range bookingIDs {
+		billing.VoidForBooking(db, id)
+		payment.RefundForBooking(db, id, reason)
+	}
 }
 
 // ==========================
@@ -116,9 +167,16 @@ func UpdateBookingStatus(db *sql.DB, bookingID string, newStatus string, rejecti
 		return errors.New("status booking tidak bisa diubah karena sudah diproses")
 	}
 
-	// Jika status Approved, kosongkan rejection reason
+	// Jika status Approved, kosongkan rejection reason.
+	// Dokumen wajib fasilitas harus lengkap & fasilitas berbayar hanya bisa disetujui setelah invoice lunas.
 	if newStatus == "approved" {
 		rejectionReason = ""
+		if err := document.CheckRequiredForApproval(db, bookingID); err != nil {
+			return err
+		}
+		if err := billing.CheckPaymentForApproval(db, bookingID); err != nil {
+			return err
+		}
 	}
 
 	// MEKANISME RETRY (Maksimal 3 kali percobaan)
@@ -132,10 +190,29 @@ func UpdateBookingStatus(db *sql.DB, bookingID string, newStatus string, rejecti
 			ticketCode = "" // Akan dikonversi jadi NULL di repository
 		}
 
-		// Panggil Repository
-		err := UpdateStatus(db, bookingID, newStatus, rejectionReason, adminID, ticketCode)
+		event := webhook.EventBookingApproved
+		if newStatus == "rejected" {
+			event = webhook.EventBookingRejected
+		}
+
+		// Panggil Repository (transaksi baru tiap percobaan karena duplikasi kode tiket membatalkan transaksi)
+		err := webhook.WithTx(db, func(tx *sql.Tx) error {
+			if err := UpdateStatus(tx, bookingID, newStatus, rejectionReason, adminID, ticketCode); err != nil {
+				return err
+			}
+			publishBookingEvent(tx, event, bookingID)
+			return nil
+		})
 
 		if err == nil {
+			syncEquipmentStatus(db, bookingID, newStatus, adminID)
+			if newStatus == "approved" {
+				SendAttendeeInvitations(db, bookingID)
+			} else {
+				billing.VoidForBooking(db, bookingID)
+				payment.RefundForBooking(db, bookingID, "booking ditolak admin")
+			}
+			notifyGuestDecision(db, bookingID, newStatus)
 			return nil // Sukses!
 		}
 
@@ -159,7 +236,11 @@ func UpdateBookingStatus(db *sql.DB, bookingID string, newStatus string, rejecti
 // ==========================
 // SCAN TICKET (CHECK-IN & CHECK-OUT)
 // ==========================
-func CheckInTicket(db *sql.DB, ticketCode string) error {
+func CheckInTicket(db *sql.DB, ticketCode string, headcount Headcount) error {
+	if err := headcount.validate(); err != nil {
+		return err
+	}
+
 	// 1. Cari booking berdasarkan kode tiket
 	booking, err := FindByTicketCode(db, ticketCode)
 	if err != nil {
@@ -190,7 +271,7 @@ func CheckInTicket(db *sql.DB, ticketCode string) error {
 		}
 
 		// Menghitung jadwal asli (mengurangi kembali buffer 10 menit)
-		originalScheduleEnd := endTimeWIB.Add(-10 * time.Minute)
+		originalScheduleEnd := endTimeWIB.Add(-bookingEndBuffer)
 
 		// Toleransi keterlambatan adalah 5 menit dari jadwal asli
 		gracePeriod := 5 * time.Minute
@@ -202,8 +283,22 @@ func CheckInTicket(db *sql.DB, ticketCode string) error {
 		}
 
 		// Memperbarui data Check-out di database dengan status kehadiran yang sesuai
-		if err := UpdateCheckOut(db, booking.ID, attendanceStatus); err != nil {
-			return errors.New("gagal memproses check-out")
+		err := webhook.WithTx(db, func(tx *sql.Tx) error {
+			if err := UpdateCheckOut(tx, booking.ID, attendanceStatus); err != nil {
+				return errors.New("gagal memproses check-out")
+			}
+			if err := recordHeadcount(tx, booking.ID, headcount); err != nil {
+				return err
+			}
+			publishBookingEvent(tx, webhook.EventBookingCheckedOut, booking.ID)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// Peralatan yang dipinjam bersama ruangan dianggap kembali lengkap saat check-out
+		if err := equipment.ReturnForBooking(db, booking.ID, ""); err != nil {
+			log.Printf("Gagal mencatat pengembalian peralatan booking %s: %v\n", booking.ID, err)
 		}
 
 		if attendanceStatus == "late" {
@@ -224,15 +319,90 @@ func CheckInTicket(db *sql.DB, ticketCode string) error {
 		return errors.New("Check-in gagal. Waktu booking Anda telah berakhir (Mangkir)")
 	}
 
-	return UpdateCheckIn(db, booking.ID)
+	err = webhook.WithTx(db, func(tx *sql.Tx) error {
+		if err := UpdateCheckIn(tx, booking.ID); err != nil {
+			return err
+		}
+		if err := recordHeadcount(tx, booking.ID, headcount); err != nil {
+			return err
+		}
+		publishBookingEvent(tx, webhook.EventBookingCheckedIn, booking.ID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Peralatan yang dipinjam bersama ruangan diserahkan saat check-in
+	if err := equipment.CheckoutForBooking(db, booking.ID, ""); err != nil {
+		log.Printf("Gagal mencatat pengambilan peralatan booking %s: %v\n", booking.ID, err)
+	}
+	return nil
+}
+
+// syncEquipmentStatus menyamakan status peralatan yang dipinjam bersama booking (approve / reject / cancel)
+func syncEquipmentStatus(db *sql.DB, bookingID string, status string, actorID string) {
+	if err := equipment.SyncBookingStatus(db, bookingID, status, actorID); err != nil {
+		log.Printf("Gagal memperbarui status peralatan booking %s: %v\n", bookingID, err)
+	}
+}
+
+// Headcount adalah jumlah orang yang (opsional) diinput petugas saat scan tiket
+type Headcount struct {
+	Expected *int
+	Actual   *int
+}
+
+func (h Headcount) validate() error {
+	for _, v := range []*int{h.Expected, h.Actual} {
+		if v != nil && (*v < 0 || *v > 100000) {
+			return errors.New("jumlah orang tidak valid")
+		}
+	}
+	return nil
+}
+
+// recordHeadcount menyimpan jumlah orang dan menandai booking yang melebihi kapasitas
+func recordHeadcount(q database.Querier, bookingID string, h Headcount) error {
+	if h.Expected == nil && h.Actual == nil {
+		return nil
+	}
+	if _, _, err := UpdateHeadcount(q, bookingID, h.Expected, h.Actual); err != nil {
+		return errors.New("gagal menyimpan jumlah orang")
+	}
+	return nil
 }
 
 // ==========================================
 // WORKER: AUTO CHECK-OUT (Sistem)
 // ==========================================
 func RunAutoCheckout(db *sql.DB) error {
-	// Memanggil fungsi repository yang sebenarnya
-	return ProcessExpiredBookings(db)
+	// Memanggil fungsi repository yang sebenarnya (event di-commit bersama perubahan status)
+	return webhook.WithTx(db, func(tx *sql.Tx) error {
+		noShowIDs, checkedOutIDs, err := ProcessExpiredBookings(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range noShowIDs {
+			publishBookingEvent(tx, webhook.EventBookingNoShow, id)
+		}
+		for _, id := range checkedOutIDs {
+			publishBookingEvent(tx, webhook.EventBookingCheckedOut, id)
+		}
+		return nil
+	})
+}
+
+// publishBookingEvent mengirim event booking ke antrian webhook. Dipanggil dengan transaksi perubahan
+// booking-nya (lihat webhook.WithTx) agar event tidak hilang / terkirim untuk perubahan yang batal.
+func publishBookingEvent(q database.Querier, event string, bookingID string) {
+	webhook.PublishLoaded(q, event, func() (interface{}, error) {
+		data, err := FindEventData(q, bookingID)
+		if err != nil {
+			return nil, fmt.Errorf("booking %s: %w", bookingID, err)
+		}
+		return data, nil
+	})
 }
 
 // ==========================
@@ -256,18 +426,20 @@ func GenerateTicketImage(b BookingResponse) ([]byte, error) {
 	dc.SetRGB(1, 1, 1)
 	dc.Clear()
 
-	// 2. Load Font
-	fontPath := "assets/fonts/Arial.ttf"
-
-	setFont := func(size float64) {
-		if err := dc.LoadFontFace(fontPath, size); err != nil {
-			fmt.Println("Warning: Font not found, text might fail to render.", err)
+	// 2. Font tertanam (lihat service_ticket.go); gagal memuat font = tiket tidak dibuat
+	var fontErr error
+	setFont := func(size float64, bold bool) {
+		face, err := fontFace(bold, size)
+		if err != nil {
+			fontErr = err
+			return
 		}
+		dc.SetFontFace(face)
 	}
 
 	// 3. Header: "UniSpace Ticket"
 	dc.SetRGB(0, 0, 0) // Hitam
-	setFont(40)
+	setFont(40, true)
 	dc.DrawStringAnchored("UniSpace Ticket", W/2, 80, 0.5, 0.5)
 
 	// Garis Pemisah Header
@@ -294,11 +466,11 @@ func GenerateTicketImage(b BookingResponse) ([]byte, error) {
 		yPos := startY + (float64(i) * gapY)
 
 		dc.SetRGB(0.5, 0.5, 0.5)
-		setFont(14)
+		setFont(14, false)
 		dc.DrawString(f.Label, 50, yPos)
 
 		dc.SetRGB(0, 0, 0)
-		setFont(22)
+		setFont(22, false)
 		dc.DrawStringAnchored(f.Value, 50, yPos+30, 0, 0)
 	}
 
@@ -319,13 +491,17 @@ func GenerateTicketImage(b BookingResponse) ([]byte, error) {
 
 	// 6. Kode Tiket
 	dc.SetRGB(0, 0, 0)
-	setFont(18)
+	setFont(18, false)
 	dc.DrawStringAnchored(b.TicketCode, W/2, qrY+140, 0.5, 0.5)
 
 	// Status
 	dc.SetRGB(0, 0.6, 0)
-	setFont(16)
-	dc.DrawStringAnchored("STATUS: APPROVED", W/2, qrY+170, 0.5, 0.5)
+	setFont(16, false)
+	dc.DrawStringAnchored("STATUS: "+strings.ToUpper(b.Status), W/2, qrY+170, 0.5, 0.5)
+
+	if fontErr != nil {
+		return nil, fmt.Errorf("gagal memuat font: %w", fontErr)
+	}
 
 	// 7. Render ke Buffer PNG
 	var buf bytes.Buffer
@@ -348,17 +524,21 @@ func formatDateIndo(start, end time.Time) string {
 	end = end.In(loc)
 
 	days := []string{"Minggu", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu"}
-	months := []string{"", "Januari", "Februari", "Maret", "April", "Mei", "Juni", "Juli", "Agustus", "September", "Oktober", "November", "Desember"}
 
 	dayStr := days[start.Weekday()]
-	monthStr := months[start.Month()]
-	dateStr := fmt.Sprintf("%d %s %d", start.Day(), monthStr, start.Year())
+	dateStr := formatDayIndo(start)
 
 	timeStr := fmt.Sprintf("%s - %s WIB", start.Format("15.04"), end.Format("15.04"))
 
 	return fmt.Sprintf("%s, %s (%s)", dayStr, dateStr, timeStr)
 }
 
+// formatDayIndo: "19 Oktober 2026" (t sudah dikonversi ke zona yang diinginkan)
+func formatDayIndo(t time.Time) string {
+	months := []string{"", "Januari", "Februari", "Maret", "April", "Mei", "Juni", "Juli", "Agustus", "September", "Oktober", "November", "Desember"}
+	return fmt.Sprintf("%d %s %d", t.Day(), months[t.Month()], t.Year())
+}
+
 // ==========================
 // EXCEL REPORT GENERATOR
 // ==========================
@@ -370,18 +550,19 @@ func GenerateExcelReport(bookings []BookingResponse, startDate, endDate string)
 	f.DeleteSheet("Sheet1")
 
 	// 1. Header Judul
-	f.MergeCell(sheetName, "A1", "I1")
+	f.MergeCell(sheetName, "A1", "N1")
 	f.SetCellValue(sheetName, "A1", fmt.Sprintf("LAPORAN KEHADIRAN PENGGUNAAN FASILITAS (%s s/d %s)", startDate, endDate))
 
 	styleTitle, _ := f.NewStyle(&excelize.Style{
 		Font:      &excelize.Font{Bold: true, Size: 14},
 		Alignment: &excelize.Alignment{Horizontal: "center"},
 	})
-	f.SetCellStyle(sheetName, "A1", "I1", styleTitle)
+	f.SetCellStyle(sheetName, "A1", "N1", styleTitle)
 
 	// 2. Header Kolom
-	headers := []string{"No", "Nama User", "NIM/NIP", "Fasilitas", "Tanggal", "Jadwal", "Check-In", "Check-Out", "Status Kehadiran"}
-	columns := []string{"A", "B", "C", "D", "E", "F", "G", "H", "I"}
+	headers := []string{"No", "Nama User", "NIM/NIP", "Fasilitas", "Tanggal", "Jadwal", "Check-In", "Check-Out", "Status Kehadiran",
+		"Kapasitas", "Perkiraan Orang", "Jumlah Hadir", "Utilisasi (%)", "Melebihi Kapasitas"}
+	columns := []string{"A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K", "L", "M", "N"}
 
 	for i, h := range headers {
 		cell := fmt.Sprintf("%s3", columns[i])
@@ -398,7 +579,7 @@ func GenerateExcelReport(bookings []BookingResponse, startDate, endDate string)
 		},
 		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
 	})
-	f.SetCellStyle(sheetName, "A3", "I3", styleHeader)
+	f.SetCellStyle(sheetName, "A3", "N3", styleHeader)
 
 	// [FIX TIMEZONE] Load lokasi WIB untuk Excel
 	loc, err := time.LoadLocation("Asia/Jakarta")
@@ -454,6 +635,22 @@ func GenerateExcelReport(bookings []BookingResponse, startDate, endDate string)
 		f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), checkOutStr)
 		f.SetCellValue(sheetName, fmt.Sprintf("I%d", row), status)
 
+		f.SetCellValue(sheetName, fmt.Sprintf("J%d", row), b.FacilityCapacity)
+		if b.ExpectedHeadcount != nil {
+			f.SetCellValue(sheetName, fmt.Sprintf("K%d", row), *b.ExpectedHeadcount)
+		}
+		if b.ActualHeadcount != nil {
+			f.SetCellValue(sheetName, fmt.Sprintf("L%d", row), *b.ActualHeadcount)
+		}
+		if rate, ok := utilizationRate(b); ok {
+			f.SetCellValue(sheetName, fmt.Sprintf("M%d", row), rate)
+		}
+		overStr := "Tidak"
+		if b.OverCapacity {
+			overStr = "Ya"
+		}
+		f.SetCellValue(sheetName, fmt.Sprintf("N%d", row), overStr)
+
 		row++
 	}
 
@@ -463,6 +660,34 @@ func GenerateExcelReport(bookings []BookingResponse, startDate, endDate string)
 	f.SetColWidth(sheetName, "D", "D", 20)
 	f.SetColWidth(sheetName, "E", "F", 15)
 	f.SetColWidth(sheetName, "I", "I", 15)
+	f.SetColWidth(sheetName, "J", "N", 16)
+
+	// 5. Sheet Ringkasan Utilisasi per Fasilitas
+	summarySheet := "Utilisasi Fasilitas"
+	f.NewSheet(summarySheet)
+
+	f.MergeCell(summarySheet, "A1", "G1")
+	f.SetCellValue(summarySheet, "A1", fmt.Sprintf("UTILISASI KAPASITAS FASILITAS (%s s/d %s)", startDate, endDate))
+	f.SetCellStyle(summarySheet, "A1", "G1", styleTitle)
+
+	summaryHeaders := []string{"Fasilitas", "Kapasitas", "Total Booking", "Booking Tercatat Jumlah Orang", "Rata-rata Jumlah Orang", "Rata-rata Utilisasi (%)", "Melebihi Kapasitas"}
+	for i, h := range summaryHeaders {
+		f.SetCellValue(summarySheet, fmt.Sprintf("%s3", columns[i]), h)
+	}
+	f.SetCellStyle(summarySheet, "A3", "G3", styleHeader)
+
+	for i, u := range SummarizeUtilization(bookings) {
+		r := i + 4
+		f.SetCellValue(summarySheet, fmt.Sprintf("A%d", r), u.FacilityName)
+		f.SetCellValue(summarySheet, fmt.Sprintf("B%d", r), u.Capacity)
+		f.SetCellValue(summarySheet, fmt.Sprintf("C%d", r), u.TotalBookings)
+		f.SetCellValue(summarySheet, fmt.Sprintf("D%d", r), u.CountedBookings)
+		f.SetCellValue(summarySheet, fmt.Sprintf("E%d", r), u.AvgHeadcount)
+		f.SetCellValue(summarySheet, fmt.Sprintf("F%d", r), u.UtilizationRate)
+		f.SetCellValue(summarySheet, fmt.Sprintf("G%d", r), u.OverCapacityCount)
+	}
+	f.SetColWidth(summarySheet, "A", "A", 25)
+	f.SetColWidth(summarySheet, "B", "G", 20)
 
 	buf, err := f.WriteToBuffer()
 	if err != nil {
@@ -472,6 +697,80 @@ func GenerateExcelReport(bookings []BookingResponse, startDate, endDate string)
 	return buf, nil
 }
 
+// ==========================
+// UTILISASI KAPASITAS
+// ==========================
+
+// FacilityUtilization adalah ringkasan jumlah orang vs kapasitas untuk satu fasilitas
+type FacilityUtilization struct {
+	FacilityID        string  `json:"facility_id"`
+	FacilityName      string  `json:"facility_name"`
+	Capacity          int     `json:"capacity"`
+	TotalBookings     int     `json:"total_bookings"`
+	CountedBookings   int     `json:"counted_bookings"` // Booking yang punya data jumlah orang
+	AvgHeadcount      float64 `json:"avg_headcount"`
+	UtilizationRate   float64 `json:"utilization_rate"` // Persen rata-rata jumlah orang / kapasitas
+	OverCapacityCount int     `json:"over_capacity_count"`
+}
+
+// bookingHeadcount memakai jumlah hadir, atau perkiraan jika jumlah hadir tidak diisi
+func bookingHeadcount(b BookingResponse) (int, bool) {
+	if b.ActualHeadcount != nil {
+		return *b.ActualHeadcount, true
+	}
+	if b.ExpectedHeadcount != nil {
+		return *b.ExpectedHeadcount, true
+	}
+	return 0, false
+}
+
+func utilizationRate(b BookingResponse) (float64, bool) {
+	n, ok := bookingHeadcount(b)
+	if !ok || b.FacilityCapacity <= 0 {
+		return 0, false
+	}
+	return math.Round(float64(n)/float64(b.FacilityCapacity)*1000) / 10, true
+}
+
+// SummarizeUtilization mengelompokkan data laporan kehadiran per fasilitas
+func SummarizeUtilization(bookings []BookingResponse) []FacilityUtilization {
+	var order []string
+	byFacility := make(map[string]*FacilityUtilization)
+	totals := make(map[string]int)
+
+	for _, b := range bookings {
+		u, ok := byFacility[b.FacilityID]
+		if !ok {
+			u = &FacilityUtilization{FacilityID: b.FacilityID, FacilityName: b.FacilityName, Capacity: b.FacilityCapacity}
+			byFacility[b.FacilityID] = u
+			order = append(order, b.FacilityID)
+		}
+
+		u.TotalBookings++
+		if b.OverCapacity {
+			u.OverCapacityCount++
+		}
+		if n, ok := bookingHeadcount(b); ok {
+			u.CountedBookings++
+			totals[b.FacilityID] += n
+		}
+	}
+
+	result := make([]FacilityUtilization, 0, len(order))
+	for _, id := range order {
+		u := byFacility[id]
+		if u.CountedBookings > 0 {
+			avg := float64(totals[id]) / float64(u.CountedBookings)
+			u.AvgHeadcount = math.Round(avg*10) / 10
+			if u.Capacity > 0 {
+				u.UtilizationRate = math.Round(avg/float64(u.Capacity)*1000) / 10
+			}
+		}
+		result = append(result, *u)
+	}
+	return result
+}
+
 // ==========================
 // SUBMIT REVIEW (USER)
 // ==========================
@@ -509,8 +808,8 @@ func SubmitReview(db *sql.DB, bookingID string, userID string, comment string) e
 // ==========================
 // GET ALL REVIEWS (ADMIN)
 // ==========================
-func GetAllReviews(db *sql.DB) ([]BookingResponse, error) {
+func GetAllReviews(db *sql.DB, campusID string) ([]BookingResponse, error) {
 	// Menggunakan GetAll tanpa filter status, namun nantinya di Handler
 	// kita akan memastikan data yang ditampilkan hanya yang memiliki review_comment
-	return GetAll(db, "completed", "", "")
+	return GetAll(db, "completed", "", "", campusID)
 }
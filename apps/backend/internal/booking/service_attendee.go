@@ -0,0 +1,456 @@
+package booking
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/mail"
+	"strings"
+	"time"
+
+	"campus-reservation-backend/internal/mailer"
+	"campus-reservation-backend/internal/whatsapp"
+)
+
+// ==========================
+// PESERTA BOOKING (ATTENDEES)
+// ==========================
+// Pemesan (atau admin) bisa melampirkan daftar peserta, baik satu per satu (undang via email / nomor HP)
+// maupun impor CSV. Setiap peserta mendapat kode tiket "AT-<8 digit ID booking>-<acak>" untuk check-in
+// di scanner yang sama dengan tiket booking. Total peserta + pemesan tidak boleh melebihi kapasitas ruangan.
+
+const (
+	attendeeCodePrefix = "AT-"
+	maxAttendeeImport  = 1000
+)
+
+type AttendeeInput struct {
+	Name  string `json:"name" example:"Budi Santoso"`
+	Email string `json:"email" example:"budi@kampus.ac.id"`
+	Phone string `json:"phone" example:"081234567890"`
+}
+
+type AddAttendeesRequest struct {
+	Attendees []AttendeeInput `json:"attendees"`
+}
+
+type AttendeeSkip struct {
+	Row    int    `json:"row"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+type AddAttendeesResult struct {
+	Added   []Attendee     `json:"added"`
+	Skipped []AttendeeSkip `json:"skipped"`
+	Total   int            `json:"total_attendees"`
+}
+
+// IsAttendeeTicketCode membedakan tiket peserta dari tiket booking saat scan
+func IsAttendeeTicketCode(code string) bool {
+	return strings.HasPrefix(code, attendeeCodePrefix)
+}
+
+// canManageAttendees: hanya pemesan dan admin
+func canManageAttendees(b attendeeBooking, userID string, isAdmin bool) bool {
+	return isAdmin || b.UserID == userID
+}
+
+// ==========================
+// TAMBAH PESERTA
+// ==========================
+func AddAttendees(db *sql.DB, bookingID string, userID string, isAdmin bool, inputs []AttendeeInput) (AddAttendeesResult, error) {
+	result := AddAttendeesResult{Added: []Attendee{}, Skipped: []AttendeeSkip{}}
+
+	if len(inputs) == 0 {
+		return result, errors.New("daftar peserta kosong")
+	}
+	if len(inputs) > maxAttendeeImport {
+		return result, fmt.Errorf("maksimal %d peserta per permintaan", maxAttendeeImport)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return result, errors.New("gagal memproses daftar peserta")
+	}
+	defer tx.Rollback()
+
+	b, err := lockAttendeeBooking(tx, bookingID)
+	if err != nil {
+		return result, errors.New("booking tidak ditemukan")
+	}
+	if !canManageAttendees(b, userID, isAdmin) {
+		return result, errors.New("anda tidak memiliki akses ke booking ini")
+	}
+	if b.Status != "pending" && b.Status != "approved" {
+		return result, errors.New("peserta hanya bisa ditambahkan ke booking yang masih aktif")
+	}
+	if time.Now().After(b.EndTime) {
+		return result, errors.New("booking sudah berakhir")
+	}
+
+	existing, err := CountAttendees(tx, bookingID)
+	if err != nil {
+		return result, errors.New("gagal menghitung peserta")
+	}
+
+	// Validasi & buang duplikat terlebih dahulu, baru cek kapasitas untuk peserta yang valid
+	var valid []Attendee
+	seen := make(map[string]bool)
+	for i, in := range inputs {
+		row := i + 1
+		a, err := normalizeAttendee(in)
+		if err != nil {
+			result.Skipped = append(result.Skipped, AttendeeSkip{Row: row, Name: in.Name, Reason: err.Error()})
+			continue
+		}
+
+		email, phone := derefString(a.Email), derefString(a.Phone)
+		if (email != "" && seen["e:"+email]) || (phone != "" && seen["p:"+phone]) {
+			result.Skipped = append(result.Skipped, AttendeeSkip{Row: row, Name: a.Name, Reason: "duplikat di daftar yang dikirim"})
+			continue
+		}
+
+		dup, err := attendeeExists(tx, bookingID, email, phone)
+		if err != nil {
+			return result, errors.New("gagal mengecek duplikasi peserta")
+		}
+		if dup {
+			result.Skipped = append(result.Skipped, AttendeeSkip{Row: row, Name: a.Name, Reason: "sudah terdaftar sebagai peserta"})
+			continue
+		}
+
+		if email != "" {
+			seen["e:"+email] = true
+		}
+		if phone != "" {
+			seen["p:"+phone] = true
+		}
+		valid = append(valid, a)
+	}
+
+	// Kapasitas dihitung termasuk pemesan
+	if 1+existing+len(valid) > b.Capacity {
+		return result, fmt.Errorf("jumlah peserta melebihi kapasitas %s (%d orang, sudah terdaftar %d + pemesan, akan ditambah %d)",
+			b.FacilityName, b.Capacity, existing, len(valid))
+	}
+
+	for _, a := range valid {
+		code, err := generateAttendeeCode(bookingID)
+		if err != nil {
+			return result, errors.New("gagal membuat kode tiket peserta")
+		}
+		a.TicketCode = code
+
+		id, err := InsertAttendee(tx, bookingID, a, userID)
+		if err != nil {
+			return result, errors.New("gagal menyimpan peserta " + a.Name)
+		}
+		a.ID = id
+		a.BookingID = bookingID
+		result.Added = append(result.Added, a)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, errors.New("gagal menyimpan daftar peserta")
+	}
+	result.Total = existing + len(result.Added)
+
+	// Tiket peserta baru berlaku setelah booking disetujui, undangan dikirim saat itu
+	if b.Status == "approved" {
+		SendAttendeeInvitations(db, bookingID)
+	}
+
+	return result, nil
+}
+
+// ParseAttendeeCSV membaca CSV dengan kolom: name, email, phone (baris header opsional)
+func ParseAttendeeCSV(r io.Reader) ([]AttendeeInput, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.New("format CSV tidak valid")
+	}
+	if len(records) == 0 {
+		return nil, errors.New("file CSV kosong")
+	}
+
+	// Urutan kolom default name,email,phone. Jika ada header, urutan mengikuti header.
+	col := map[string]int{"name": 0, "email": 1, "phone": 2}
+	first := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(records[0][0], "\ufeff")))
+	if first == "name" || first == "nama" || first == "email" || first == "phone" || first == "no_hp" {
+		col = map[string]int{"name": -1, "email": -1, "phone": -1}
+		for i, h := range records[0] {
+			switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))) {
+			case "name", "nama":
+				col["name"] = i
+			case "email":
+				col["email"] = i
+			case "phone", "no_hp", "hp", "telepon":
+				col["phone"] = i
+			}
+		}
+		if col["name"] < 0 {
+			return nil, errors.New("kolom name/nama tidak ditemukan di header CSV")
+		}
+		records = records[1:]
+	}
+
+	get := func(rec []string, key string) string {
+		i := col[key]
+		if i < 0 || i >= len(rec) {
+			return ""
+		}
+		return rec[i]
+	}
+
+	var inputs []AttendeeInput
+	for _, rec := range records {
+		in := AttendeeInput{Name: get(rec, "name"), Email: get(rec, "email"), Phone: get(rec, "phone")}
+		// Lewati baris kosong
+		if strings.TrimSpace(in.Name+in.Email+in.Phone) == "" {
+			continue
+		}
+		inputs = append(inputs, in)
+	}
+	return inputs, nil
+}
+
+func normalizeAttendee(in AttendeeInput) (Attendee, error) {
+	var a Attendee
+	a.Name = strings.TrimSpace(in.Name)
+	if a.Name == "" {
+		return a, errors.New("nama wajib diisi")
+	}
+	if len(a.Name) > 150 {
+		return a, errors.New("nama terlalu panjang")
+	}
+
+	email := strings.ToLower(strings.TrimSpace(in.Email))
+	phone := normalizeAttendeePhone(in.Phone)
+
+	if email == "" && phone == "" {
+		return a, errors.New("email atau nomor HP wajib diisi")
+	}
+	if email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return a, errors.New("format email tidak valid")
+		}
+		a.Email = &email
+	}
+	if phone != "" {
+		if len(phone) < 9 || len(phone) > 15 {
+			return a, errors.New("format nomor HP tidak valid")
+		}
+		a.Phone = &phone
+	}
+	return a, nil
+}
+
+// normalizeAttendeePhone menyamakan format dengan kolom users.phone (08xxx)
+func normalizeAttendeePhone(phone string) string {
+	phone = strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+
+	if strings.HasPrefix(phone, "62") {
+		phone = "0" + phone[2:]
+	} else if strings.HasPrefix(phone, "8") {
+		phone = "0" + phone
+	}
+	return phone
+}
+
+func generateAttendeeCode(bookingID string) (string, error) {
+	const charset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	suffix := make([]byte, 8)
+	for i := range suffix {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			return "", err
+		}
+		suffix[i] = charset[n.Int64()]
+	}
+
+	prefix := strings.ToUpper(strings.ReplaceAll(bookingID, "-", ""))
+	if len(prefix) > 8 {
+		prefix = prefix[:8]
+	}
+	return attendeeCodePrefix + prefix + "-" + string(suffix), nil
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// ==========================
+// LIHAT & HAPUS PESERTA
+// ==========================
+func GetAttendees(db *sql.DB, bookingID string, userID string, isAdmin bool) ([]Attendee, error) {
+	_, ownerID, err := FindByID(db, bookingID)
+	if err != nil {
+		return nil, errors.New("booking tidak ditemukan")
+	}
+	if !isAdmin && ownerID != userID {
+		return nil, errors.New("anda tidak memiliki akses ke booking ini")
+	}
+	return FindAttendees(db, bookingID)
+}
+
+func RemoveAttendee(db *sql.DB, bookingID, attendeeID string, userID string, isAdmin bool) error {
+	_, ownerID, err := FindByID(db, bookingID)
+	if err != nil {
+		return errors.New("booking tidak ditemukan")
+	}
+	if !isAdmin && ownerID != userID {
+		return errors.New("anda tidak memiliki akses ke booking ini")
+	}
+
+	n, err := DeleteAttendee(db, bookingID, attendeeID)
+	if err != nil {
+		return errors.New("gagal menghapus peserta")
+	}
+	if n == 0 {
+		return errors.New("peserta tidak ditemukan")
+	}
+	return nil
+}
+
+// ==========================
+// TIKET PESERTA
+// ==========================
+
+// GetAttendeeTicket mengembalikan data tiket peserta. Bisa diakses pemesan, admin, atau peserta yang
+// akunnya cocok (email / nomor HP). Tanpa login, kode tiket itu sendiri yang menjadi bukti akses.
+func GetAttendeeTicket(db *sql.DB, code string) (*BookingResponse, Attendee, error) {
+	a, err := FindAttendeeByTicketCode(db, code)
+	if err != nil {
+		return nil, a, errors.New("tiket peserta tidak ditemukan")
+	}
+
+	b, err := FindDetailByID(db, a.BookingID)
+	if err != nil {
+		return nil, a, errors.New("booking tidak ditemukan")
+	}
+	if b.Status != "approved" && b.Status != "completed" {
+		return nil, a, errors.New("Tiket belum tersedia (Status: " + b.Status + ")")
+	}
+	return b, a, nil
+}
+
+// GenerateAttendeeTicketImage memakai desain tiket yang sama, dengan nama & kode milik peserta
+func GenerateAttendeeTicketImage(b BookingResponse, a Attendee) ([]byte, error) {
+	t := b
+	t.UserName = a.Name
+	t.User.Profile.IdentityNumber = "Peserta acara " + b.UserName
+	t.TicketCode = a.TicketCode
+	return GenerateTicketImage(t)
+}
+
+// SendAttendeeInvitations mengirim tiket ke peserta yang belum diundang (via WhatsApp, atau email jika tidak ada nomor HP).
+// Dipanggil setelah peserta ditambahkan ke booking yang sudah disetujui, atau saat booking disetujui.
+func SendAttendeeInvitations(db *sql.DB, bookingID string) {
+	b, err := FindDetailByID(db, bookingID)
+	if err != nil {
+		return
+	}
+
+	attendees, err := ClaimUninvitedAttendees(db, bookingID)
+	if err != nil {
+		log.Printf("Gagal mengambil peserta booking %s: %v\n", bookingID, err)
+		return
+	}
+	if len(attendees) == 0 {
+		return
+	}
+
+	when := formatDateIndo(b.StartTime, b.EndTime)
+
+	go func() {
+		for _, a := range attendees {
+			link := attendeeTicketURL(a.TicketCode)
+			msg := fmt.Sprintf("Halo %s,\n\nAnda terdaftar sebagai peserta di %s pada %s (pemesan: %s).\n\nKode tiket Anda: *%s*\nTunjukkan QR tiket saat masuk: %s",
+				a.Name, b.FacilityName, when, b.UserName, a.TicketCode, link)
+
+			if a.Phone != nil {
+				if err := whatsapp.SendMessage(*a.Phone, msg); err != nil {
+					log.Printf("Gagal mengirim undangan WA ke %s: %v\n", *a.Phone, err)
+				} else {
+					continue
+				}
+			}
+			if a.Email != nil {
+				if err := mailer.Send(*a.Email, "Undangan Acara - "+b.FacilityName, strings.ReplaceAll(msg, "*", "")); err != nil {
+					log.Printf("Gagal mengirim undangan email ke %s: %v\n", *a.Email, err)
+				}
+			}
+		}
+	}()
+}
+
+// attendeeTicketURL adalah halaman frontend yang menampilkan tiket peserta (GET /attendee-tickets/:code)
+func attendeeTicketURL(code string) string {
+	return frontendBaseURL() + "/attendee-tickets/" + code
+}
+
+// ==========================
+// CHECK-IN PESERTA
+// ==========================
+func CheckInAttendee(db *sql.DB, code string) (Attendee, error) {
+	a, err := FindAttendeeByTicketCode(db, code)
+	if err != nil {
+		return a, errors.New("kode tiket tidak valid atau tidak ditemukan")
+	}
+
+	b, err := FindDetailByID(db, a.BookingID)
+	if err != nil {
+		return a, errors.New("booking tidak ditemukan")
+	}
+	if b.Status != "approved" {
+		return a, errors.New("tiket tidak valid karena booking belum disetujui")
+	}
+
+	if a.IsCheckedIn {
+		return a, errors.New("peserta ini sudah check-in")
+	}
+
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		loc = time.Local
+	}
+	now := time.Now().In(loc)
+	startTimeWIB := b.StartTime.In(loc)
+
+	// Rentang waktu sama dengan check-in booking: sejak StartTime hingga EndTime (+10m buffer)
+	if now.Before(startTimeWIB) {
+		return a, fmt.Errorf("Check-in gagal. Acara baru dimulai jam %s WIB", startTimeWIB.Format("15:04"))
+	}
+	if now.After(b.EndTime) {
+		return a, errors.New("Check-in gagal. Waktu acara telah berakhir")
+	}
+
+	n, err := UpdateAttendeeCheckIn(db, a.ID)
+	if err != nil {
+		return a, errors.New("gagal memproses check-in peserta")
+	}
+	if n == 0 {
+		return a, errors.New("peserta ini sudah check-in")
+	}
+
+	a.IsCheckedIn = true
+	return a, nil
+}
@@ -0,0 +1,141 @@
+package booking
+
+import (
+	"database/sql"
+	"time"
+
+	"campus-reservation-backend/internal/billing"
+)
+
+// ==========================
+// LAYAR PINTU RUANGAN (DOOR DISPLAY)
+// ==========================
+// Ringkasan "sekarang / berikutnya" untuk tablet di depan ruangan.
+// Datanya sama dengan GetScheduleByFacility, hanya dipilah untuk hari ini (WIB).
+
+// Durasi minimal slot kosong agar ruangan bisa langsung dipakai tanpa booking sebelumnya.
+// Ditambah buffer 10 menit yang sama dengan CreateBooking.
+const (
+	walkUpMinDuration = 30 * time.Minute
+	bookingEndBuffer  = billing.BookingEndBuffer
+)
+
+type DisplayAvailability struct {
+	Status          string     `json:"status"` // available | occupied | unavailable
+	Label           string     `json:"label"`
+	FreeUntil       *time.Time `json:"free_until,omitempty"`
+	FreeMinutes     *int       `json:"free_minutes,omitempty"`
+	WalkUpAvailable bool       `json:"walk_up_available"`
+}
+
+type FacilityDisplay struct {
+	Facility         DisplayFacility     `json:"facility"`
+	GeneratedAt      time.Time           `json:"generated_at"`
+	Current          *ScheduleResponse   `json:"current"`
+	RemainingMinutes *int                `json:"remaining_minutes,omitempty"`
+	Next             []ScheduleResponse  `json:"next"`
+	Today            []ScheduleResponse  `json:"today"`
+	Availability     DisplayAvailability `json:"availability"`
+	WalkUpURL        string              `json:"walk_up_url,omitempty"` // Isi QR di layar pintu
+}
+
+// GetFacilityDisplay menyusun data layar pintu untuk satu fasilitas
+func GetFacilityDisplay(db *sql.DB, facilityID string) (FacilityDisplay, error) {
+	facility, err := FindDisplayFacility(db, facilityID)
+	if err != nil {
+		return FacilityDisplay{}, err
+	}
+
+	schedules, err := GetScheduleByFacility(db, facilityID)
+	if err != nil {
+		return FacilityDisplay{}, err
+	}
+
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		loc = time.Local
+	}
+
+	d := buildFacilityDisplay(facility, schedules, time.Now().In(loc))
+	if facility.AllowWalkUp {
+		d.WalkUpURL = WalkUpURL(facility.ID)
+	}
+	return d, nil
+}
+
+func buildFacilityDisplay(facility DisplayFacility, schedules []ScheduleResponse, now time.Time) FacilityDisplay {
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	d := FacilityDisplay{
+		Facility:    facility,
+		GeneratedAt: now,
+		Next:        []ScheduleResponse{},
+		Today:       []ScheduleResponse{},
+	}
+
+	blockedNow := false
+	freeUntil := dayEnd
+
+	for i := range schedules {
+		s := schedules[i]
+		end := effectiveEnd(s)
+
+		// Booking pending & approved sama-sama menahan slot (sama seperti GetConflictingBooking)
+		if s.Status == "approved" || s.Status == "pending" {
+			if !s.StartTime.After(now) && end.After(now) {
+				blockedNow = true
+			} else if s.StartTime.After(now) && s.StartTime.Before(freeUntil) {
+				freeUntil = s.StartTime
+			}
+		}
+
+		if s.Status != "approved" && s.Status != "completed" {
+			continue
+		}
+		if s.AttendanceStatus == "no_show" {
+			continue
+		}
+		if !s.StartTime.Before(dayEnd) || !end.After(dayStart) {
+			continue
+		}
+
+		d.Today = append(d.Today, s)
+
+		switch {
+		case !s.StartTime.After(now) && end.After(now) && d.Current == nil:
+			current := s
+			d.Current = &current
+			remaining := int(end.Sub(now).Minutes())
+			d.RemainingMinutes = &remaining
+		case s.StartTime.After(now) && s.Status == "approved":
+			d.Next = append(d.Next, s)
+		}
+	}
+
+	switch {
+	case !facility.IsActive:
+		d.Availability = DisplayAvailability{Status: "unavailable", Label: "Tidak Tersedia (Maintenance)"}
+	case blockedNow || d.Current != nil:
+		d.Availability = DisplayAvailability{Status: "occupied", Label: "Sedang Digunakan"}
+	default:
+		freeMinutes := int(freeUntil.Sub(now).Minutes())
+		d.Availability = DisplayAvailability{
+			Status:          "available",
+			Label:           "Tersedia",
+			FreeUntil:       &freeUntil,
+			FreeMinutes:     &freeMinutes,
+			WalkUpAvailable: facility.AllowWalkUp && freeUntil.Sub(now) >= walkUpMinDuration+bookingEndBuffer,
+		}
+	}
+
+	return d
+}
+
+// effectiveEnd: booking yang sudah check-out lebih awal selesai di actual_end_time
+func effectiveEnd(s ScheduleResponse) time.Time {
+	if s.ActualEndTime != nil {
+		return *s.ActualEndTime
+	}
+	return s.EndTime
+}
@@ -0,0 +1,276 @@
+package booking
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"campus-reservation-backend/internal/auth"
+	"campus-reservation-backend/internal/billing"
+	"campus-reservation-backend/internal/mailer"
+
+	"github.com/google/uuid"
+)
+
+// ==========================
+// BOOKING TAMU (ORGANISASI LUAR KAMPUS)
+// ==========================
+// Alur: organisasi mengisi form + surat permohonan tanpa login -> akun tamu ringan (tanpa password, tarif external)
+// dan booking pending biasa dibuat -> tamu memantau status & membayar lewat link berisi token rahasia ->
+// setelah admin menyetujui, akun tamu diaktifkan (link buat password) sehingga booking dikelola seperti booking biasa.
+
+type GuestBookingInput struct {
+	FacilityID       string
+	StartTime        time.Time
+	EndTime          time.Time
+	OrganizationName string
+	ContactName      string
+	ContactEmail     string
+	ContactPhone     string
+	Purpose          string
+	DocumentPath     string
+	DocumentName     string
+}
+
+// GuestBookingResult dikembalikan sekali saat pengajuan (access_token tidak disimpan dalam bentuk asli)
+type GuestBookingResult struct {
+	ID          string           `json:"id"`
+	BookingID   string           `json:"booking_id"`
+	AccessToken string           `json:"access_token"`
+	StatusURL   string           `json:"status_url"`
+	Quote       *billing.Quote   `json:"quote,omitempty"`
+	Invoice     *billing.Invoice `json:"invoice"`
+}
+
+func hashGuestToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func guestStatusURL(token string) string {
+	return frontendBaseURL() + "/guest-bookings/" + token
+}
+
+func validateGuestInput(in *GuestBookingInput) error {
+	in.OrganizationName = strings.TrimSpace(in.OrganizationName)
+	in.ContactName = strings.TrimSpace(in.ContactName)
+	in.ContactEmail = strings.ToLower(strings.TrimSpace(in.ContactEmail))
+	in.ContactPhone = strings.TrimSpace(in.ContactPhone)
+	in.Purpose = strings.TrimSpace(in.Purpose)
+
+	switch {
+	case in.OrganizationName == "":
+		return errors.New("nama organisasi wajib diisi")
+	case len(in.OrganizationName) > 150:
+		return errors.New("nama organisasi maksimal 150 karakter")
+	case in.ContactName == "":
+		return errors.New("nama narahubung wajib diisi")
+	case in.ContactPhone == "":
+		return errors.New("nomor telepon narahubung wajib diisi")
+	case in.Purpose == "":
+		return errors.New("keperluan acara wajib diisi")
+	}
+	if addr, err := mail.ParseAddress(in.ContactEmail); err != nil || addr.Address != in.ContactEmail {
+		return errors.New("email narahubung tidak valid")
+	}
+	if !in.StartTime.After(time.Now()) {
+		return errors.New("waktu mulai harus di masa depan")
+	}
+	return nil
+}
+
+// resolveGuestUser memakai akun tamu yang sudah ada (pengajuan berulang) atau membuat akun tamu baru
+func resolveGuestUser(db *sql.DB, in GuestBookingInput) (string, error) {
+	userID, isGuest, err := findGuestUserByEmail(db, in.ContactEmail)
+	switch {
+	case err == nil && !isGuest:
+		return "", errors.New("email sudah terdaftar sebagai akun UniSpace, silakan login dan ajukan booking biasa")
+	case err == nil:
+		return userID, nil
+	case err != sql.ErrNoRows:
+		return "", err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// Username unik untuk akun tamu; nama asli disimpan di profil
+	name := "tamu-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	userID, err = insertGuestUser(tx, name, in.ContactEmail, in.ContactName, in.ContactPhone, in.OrganizationName)
+	if err != nil {
+		return "", errors.New("gagal membuat akun tamu")
+	}
+	return userID, tx.Commit()
+}
+
+// CreateGuestBooking membuat akun tamu (jika perlu), booking pending, quote & invoice tarif external
+func CreateGuestBooking(db *sql.DB, in GuestBookingInput) (GuestBookingResult, error) {
+	if err := validateGuestInput(&in); err != nil {
+		return GuestBookingResult{}, err
+	}
+
+	facility, err := findGuestFacility(db, in.FacilityID)
+	if err != nil {
+		return GuestBookingResult{}, errors.New("fasilitas tidak ditemukan")
+	}
+	if !facility.IsActive {
+		return GuestBookingResult{}, errors.New("fasilitas sedang tidak tersedia (maintenance)")
+	}
+	if !facility.AllowGuestBooking {
+		return GuestBookingResult{}, errors.New("fasilitas ini tidak menerima booking tamu")
+	}
+
+	userID, err := resolveGuestUser(db, in)
+	if err != nil {
+		return GuestBookingResult{}, err
+	}
+
+	b := Booking{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		FacilityID: in.FacilityID,
+		StartTime:  in.StartTime,
+		EndTime:    in.EndTime,
+		Purpose:    in.OrganizationName + " - " + in.Purpose,
+		Status:     "pending",
+	}
+	if err := CreateBooking(db, b); err != nil {
+		return GuestBookingResult{}, err
+	}
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return GuestBookingResult{}, err
+	}
+	token := hex.EncodeToString(raw)
+
+	id, err := insertGuestRequest(db, GuestBooking{
+		BookingID:        b.ID,
+		GuestUserID:      userID,
+		OrganizationName: in.OrganizationName,
+		ContactName:      in.ContactName,
+		ContactEmail:     in.ContactEmail,
+		ContactPhone:     in.ContactPhone,
+		Purpose:          in.Purpose,
+		DocumentPath:     in.DocumentPath,
+		DocumentName:     in.DocumentName,
+	}, hashGuestToken(token))
+	if err != nil {
+		// Booking tanpa data pengajuan tidak bisa dipantau tamu: dibatalkan agar slot tidak terkunci
+		if cancelErr := CancelBooking(db, b.ID, userID); cancelErr != nil {
+			log.Printf("Gagal membatalkan booking tamu %s: %v\n", b.ID, cancelErr)
+		}
+		return GuestBookingResult{}, errors.New("gagal menyimpan pengajuan booking tamu")
+	}
+
+	res := GuestBookingResult{
+		ID:          id,
+		BookingID:   b.ID,
+		AccessToken: token,
+		StatusURL:   guestStatusURL(token),
+	}
+	if quote, inv, err := billing.GetBookingBilling(db, b.ID); err == nil {
+		res.Quote, res.Invoice = &quote, inv
+	}
+
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	body := fmt.Sprintf("Halo %s,\n\nPengajuan booking %s untuk %s pada %s - %s WIB sudah kami terima dan menunggu persetujuan admin.\n\n",
+		in.ContactName, facility.Name, in.OrganizationName,
+		in.StartTime.In(loc).Format("02 Jan 2006 15:04"), in.EndTime.In(loc).Format("15:04"))
+	if res.Invoice != nil {
+		body += fmt.Sprintf("Invoice %s sebesar Rp %s dapat dibayar dari halaman status.\n\n", res.Invoice.Number, billing.FormatRupiah(res.Invoice.Amount))
+	}
+	body += "Pantau status pengajuan di:\n" + res.StatusURL + "\n\nJangan bagikan link ini kepada pihak lain."
+	sendGuestEmail(in.ContactEmail, "Pengajuan Booking "+facility.Name, body)
+
+	return res, nil
+}
+
+// FindGuestBookingByToken dipakai halaman status tamu (tanpa login)
+func FindGuestBookingByToken(db *sql.DB, token string) (GuestBooking, error) {
+	if strings.TrimSpace(token) == "" {
+		return GuestBooking{}, errors.New("pengajuan tidak ditemukan")
+	}
+	g, err := findGuestBookingByTokenHash(db, hashGuestToken(token))
+	if err != nil {
+		return g, errors.New("pengajuan tidak ditemukan")
+	}
+	return g, nil
+}
+
+// CancelGuestBooking: tamu membatalkan pengajuan yang masih pending
+func CancelGuestBooking(db *sql.DB, token string) error {
+	g, err := FindGuestBookingByToken(db, token)
+	if err != nil {
+		return err
+	}
+	return CancelBooking(db, g.BookingID, g.GuestUserID)
+}
+
+// notifyGuestDecision dipanggil setelah admin menyetujui / menolak booking.
+// Booking tamu yang disetujui diubah menjadi booking biasa: akun tamu diaktifkan & tamu membuat password.
+func notifyGuestDecision(db *sql.DB, bookingID, newStatus string) {
+	g, err := findGuestBookingByBooking(db, bookingID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Gagal memuat data booking tamu %s: %v\n", bookingID, err)
+		}
+		return
+	}
+
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	when := g.StartTime.In(loc).Format("02 Jan 2006 15:04") + " - " + g.EndTime.In(loc).Format("15:04") + " WIB"
+
+	if newStatus != "approved" {
+		body := fmt.Sprintf("Halo %s,\n\nMohon maaf, pengajuan booking %s pada %s ditolak.", g.ContactName, g.FacilityName, when)
+		if g.RejectionReason != "" {
+			body += "\nAlasan: " + g.RejectionReason
+		}
+		sendGuestEmail(g.ContactEmail, "Pengajuan Booking Ditolak - "+g.FacilityName, body)
+		return
+	}
+
+	converted, err := markGuestConverted(db, g.ID, g.GuestUserID)
+	if err != nil {
+		log.Printf("Gagal mengaktifkan akun tamu %s: %v\n", g.GuestUserID, err)
+		return
+	}
+
+	body := fmt.Sprintf("Halo %s,\n\nBooking %s pada %s untuk %s telah DISETUJUI.\nKode tiket: %s\n\n",
+		g.ContactName, g.FacilityName, when, g.OrganizationName, g.TicketCode)
+
+	var hasPassword bool
+	if err := db.QueryRow(`SELECT password_hash IS NOT NULL FROM users WHERE id::text = $1`, g.GuestUserID).Scan(&hasPassword); err != nil {
+		log.Printf("Gagal memeriksa akun tamu %s: %v\n", g.GuestUserID, err)
+	}
+	if converted && !hasPassword {
+		link, err := auth.CreateActivationLink(db, g.GuestUserID)
+		if err != nil {
+			log.Printf("Gagal membuat link aktivasi akun tamu %s: %v\n", g.GuestUserID, err)
+		} else {
+			body += "Akun UniSpace untuk " + g.ContactEmail + " sudah dibuat. Buat password lewat link berikut (berlaku 3 hari) " +
+				"untuk mengunduh tiket, menambah peserta dan check-in:\n" + link
+		}
+	} else {
+		body += "Login ke UniSpace dengan email " + g.ContactEmail + " untuk mengunduh tiket."
+	}
+	sendGuestEmail(g.ContactEmail, "Booking Disetujui - "+g.FacilityName, body)
+}
+
+func sendGuestEmail(to, subject, body string) {
+	go func() {
+		if err := mailer.Send(to, subject, body); err != nil {
+			log.Printf("Gagal mengirim email booking tamu ke %s: %v\n", to, err)
+		}
+	}()
+}
@@ -0,0 +1,321 @@
+package booking
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+// ========================================================
+// TIKET PDF & SURAT KONFIRMASI BOOKING
+// ========================================================
+// Format dipilih lewat GET /bookings/:id/ticket?format=png|pdf|letter. Semua format memakai font Go
+// (golang.org/x/image/font/gofont) yang ikut terkompilasi ke binary, jadi tidak bergantung pada file font di server.
+
+// pdfFont: nama keluarga font tertanam di dokumen PDF
+const pdfFont = "GoFont"
+
+// Format unduhan tiket
+const (
+	TicketFormatPNG    = "png"
+	TicketFormatPDF    = "pdf"
+	TicketFormatLetter = "letter"
+)
+
+// ==========================
+// FONT TERTANAM
+// ==========================
+
+var (
+	fontOnce    sync.Once
+	fontRegular *opentype.Font
+	fontBold    *opentype.Font
+	fontErr     error
+)
+
+// fontFace membuat font face untuk gambar tiket (gg)
+func fontFace(bold bool, size float64) (font.Face, error) {
+	fontOnce.Do(func() {
+		if fontRegular, fontErr = opentype.Parse(goregular.TTF); fontErr != nil {
+			return
+		}
+		fontBold, fontErr = opentype.Parse(gobold.TTF)
+	})
+	if fontErr != nil {
+		return nil, fontErr
+	}
+
+	f := fontRegular
+	if bold {
+		f = fontBold
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// ==========================
+// HELPER PDF
+// ==========================
+
+func newPDF(pageSize, title string) *fpdf.Fpdf {
+	pdf := fpdf.New("P", "mm", pageSize, "")
+	pdf.AddUTF8FontFromBytes(pdfFont, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", gobold.TTF)
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("UniSpace", true)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+	return pdf
+}
+
+// drawQR menempel QR code (PNG) berukuran size x size mm
+func drawQR(pdf *fpdf.Fpdf, name, content string, x, y, size float64) error {
+	png, err := qrcode.Encode(content, qrcode.Medium, 512)
+	if err != nil {
+		return errors.New("gagal membuat QR code")
+	}
+	opt := fpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader(name, opt, bytes.NewReader(png))
+	pdf.ImageOptions(name, x, y, size, size, false, opt, 0, "")
+	return nil
+}
+
+func outputPDF(pdf *fpdf.Fpdf) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("gagal membuat PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ==========================
+// TIKET PDF (A6)
+// ==========================
+
+func GenerateTicketPDF(b BookingResponse) ([]byte, error) {
+	const W, margin = 105.0, 8.0
+	pdf := newPDF("A6", "Tiket "+b.TicketCode)
+
+	// 1. Header
+	pdf.SetFont(pdfFont, "B", 16)
+	pdf.SetXY(margin, 8)
+	pdf.CellFormat(W-2*margin, 8, "UniSpace Ticket", "", 1, "C", false, 0, "")
+	pdf.SetDrawColor(204, 204, 204)
+	pdf.SetLineWidth(0.4)
+	pdf.Line(margin, 19, W-margin, 19)
+
+	// 2. Isi tiket (sama dengan tiket PNG)
+	fields := []struct {
+		Label string
+		Value string
+	}{
+		{"NAMA PEMESAN", strings.ToUpper(b.UserName)},
+		{"IDENTITAS (NIM/NIP)", b.User.Profile.IdentityNumber},
+		{"RUANGAN", strings.ToUpper(b.FacilityName)},
+		{"WAKTU PENGGUNAAN", formatDateIndo(b.StartTime, b.EndTime)},
+	}
+	pdf.SetY(23)
+	for _, f := range fields {
+		pdf.SetX(margin)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.SetFont(pdfFont, "", 7)
+		pdf.CellFormat(W-2*margin, 4, f.Label, "", 1, "L", false, 0, "")
+
+		value := f.Value
+		if value == "" {
+			value = "-"
+		}
+		pdf.SetX(margin)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetFont(pdfFont, "B", 10)
+		pdf.MultiCell(W-2*margin, 5, value, "", "L", false)
+		pdf.Ln(2)
+	}
+
+	// 3. QR code berisi kode tiket (dipindai petugas / kiosk saat check-in)
+	const qrSize = 42.0
+	qrY := pdf.GetY() + 2
+	if err := drawQR(pdf, "ticket-qr", b.TicketCode, (W-qrSize)/2, qrY, qrSize); err != nil {
+		return nil, err
+	}
+
+	// 4. Kode tiket & status
+	pdf.SetXY(margin, qrY+qrSize+1)
+	pdf.SetFont(pdfFont, "B", 12)
+	pdf.CellFormat(W-2*margin, 6, b.TicketCode, "", 1, "C", false, 0, "")
+	pdf.SetX(margin)
+	pdf.SetTextColor(0, 153, 0)
+	pdf.SetFont(pdfFont, "B", 9)
+	pdf.CellFormat(W-2*margin, 5, "STATUS: "+strings.ToUpper(b.Status), "", 1, "C", false, 0, "")
+
+	// 5. Link verifikasi
+	verifyURL := TicketVerifyURL(b)
+	pdf.SetXY(margin, 133)
+	pdf.SetTextColor(110, 110, 110)
+	pdf.SetFont(pdfFont, "", 6)
+	pdf.CellFormat(W-2*margin, 3, "Verifikasi keaslian tiket:", "", 1, "C", false, 0, "")
+	pdf.SetX(margin)
+	pdf.LinkString(margin, pdf.GetY(), W-2*margin, 6, verifyURL)
+	pdf.MultiCell(W-2*margin, 3, verifyURL, "", "C", false)
+
+	return outputPDF(pdf)
+}
+
+// ==========================
+// SURAT KONFIRMASI (A4)
+// ==========================
+
+// letterNumber: nomor surat dari kode tiket (SKP = Surat Konfirmasi Peminjaman), unik per booking
+func letterNumber(l BookingLetter) string {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		loc = time.Local
+	}
+	year := time.Now().In(loc).Year()
+	if l.ApprovedAt != nil {
+		year = l.ApprovedAt.In(loc).Year()
+	}
+	return fmt.Sprintf("SKP/%s/%d", l.TicketCode, year)
+}
+
+func GenerateConfirmationLetter(l BookingLetter) ([]byte, error) {
+	const W, margin = 210.0, 20.0
+	const contentW = W - 2*margin
+	pdf := newPDF("A4", "Surat Konfirmasi Booking "+l.TicketCode)
+
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		loc = time.Local
+	}
+
+	// 1. Kop surat
+	campusName := l.CampusName
+	if campusName == "" {
+		campusName = "Sistem Reservasi Fasilitas Kampus"
+	}
+	pdf.SetXY(margin, 18)
+	pdf.SetFont(pdfFont, "B", 20)
+	pdf.CellFormat(contentW, 9, "UNISPACE", "", 1, "C", false, 0, "")
+	pdf.SetX(margin)
+	pdf.SetFont(pdfFont, "B", 12)
+	pdf.CellFormat(contentW, 6, campusName, "", 1, "C", false, 0, "")
+	if l.CampusAddress != "" {
+		pdf.SetX(margin)
+		pdf.SetFont(pdfFont, "", 9)
+		pdf.MultiCell(contentW, 4.5, l.CampusAddress, "", "C", false)
+	}
+	lineY := pdf.GetY() + 3
+	pdf.SetLineWidth(0.8)
+	pdf.Line(margin, lineY, W-margin, lineY)
+	pdf.SetLineWidth(0.2)
+	pdf.Line(margin, lineY+1.2, W-margin, lineY+1.2)
+
+	// 2. Judul & nomor surat
+	pdf.SetXY(margin, lineY+9)
+	pdf.SetFont(pdfFont, "B", 13)
+	pdf.CellFormat(contentW, 7, "SURAT KONFIRMASI PEMINJAMAN FASILITAS", "", 1, "C", false, 0, "")
+	pdf.SetX(margin)
+	pdf.SetFont(pdfFont, "", 10)
+	pdf.CellFormat(contentW, 5, "Nomor: "+letterNumber(l), "", 1, "C", false, 0, "")
+	pdf.Ln(8)
+
+	// 3. Isi surat
+	pdf.SetFont(pdfFont, "", 11)
+	pdf.SetX(margin)
+	pdf.MultiCell(contentW, 6, "Dengan ini menerangkan bahwa permohonan peminjaman fasilitas berikut telah DISETUJUI:", "", "L", false)
+	pdf.Ln(3)
+
+	identity := l.User.Profile.IdentityNumber
+	if identity == "" {
+		identity = "-"
+	}
+	location := l.FacilityLocation
+	if location == "" {
+		location = "-"
+	}
+	purpose := l.Purpose
+	if purpose == "" {
+		purpose = "-"
+	}
+	details := []struct {
+		Label string
+		Value string
+	}{
+		{"Nama Pemesan", l.UserName},
+		{"NIM / NIP", identity},
+		{"Fasilitas", l.FacilityName},
+		{"Lokasi", location},
+		{"Waktu Penggunaan", formatDateIndo(l.StartTime, l.EndTime)},
+		{"Keperluan", purpose},
+		{"Kode Tiket", l.TicketCode},
+		{"Status", strings.ToUpper(l.Status)},
+	}
+	const labelW = 45.0
+	for _, d := range details {
+		pdf.SetX(margin + 5)
+		pdf.SetFont(pdfFont, "", 11)
+		pdf.CellFormat(labelW, 6.5, d.Label, "", 0, "L", false, 0, "")
+		pdf.CellFormat(4, 6.5, ":", "", 0, "L", false, 0, "")
+		pdf.SetFont(pdfFont, "B", 11)
+		pdf.MultiCell(contentW-5-labelW-4, 6.5, d.Value, "", "L", false)
+	}
+	pdf.Ln(4)
+
+	verifyURL := TicketVerifyURL(l.BookingResponse)
+	pdf.SetFont(pdfFont, "", 11)
+	pdf.SetX(margin)
+	pdf.MultiCell(contentW, 6, "Surat ini wajib ditunjukkan kepada petugas saat menggunakan fasilitas. "+
+		"Surat diterbitkan secara elektronik dan sah tanpa tanda tangan basah. Keaslian surat dapat diperiksa "+
+		"dengan memindai kode QR di bawah atau membuka tautan verifikasi.", "", "J", false)
+
+	// 4. QR verifikasi (kiri) & penyetuju (kanan)
+	blockY := pdf.GetY() + 12
+	const qrSize = 38.0
+	if err := drawQR(pdf, "letter-qr", verifyURL, margin, blockY, qrSize); err != nil {
+		return nil, err
+	}
+	pdf.SetXY(margin, blockY+qrSize)
+	pdf.SetFont(pdfFont, "", 8)
+	pdf.CellFormat(qrSize, 4, "Pindai untuk verifikasi", "", 0, "C", false, 0, "")
+
+	const signX, signW = 120.0, W - margin - 120.0
+	pdf.SetXY(signX, blockY)
+	pdf.SetFont(pdfFont, "", 11)
+	pdf.CellFormat(signW, 6, "Diterbitkan, "+formatDayIndo(time.Now().In(loc)), "", 2, "L", false, 0, "")
+	pdf.CellFormat(signW, 6, "Disetujui oleh,", "", 2, "L", false, 0, "")
+	pdf.SetXY(signX, blockY+30)
+	approver, role := l.ApproverName, "Administrator Fasilitas"
+	if approver == "" {
+		approver, role = "Sistem UniSpace", "Persetujuan otomatis"
+	}
+	pdf.SetFont(pdfFont, "B", 11)
+	pdf.CellFormat(signW, 6, approver, "", 2, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "", 10)
+	pdf.CellFormat(signW, 5, role, "", 2, "L", false, 0, "")
+	if l.ApprovedAt != nil {
+		pdf.SetFont(pdfFont, "", 9)
+		pdf.CellFormat(signW, 5, "Disetujui "+formatDayIndo(l.ApprovedAt.In(loc))+" "+l.ApprovedAt.In(loc).Format("15.04")+" WIB",
+			"", 2, "L", false, 0, "")
+	}
+
+	// 5. Footer
+	pdf.SetDrawColor(204, 204, 204)
+	pdf.Line(margin, 275, W-margin, 275)
+	pdf.SetXY(margin, 276)
+	pdf.SetTextColor(110, 110, 110)
+	pdf.SetFont(pdfFont, "", 8)
+	pdf.CellFormat(contentW, 4, "Dokumen ini dibuat secara elektronik oleh UniSpace. Verifikasi keaslian:", "", 1, "C", false, 0, "")
+	pdf.SetX(margin)
+	pdf.CellFormat(contentW, 4, verifyURL, "", 1, "C", false, 0, verifyURL)
+
+	return outputPDF(pdf)
+}
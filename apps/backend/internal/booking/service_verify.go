@@ -0,0 +1,112 @@
+package booking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"campus-reservation-backend/internal/secret"
+)
+
+// ========================================================
+// VERIFIKASI PUBLIK TIKET & SURAT
+// ========================================================
+// QR / link verifikasi pada tiket PDF dan surat konfirmasi berisi kode tiket + signature HMAC atas data yang
+// tercetak (kode, fasilitas, jadwal). Halaman verifikasi tidak butuh login dan hanya menampilkan ringkasan tanpa
+// data pribadi pemesan. Signature yang tidak cocok berarti link diubah / dipalsukan, atau dokumen dicetak
+// sebelum jadwal booking diubah.
+
+// Hasil verifikasi
+const (
+	VerifyValid       = "valid"
+	VerifyExpired     = "expired"  // booking sudah selesai / lewat waktunya
+	VerifyCanceled    = "canceled" // dibatalkan atau dihapus
+	VerifyNotApproved = "not_approved"
+	VerifyTampered    = "tampered"
+	VerifyNotFound    = "not_found"
+)
+
+// ticketSignatureBytes: panjang signature (byte) agar URL & QR tetap pendek
+const ticketSignatureBytes = 10
+
+type TicketVerification struct {
+	Result     string     `json:"result"`
+	Valid      bool       `json:"valid"`
+	Message    string     `json:"message"`
+	TicketCode string     `json:"ticket_code"`
+	Facility   string     `json:"facility,omitempty"`
+	Campus     string     `json:"campus,omitempty"`
+	StartTime  *time.Time `json:"start_time,omitempty"`
+	EndTime    *time.Time `json:"end_time,omitempty"`
+	Schedule   string     `json:"schedule,omitempty"` // teks jadwal WIB siap tampil
+	Status     string     `json:"status,omitempty"`
+	CheckedIn  bool       `json:"checked_in"`
+	VerifiedAt time.Time  `json:"verified_at"`
+}
+
+// ticketSigningSecret: TICKET_SIGNING_SECRET atau kunci turunan JWT_SECRET khusus verifikasi tiket
+func ticketSigningSecret() []byte {
+	return secret.Key("TICKET_SIGNING_SECRET", "ticket-verify")
+}
+
+// ticketSignature menandatangani data yang tercetak pada dokumen
+func ticketSignature(code, facilityID string, start, end time.Time) string {
+	mac := hmac.New(sha256.New, ticketSigningSecret())
+	mac.Write([]byte(code + "\n" + facilityID + "\n" +
+		strconv.FormatInt(start.Unix(), 10) + "\n" + strconv.FormatInt(end.Unix(), 10)))
+	return hex.EncodeToString(mac.Sum(nil)[:ticketSignatureBytes])
+}
+
+// TicketVerifyURL: halaman verifikasi publik yang dicetak (dan di-QR) pada tiket PDF & surat
+func TicketVerifyURL(b BookingResponse) string {
+	return frontendBaseURL() + "/verify/" + url.PathEscape(b.TicketCode) +
+		"?sig=" + ticketSignature(b.TicketCode, b.FacilityID, b.StartTime, b.EndTime)
+}
+
+// VerifyTicket memeriksa keaslian & keberlakuan tiket. Error hanya untuk kegagalan database;
+// kode yang tidak ditemukan dikembalikan sebagai Result VerifyNotFound.
+func VerifyTicket(db *sql.DB, code, sig string) (TicketVerification, error) {
+	v := TicketVerification{TicketCode: code, VerifiedAt: time.Now()}
+
+	r, err := findTicketVerifyRow(db, code)
+	if errors.Is(err, sql.ErrNoRows) {
+		v.Result, v.Message = VerifyNotFound, "Kode tiket tidak terdaftar"
+		return v, nil
+	}
+	if err != nil {
+		return v, err
+	}
+
+	// Ringkasan hanya ditampilkan jika dokumen asli, agar kode tebakan tidak membuka jadwal booking
+	expected := ticketSignature(code, r.FacilityID, r.StartTime, r.EndTime)
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		v.Result = VerifyTampered
+		v.Message = "Dokumen tidak cocok dengan data booking: link/QR telah diubah, dipalsukan, " +
+			"atau dokumen dicetak sebelum jadwal diubah"
+		return v, nil
+	}
+
+	v.Facility, v.Campus = r.FacilityName, r.CampusName
+	v.StartTime, v.EndTime = &r.StartTime, &r.EndTime
+	v.Schedule = formatDateIndo(r.StartTime, r.EndTime)
+	v.Status, v.CheckedIn = r.Status, r.IsCheckedIn
+
+	switch {
+	case r.Deleted:
+		v.Result, v.Message = VerifyCanceled, "Booking sudah dihapus, dokumen tidak berlaku"
+	case r.Status == "canceled":
+		v.Result, v.Message = VerifyCanceled, "Booking sudah dibatalkan, dokumen tidak berlaku"
+	case r.Status == "completed" || (r.Status == "approved" && time.Now().After(r.EndTime)):
+		v.Result, v.Message = VerifyExpired, "Dokumen asli, namun waktu penggunaan sudah berakhir"
+	case r.Status == "approved":
+		v.Result, v.Valid, v.Message = VerifyValid, true, "Dokumen asli dan berlaku"
+	default:
+		v.Result, v.Message = VerifyNotApproved, "Booking tidak dalam status disetujui, dokumen tidak berlaku"
+	}
+	return v, nil
+}
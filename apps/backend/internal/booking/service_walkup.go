@@ -0,0 +1,160 @@
+package booking
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"campus-reservation-backend/internal/billing"
+	"campus-reservation-backend/internal/campus"
+	"campus-reservation-backend/internal/document"
+	"campus-reservation-backend/internal/webhook"
+
+	"github.com/google/uuid"
+	"github.com/skip2/go-qrcode"
+)
+
+// ==========================
+// WALK-UP (BOOKING INSTAN DARI LAYAR PINTU)
+// ==========================
+// Alur: user scan QR di layar pintu -> login di frontend -> pilih 30/60 menit.
+// Booking langsung berstatus approved dan sudah check-in, sehingga check-out & auto checkout berjalan seperti biasa.
+
+type WalkUpRequest struct {
+	DurationMinutes int    `json:"duration_minutes" example:"30"`
+	Purpose         string `json:"purpose" example:"Diskusi kelompok"`
+}
+
+// WalkUpURL adalah halaman frontend yang dibuka dari QR di layar pintu
+func WalkUpURL(facilityID string) string {
+	return frontendBaseURL() + "/walk-up/" + facilityID
+}
+
+func frontendBaseURL() string {
+	if u := os.Getenv("FRONTEND_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	return "http://localhost:3001"
+}
+
+// GenerateWalkUpQR membuat gambar QR (PNG) berisi WalkUpURL
+func GenerateWalkUpQR(facilityID string) ([]byte, error) {
+	png, err := qrcode.Encode(WalkUpURL(facilityID), qrcode.Medium, 512)
+	if err != nil {
+		return nil, errors.New("gagal membuat QR code")
+	}
+	return png, nil
+}
+
+func CreateWalkUpBooking(db *sql.DB, userID string, facilityID string, req WalkUpRequest) (*BookingResponse, error) {
+	if userID == "" {
+		return nil, errors.New("user tidak valid")
+	}
+
+	facility, err := FindDisplayFacility(db, facilityID)
+	if err != nil {
+		return nil, errors.New("fasilitas tidak ditemukan")
+	}
+
+	// 1. CEK KEBIJAKAN FASILITAS
+	if !facility.IsActive {
+		return nil, errors.New("fasilitas sedang tidak tersedia (maintenance)")
+	}
+	if !facility.AllowWalkUp {
+		return nil, errors.New("fasilitas ini tidak menerima booking walk-up, silakan ajukan booking biasa")
+	}
+	if req.DurationMinutes != 30 && req.DurationMinutes != 60 {
+		return nil, errors.New("durasi walk-up hanya boleh 30 atau 60 menit")
+	}
+	if req.DurationMinutes > facility.WalkUpMaxMinutes {
+		return nil, fmt.Errorf("durasi walk-up maksimal untuk fasilitas ini adalah %d menit", facility.WalkUpMaxMinutes)
+	}
+
+	purpose := strings.TrimSpace(req.Purpose)
+	if purpose == "" {
+		purpose = "Walk-up"
+	}
+
+	// Dibulatkan ke menit agar jadwal rapi, buffer 10 menit sama seperti CreateBooking
+	start := time.Now().Truncate(time.Minute)
+	end := start.Add(time.Duration(req.DurationMinutes)*time.Minute + bookingEndBuffer)
+
+	// Walk-up tetap mengikuti jam operasional fasilitas / gedung
+	if err := campus.CheckBookingHours(db, facilityID, start, end.Add(-bookingEndBuffer)); err != nil {
+		return nil, err
+	}
+
+	// Fasilitas yang wajib lunas sebelum dipakai tidak bisa walk-up (booking walk-up langsung disetujui)
+	quote, err := billing.GetQuote(db, facilityID, userID, start, end.Add(-bookingEndBuffer))
+	if err != nil {
+		return nil, err
+	}
+	if quote.RequiresPayment {
+		return nil, errors.New("fasilitas ini wajib dibayar sebelum dipakai, silakan ajukan booking biasa")
+	}
+	// Begitu juga fasilitas yang mewajibkan dokumen (misal surat izin) untuk user ini
+	required, err := document.HasRequirements(db, facilityID, userID)
+	if err != nil {
+		return nil, errors.New("gagal memeriksa dokumen wajib fasilitas")
+	}
+	if required {
+		return nil, errors.New("fasilitas ini mewajibkan dokumen pendukung, silakan ajukan booking biasa")
+	}
+
+	// 2. CEK BENTROK
+	conflictStart, conflictEnd, err := GetConflictingBooking(db, facilityID, start, end)
+	if err != nil {
+		return nil, errors.New("gagal mengecek ketersediaan ruangan")
+	}
+	if conflictStart != nil {
+		loc, _ := time.LoadLocation("Asia/Jakarta")
+		tStart := conflictStart.In(loc).Format("02 Jan 2006, 15:04")
+		tEnd := conflictEnd.In(loc).Format("15:04")
+
+		return nil, fmt.Errorf("Ruangan sudah dibooking pada: %s - %s WIB. Silakan pilih durasi lain.", tStart, tEnd)
+	}
+
+	// 3. INSERT (retry jika kode tiket kebetulan sama, seperti UpdateBookingStatus)
+	b := Booking{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		FacilityID: facilityID,
+		StartTime:  start,
+		EndTime:    end,
+		Purpose:    purpose,
+	}
+
+	maxRetries := 3
+	for i := 0; i < maxRetries; i++ {
+		b.TicketCode = sql.NullString{String: generateTicketCode(), Valid: true}
+
+		err = webhook.WithTx(db, func(tx *sql.Tx) error {
+			if err := InsertWalkUp(tx, b); err != nil {
+				return err
+			}
+			publishBookingEvent(tx, webhook.EventBookingCreated, b.ID)
+			publishBookingEvent(tx, webhook.EventBookingApproved, b.ID)
+			publishBookingEvent(tx, webhook.EventBookingCheckedIn, b.ID)
+			return nil
+		})
+		if err == nil {
+			break
+		}
+		if !strings.Contains(err.Error(), "bookings_ticket_code_key") {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, errors.New("gagal memproses booking: terjadi duplikasi kode tiket berulang kali")
+	}
+
+	if _, _, err := billing.IssueForBooking(db, b.ID, start, end.Add(-bookingEndBuffer)); err != nil {
+		log.Printf("Gagal membuat invoice booking walk-up %s: %v\n", b.ID, err)
+	}
+
+	return FindDetailByID(db, b.ID)
+}
@@ -0,0 +1,478 @@
+package campus
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"campus-reservation-backend/internal/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ========================================================
+// REQUEST DTO
+// ========================================================
+
+type CampusRequest struct {
+	Name    string `json:"name" example:"Kampus Utama"`
+	Code    string `json:"code" example:"UTAMA"`
+	Address string `json:"address"`
+	Hours
+}
+
+type BuildingRequest struct {
+	CampusID string `json:"campus_id"`
+	Name     string `json:"name" example:"Gedung B"`
+	Code     string `json:"code" example:"GB"`
+	Hours
+}
+
+type FloorRequest struct {
+	BuildingID string `json:"building_id"`
+	Level      int    `json:"level" example:"2"`
+	Name       string `json:"name" example:"Lantai 2"`
+}
+
+func errorStatus(err error) int {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "tidak ditemukan"):
+		return 404
+	case strings.Contains(msg, "sudah dipakai"), strings.Contains(msg, "sudah ada"), strings.Contains(msg, "masih memiliki"):
+		return 409
+	case strings.Contains(msg, "kampus lain"):
+		return 403
+	}
+	return 400
+}
+
+// checkCampusScope memastikan admin kampus hanya mengelola gedung / lantai / fasilitas di kampusnya.
+// err adalah hasil pencarian kampus pemilik data; sql.ErrNoRows diubah menjadi pesan notFound.
+func checkCampusScope(c *fiber.Ctx, campusID string, err error, notFound string) error {
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New(notFound)
+		}
+		return err
+	}
+	if !auth.CampusAllowed(c, campusID) {
+		return errors.New("data ini milik kampus lain")
+	}
+	return nil
+}
+
+func respondError(c *fiber.Ctx, err error) error {
+	return c.Status(errorStatus(err)).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// ========================================================
+// HANDLER: JELAJAH HIERARKI (SEMUA USER LOGIN)
+// ========================================================
+
+func ListCampusesHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		campuses, err := FindCampuses(db)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal mengambil data kampus",
+			})
+		}
+		return c.JSON(campuses)
+	}
+}
+
+// GetCampusHandler menampilkan satu kampus beserta daftar gedungnya
+func GetCampusHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		campus, err := FindCampusByID(db, c.Params("id"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Kampus tidak ditemukan",
+			})
+		}
+		buildings, err := FindBuildings(db, campus.ID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal mengambil data gedung",
+			})
+		}
+		return c.JSON(fiber.Map{
+			"campus":    campus,
+			"buildings": buildings,
+		})
+	}
+}
+
+// ListBuildingsHandler: /buildings (opsional ?campus_id=) atau /campuses/:id/buildings
+func ListBuildingsHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		campusID := c.Params("id", c.Query("campus_id"))
+
+		buildings, err := FindBuildings(db, campusID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal mengambil data gedung",
+			})
+		}
+		return c.JSON(buildings)
+	}
+}
+
+// GetBuildingHandler menampilkan satu gedung, daftar lantai, dan jam operasional efektifnya
+func GetBuildingHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		building, err := FindBuildingByID(db, c.Params("id"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Gedung tidak ditemukan",
+			})
+		}
+		floors, err := FindFloors(db, building.ID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal mengambil data lantai",
+			})
+		}
+
+		effective := building.Hours
+		if campus, err := FindCampusByID(db, building.CampusID); err == nil {
+			effective = resolveHours([]Hours{building.Hours, campus.Hours})
+		}
+
+		return c.JSON(fiber.Map{
+			"building":        building,
+			"effective_hours": effective,
+			"floors":          floors,
+		})
+	}
+}
+
+func ListFloorsHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		floors, err := FindFloors(db, c.Params("id"))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal mengambil data lantai",
+			})
+		}
+		return c.JSON(floors)
+	}
+}
+
+func ListFloorFacilitiesHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		floor, err := FindFloorByID(db, c.Params("id"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Lantai tidak ditemukan",
+			})
+		}
+		facilities, err := FindFloorFacilities(db, floor.ID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal mengambil data fasilitas",
+			})
+		}
+		return c.JSON(fiber.Map{
+			"floor":      floor,
+			"facilities": facilities,
+		})
+	}
+}
+
+// GetFacilityHoursHandler menampilkan jam operasional efektif beserta sumber pewarisannya
+func GetFacilityHoursHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		effective, chain, err := EffectiveHours(db, c.Params("id"))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return c.Status(404).JSON(fiber.Map{
+					"error": "Fasilitas tidak ditemukan",
+				})
+			}
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal mengambil jam operasional",
+			})
+		}
+		return c.JSON(fiber.Map{
+			"effective": effective,
+			"facility":  chain[0],
+			"building":  chain[1],
+			"campus":    chain[2],
+		})
+	}
+}
+
+// ========================================================
+// HANDLER: KELOLA HIERARKI (ADMIN)
+// ========================================================
+
+func CreateCampusHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req CampusRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		id, err := CreateCampus(db, Campus{Name: req.Name, Code: req.Code, Address: req.Address, Hours: req.Hours})
+		if err != nil {
+			return respondError(c, err)
+		}
+		return c.Status(201).JSON(fiber.Map{
+			"message": "Kampus berhasil ditambahkan",
+			"id":      id,
+		})
+	}
+}
+
+func UpdateCampusHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req CampusRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		if err := EditCampus(db, c.Params("id"), Campus{Name: req.Name, Code: req.Code, Address: req.Address, Hours: req.Hours}); err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"message": "Kampus berhasil diperbarui",
+		})
+	}
+}
+
+func DeleteCampusHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := RemoveCampus(db, c.Params("id")); err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"message": "Kampus berhasil dihapus",
+		})
+	}
+}
+
+func CreateBuildingHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req BuildingRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		if scope := auth.AdminCampusID(c); scope != "" && req.CampusID == "" {
+			req.CampusID = scope
+		}
+		if err := checkCampusScope(c, req.CampusID, nil, ""); err != nil {
+			return respondError(c, err)
+		}
+
+		id, err := CreateBuilding(db, Building{CampusID: req.CampusID, Name: req.Name, Code: req.Code, Hours: req.Hours})
+		if err != nil {
+			return respondError(c, err)
+		}
+		return c.Status(201).JSON(fiber.Map{
+			"message": "Gedung berhasil ditambahkan",
+			"id":      id,
+		})
+	}
+}
+
+func UpdateBuildingHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req BuildingRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		campusID, err := FindBuildingCampusID(db, c.Params("id"))
+		if err := checkCampusScope(c, campusID, err, "gedung tidak ditemukan"); err != nil {
+			return respondError(c, err)
+		}
+		if err := checkCampusScope(c, req.CampusID, nil, ""); err != nil {
+			return respondError(c, err)
+		}
+
+		if err := EditBuilding(db, c.Params("id"), Building{CampusID: req.CampusID, Name: req.Name, Code: req.Code, Hours: req.Hours}); err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"message": "Gedung berhasil diperbarui",
+		})
+	}
+}
+
+func DeleteBuildingHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		campusID, err := FindBuildingCampusID(db, c.Params("id"))
+		if err := checkCampusScope(c, campusID, err, "gedung tidak ditemukan"); err != nil {
+			return respondError(c, err)
+		}
+
+		if err := RemoveBuilding(db, c.Params("id")); err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"message": "Gedung berhasil dihapus",
+		})
+	}
+}
+
+func CreateFloorHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req FloorRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		if req.BuildingID != "" {
+			campusID, err := FindBuildingCampusID(db, req.BuildingID)
+			if err := checkCampusScope(c, campusID, err, "gedung tidak ditemukan"); err != nil {
+				return respondError(c, err)
+			}
+		}
+
+		id, err := CreateFloor(db, Floor{BuildingID: req.BuildingID, Level: req.Level, Name: req.Name})
+		if err != nil {
+			return respondError(c, err)
+		}
+		return c.Status(201).JSON(fiber.Map{
+			"message": "Lantai berhasil ditambahkan",
+			"id":      id,
+		})
+	}
+}
+
+func UpdateFloorHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req FloorRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		campusID, err := FindFloorCampusID(db, c.Params("id"))
+		if err := checkCampusScope(c, campusID, err, "lantai tidak ditemukan"); err != nil {
+			return respondError(c, err)
+		}
+
+		if err := EditFloor(db, c.Params("id"), Floor{Level: req.Level, Name: req.Name}); err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"message": "Lantai berhasil diperbarui",
+		})
+	}
+}
+
+func DeleteFloorHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		campusID, err := FindFloorCampusID(db, c.Params("id"))
+		if err := checkCampusScope(c, campusID, err, "lantai tidak ditemukan"); err != nil {
+			return respondError(c, err)
+		}
+
+		if err := RemoveFloor(db, c.Params("id")); err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"message": "Lantai berhasil dihapus",
+		})
+	}
+}
+
+// UpdateFacilityHoursHandler mengatur jam operasional khusus satu fasilitas (kosongkan untuk ikut gedung)
+func UpdateFacilityHoursHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req Hours
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		campusID, err := FindFacilityCampusID(db, c.Params("id"))
+		if err := checkCampusScope(c, campusID, err, "fasilitas tidak ditemukan"); err != nil {
+			return respondError(c, err)
+		}
+
+		if err := SetFacilityHours(db, c.Params("id"), req); err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"message": "Jam operasional fasilitas berhasil diperbarui",
+		})
+	}
+}
+
+// ========================================================
+// HANDLER: LAPORAN PER GEDUNG (ADMIN)
+// ========================================================
+
+// BuildingReportHandler: ?start_date=YYYY-MM-DD&end_date=YYYY-MM-DD (default 30 hari terakhir), opsional ?campus_id=
+// Admin kampus selalu hanya melihat gedung di kampusnya.
+func BuildingReportHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		loc := jakarta()
+		today := dateOf(time.Now().In(loc))
+
+		start := today.AddDate(0, 0, -30)
+		end := today.AddDate(0, 0, 1)
+
+		if v := c.Query("start_date"); v != "" {
+			t, err := time.ParseInLocation("2006-01-02", v, loc)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Format start_date salah (YYYY-MM-DD)",
+				})
+			}
+			start = t
+		}
+		if v := c.Query("end_date"); v != "" {
+			t, err := time.ParseInLocation("2006-01-02", v, loc)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Format end_date salah (YYYY-MM-DD)",
+				})
+			}
+			// end_date inklusif
+			end = t.AddDate(0, 0, 1)
+		}
+		if !start.Before(end) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "start_date harus sebelum end_date",
+			})
+		}
+		if end.Sub(start) > 366*24*time.Hour {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Rentang laporan maksimal 1 tahun",
+			})
+		}
+
+		reports, err := GetBuildingReports(db, start, end, auth.CampusFilter(c))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal membuat laporan per gedung",
+			})
+		}
+		return c.JSON(fiber.Map{
+			"start_date": start.Format("2006-01-02"),
+			"end_date":   end.AddDate(0, 0, -1).Format("2006-01-02"),
+			"buildings":  reports,
+		})
+	}
+}
@@ -0,0 +1,487 @@
+package campus
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ==========================
+// MODEL
+// ==========================
+
+// Hours adalah jam operasional pada satu level hierarki. Nil = ikut level di atasnya.
+type Hours struct {
+	OpensAt  *string `json:"opens_at"`  // "07:00"
+	ClosesAt *string `json:"closes_at"` // "21:00"
+	OpenDays []int   `json:"open_days"` // 1 = Senin ... 7 = Minggu, kosong = ikut level di atasnya
+}
+
+type Campus struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Code          string    `json:"code"`
+	Address       string    `json:"address"`
+	Hours         Hours     `json:"hours"`
+	BuildingCount int       `json:"building_count"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type Building struct {
+	ID            string    `json:"id"`
+	CampusID      string    `json:"campus_id"`
+	CampusName    string    `json:"campus_name"`
+	Name          string    `json:"name"`
+	Code          string    `json:"code"`
+	Hours         Hours     `json:"hours"`
+	FloorCount    int       `json:"floor_count"`
+	FacilityCount int       `json:"facility_count"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type Floor struct {
+	ID            string `json:"id"`
+	BuildingID    string `json:"building_id"`
+	BuildingName  string `json:"building_name"`
+	Level         int    `json:"level"`
+	Name          string `json:"name"`
+	FacilityCount int    `json:"facility_count"`
+}
+
+// FacilitySummary adalah data ringkas fasilitas saat menjelajah hierarki
+type FacilitySummary struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Capacity int    `json:"capacity"`
+	IsActive bool   `json:"is_active"`
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanHours membaca kolom opens_at, closes_at, open_days (TIME dibaca sebagai "HH:MM")
+func scanHours(opens, closes sql.NullString, days pq.Int64Array) Hours {
+	var h Hours
+	if opens.Valid {
+		v := opens.String[:5]
+		h.OpensAt = &v
+	}
+	if closes.Valid {
+		v := closes.String[:5]
+		h.ClosesAt = &v
+	}
+	for _, d := range days {
+		h.OpenDays = append(h.OpenDays, int(d))
+	}
+	return h
+}
+
+func daysArg(days []int) interface{} {
+	if len(days) == 0 {
+		return nil
+	}
+	out := make(pq.Int64Array, len(days))
+	for i, d := range days {
+		out[i] = int64(d)
+	}
+	return out
+}
+
+// ==========================
+// KAMPUS
+// ==========================
+
+func InsertCampus(db *sql.DB, c Campus) (string, error) {
+	var id string
+	err := db.QueryRow(`
+		INSERT INTO campuses (name, code, address, opens_at, closes_at, open_days)
+		VALUES ($1, $2, NULLIF($3, ''), $4::time, $5::time, $6::smallint[])
+		RETURNING id
+	`, c.Name, c.Code, c.Address, c.Hours.OpensAt, c.Hours.ClosesAt, daysArg(c.Hours.OpenDays)).Scan(&id)
+	return id, err
+}
+
+func UpdateCampus(db *sql.DB, id string, c Campus) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE campuses
+		SET name = $1, code = $2, address = NULLIF($3, ''), opens_at = $4::time, closes_at = $5::time,
+			open_days = $6::smallint[], updated_at = NOW()
+		WHERE id = $7
+	`, c.Name, c.Code, c.Address, c.Hours.OpensAt, c.Hours.ClosesAt, daysArg(c.Hours.OpenDays), id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func DeleteCampus(db *sql.DB, id string) (int64, error) {
+	res, err := db.Exec(`DELETE FROM campuses WHERE id = $1`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const campusSelect = `
+	SELECT c.id, c.name, c.code, COALESCE(c.address, ''), c.opens_at::text, c.closes_at::text, c.open_days,
+		(SELECT COUNT(*) FROM buildings b WHERE b.campus_id = c.id)::int, c.created_at
+	FROM campuses c`
+
+func FindCampuses(db *sql.DB) ([]Campus, error) {
+	rows, err := db.Query(campusSelect + ` ORDER BY c.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	campuses := []Campus{}
+	for rows.Next() {
+		c, err := scanCampus(rows)
+		if err != nil {
+			return nil, err
+		}
+		campuses = append(campuses, c)
+	}
+	return campuses, rows.Err()
+}
+
+func FindCampusByID(db *sql.DB, id string) (Campus, error) {
+	return scanCampus(db.QueryRow(campusSelect+` WHERE c.id = $1`, id))
+}
+
+func scanCampus(row rowScanner) (Campus, error) {
+	var c Campus
+	var opens, closes sql.NullString
+	var days pq.Int64Array
+	err := row.Scan(&c.ID, &c.Name, &c.Code, &c.Address, &opens, &closes, &days, &c.BuildingCount, &c.CreatedAt)
+	c.Hours = scanHours(opens, closes, days)
+	return c, err
+}
+
+// ==========================
+// GEDUNG
+// ==========================
+
+func InsertBuilding(db *sql.DB, b Building) (string, error) {
+	var id string
+	err := db.QueryRow(`
+		INSERT INTO buildings (campus_id, name, code, opens_at, closes_at, open_days)
+		VALUES ($1, $2, NULLIF($3, ''), $4::time, $5::time, $6::smallint[])
+		RETURNING id
+	`, b.CampusID, b.Name, b.Code, b.Hours.OpensAt, b.Hours.ClosesAt, daysArg(b.Hours.OpenDays)).Scan(&id)
+	return id, err
+}
+
+func UpdateBuilding(db *sql.DB, id string, b Building) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE buildings
+		SET campus_id = $1, name = $2, code = NULLIF($3, ''), opens_at = $4::time, closes_at = $5::time,
+			open_days = $6::smallint[], updated_at = NOW()
+		WHERE id = $7
+	`, b.CampusID, b.Name, b.Code, b.Hours.OpensAt, b.Hours.ClosesAt, daysArg(b.Hours.OpenDays), id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func DeleteBuilding(db *sql.DB, id string) (int64, error) {
+	res, err := db.Exec(`DELETE FROM buildings WHERE id = $1`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const buildingSelect = `
+	SELECT b.id, b.campus_id, c.name, b.name, COALESCE(b.code, ''), b.opens_at::text, b.closes_at::text, b.open_days,
+		(SELECT COUNT(*) FROM floors fl WHERE fl.building_id = b.id)::int,
+		(SELECT COUNT(*) FROM facilities f JOIN floors fl ON f.floor_id = fl.id
+		  WHERE fl.building_id = b.id AND f.deleted_at IS NULL)::int,
+		b.created_at
+	FROM buildings b
+	JOIN campuses c ON c.id = b.campus_id`
+
+func FindBuildings(db *sql.DB, campusID string) ([]Building, error) {
+	rows, err := db.Query(buildingSelect+`
+		WHERE ($1::text = '' OR b.campus_id::text = $1::text)
+		ORDER BY c.name, b.name
+	`, campusID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buildings := []Building{}
+	for rows.Next() {
+		b, err := scanBuilding(rows)
+		if err != nil {
+			return nil, err
+		}
+		buildings = append(buildings, b)
+	}
+	return buildings, rows.Err()
+}
+
+func FindBuildingByID(db *sql.DB, id string) (Building, error) {
+	return scanBuilding(db.QueryRow(buildingSelect+` WHERE b.id = $1`, id))
+}
+
+func scanBuilding(row rowScanner) (Building, error) {
+	var b Building
+	var opens, closes sql.NullString
+	var days pq.Int64Array
+	err := row.Scan(&b.ID, &b.CampusID, &b.CampusName, &b.Name, &b.Code, &opens, &closes, &days,
+		&b.FloorCount, &b.FacilityCount, &b.CreatedAt)
+	b.Hours = scanHours(opens, closes, days)
+	return b, err
+}
+
+// ==========================
+// LANTAI
+// ==========================
+
+func InsertFloor(db *sql.DB, f Floor) (string, error) {
+	var id string
+	err := db.QueryRow(`
+		INSERT INTO floors (building_id, level, name)
+		VALUES ($1, $2, NULLIF($3, ''))
+		RETURNING id
+	`, f.BuildingID, f.Level, f.Name).Scan(&id)
+	return id, err
+}
+
+func UpdateFloor(db *sql.DB, id string, f Floor) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE floors SET level = $1, name = NULLIF($2, ''), updated_at = NOW()
+		WHERE id = $3
+	`, f.Level, f.Name, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func DeleteFloor(db *sql.DB, id string) (int64, error) {
+	res, err := db.Exec(`DELETE FROM floors WHERE id = $1`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const floorSelect = `
+	SELECT fl.id, fl.building_id, b.name, fl.level, COALESCE(fl.name, ''),
+		(SELECT COUNT(*) FROM facilities f WHERE f.floor_id = fl.id AND f.deleted_at IS NULL)::int
+	FROM floors fl
+	JOIN buildings b ON b.id = fl.building_id`
+
+func FindFloors(db *sql.DB, buildingID string) ([]Floor, error) {
+	rows, err := db.Query(floorSelect+` WHERE fl.building_id = $1 ORDER BY fl.level`, buildingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	floors := []Floor{}
+	for rows.Next() {
+		var f Floor
+		if err := rows.Scan(&f.ID, &f.BuildingID, &f.BuildingName, &f.Level, &f.Name, &f.FacilityCount); err != nil {
+			return nil, err
+		}
+		floors = append(floors, f)
+	}
+	return floors, rows.Err()
+}
+
+func FindFloorByID(db *sql.DB, id string) (Floor, error) {
+	var f Floor
+	err := db.QueryRow(floorSelect+` WHERE fl.id = $1`, id).
+		Scan(&f.ID, &f.BuildingID, &f.BuildingName, &f.Level, &f.Name, &f.FacilityCount)
+	return f, err
+}
+
+func FindFloorFacilities(db *sql.DB, floorID string) ([]FacilitySummary, error) {
+	rows, err := db.Query(`
+		SELECT id, name, category, capacity, is_active
+		FROM facilities
+		WHERE floor_id = $1 AND deleted_at IS NULL
+		ORDER BY name
+	`, floorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facilities := []FacilitySummary{}
+	for rows.Next() {
+		var f FacilitySummary
+		if err := rows.Scan(&f.ID, &f.Name, &f.Category, &f.Capacity, &f.IsActive); err != nil {
+			return nil, err
+		}
+		facilities = append(facilities, f)
+	}
+	return facilities, rows.Err()
+}
+
+// ==========================
+// KAMPUS PEMILIK (CAKUPAN ADMIN)
+// ==========================
+
+func FindBuildingCampusID(db *sql.DB, buildingID string) (string, error) {
+	var campusID string
+	err := db.QueryRow(`SELECT campus_id::text FROM buildings WHERE id::text = $1`, buildingID).Scan(&campusID)
+	return campusID, err
+}
+
+func FindFloorCampusID(db *sql.DB, floorID string) (string, error) {
+	var campusID string
+	err := db.QueryRow(`
+		SELECT b.campus_id::text FROM floors fl JOIN buildings b ON b.id = fl.building_id WHERE fl.id::text = $1
+	`, floorID).Scan(&campusID)
+	return campusID, err
+}
+
+func FindFacilityCampusID(db *sql.DB, facilityID string) (string, error) {
+	var campusID string
+	err := db.QueryRow(`
+		SELECT COALESCE(campus_id::text, '') FROM facilities WHERE id::text = $1 AND deleted_at IS NULL
+	`, facilityID).Scan(&campusID)
+	return campusID, err
+}
+
+// ==========================
+// JAM OPERASIONAL EFEKTIF
+// ==========================
+
+// FindHoursChain mengambil jam operasional fasilitas, gedung & kampusnya (urut dari yang paling spesifik)
+func FindHoursChain(db *sql.DB, facilityID string) ([]Hours, error) {
+	var fo, fc, bo, bc, co, cc sql.NullString
+	var fd, bd, cd pq.Int64Array
+	err := db.QueryRow(`
+		SELECT f.opens_at::text, f.closes_at::text, f.open_days,
+			b.opens_at::text, b.closes_at::text, b.open_days,
+			c.opens_at::text, c.closes_at::text, c.open_days
+		FROM facilities f
+		LEFT JOIN floors fl ON fl.id = f.floor_id
+		LEFT JOIN buildings b ON b.id = fl.building_id
+		LEFT JOIN campuses c ON c.id = COALESCE(b.campus_id, f.campus_id)
+		WHERE f.id = $1
+	`, facilityID).Scan(&fo, &fc, &fd, &bo, &bc, &bd, &co, &cc, &cd)
+	if err != nil {
+		return nil, err
+	}
+	return []Hours{scanHours(fo, fc, fd), scanHours(bo, bc, bd), scanHours(co, cc, cd)}, nil
+}
+
+// UpdateFacilityHours menyimpan jam operasional khusus fasilitas (nil = ikut gedung)
+func UpdateFacilityHours(db *sql.DB, facilityID string, h Hours) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE facilities SET opens_at = $1::time, closes_at = $2::time, open_days = $3::smallint[], updated_at = NOW()
+		WHERE id = $4 AND deleted_at IS NULL
+	`, h.OpensAt, h.ClosesAt, daysArg(h.OpenDays), facilityID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ==========================
+// LAPORAN PER GEDUNG
+// ==========================
+
+type BuildingReport struct {
+	BuildingID     string  `json:"building_id"`
+	BuildingName   string  `json:"building_name"`
+	CampusID       string  `json:"campus_id"`
+	CampusName     string  `json:"campus_name"`
+	FacilityCount  int     `json:"facility_count"`
+	TotalBookings  int     `json:"total_bookings"`
+	Approved       int     `json:"approved"`
+	Rejected       int     `json:"rejected"`
+	Canceled       int     `json:"canceled"`
+	CheckedIn      int     `json:"checked_in"`
+	NoShow         int     `json:"no_show"`
+	BookedHours    float64 `json:"booked_hours"`
+	AvgHeadcount   float64 `json:"avg_headcount"`
+	OverCapacity   int     `json:"over_capacity"`
+	UtilizationPct float64 `json:"utilization_pct"` // jam terpakai / (jam buka x jumlah fasilitas), diisi service
+}
+
+// FindBuildingReports menjumlahkan booking per gedung pada rentang [start, end).
+// Fasilitas yang belum ditempatkan di lantai mana pun dikelompokkan sebagai "Tanpa Gedung" per kampus.
+// campusID kosong = semua kampus.
+func FindBuildingReports(db *sql.DB, start, end time.Time, campusID string) ([]BuildingReport, error) {
+	rows, err := db.Query(`
+		SELECT
+			COALESCE(b.id::text, ''), COALESCE(b.name, 'Tanpa Gedung'), COALESCE(c.id::text, ''), COALESCE(c.name, ''),
+			COUNT(DISTINCT f.id)::int,
+			COUNT(bk.id)::int,
+			COUNT(bk.id) FILTER (WHERE bk.status IN ('approved', 'completed'))::int,
+			COUNT(bk.id) FILTER (WHERE bk.status = 'rejected')::int,
+			COUNT(bk.id) FILTER (WHERE bk.status = 'canceled')::int,
+			COUNT(bk.id) FILTER (WHERE bk.is_checked_in)::int,
+			COUNT(bk.id) FILTER (WHERE bk.attendance_status = 'no_show')::int,
+			COALESCE(SUM(EXTRACT(EPOCH FROM (COALESCE(bk.actual_end_time, bk.end_time) - bk.start_time)) / 3600)
+				FILTER (WHERE bk.status IN ('approved', 'completed')), 0)::float8,
+			COALESCE(AVG(bk.actual_headcount) FILTER (WHERE bk.actual_headcount IS NOT NULL), 0)::float8,
+			COUNT(bk.id) FILTER (WHERE bk.over_capacity)::int
+		FROM facilities f
+		LEFT JOIN floors fl ON fl.id = f.floor_id
+		LEFT JOIN buildings b ON b.id = fl.building_id
+		LEFT JOIN campuses c ON c.id = f.campus_id
+		LEFT JOIN bookings bk ON bk.facility_id = f.id
+			AND bk.deleted_at IS NULL
+			AND bk.start_time >= $1 AND bk.start_time < $2
+		WHERE f.deleted_at IS NULL
+		  AND ($3::text = '' OR f.campus_id::text = $3::text)
+		GROUP BY b.id, b.name, c.id, c.name
+		ORDER BY c.name NULLS LAST, b.name NULLS LAST
+	`, start, end, campusID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []BuildingReport{}
+	for rows.Next() {
+		var r BuildingReport
+		if err := rows.Scan(&r.BuildingID, &r.BuildingName, &r.CampusID, &r.CampusName, &r.FacilityCount, &r.TotalBookings,
+			&r.Approved, &r.Rejected, &r.Canceled, &r.CheckedIn, &r.NoShow, &r.BookedHours, &r.AvgHeadcount,
+			&r.OverCapacity); err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, rows.Err()
+}
+
+// FindBuildingFacilityIDs dipakai untuk menghitung kapasitas jam buka per gedung.
+// campusID membedakan kelompok "Tanpa Gedung" antar kampus (buildingID kosong).
+func FindBuildingFacilityIDs(db *sql.DB, buildingID, campusID string) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT f.id
+		FROM facilities f
+		LEFT JOIN floors fl ON fl.id = f.floor_id
+		WHERE f.deleted_at IS NULL
+		  AND COALESCE(fl.building_id::text, '') = $1::text
+		  AND COALESCE(f.campus_id::text, '') = $2::text
+	`, buildingID, campusID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,374 @@
+package campus
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ==========================
+// VALIDASI JAM OPERASIONAL
+// ==========================
+
+const hoursLayout = "15:04"
+
+var dayNames = []string{"", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu", "Minggu"}
+
+func (h *Hours) normalize() error {
+	for _, p := range []**string{&h.OpensAt, &h.ClosesAt} {
+		if *p != nil && strings.TrimSpace(**p) == "" {
+			*p = nil
+		}
+	}
+	if (h.OpensAt == nil) != (h.ClosesAt == nil) {
+		return errors.New("jam buka dan jam tutup harus diisi bersamaan")
+	}
+	if h.OpensAt != nil {
+		opens, err1 := time.Parse(hoursLayout, strings.TrimSpace(*h.OpensAt))
+		closes, err2 := time.Parse(hoursLayout, strings.TrimSpace(*h.ClosesAt))
+		if err1 != nil || err2 != nil {
+			return errors.New("format jam harus HH:MM")
+		}
+		if !opens.Before(closes) {
+			return errors.New("jam buka harus sebelum jam tutup")
+		}
+		o, c := opens.Format(hoursLayout), closes.Format(hoursLayout)
+		h.OpensAt, h.ClosesAt = &o, &c
+	}
+
+	days := []int{}
+	for _, d := range h.OpenDays {
+		if d < 1 || d > 7 {
+			return errors.New("hari buka harus antara 1 (Senin) sampai 7 (Minggu)")
+		}
+		if !slices.Contains(days, d) {
+			days = append(days, d)
+		}
+	}
+	slices.Sort(days)
+	h.OpenDays = days
+	return nil
+}
+
+// resolveHours menggabungkan jam dari level paling spesifik ke paling umum.
+// Jam buka-tutup dan hari buka diwariskan terpisah, jadi gedung bisa hanya mengubah jam tanpa mengubah hari.
+func resolveHours(chain []Hours) Hours {
+	var out Hours
+	for _, h := range chain {
+		if out.OpensAt == nil && h.OpensAt != nil {
+			out.OpensAt, out.ClosesAt = h.OpensAt, h.ClosesAt
+		}
+		if len(out.OpenDays) == 0 && len(h.OpenDays) > 0 {
+			out.OpenDays = h.OpenDays
+		}
+	}
+	return out
+}
+
+func jakarta() *time.Location {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+func isoWeekday(t time.Time) int {
+	d := int(t.Weekday())
+	if d == 0 {
+		return 7
+	}
+	return d
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// clock mengembalikan waktu pada tanggal t dengan jam "HH:MM"
+func clock(t time.Time, hhmm string) time.Time {
+	c, _ := time.Parse(hoursLayout, hhmm)
+	return time.Date(t.Year(), t.Month(), t.Day(), c.Hour(), c.Minute(), 0, 0, t.Location())
+}
+
+// Allows memeriksa apakah rentang booking [start, end] berada di dalam jam operasional
+func (h Hours) Allows(start, end time.Time) error {
+	loc := jakarta()
+	start, end = start.In(loc), end.In(loc)
+
+	if len(h.OpenDays) > 0 {
+		last := dateOf(end)
+		for d := dateOf(start); !d.After(last); d = d.AddDate(0, 0, 1) {
+			if !slices.Contains(h.OpenDays, isoWeekday(d)) {
+				return fmt.Errorf("Fasilitas tutup pada hari %s", dayNames[isoWeekday(d)])
+			}
+		}
+	}
+
+	if h.OpensAt == nil {
+		return nil
+	}
+
+	opens, closes := clock(start, *h.OpensAt), clock(start, *h.ClosesAt)
+	if !dateOf(start).Equal(dateOf(end)) || start.Before(opens) || end.After(closes) {
+		return fmt.Errorf("Fasilitas hanya bisa dibooking pukul %s - %s WIB", *h.OpensAt, *h.ClosesAt)
+	}
+	return nil
+}
+
+// dailyOpenHours adalah jumlah jam buka dalam satu hari buka
+func (h Hours) dailyOpenHours() float64 {
+	if h.OpensAt == nil {
+		return 24
+	}
+	opens, _ := time.Parse(hoursLayout, *h.OpensAt)
+	closes, _ := time.Parse(hoursLayout, *h.ClosesAt)
+	return closes.Sub(opens).Hours()
+}
+
+// openHoursBetween menghitung total jam buka pada rentang tanggal [start, end)
+func (h Hours) openHoursBetween(start, end time.Time) float64 {
+	total := 0.0
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		if len(h.OpenDays) == 0 || slices.Contains(h.OpenDays, isoWeekday(d)) {
+			total += h.dailyOpenHours()
+		}
+	}
+	return total
+}
+
+// EffectiveHours mengembalikan jam operasional fasilitas setelah pewarisan fasilitas -> gedung -> kampus
+func EffectiveHours(db *sql.DB, facilityID string) (Hours, []Hours, error) {
+	chain, err := FindHoursChain(db, facilityID)
+	if err != nil {
+		return Hours{}, nil, err
+	}
+	return resolveHours(chain), chain, nil
+}
+
+// CheckBookingHours dipakai package booking sebelum booking dibuat
+func CheckBookingHours(db *sql.DB, facilityID string, start, end time.Time) error {
+	hours, _, err := EffectiveHours(db, facilityID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("fasilitas tidak ditemukan")
+		}
+		return errors.New("gagal mengecek jam operasional fasilitas")
+	}
+	return hours.Allows(start, end)
+}
+
+func SetFacilityHours(db *sql.DB, facilityID string, h Hours) error {
+	if err := h.normalize(); err != nil {
+		return err
+	}
+	n, err := UpdateFacilityHours(db, facilityID, h)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New("fasilitas tidak ditemukan")
+	}
+	return nil
+}
+
+// ==========================
+// CRUD KAMPUS / GEDUNG / LANTAI
+// ==========================
+
+func mapHierarchyError(err error, duplicateMsg, inUseMsg string) error {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "duplicate key"):
+		return errors.New(duplicateMsg)
+	case strings.Contains(msg, "update or delete on table"):
+		return errors.New(inUseMsg)
+	case strings.Contains(msg, "violates foreign key"):
+		return errors.New("data induk tidak ditemukan")
+	}
+	return err
+}
+
+func validateCampus(c *Campus) error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Code = strings.ToUpper(strings.TrimSpace(c.Code))
+	c.Address = strings.TrimSpace(c.Address)
+	if c.Name == "" {
+		return errors.New("nama kampus wajib diisi")
+	}
+	if c.Code == "" || len(c.Code) > 20 {
+		return errors.New("kode kampus wajib diisi (maksimal 20 karakter)")
+	}
+	return c.Hours.normalize()
+}
+
+func CreateCampus(db *sql.DB, c Campus) (string, error) {
+	if err := validateCampus(&c); err != nil {
+		return "", err
+	}
+	id, err := InsertCampus(db, c)
+	if err != nil {
+		return "", mapHierarchyError(err, "kode kampus sudah dipakai", "")
+	}
+	return id, nil
+}
+
+func EditCampus(db *sql.DB, id string, c Campus) error {
+	if err := validateCampus(&c); err != nil {
+		return err
+	}
+	n, err := UpdateCampus(db, id, c)
+	if err != nil {
+		return mapHierarchyError(err, "kode kampus sudah dipakai", "")
+	}
+	if n == 0 {
+		return errors.New("kampus tidak ditemukan")
+	}
+	return nil
+}
+
+func RemoveCampus(db *sql.DB, id string) error {
+	n, err := DeleteCampus(db, id)
+	if err != nil {
+		return mapHierarchyError(err, "", "kampus masih memiliki gedung, hapus atau pindahkan gedung terlebih dahulu")
+	}
+	if n == 0 {
+		return errors.New("kampus tidak ditemukan")
+	}
+	return nil
+}
+
+func validateBuilding(b *Building) error {
+	b.Name = strings.TrimSpace(b.Name)
+	b.Code = strings.ToUpper(strings.TrimSpace(b.Code))
+	if b.CampusID == "" {
+		return errors.New("kampus wajib dipilih")
+	}
+	if b.Name == "" {
+		return errors.New("nama gedung wajib diisi")
+	}
+	return b.Hours.normalize()
+}
+
+func CreateBuilding(db *sql.DB, b Building) (string, error) {
+	if err := validateBuilding(&b); err != nil {
+		return "", err
+	}
+	id, err := InsertBuilding(db, b)
+	if err != nil {
+		return "", mapHierarchyError(err, "nama gedung sudah dipakai di kampus ini", "")
+	}
+	return id, nil
+}
+
+func EditBuilding(db *sql.DB, id string, b Building) error {
+	if err := validateBuilding(&b); err != nil {
+		return err
+	}
+	n, err := UpdateBuilding(db, id, b)
+	if err != nil {
+		return mapHierarchyError(err, "nama gedung sudah dipakai di kampus ini", "")
+	}
+	if n == 0 {
+		return errors.New("gedung tidak ditemukan")
+	}
+	return nil
+}
+
+func RemoveBuilding(db *sql.DB, id string) error {
+	n, err := DeleteBuilding(db, id)
+	if err != nil {
+		return mapHierarchyError(err, "", "gedung masih memiliki lantai, hapus lantai terlebih dahulu")
+	}
+	if n == 0 {
+		return errors.New("gedung tidak ditemukan")
+	}
+	return nil
+}
+
+func validateFloor(f *Floor) error {
+	f.Name = strings.TrimSpace(f.Name)
+	if f.Level < -10 || f.Level > 200 {
+		return errors.New("lantai tidak valid")
+	}
+	return nil
+}
+
+func CreateFloor(db *sql.DB, f Floor) (string, error) {
+	if f.BuildingID == "" {
+		return "", errors.New("gedung wajib dipilih")
+	}
+	if err := validateFloor(&f); err != nil {
+		return "", err
+	}
+	id, err := InsertFloor(db, f)
+	if err != nil {
+		return "", mapHierarchyError(err, "lantai tersebut sudah ada di gedung ini", "")
+	}
+	return id, nil
+}
+
+func EditFloor(db *sql.DB, id string, f Floor) error {
+	if err := validateFloor(&f); err != nil {
+		return err
+	}
+	n, err := UpdateFloor(db, id, f)
+	if err != nil {
+		return mapHierarchyError(err, "lantai tersebut sudah ada di gedung ini", "")
+	}
+	if n == 0 {
+		return errors.New("lantai tidak ditemukan")
+	}
+	return nil
+}
+
+func RemoveFloor(db *sql.DB, id string) error {
+	n, err := DeleteFloor(db, id)
+	if err != nil {
+		return mapHierarchyError(err, "", "lantai masih memiliki fasilitas, pindahkan fasilitas terlebih dahulu")
+	}
+	if n == 0 {
+		return errors.New("lantai tidak ditemukan")
+	}
+	return nil
+}
+
+// ==========================
+// LAPORAN PER GEDUNG
+// ==========================
+
+// GetBuildingReports menghitung rekap booking per gedung beserta utilisasi jam buka.
+// Utilisasi = jam terpakai / (jam buka efektif tiap fasilitas di gedung tersebut pada rentang tanggal).
+func GetBuildingReports(db *sql.DB, start, end time.Time, campusID string) ([]BuildingReport, error) {
+	reports, err := FindBuildingReports(db, start, end, campusID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range reports {
+		ids, err := FindBuildingFacilityIDs(db, reports[i].BuildingID, reports[i].CampusID)
+		if err != nil {
+			return nil, err
+		}
+
+		openHours := 0.0
+		for _, id := range ids {
+			hours, _, err := EffectiveHours(db, id)
+			if err != nil {
+				return nil, err
+			}
+			openHours += hours.openHoursBetween(start, end)
+		}
+
+		reports[i].BookedHours = math.Round(reports[i].BookedHours*10) / 10
+		reports[i].AvgHeadcount = math.Round(reports[i].AvgHeadcount*10) / 10
+		if openHours > 0 {
+			reports[i].UtilizationPct = math.Round(reports[i].BookedHours/openHours*1000) / 10
+		}
+	}
+	return reports, nil
+}
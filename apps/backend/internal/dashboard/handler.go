@@ -4,6 +4,9 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"math"
+
+	"campus-reservation-backend/internal/auth"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -28,6 +31,17 @@ type PeakHour struct {
 	Count int `json:"count"`
 }
 
+// FacilityUtilization: rata-rata jumlah orang vs kapasitas dalam 30 hari terakhir
+type FacilityUtilization struct {
+	FacilityID        string  `json:"facility_id"`
+	Name              string  `json:"name"`
+	Capacity          int     `json:"capacity"`
+	CountedBookings   int     `json:"counted_bookings"`
+	AvgHeadcount      float64 `json:"avg_headcount"`
+	UtilizationRate   float64 `json:"utilization_rate"`
+	OverCapacityCount int     `json:"over_capacity_count"`
+}
+
 type DashboardStats struct {
 	TotalUsers      int             `json:"total_users"`
 	TotalFacilities int             `json:"total_facilities"`
@@ -36,35 +50,47 @@ type DashboardStats struct {
 	TopFacilities   []TopFacility   `json:"top_facilities"`
 	RecentBookings  []RecentBooking `json:"recent_bookings"`
 	PeakHours       []PeakHour      `json:"peak_hours"`
+	// Kepatuhan kapasitas (30 hari terakhir)
+	OverCapacityBookings int                   `json:"over_capacity_bookings"`
+	Utilization          []FacilityUtilization `json:"utilization"`
 }
 
 // ==========================
 // LOGIC
 // ==========================
-func GetStats(db *sql.DB) (DashboardStats, error) {
+
+// campusCond membatasi query ke fasilitas satu kampus ($1 kosong = semua kampus)
+const campusCond = `($1::text = '' OR f.campus_id::text = $1::text)`
+
+// GetStats menghitung statistik dashboard. campusID kosong = semua kampus.
+// Jumlah user tidak dibatasi kampus karena user boleh booking lintas kampus.
+func GetStats(db *sql.DB, campusID string) (DashboardStats, error) {
 	// Inisialisasi slice dengan array kosong agar JSON return "[]" bukan "null"
 	stats := DashboardStats{
 		TopFacilities:  []TopFacility{},
 		RecentBookings: []RecentBooking{},
 		PeakHours:      []PeakHour{},
+		Utilization:    []FacilityUtilization{},
 	}
 
 	// 1. Hitung Angka Utama
 	db.QueryRow("SELECT COUNT(*) FROM users WHERE role = 'user'").Scan(&stats.TotalUsers)
-	db.QueryRow("SELECT COUNT(*) FROM facilities WHERE deleted_at IS NULL").Scan(&stats.TotalFacilities)
-	db.QueryRow("SELECT COUNT(*) FROM bookings WHERE status = 'pending'").Scan(&stats.PendingBookings)
-	db.QueryRow("SELECT COUNT(*) FROM bookings WHERE status = 'approved' AND end_time > NOW()").Scan(&stats.ActiveBookings)
+	db.QueryRow("SELECT COUNT(*) FROM facilities f WHERE f.deleted_at IS NULL AND "+campusCond, campusID).Scan(&stats.TotalFacilities)
+	db.QueryRow(`SELECT COUNT(*) FROM bookings b JOIN facilities f ON b.facility_id = f.id
+		WHERE b.status = 'pending' AND `+campusCond, campusID).Scan(&stats.PendingBookings)
+	db.QueryRow(`SELECT COUNT(*) FROM bookings b JOIN facilities f ON b.facility_id = f.id
+		WHERE b.status = 'approved' AND b.end_time > NOW() AND `+campusCond, campusID).Scan(&stats.ActiveBookings)
 
 	// 2. Ambil 5 Fasilitas Terpopuler
 	rows, err := db.Query(`
 		SELECT f.name, COUNT(b.id) as total
 		FROM bookings b
 		JOIN facilities f ON b.facility_id = f.id
-		WHERE b.status != 'rejected'
+		WHERE b.status != 'rejected' AND `+campusCond+`
 		GROUP BY f.name
 		ORDER BY total DESC
 		LIMIT 5
-	`)
+	`, campusID)
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
@@ -90,11 +116,12 @@ func GetStats(db *sql.DB) (DashboardStats, error) {
 		FROM bookings b
 		LEFT JOIN users u ON b.user_id = u.id
 		LEFT JOIN facilities f ON b.facility_id = f.id
+		WHERE ` + campusCond + `
 		ORDER BY b.created_at DESC
 		LIMIT 5
 	`
 
-	recentRows, err := db.Query(recentQuery)
+	recentRows, err := db.Query(recentQuery, campusID)
 	if err != nil {
 		log.Println("Error query recent bookings:", err)
 	} else {
@@ -118,11 +145,12 @@ func GetStats(db *sql.DB) (DashboardStats, error) {
 	}
 
 	peakRows, err := db.Query(`
-		SELECT EXTRACT(HOUR FROM start_time)::int as hour, COUNT(*) 
-		FROM bookings 
-		WHERE status != 'rejected' 
+		SELECT EXTRACT(HOUR FROM b.start_time)::int as hour, COUNT(*) 
+		FROM bookings b
+		JOIN facilities f ON b.facility_id = f.id
+		WHERE b.status != 'rejected' AND `+campusCond+`
 		GROUP BY hour
-	`)
+	`, campusID)
 
 	if err == nil {
 		defer peakRows.Close()
@@ -139,15 +167,58 @@ func GetStats(db *sql.DB) (DashboardStats, error) {
 		stats.PeakHours = append(stats.PeakHours, PeakHour{Hour: h, Count: hoursMap[h]})
 	}
 
+	// 5. Utilisasi Kapasitas per Fasilitas (jumlah hadir, atau perkiraan jika tidak diisi)
+	db.QueryRow(`
+		SELECT COUNT(*) FROM bookings b
+		JOIN facilities f ON b.facility_id = f.id
+		WHERE b.over_capacity = true AND b.deleted_at IS NULL AND b.start_time >= NOW() - INTERVAL '30 days'
+		  AND `+campusCond, campusID).Scan(&stats.OverCapacityBookings)
+
+	utilRows, err := db.Query(`
+		SELECT 
+			f.id, f.name, f.capacity,
+			COUNT(COALESCE(b.actual_headcount, b.expected_headcount)),
+			COALESCE(AVG(COALESCE(b.actual_headcount, b.expected_headcount)), 0)::float8,
+			COUNT(*) FILTER (WHERE b.over_capacity)
+		FROM facilities f
+		JOIN bookings b ON b.facility_id = f.id
+		WHERE f.deleted_at IS NULL
+		  AND b.deleted_at IS NULL
+		  AND b.status IN ('approved', 'completed')
+		  AND b.start_time >= NOW() - INTERVAL '30 days'
+		  AND `+campusCond+`
+		GROUP BY f.id, f.name, f.capacity
+		HAVING COUNT(COALESCE(b.actual_headcount, b.expected_headcount)) > 0
+		ORDER BY f.name
+	`, campusID)
+	if err != nil {
+		log.Println("Error query utilization:", err)
+	} else {
+		defer utilRows.Close()
+		for utilRows.Next() {
+			var u FacilityUtilization
+			if err := utilRows.Scan(&u.FacilityID, &u.Name, &u.Capacity, &u.CountedBookings, &u.AvgHeadcount, &u.OverCapacityCount); err != nil {
+				continue
+			}
+			if u.Capacity > 0 {
+				u.UtilizationRate = math.Round(u.AvgHeadcount/float64(u.Capacity)*1000) / 10
+			}
+			u.AvgHeadcount = math.Round(u.AvgHeadcount*10) / 10
+			stats.Utilization = append(stats.Utilization, u)
+		}
+	}
+
 	return stats, nil
 }
 
 // ==========================
 // HANDLER HTTP
 // ==========================
+// DashboardHandler: admin pusat bisa memilih ?campus_id= (kosong = semua kampus),
+// admin kampus selalu melihat statistik kampusnya sendiri.
 func DashboardHandler(db *sql.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		stats, err := GetStats(db)
+		stats, err := GetStats(db, auth.CampusFilter(c))
 		if err != nil {
 			log.Println("Dashboard Error:", err)
 			return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil data statistik"})
@@ -0,0 +1,15 @@
+-- Versi sesi user: dinaikkan setiap kali semua token JWT lama harus dicabut
+-- (misal setelah reset password). Token yang membawa versi lama akan ditolak middleware.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS session_version INTEGER NOT NULL DEFAULT 0;
+
+-- Token reset password via email (link sekali pakai).
+-- Nilai jti diambil dari claim token yang ditandatangani JWT_SECRET.
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    jti UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user ON password_reset_tokens (user_id);
@@ -0,0 +1,13 @@
+-- Jumlah percobaan verifikasi per kode OTP. Kode dihapus jika melewati batas.
+ALTER TABLE verification_codes ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+
+-- Penyimpanan rate limit (per nomor HP / per IP) beserta cooldown eksponensial.
+-- key contoh: 'otp_request:phone:0812...' atau 'otp_request:ip:127.0.0.1'
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key VARCHAR(255) PRIMARY KEY,
+    hits INTEGER NOT NULL DEFAULT 0,
+    window_start TIMESTAMPTZ NOT NULL DEFAULT now(),
+    strikes INTEGER NOT NULL DEFAULT 0,
+    blocked_until TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT now()
+);
@@ -0,0 +1,26 @@
+-- Riwayat percobaan login (berhasil & gagal) untuk audit dan deteksi serangan
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    identifier VARCHAR(150) NOT NULL, -- email atau nomor HP yang dimasukkan
+    method VARCHAR(20) NOT NULL,      -- password / otp
+    ip_address VARCHAR(64),
+    user_agent TEXT,
+    success BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_user ON login_attempts (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip ON login_attempts (ip_address, created_at DESC);
+
+-- Perangkat yang pernah dipakai login. Login dari perangkat baru memicu notifikasi ke user.
+CREATE TABLE IF NOT EXISTS user_devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_hash VARCHAR(64) NOT NULL,
+    user_agent TEXT,
+    last_ip VARCHAR(64),
+    first_seen_at TIMESTAMPTZ DEFAULT now(),
+    last_seen_at TIMESTAMPTZ DEFAULT now(),
+    CONSTRAINT user_devices_user_device_key UNIQUE (user_id, device_hash)
+);
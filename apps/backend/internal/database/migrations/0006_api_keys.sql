@@ -0,0 +1,16 @@
+-- API key untuk kiosk scanner & integrasi. Key asli hanya ditampilkan sekali; yang disimpan hanya hash SHA-256.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL UNIQUE, -- Bagian awal key untuk pencarian & identifikasi di UI
+    key_hash VARCHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    facility_id UUID REFERENCES facilities(id) ON DELETE CASCADE, -- NULL = semua fasilitas
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    last_used_ip VARCHAR(64),
+    revoked_at TIMESTAMPTZ,
+    revoked_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
@@ -0,0 +1,35 @@
+-- Kirim NOTIFY setiap kali data booking berubah, agar semua instance backend
+-- bisa meneruskan perubahan ke client lewat Server-Sent Events.
+CREATE OR REPLACE FUNCTION notify_booking_change() RETURNS trigger AS $$
+DECLARE
+    rec RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+    ELSE
+        rec := NEW;
+    END IF;
+
+    PERFORM pg_notify('booking_changes', json_build_object(
+        'op', lower(TG_OP),
+        'id', rec.id,
+        'facility_id', rec.facility_id,
+        'user_id', rec.user_id,
+        'status', rec.status,
+        'start_time', rec.start_time,
+        'end_time', rec.end_time,
+        'actual_end_time', rec.actual_end_time,
+        'attendance_status', rec.attendance_status,
+        'is_checked_in', rec.is_checked_in,
+        'is_checked_out', rec.is_checked_out,
+        'deleted', rec.deleted_at IS NOT NULL
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS bookings_notify_change ON bookings;
+CREATE TRIGGER bookings_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON bookings
+    FOR EACH ROW EXECUTE FUNCTION notify_booking_change();
@@ -0,0 +1,10 @@
+-- Kebijakan walk-up per fasilitas: ruangan yang sedang kosong boleh langsung dipakai
+-- tanpa persetujuan admin (scan QR di layar pintu), dengan durasi maksimal tertentu.
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS allow_walk_up BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS walk_up_max_minutes INTEGER NOT NULL DEFAULT 60;
+
+ALTER TABLE facilities DROP CONSTRAINT IF EXISTS facilities_walk_up_max_minutes_check;
+ALTER TABLE facilities ADD CONSTRAINT facilities_walk_up_max_minutes_check CHECK (walk_up_max_minutes IN (30, 60));
+
+-- Penanda booking yang dibuat lewat walk-up (disetujui & check-in otomatis)
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS is_walk_up BOOLEAN NOT NULL DEFAULT false;
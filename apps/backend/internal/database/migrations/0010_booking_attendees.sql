@@ -0,0 +1,22 @@
+-- Daftar peserta untuk booking acara (seminar, kuliah umum, dll).
+-- Setiap peserta mendapat kode tiket/QR sendiri untuk check-in per orang.
+CREATE TABLE IF NOT EXISTS booking_attendees (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    name VARCHAR(150) NOT NULL,
+    email VARCHAR(150),
+    phone VARCHAR(20),
+    ticket_code VARCHAR(40) NOT NULL UNIQUE,
+    is_checked_in BOOLEAN NOT NULL DEFAULT false,
+    checked_in_at TIMESTAMPTZ,
+    invited_at TIMESTAMPTZ,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (email IS NOT NULL OR phone IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_attendees_booking ON booking_attendees (booking_id);
+CREATE INDEX IF NOT EXISTS idx_booking_attendees_user ON booking_attendees (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_booking_attendees_email ON booking_attendees (booking_id, lower(email)) WHERE email IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_booking_attendees_phone ON booking_attendees (booking_id, phone) WHERE phone IS NOT NULL;
@@ -0,0 +1,7 @@
+-- Jumlah orang yang diperkirakan & yang benar-benar hadir, dicatat petugas saat scan tiket.
+-- over_capacity ditandai jika salah satunya melebihi kapasitas fasilitas.
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS expected_headcount INTEGER CHECK (expected_headcount >= 0);
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS actual_headcount INTEGER CHECK (actual_headcount >= 0);
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS over_capacity BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_bookings_over_capacity ON bookings (facility_id) WHERE over_capacity = true;
@@ -0,0 +1,54 @@
+-- Inventaris peralatan yang bisa dipinjam (proyektor, mikrofon, kabel roll, dll),
+-- bersama booking ruangan atau berdiri sendiri.
+CREATE TABLE IF NOT EXISTS equipment (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    total_quantity INTEGER NOT NULL CHECK (total_quantity >= 0),
+    home_facility_id UUID REFERENCES facilities(id) ON DELETE SET NULL,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_equipment_home_facility ON equipment (home_facility_id) WHERE deleted_at IS NULL;
+
+-- status: pending -> approved / rejected / canceled -> completed (setelah dikembalikan)
+CREATE TABLE IF NOT EXISTS equipment_reservations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    booking_id UUID REFERENCES bookings(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id),
+    start_time TIMESTAMPTZ NOT NULL,
+    end_time TIMESTAMPTZ NOT NULL,
+    purpose TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'approved', 'rejected', 'canceled', 'completed')),
+    rejection_reason TEXT,
+    checked_out_at TIMESTAMPTZ,
+    checked_out_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    returned_at TIMESTAMPTZ,
+    returned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    return_note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    CHECK (start_time < end_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_equipment_reservations_booking ON equipment_reservations (booking_id);
+CREATE INDEX IF NOT EXISTS idx_equipment_reservations_user ON equipment_reservations (user_id);
+CREATE INDEX IF NOT EXISTS idx_equipment_reservations_time ON equipment_reservations (start_time, end_time)
+    WHERE status IN ('pending', 'approved');
+
+CREATE TABLE IF NOT EXISTS equipment_reservation_items (
+    reservation_id UUID NOT NULL REFERENCES equipment_reservations(id) ON DELETE CASCADE,
+    equipment_id UUID NOT NULL REFERENCES equipment(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    returned_quantity INTEGER CHECK (returned_quantity >= 0),
+    PRIMARY KEY (reservation_id, equipment_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_equipment_reservation_items_equipment ON equipment_reservation_items (equipment_id);
@@ -0,0 +1,31 @@
+-- Atribut terstruktur fasilitas: kategori, fasilitas pendukung (amenities), aksesibilitas, gedung & lantai,
+-- serta kolom full-text search untuk pencarian di daftar fasilitas.
+ALTER TABLE facilities
+    ADD COLUMN IF NOT EXISTS category VARCHAR(30) NOT NULL DEFAULT 'other',
+    ADD COLUMN IF NOT EXISTS amenities TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS wheelchair_accessible BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS accessible_restroom BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS hearing_loop BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS building VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS floor INTEGER;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'facilities_category_check') THEN
+        ALTER TABLE facilities ADD CONSTRAINT facilities_category_check
+            CHECK (category IN ('lab', 'auditorium', 'discussion_room', 'workshop', 'classroom', 'sport', 'other'));
+    END IF;
+END $$;
+
+-- Konfigurasi 'simple' dipakai karena nama & deskripsi campuran Bahasa Indonesia / Inggris
+ALTER TABLE facilities
+    ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(name, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(location, '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(description, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_facilities_search ON facilities USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_facilities_amenities ON facilities USING GIN (amenities);
+CREATE INDEX IF NOT EXISTS idx_facilities_category ON facilities (category) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_facilities_building ON facilities (building, floor) WHERE deleted_at IS NULL;
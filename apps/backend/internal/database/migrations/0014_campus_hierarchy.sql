@@ -0,0 +1,84 @@
+-- Hierarki lokasi: kampus -> gedung -> lantai -> fasilitas.
+-- Jam operasional diwariskan: fasilitas -> gedung -> kampus (NULL = ikut level di atasnya,
+-- jika sampai kampus tetap NULL berarti buka 24 jam setiap hari).
+CREATE TABLE IF NOT EXISTS campuses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    code VARCHAR(20) NOT NULL UNIQUE,
+    address TEXT,
+    opens_at TIME,
+    closes_at TIME,
+    open_days SMALLINT[], -- hari ISO: 1 = Senin ... 7 = Minggu
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((opens_at IS NULL) = (closes_at IS NULL)),
+    CHECK (opens_at IS NULL OR opens_at < closes_at)
+);
+
+CREATE TABLE IF NOT EXISTS buildings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    campus_id UUID NOT NULL REFERENCES campuses(id) ON DELETE RESTRICT,
+    name VARCHAR(100) NOT NULL,
+    code VARCHAR(20),
+    opens_at TIME,
+    closes_at TIME,
+    open_days SMALLINT[],
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (campus_id, name),
+    CHECK ((opens_at IS NULL) = (closes_at IS NULL)),
+    CHECK (opens_at IS NULL OR opens_at < closes_at)
+);
+
+CREATE TABLE IF NOT EXISTS floors (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    building_id UUID NOT NULL REFERENCES buildings(id) ON DELETE RESTRICT,
+    level INTEGER NOT NULL,
+    name VARCHAR(100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (building_id, level)
+);
+
+ALTER TABLE facilities
+    ADD COLUMN IF NOT EXISTS floor_id UUID REFERENCES floors(id) ON DELETE RESTRICT,
+    ADD COLUMN IF NOT EXISTS opens_at TIME,
+    ADD COLUMN IF NOT EXISTS closes_at TIME,
+    ADD COLUMN IF NOT EXISTS open_days SMALLINT[];
+
+CREATE INDEX IF NOT EXISTS idx_buildings_campus ON buildings (campus_id);
+CREATE INDEX IF NOT EXISTS idx_facilities_floor ON facilities (floor_id) WHERE deleted_at IS NULL;
+
+-- Migrasi data lama: kolom teks building / floor dari fasilitas dipindah ke hierarki di bawah "Kampus Utama"
+DO $$
+DECLARE
+    main_campus UUID;
+BEGIN
+    IF EXISTS (SELECT 1 FROM facilities WHERE floor_id IS NULL AND COALESCE(building, '') <> '') THEN
+        INSERT INTO campuses (name, code) VALUES ('Kampus Utama', 'UTAMA')
+        ON CONFLICT (code) DO NOTHING;
+        SELECT id INTO main_campus FROM campuses WHERE code = 'UTAMA';
+
+        INSERT INTO buildings (campus_id, name)
+        SELECT DISTINCT main_campus, TRIM(building)
+        FROM facilities
+        WHERE floor_id IS NULL AND COALESCE(TRIM(building), '') <> ''
+        ON CONFLICT (campus_id, name) DO NOTHING;
+
+        INSERT INTO floors (building_id, level)
+        SELECT DISTINCT b.id, COALESCE(f.floor, 1)
+        FROM facilities f
+        JOIN buildings b ON b.campus_id = main_campus AND b.name = TRIM(f.building)
+        WHERE f.floor_id IS NULL
+        ON CONFLICT (building_id, level) DO NOTHING;
+
+        UPDATE facilities f
+        SET floor_id = fl.id
+        FROM buildings b
+        JOIN floors fl ON fl.building_id = b.id
+        WHERE f.floor_id IS NULL
+          AND b.campus_id = main_campus
+          AND b.name = TRIM(f.building)
+          AND fl.level = COALESCE(f.floor, 1);
+    END IF;
+END $$;
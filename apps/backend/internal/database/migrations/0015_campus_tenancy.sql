@@ -0,0 +1,96 @@
+-- Multi-kampus: setiap fasilitas (dan booking-nya) milik satu kampus.
+-- Admin dengan admin_campus_id hanya mengelola kampus tersebut; admin tanpa admin_campus_id adalah admin pusat.
+ALTER TABLE facilities
+    ADD COLUMN IF NOT EXISTS campus_id UUID REFERENCES campuses(id) ON DELETE RESTRICT;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS admin_campus_id UUID REFERENCES campuses(id) ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS idx_facilities_campus ON facilities (campus_id) WHERE deleted_at IS NULL;
+
+-- Isi campus_id dari hierarki lantai -> gedung
+UPDATE facilities f
+SET campus_id = b.campus_id
+FROM floors fl
+JOIN buildings b ON b.id = fl.building_id
+WHERE f.floor_id = fl.id AND f.campus_id IS NULL;
+
+-- Fasilitas yang belum punya gedung masuk ke kampus yang sudah ada (atau "Kampus Utama")
+DO $$
+DECLARE
+    main_campus UUID;
+BEGIN
+    IF EXISTS (SELECT 1 FROM facilities WHERE campus_id IS NULL) THEN
+        SELECT id INTO main_campus FROM campuses ORDER BY (code = 'UTAMA') DESC, created_at LIMIT 1;
+        IF main_campus IS NULL THEN
+            INSERT INTO campuses (name, code) VALUES ('Kampus Utama', 'UTAMA') RETURNING id INTO main_campus;
+        END IF;
+        UPDATE facilities SET campus_id = main_campus WHERE campus_id IS NULL;
+    END IF;
+END $$;
+
+-- campus_id fasilitas selalu mengikuti gedung tempat lantainya berada
+CREATE OR REPLACE FUNCTION sync_facility_campus() RETURNS trigger AS $$
+BEGIN
+    IF NEW.floor_id IS NOT NULL THEN
+        SELECT b.campus_id INTO NEW.campus_id
+        FROM floors fl JOIN buildings b ON b.id = fl.building_id
+        WHERE fl.id = NEW.floor_id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS facilities_sync_campus ON facilities;
+CREATE TRIGGER facilities_sync_campus
+    BEFORE INSERT OR UPDATE OF floor_id, campus_id ON facilities
+    FOR EACH ROW EXECUTE FUNCTION sync_facility_campus();
+
+CREATE OR REPLACE FUNCTION sync_building_campus() RETURNS trigger AS $$
+BEGIN
+    UPDATE facilities f SET campus_id = NEW.campus_id
+    FROM floors fl
+    WHERE fl.building_id = NEW.id AND f.floor_id = fl.id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS buildings_sync_campus ON buildings;
+CREATE TRIGGER buildings_sync_campus
+    AFTER UPDATE OF campus_id ON buildings
+    FOR EACH ROW WHEN (OLD.campus_id IS DISTINCT FROM NEW.campus_id)
+    EXECUTE FUNCTION sync_building_campus();
+
+-- NOTIFY booking ikut membawa campus_id agar stream admin kampus bisa disaring
+CREATE OR REPLACE FUNCTION notify_booking_change() RETURNS trigger AS $$
+DECLARE
+    rec RECORD;
+    rec_campus UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+    ELSE
+        rec := NEW;
+    END IF;
+
+    SELECT campus_id INTO rec_campus FROM facilities WHERE id = rec.facility_id;
+
+    PERFORM pg_notify('booking_changes', json_build_object(
+        'op', lower(TG_OP),
+        'id', rec.id,
+        'facility_id', rec.facility_id,
+        'campus_id', rec_campus,
+        'user_id', rec.user_id,
+        'status', rec.status,
+        'start_time', rec.start_time,
+        'end_time', rec.end_time,
+        'actual_end_time', rec.actual_end_time,
+        'attendance_status', rec.attendance_status,
+        'is_checked_in', rec.is_checked_in,
+        'is_checked_out', rec.is_checked_out,
+        'deleted', rec.deleted_at IS NOT NULL
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,76 @@
+-- Booking berbayar: aturan harga, quote per booking dan invoice bernomor urut.
+-- Jenis user menentukan tarif: mahasiswa & staf (internal) atau pihak luar (external).
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS user_type VARCHAR(20) NOT NULL DEFAULT 'student'
+        CHECK (user_type IN ('student', 'staff', 'external'));
+
+ALTER TABLE facilities
+    ADD COLUMN IF NOT EXISTS requires_payment BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- facility_id NULL = berlaku untuk semua fasilitas; user_type 'all' = semua jenis user.
+-- Aturan paling spesifik yang dipakai: fasilitas+jenis user > fasilitas > global+jenis user > global.
+CREATE TABLE IF NOT EXISTS pricing_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    facility_id UUID REFERENCES facilities(id) ON DELETE CASCADE,
+    user_type VARCHAR(20) NOT NULL DEFAULT 'all' CHECK (user_type IN ('all', 'student', 'staff', 'external')),
+    hourly_rate NUMERIC(12, 2) CHECK (hourly_rate >= 0), -- NULL = pakai facilities.price
+    weekend_surcharge_pct NUMERIC(5, 2) NOT NULL DEFAULT 0 CHECK (weekend_surcharge_pct BETWEEN 0 AND 500),
+    free_hours_per_month NUMERIC(6, 2) NOT NULL DEFAULT 0 CHECK (free_hours_per_month >= 0),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pricing_rules_scope
+    ON pricing_rules (COALESCE(facility_id, '00000000-0000-0000-0000-000000000000'::uuid), user_type);
+
+-- Rincian harga yang dihitung saat booking dibuat (tidak berubah walau aturan harga diubah kemudian)
+CREATE TABLE IF NOT EXISTS booking_quotes (
+    booking_id UUID PRIMARY KEY REFERENCES bookings(id) ON DELETE CASCADE,
+    rule_id UUID REFERENCES pricing_rules(id) ON DELETE SET NULL,
+    user_type VARCHAR(20) NOT NULL,
+    hourly_rate NUMERIC(12, 2) NOT NULL,
+    hours NUMERIC(8, 2) NOT NULL,
+    weekend_hours NUMERIC(8, 2) NOT NULL DEFAULT 0,
+    free_hours NUMERIC(8, 2) NOT NULL DEFAULT 0,
+    weekend_surcharge_pct NUMERIC(5, 2) NOT NULL DEFAULT 0,
+    subtotal NUMERIC(12, 2) NOT NULL,
+    surcharge NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    discount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    total NUMERIC(12, 2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_quotes_rule ON booking_quotes (rule_id) WHERE free_hours > 0;
+
+-- Nomor invoice berurutan per tahun (INV/2026/000001), dikunci per baris agar tidak ada nomor ganda
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+    year INTEGER PRIMARY KEY,
+    last_number INTEGER NOT NULL DEFAULT 0
+);
+
+-- Data pelanggan & fasilitas disalin agar invoice tetap utuh walau booking / fasilitas dihapus
+CREATE TABLE IF NOT EXISTS invoices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    number VARCHAR(30) NOT NULL UNIQUE,
+    booking_id UUID UNIQUE REFERENCES bookings(id) ON DELETE SET NULL,
+    user_id UUID REFERENCES users(id),
+    campus_id UUID REFERENCES campuses(id) ON DELETE SET NULL,
+    customer_name VARCHAR(150) NOT NULL,
+    customer_email VARCHAR(150) NOT NULL,
+    facility_name VARCHAR(150) NOT NULL,
+    description TEXT NOT NULL,
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount >= 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'unpaid' CHECK (status IN ('unpaid', 'paid', 'void')),
+    issued_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    due_at TIMESTAMPTZ,
+    paid_at TIMESTAMPTZ,
+    paid_by UUID REFERENCES users(id),
+    payment_ref VARCHAR(100),
+    voided_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoices_user ON invoices (user_id, issued_at DESC);
+CREATE INDEX IF NOT EXISTS idx_invoices_status ON invoices (status, issued_at DESC);
@@ -0,0 +1,40 @@
+-- Pembayaran online invoice lewat payment gateway (Midtrans) atau simulator lokal.
+-- Satu invoice boleh punya beberapa percobaan pembayaran (link baru dibuat jika link lama kedaluwarsa / gagal).
+ALTER TABLE invoices DROP CONSTRAINT IF EXISTS invoices_status_check;
+ALTER TABLE invoices
+    ADD CONSTRAINT invoices_status_check CHECK (status IN ('unpaid', 'paid', 'void', 'refunded'));
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS refunded_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS payments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    provider VARCHAR(20) NOT NULL,
+    order_id VARCHAR(64) NOT NULL UNIQUE, -- ID transaksi yang dikirim ke provider
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'paid', 'failed', 'expired', 'refunded')),
+    payment_url TEXT NOT NULL,
+    provider_ref VARCHAR(100),
+    expires_at TIMESTAMPTZ NOT NULL,
+    paid_at TIMESTAMPTZ,
+    refunded_at TIMESTAMPTZ,
+    refund_ref VARCHAR(100),
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_invoice ON payments (invoice_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_payments_pending ON payments (created_at) WHERE status = 'pending';
+
+-- Jejak callback, hasil polling & refund (payload mentah dari provider)
+CREATE TABLE IF NOT EXISTS payment_events (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('callback', 'poll', 'refund', 'expire')),
+    status VARCHAR(20) NOT NULL,
+    payload TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_events_payment ON payment_events (payment_id, created_at);
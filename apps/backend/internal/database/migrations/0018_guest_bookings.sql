@@ -0,0 +1,24 @@
+-- Booking tamu untuk organisasi luar kampus (tanpa registrasi).
+-- Pengajuan membuat akun tamu ringan (tanpa password, jenis user external) dan booking pending biasa.
+-- Setelah booking disetujui, akun tamu diaktifkan menjadi akun biasa lewat link buat password.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_guest BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS allow_guest_booking BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS guest_booking_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    booking_id UUID NOT NULL UNIQUE REFERENCES bookings(id) ON DELETE CASCADE,
+    guest_user_id UUID NOT NULL REFERENCES users(id),
+    organization_name VARCHAR(150) NOT NULL,
+    contact_name VARCHAR(100) NOT NULL,
+    contact_email VARCHAR(150) NOT NULL,
+    contact_phone VARCHAR(30) NOT NULL,
+    purpose TEXT NOT NULL,
+    document_path TEXT NOT NULL,     -- surat permohonan / proposal (disimpan di luar folder publik)
+    document_name VARCHAR(255) NOT NULL,
+    access_token_hash VARCHAR(64) NOT NULL UNIQUE, -- SHA256 token link pantau status yang dikirim ke email tamu
+    converted_at TIMESTAMPTZ,       -- akun tamu diaktifkan setelah booking disetujui
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_guest_booking_requests_created ON guest_booking_requests (created_at DESC);
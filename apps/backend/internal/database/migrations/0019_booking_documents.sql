@@ -0,0 +1,27 @@
+-- Dokumen pendukung booking (surat izin, proposal, dll) dan aturan dokumen wajib per fasilitas.
+-- user_type 'all' = wajib untuk semua pemesan; selain itu hanya untuk jenis user tersebut (misal student untuk UKM).
+CREATE TABLE IF NOT EXISTS facility_document_requirements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    facility_id UUID NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    user_type VARCHAR(20) NOT NULL DEFAULT 'all' CHECK (user_type IN ('all', 'student', 'staff', 'external')),
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (facility_id, name)
+);
+
+-- File disimpan di luar folder publik /uploads; hanya pemesan & admin yang bisa mengunduh
+CREATE TABLE IF NOT EXISTS booking_documents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    requirement_id UUID REFERENCES facility_document_requirements(id) ON DELETE SET NULL, -- NULL = lampiran tambahan
+    file_path TEXT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    uploaded_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_documents_booking ON booking_documents (booking_id, created_at);
@@ -0,0 +1,10 @@
+-- Penyetuju booking untuk surat konfirmasi. updated_by tidak bisa dipakai karena tertimpa perubahan berikutnya
+-- (check-out, pembatalan, dsb). Walk-up disetujui otomatis sehingga approved_by kosong.
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS approved_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS approved_at TIMESTAMPTZ;
+
+-- Data lama: perkiraan terbaik dari updated_by untuk booking yang sudah disetujui
+UPDATE bookings
+SET approved_by = CASE WHEN is_walk_up THEN NULL ELSE updated_by END,
+    approved_at = COALESCE(updated_at, created_at)
+WHERE status::text IN ('approved', 'completed') AND approved_at IS NULL;
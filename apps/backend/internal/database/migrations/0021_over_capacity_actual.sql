@@ -0,0 +1,7 @@
+-- over_capacity kini mengikuti angka yang sama dengan utilisasi: jumlah hadir (actual) jika sudah dicatat,
+-- jika belum memakai perkiraan (expected). Tandai ulang data lama.
+UPDATE bookings b
+SET over_capacity = COALESCE(b.actual_headcount, b.expected_headcount, 0) > f.capacity
+FROM facilities f
+WHERE f.id = b.facility_id
+  AND (b.expected_headcount IS NOT NULL OR b.actual_headcount IS NOT NULL);
@@ -0,0 +1,11 @@
+package database
+
+import "database/sql"
+
+// Querier dipenuhi oleh *sql.DB maupun *sql.Tx, sehingga fungsi repository bisa dipanggil di luar
+// maupun di dalam transaksi (misal perubahan data beserta event webhook-nya).
+type Querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
@@ -0,0 +1,267 @@
+package document
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"campus-reservation-backend/internal/auth"
+	"campus-reservation-backend/internal/storage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// downloadURLTTL: masa berlaku signed URL dokumen di response daftar
+const downloadURLTTL = 15 * time.Minute
+
+type RequirementRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	UserType    string `json:"user_type"` // all | student | staff | external (default all)
+}
+
+func errorStatus(err error) int {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "tidak ditemukan"):
+		return 404
+	case strings.Contains(msg, "sudah ada"):
+		return 409
+	case strings.Contains(msg, "gagal menyimpan"):
+		return 500
+	}
+	return 400
+}
+
+func respondError(c *fiber.Ctx, err error) error {
+	return c.Status(errorStatus(err)).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+func isAdmin(c *fiber.Ctx) bool {
+	role, _ := c.Locals("role").(string)
+	return role == "admin"
+}
+
+// loadBooking: pemilik booking atau admin (admin kampus hanya kampusnya); selain itu dianggap tidak ditemukan
+func loadBooking(c *fiber.Ctx, db *sql.DB) (bookingInfo, bool) {
+	b, err := findBookingInfo(db, c.Params("id"))
+	if err != nil {
+		return b, false
+	}
+	if isAdmin(c) {
+		return b, auth.CampusAllowed(c, b.CampusID)
+	}
+	me, _ := c.Locals("user_id").(string)
+	return b, b.UserID == me
+}
+
+// canModify: pemilik hanya boleh mengubah lampiran selama booking masih pending
+func canModify(c *fiber.Ctx, b bookingInfo) bool {
+	return isAdmin(c) || b.Status == "pending"
+}
+
+// ========================================================
+// HANDLER: DOKUMEN BOOKING
+// ========================================================
+
+// UploadHandler: POST /bookings/:id/documents (multipart: file, requirement_id opsional)
+func UploadHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		b, ok := loadBooking(c, db)
+		if !ok {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Booking tidak ditemukan",
+			})
+		}
+		if !canModify(c, b) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Dokumen hanya bisa ditambahkan selama booking masih pending",
+			})
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "File dokumen (file) wajib diunggah",
+			})
+		}
+
+		d, err := Attach(db, b.ID, c.FormValue("requirement_id"), c.Locals("user_id").(string), file)
+		if err != nil {
+			return respondError(c, err)
+		}
+		return c.Status(201).JSON(d)
+	}
+}
+
+// ListHandler: GET /bookings/:id/documents -> lampiran & checklist dokumen wajib
+func ListHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		b, ok := loadBooking(c, db)
+		if !ok {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Booking tidak ditemukan",
+			})
+		}
+
+		docs, err := FindByBooking(db, b.ID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal memuat dokumen booking",
+			})
+		}
+		for i := range docs {
+			docs[i].DownloadURL, _ = storage.SignedURL(docs[i].FilePath, downloadURLTTL, docs[i].FileName)
+		}
+		reqs, err := checklist(db, b)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal memuat dokumen wajib",
+			})
+		}
+
+		complete := true
+		for _, r := range reqs {
+			complete = complete && r.Fulfilled
+		}
+		return c.JSON(fiber.Map{
+			"documents":    docs,
+			"requirements": reqs,
+			"complete":     complete,
+		})
+	}
+}
+
+// DownloadHandler: GET /bookings/:id/documents/:docId (isi file dialirkan lewat API; alternatifnya download_url di daftar)
+func DownloadHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		b, ok := loadBooking(c, db)
+		if !ok {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Booking tidak ditemukan",
+			})
+		}
+
+		d, err := findDocument(db, b.ID, c.Params("docId"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Dokumen tidak ditemukan",
+			})
+		}
+		body, err := storage.Open(d.FilePath)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "File dokumen tidak ditemukan",
+			})
+		}
+		c.Attachment(d.FileName)
+		c.Set("Content-Type", d.ContentType)
+		return c.SendStream(body)
+	}
+}
+
+// DeleteHandler: DELETE /bookings/:id/documents/:docId
+func DeleteHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		b, ok := loadBooking(c, db)
+		if !ok {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Booking tidak ditemukan",
+			})
+		}
+		if !canModify(c, b) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Dokumen hanya bisa dihapus selama booking masih pending",
+			})
+		}
+
+		if err := Remove(db, b.ID, c.Params("docId")); err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"message": "Dokumen berhasil dihapus",
+		})
+	}
+}
+
+// ========================================================
+// HANDLER: DOKUMEN WAJIB FASILITAS
+// ========================================================
+
+// ListRequirementsHandler: GET /facilities/:id/document-requirements (?user_type= untuk yang berlaku saja)
+func ListRequirementsHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		list, err := FindRequirements(db, c.Params("id"), c.Query("user_type"))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal memuat dokumen wajib",
+			})
+		}
+		return c.JSON(list)
+	}
+}
+
+// CreateRequirementHandler (admin): POST /facilities/:id/document-requirements
+func CreateRequirementHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req RequirementRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		facilityID := c.Params("id")
+		campusID, err := findFacilityCampus(db, facilityID)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Fasilitas tidak ditemukan",
+			})
+		}
+		if !auth.CampusAllowed(c, campusID) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Fasilitas ini milik kampus lain",
+			})
+		}
+
+		id, err := CreateRequirement(db, Requirement{
+			FacilityID:  facilityID,
+			Name:        req.Name,
+			Description: req.Description,
+			UserType:    req.UserType,
+		}, c.Locals("user_id").(string))
+		if err != nil {
+			return respondError(c, err)
+		}
+		return c.Status(201).JSON(fiber.Map{
+			"message": "Dokumen wajib berhasil ditambahkan",
+			"id":      id,
+		})
+	}
+}
+
+// DeleteRequirementHandler (admin): DELETE /facilities/:id/document-requirements/:reqId
+func DeleteRequirementHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		r, err := FindRequirementByID(db, c.Params("reqId"))
+		if err != nil || r.FacilityID != c.Params("id") {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Dokumen wajib tidak ditemukan",
+			})
+		}
+		if !auth.CampusAllowed(c, r.CampusID) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Fasilitas ini milik kampus lain",
+			})
+		}
+
+		if err := RemoveRequirement(db, r.ID); err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"message": "Dokumen wajib berhasil dihapus",
+		})
+	}
+}
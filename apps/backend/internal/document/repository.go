@@ -0,0 +1,200 @@
+package document
+
+import (
+	"database/sql"
+	"time"
+
+	"campus-reservation-backend/internal/database"
+)
+
+// ==========================
+// MODEL
+// ==========================
+
+// Requirement adalah dokumen yang wajib dilampirkan sebelum booking fasilitas tsb dapat disetujui
+type Requirement struct {
+	ID          string    `json:"id"`
+	FacilityID  string    `json:"facility_id"`
+	CampusID    string    `json:"-"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	UserType    string    `json:"user_type"` // all | student | staff | external
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Document struct {
+	ID              string    `json:"id"`
+	BookingID       string    `json:"booking_id"`
+	RequirementID   *string   `json:"requirement_id"` // nil = lampiran tambahan
+	RequirementName string    `json:"requirement_name,omitempty"`
+	FilePath        string    `json:"-"`                      // key storage (private/booking-documents/...)
+	DownloadURL     string    `json:"download_url,omitempty"` // signed URL sementara
+	FileName        string    `json:"file_name"`
+	ContentType     string    `json:"content_type"`
+	SizeBytes       int64     `json:"size_bytes"`
+	UploadedBy      *string   `json:"uploaded_by"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// RequirementStatus: checklist dokumen wajib sebuah booking
+type RequirementStatus struct {
+	Requirement
+	Fulfilled bool `json:"fulfilled"`
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// ==========================
+// ATURAN DOKUMEN WAJIB
+// ==========================
+
+func insertRequirement(db *sql.DB, r Requirement, userID string) (string, error) {
+	var id string
+	err := db.QueryRow(`
+		INSERT INTO facility_document_requirements (facility_id, name, description, user_type, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, r.FacilityID, r.Name, r.Description, r.UserType, userID).Scan(&id)
+	return id, err
+}
+
+func deleteRequirement(db *sql.DB, id string) (int64, error) {
+	res, err := db.Exec(`DELETE FROM facility_document_requirements WHERE id::text = $1`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const requirementSelect = `
+	SELECT r.id, r.facility_id::text, COALESCE(f.campus_id::text, ''), r.name, r.description, r.user_type, r.created_at
+	FROM facility_document_requirements r
+	JOIN facilities f ON f.id = r.facility_id`
+
+func scanRequirement(row rowScanner) (Requirement, error) {
+	var r Requirement
+	err := row.Scan(&r.ID, &r.FacilityID, &r.CampusID, &r.Name, &r.Description, &r.UserType, &r.CreatedAt)
+	return r, err
+}
+
+func FindRequirementByID(q database.Querier, id string) (Requirement, error) {
+	return scanRequirement(q.QueryRow(requirementSelect+` WHERE r.id::text = $1`, id))
+}
+
+// FindRequirements: userType kosong = semua aturan fasilitas, terisi = aturan yang berlaku untuk jenis user tsb
+func FindRequirements(q database.Querier, facilityID, userType string) ([]Requirement, error) {
+	rows, err := q.Query(requirementSelect+`
+		WHERE r.facility_id::text = $1
+		  AND ($2::text = '' OR r.user_type = 'all' OR r.user_type = $2::text)
+		ORDER BY r.created_at
+	`, facilityID, userType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Requirement{}
+	for rows.Next() {
+		r, err := scanRequirement(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// ==========================
+// DATA BOOKING
+// ==========================
+
+type bookingInfo struct {
+	ID         string
+	FacilityID string
+	CampusID   string
+	UserID     string
+	UserType   string
+	Status     string
+}
+
+func findBookingInfo(q database.Querier, bookingID string) (bookingInfo, error) {
+	var b bookingInfo
+	err := q.QueryRow(`
+		SELECT b.id::text, b.facility_id::text, COALESCE(f.campus_id::text, ''), b.user_id::text,
+			u.user_type, b.status::text
+		FROM bookings b
+		JOIN facilities f ON f.id = b.facility_id
+		JOIN users u ON u.id = b.user_id
+		WHERE b.id::text = $1 AND b.deleted_at IS NULL
+	`, bookingID).Scan(&b.ID, &b.FacilityID, &b.CampusID, &b.UserID, &b.UserType, &b.Status)
+	return b, err
+}
+
+func findFacilityCampus(q database.Querier, facilityID string) (string, error) {
+	var campusID string
+	err := q.QueryRow(`SELECT COALESCE(campus_id::text, '') FROM facilities WHERE id::text = $1 AND deleted_at IS NULL`, facilityID).Scan(&campusID)
+	return campusID, err
+}
+
+func findUserType(q database.Querier, userID string) (string, error) {
+	var userType string
+	err := q.QueryRow(`SELECT user_type FROM users WHERE id::text = $1`, userID).Scan(&userType)
+	return userType, err
+}
+
+// ==========================
+// DOKUMEN BOOKING
+// ==========================
+
+func insertDocument(q database.Querier, d *Document) error {
+	return q.QueryRow(`
+		INSERT INTO booking_documents (booking_id, requirement_id, file_path, file_name, content_type, size_bytes, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, d.BookingID, d.RequirementID, d.FilePath, d.FileName, d.ContentType, d.SizeBytes, d.UploadedBy).Scan(&d.ID, &d.CreatedAt)
+}
+
+const documentSelect = `
+	SELECT d.id, d.booking_id::text, d.requirement_id::text, COALESCE(r.name, ''), d.file_path, d.file_name,
+		d.content_type, d.size_bytes, d.uploaded_by::text, d.created_at
+	FROM booking_documents d
+	LEFT JOIN facility_document_requirements r ON r.id = d.requirement_id`
+
+func scanDocument(row rowScanner) (Document, error) {
+	var d Document
+	err := row.Scan(&d.ID, &d.BookingID, &d.RequirementID, &d.RequirementName, &d.FilePath, &d.FileName,
+		&d.ContentType, &d.SizeBytes, &d.UploadedBy, &d.CreatedAt)
+	return d, err
+}
+
+func FindByBooking(q database.Querier, bookingID string) ([]Document, error) {
+	rows, err := q.Query(documentSelect+` WHERE d.booking_id::text = $1 ORDER BY d.created_at`, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Document{}
+	for rows.Next() {
+		d, err := scanDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+func findDocument(q database.Querier, bookingID, id string) (Document, error) {
+	return scanDocument(q.QueryRow(documentSelect+` WHERE d.booking_id::text = $1 AND d.id::text = $2`, bookingID, id))
+}
+
+func deleteDocument(q database.Querier, id string) (int64, error) {
+	res, err := q.Exec(`DELETE FROM booking_documents WHERE id::text = $1`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,270 @@
+package document
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"campus-reservation-backend/internal/database"
+	"campus-reservation-backend/internal/storage"
+)
+
+// ==========================
+// DOKUMEN PENDUKUNG BOOKING
+// ==========================
+// Surat izin, proposal, dll dilampirkan ke booking (saat dibuat atau sesudahnya). Fasilitas dapat mewajibkan
+// dokumen tertentu; booking baru bisa disetujui admin setelah semua dokumen wajib terlampir.
+
+const MaxSize = 10 * 1024 * 1024
+
+// allowedTypes: ekstensi -> content type hasil deteksi isi file (bukan dari header klien)
+var allowedTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+}
+
+var userTypes = []string{"all", "student", "staff", "external"}
+
+// ==========================
+// ATURAN DOKUMEN WAJIB
+// ==========================
+
+func validateRequirement(r *Requirement) error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Description = strings.TrimSpace(r.Description)
+	if r.Name == "" {
+		return errors.New("nama dokumen wajib diisi")
+	}
+	if len(r.Name) > 100 {
+		return errors.New("nama dokumen maksimal 100 karakter")
+	}
+	if r.UserType == "" {
+		r.UserType = "all"
+	}
+	if !slices.Contains(userTypes, r.UserType) {
+		return errors.New("jenis user tidak valid (all, student, staff, external)")
+	}
+	return nil
+}
+
+func CreateRequirement(db *sql.DB, r Requirement, userID string) (string, error) {
+	if err := validateRequirement(&r); err != nil {
+		return "", err
+	}
+	id, err := insertRequirement(db, r, userID)
+	if err != nil {
+		msg := err.Error()
+		switch {
+		case strings.Contains(msg, "duplicate key"):
+			return "", errors.New("dokumen wajib dengan nama ini sudah ada untuk fasilitas tersebut")
+		case strings.Contains(msg, "violates foreign key"), strings.Contains(msg, "invalid input syntax for type uuid"):
+			return "", errors.New("fasilitas tidak ditemukan")
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+// RemoveRequirement: dokumen yang sudah terlampir tetap disimpan sebagai lampiran tambahan
+func RemoveRequirement(db *sql.DB, id string) error {
+	n, err := deleteRequirement(db, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New("dokumen wajib tidak ditemukan")
+	}
+	return nil
+}
+
+// ==========================
+// CHECKLIST & PERSETUJUAN
+// ==========================
+
+func checklist(q database.Querier, b bookingInfo) ([]RequirementStatus, error) {
+	reqs, err := FindRequirements(q, b.FacilityID, b.UserType)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := FindByBooking(q, b.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	fulfilled := map[string]bool{}
+	for _, d := range docs {
+		if d.RequirementID != nil {
+			fulfilled[*d.RequirementID] = true
+		}
+	}
+
+	list := make([]RequirementStatus, 0, len(reqs))
+	for _, r := range reqs {
+		list = append(list, RequirementStatus{Requirement: r, Fulfilled: fulfilled[r.ID]})
+	}
+	return list, nil
+}
+
+// Checklist menampilkan dokumen wajib yang berlaku untuk booking beserta status kelengkapannya
+func Checklist(q database.Querier, bookingID string) ([]RequirementStatus, error) {
+	b, err := findBookingInfo(q, bookingID)
+	if err != nil {
+		return nil, errors.New("booking tidak ditemukan")
+	}
+	return checklist(q, b)
+}
+
+// MissingForBooking mengembalikan nama dokumen wajib yang belum dilampirkan
+func MissingForBooking(q database.Querier, bookingID string) ([]string, error) {
+	list, err := Checklist(q, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, r := range list {
+		if !r.Fulfilled {
+			missing = append(missing, r.Name)
+		}
+	}
+	return missing, nil
+}
+
+// CheckRequiredForApproval dipanggil sebelum admin menyetujui booking
+func CheckRequiredForApproval(q database.Querier, bookingID string) error {
+	missing, err := MissingForBooking(q, bookingID)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("dokumen wajib belum lengkap: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// HasRequirements: dipakai booking yang langsung disetujui (walk-up) untuk menolak fasilitas berdokumen wajib
+func HasRequirements(q database.Querier, facilityID, userID string) (bool, error) {
+	userType, err := findUserType(q, userID)
+	if err != nil {
+		return false, err
+	}
+	reqs, err := FindRequirements(q, facilityID, userType)
+	if err != nil {
+		return false, err
+	}
+	return len(reqs) > 0, nil
+}
+
+// ==========================
+// UNGGAH & HAPUS DOKUMEN
+// ==========================
+
+// detectContentType membaca awal file untuk memastikan isinya sesuai ekstensi
+func detectContentType(file *multipart.FileHeader) (string, error) {
+	f, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// ValidateFile memeriksa ekstensi, ukuran & isi file dokumen (PDF/JPG/PNG). Mengembalikan content type-nya.
+// Dipakai juga oleh upload dokumen booking tamu.
+func ValidateFile(file *multipart.FileHeader) (string, error) {
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	contentType, ok := allowedTypes[ext]
+	if !ok {
+		return "", errors.New("dokumen harus berupa PDF, JPG atau PNG")
+	}
+	if file.Size > MaxSize {
+		return "", errors.New("ukuran dokumen maksimal 10MB")
+	}
+	detected, err := detectContentType(file)
+	if err != nil || detected != contentType {
+		return "", errors.New("isi file tidak sesuai dengan format PDF, JPG atau PNG")
+	}
+	return contentType, nil
+}
+
+// prepareUpload memvalidasi file & requirement lalu menyiapkan record dokumen (path belum terisi)
+func prepareUpload(q database.Querier, b bookingInfo, requirementID, uploaderID string, file *multipart.FileHeader) (Document, error) {
+	contentType, err := ValidateFile(file)
+	if err != nil {
+		return Document{}, err
+	}
+
+	d := Document{
+		BookingID:   b.ID,
+		FileName:    filepath.Base(file.Filename),
+		ContentType: contentType,
+		SizeBytes:   file.Size,
+		UploadedBy:  &uploaderID,
+	}
+
+	if requirementID = strings.TrimSpace(requirementID); requirementID != "" {
+		reqs, err := FindRequirements(q, b.FacilityID, b.UserType)
+		if err != nil {
+			return Document{}, err
+		}
+		idx := slices.IndexFunc(reqs, func(r Requirement) bool { return r.ID == requirementID })
+		if idx < 0 {
+			return Document{}, errors.New("dokumen wajib tidak ditemukan untuk booking ini")
+		}
+		d.RequirementID = &requirementID
+		d.RequirementName = reqs[idx].Name
+	}
+	return d, nil
+}
+
+// Attach memvalidasi lalu menyimpan file lewat storage (mekanisme yang sama dengan foto fasilitas, prefix privat)
+// dan mencatatnya. File dilepas lagi jika pencatatan gagal.
+func Attach(db *sql.DB, bookingID, requirementID, uploaderID string, file *multipart.FileHeader) (Document, error) {
+	b, err := findBookingInfo(db, bookingID)
+	if err != nil {
+		return Document{}, errors.New("booking tidak ditemukan")
+	}
+	d, err := prepareUpload(db, b, requirementID, uploaderID, file)
+	if err != nil {
+		return Document{}, err
+	}
+
+	obj, err := storage.SaveFile(file, storage.PrefixBookingDocuments)
+	if err != nil {
+		log.Printf("Gagal menyimpan dokumen booking %s: %v\n", b.ID, err)
+		return Document{}, errors.New("gagal menyimpan dokumen")
+	}
+	d.FilePath = obj.Key
+
+	if err := insertDocument(db, &d); err != nil {
+		storage.Release(db, obj.Key)
+		return Document{}, errors.New("gagal menyimpan dokumen")
+	}
+	return d, nil
+}
+
+func Remove(db *sql.DB, bookingID, id string) error {
+	d, err := findDocument(db, bookingID, id)
+	if err != nil {
+		return errors.New("dokumen tidak ditemukan")
+	}
+	if _, err := deleteDocument(db, d.ID); err != nil {
+		return err
+	}
+	storage.Release(db, d.FilePath)
+	return nil
+}
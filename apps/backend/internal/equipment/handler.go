@@ -0,0 +1,436 @@
+package equipment
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"campus-reservation-backend/internal/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ========================================================
+// HANDLER: INVENTARIS PERALATAN
+// ========================================================
+
+type EquipmentRequest struct {
+	Name           string  `json:"name" example:"Proyektor Epson"`
+	Description    string  `json:"description"`
+	TotalQuantity  int     `json:"total_quantity" example:"5"`
+	HomeFacilityID *string `json:"home_facility_id"`
+	IsActive       *bool   `json:"is_active"`
+}
+
+func (r EquipmentRequest) toEquipment() Equipment {
+	active := true
+	if r.IsActive != nil {
+		active = *r.IsActive
+	}
+	return Equipment{
+		Name:           r.Name,
+		Description:    r.Description,
+		TotalQuantity:  r.TotalQuantity,
+		HomeFacilityID: r.HomeFacilityID,
+		IsActive:       active,
+	}
+}
+
+type UpdateReservationStatusRequest struct {
+	Status          string `json:"status"`
+	RejectionReason string `json:"rejection_reason"`
+}
+
+type AttachEquipmentRequest struct {
+	Items []ItemRequest `json:"items"`
+}
+
+const timeLayout = "2006-01-02T15:04:05"
+
+func jakarta() *time.Location {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+func isAdmin(c *fiber.Ctx) bool {
+	role, _ := c.Locals("role").(string)
+	return role == "admin"
+}
+
+// ==========================
+// CAKUPAN KAMPUS ADMIN
+// ==========================
+// Peralatan mengikuti kampus fasilitas asalnya (home_facility_id). Peralatan tanpa fasilitas asal hanya dikelola
+// admin pusat. Reservasi mengikuti kampus fasilitas booking-nya, atau kampus asal peralatannya jika tanpa booking.
+
+// homeFacilityAllowed: admin kampus hanya boleh menempatkan peralatan di fasilitas kampusnya
+func homeFacilityAllowed(c *fiber.Ctx, db *sql.DB, facilityID *string) bool {
+	if auth.AdminCampusID(c) == "" {
+		return true
+	}
+	if facilityID == nil {
+		return false
+	}
+	campusID, err := FindFacilityCampusID(db, *facilityID)
+	return err == nil && auth.CampusAllowed(c, campusID)
+}
+
+func equipmentCampusAllowed(c *fiber.Ctx, db *sql.DB, id string) bool {
+	if auth.AdminCampusID(c) == "" {
+		return true
+	}
+	campusID, err := FindEquipmentCampusID(db, id)
+	return err == nil && auth.CampusAllowed(c, campusID)
+}
+
+func reservationCampusAllowed(c *fiber.Ctx, db *sql.DB, id string) bool {
+	scope := auth.AdminCampusID(c)
+	if scope == "" {
+		return true
+	}
+	ok, err := ReservationInCampus(db, id, scope)
+	return err == nil && ok
+}
+
+func bookingCampusAllowed(c *fiber.Ctx, db *sql.DB, bookingID string) bool {
+	if auth.AdminCampusID(c) == "" {
+		return true
+	}
+	campusID, err := FindBookingCampusID(db, bookingID)
+	return err == nil && auth.CampusAllowed(c, campusID)
+}
+
+func errorStatus(err error) int {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "tidak ditemukan"):
+		return 404
+	case strings.Contains(msg, "tidak punya hak"):
+		return 403
+	case strings.Contains(msg, "hanya tersedia"), strings.Contains(msg, "sudah diproses"), strings.Contains(msg, "sudah berubah"):
+		return 409
+	}
+	return 400
+}
+
+func ListHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var start, end *time.Time
+		if c.Query("start_time") != "" || c.Query("end_time") != "" {
+			s, err1 := time.ParseInLocation(timeLayout, c.Query("start_time"), jakarta())
+			e, err2 := time.ParseInLocation(timeLayout, c.Query("end_time"), jakarta())
+			if err1 != nil || err2 != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Format tanggal salah (YYYY-MM-DDTHH:MM:SS)",
+				})
+			}
+			start, end = &s, &e
+		}
+
+		// User biasa hanya melihat peralatan yang aktif; admin kampus hanya peralatan kampusnya
+		activeOnly := !isAdmin(c) || c.Query("active") == "true"
+		campusID := ""
+		if isAdmin(c) {
+			campusID = auth.CampusFilter(c)
+		}
+
+		items, err := ListEquipment(db, c.Query("facility_id"), activeOnly, campusID, start, end)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(items)
+	}
+}
+
+func CreateHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID, _ := c.Locals("user_id").(string)
+
+		var req EquipmentRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+		if !homeFacilityAllowed(c, db, req.HomeFacilityID) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Admin kampus hanya dapat menempatkan peralatan di fasilitas kampusnya",
+			})
+		}
+
+		id, err := CreateEquipment(db, req.toEquipment(), adminID)
+		if err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(201).JSON(fiber.Map{
+			"message": "Peralatan berhasil ditambahkan",
+			"id":      id,
+		})
+	}
+}
+
+func UpdateHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID, _ := c.Locals("user_id").(string)
+
+		var req EquipmentRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+		if !equipmentCampusAllowed(c, db, c.Params("id")) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Peralatan ini milik kampus lain",
+			})
+		}
+		if !homeFacilityAllowed(c, db, req.HomeFacilityID) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Admin kampus hanya dapat menempatkan peralatan di fasilitas kampusnya",
+			})
+		}
+
+		if err := UpdateEquipment(db, c.Params("id"), req.toEquipment(), adminID); err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"message": "Peralatan berhasil diperbarui",
+		})
+	}
+}
+
+func DeleteHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID, _ := c.Locals("user_id").(string)
+		if !equipmentCampusAllowed(c, db, c.Params("id")) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Peralatan ini milik kampus lain",
+			})
+		}
+
+		if err := DeleteEquipment(db, c.Params("id"), adminID); err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"message": "Peralatan berhasil dihapus",
+		})
+	}
+}
+
+// ========================================================
+// HANDLER: RESERVASI PERALATAN
+// ========================================================
+
+// CreateReservationHandler untuk peminjaman peralatan tanpa booking ruangan
+func CreateReservationHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+
+		var req CreateReservationRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		start, err1 := time.ParseInLocation(timeLayout, req.StartTime, jakarta())
+		end, err2 := time.ParseInLocation(timeLayout, req.EndTime, jakarta())
+		if err1 != nil || err2 != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format tanggal salah (YYYY-MM-DDTHH:MM:SS)",
+			})
+		}
+
+		id, err := CreateReservation(db, userID, start, end, req.Purpose, req.Items)
+		if err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(201).JSON(fiber.Map{
+			"message": "Peminjaman peralatan berhasil diajukan, menunggu persetujuan admin",
+			"id":      id,
+		})
+	}
+}
+
+// AttachToBookingHandler menambahkan peralatan ke booking ruangan yang sudah ada
+func AttachToBookingHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+
+		var req AttachEquipmentRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+		if isAdmin(c) && !bookingCampusAllowed(c, db, c.Params("id")) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Booking ini milik kampus lain",
+			})
+		}
+
+		id, err := ReserveForBooking(db, c.Params("id"), userID, isAdmin(c), req.Items)
+		if err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(201).JSON(fiber.Map{
+			"message": "Peralatan berhasil ditambahkan ke booking",
+			"id":      id,
+		})
+	}
+}
+
+// ListReservationsHandler: admin melihat semua (filter ?status=&booking_id=&user_id=), user hanya miliknya
+func ListReservationsHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+
+		filter := ReservationFilter{
+			UserID:    userID,
+			BookingID: c.Query("booking_id"),
+			Status:    c.Query("status"),
+		}
+		if isAdmin(c) {
+			filter.UserID = c.Query("user_id")
+			filter.CampusID = auth.CampusFilter(c)
+		}
+
+		reservations, err := GetReservations(db, filter)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal mengambil data reservasi peralatan",
+			})
+		}
+		return c.JSON(reservations)
+	}
+}
+
+func GetReservationHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+		if isAdmin(c) && !reservationCampusAllowed(c, db, c.Params("id")) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Reservasi ini milik kampus lain",
+			})
+		}
+
+		r, err := GetReservation(db, c.Params("id"), userID, isAdmin(c))
+		if err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(r)
+	}
+}
+
+func CancelReservationHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+		if isAdmin(c) && !reservationCampusAllowed(c, db, c.Params("id")) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Reservasi ini milik kampus lain",
+			})
+		}
+
+		if err := CancelReservation(db, c.Params("id"), userID, isAdmin(c)); err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"message": "Reservasi peralatan dibatalkan",
+		})
+	}
+}
+
+func UpdateReservationStatusHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID, _ := c.Locals("user_id").(string)
+
+		var req UpdateReservationStatusRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+		if !reservationCampusAllowed(c, db, c.Params("id")) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Reservasi ini milik kampus lain",
+			})
+		}
+
+		if err := UpdateReservationStatusByAdmin(db, c.Params("id"), req.Status, req.RejectionReason, adminID); err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"message": "Status reservasi peralatan berhasil diperbarui",
+		})
+	}
+}
+
+func CheckoutReservationHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID, _ := c.Locals("user_id").(string)
+		if !reservationCampusAllowed(c, db, c.Params("id")) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Reservasi ini milik kampus lain",
+			})
+		}
+
+		if err := CheckoutReservation(db, c.Params("id"), adminID); err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"message": "Pengambilan peralatan dicatat",
+		})
+	}
+}
+
+func ReturnReservationHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID, _ := c.Locals("user_id").(string)
+
+		var req ReturnRequest
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Format request tidak valid",
+				})
+			}
+		}
+		if !reservationCampusAllowed(c, db, c.Params("id")) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Reservasi ini milik kampus lain",
+			})
+		}
+
+		if err := ReturnReservation(db, c.Params("id"), adminID, req); err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"message": "Pengembalian peralatan dicatat",
+		})
+	}
+}
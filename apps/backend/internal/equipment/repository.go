@@ -0,0 +1,499 @@
+package equipment
+
+import (
+	"database/sql"
+	"time"
+
+	"campus-reservation-backend/internal/database"
+
+	"github.com/lib/pq"
+)
+
+// ==========================
+// MODEL
+// ==========================
+
+type Equipment struct {
+	ID               string  `json:"id"`
+	Name             string  `json:"name"`
+	Description      string  `json:"description"`
+	TotalQuantity    int     `json:"total_quantity"`
+	HomeFacilityID   *string `json:"home_facility_id"`
+	HomeFacilityName string  `json:"home_facility_name,omitempty"`
+	IsActive         bool    `json:"is_active"`
+	// Diisi jika daftar diminta dengan rentang waktu (?start_time=&end_time=)
+	Available *int `json:"available,omitempty"`
+}
+
+type ReservationItem struct {
+	EquipmentID      string `json:"equipment_id"`
+	EquipmentName    string `json:"equipment_name"`
+	Quantity         int    `json:"quantity"`
+	ReturnedQuantity *int   `json:"returned_quantity,omitempty"`
+}
+
+type Reservation struct {
+	ID              string            `json:"id"`
+	BookingID       *string           `json:"booking_id"`
+	UserID          string            `json:"user_id"`
+	UserName        string            `json:"user_name"`
+	StartTime       time.Time         `json:"start_time"`
+	EndTime         time.Time         `json:"end_time"`
+	Purpose         string            `json:"purpose"`
+	Status          string            `json:"status"`
+	RejectionReason string            `json:"rejection_reason,omitempty"`
+	CheckedOutAt    *time.Time        `json:"checked_out_at,omitempty"`
+	ReturnedAt      *time.Time        `json:"returned_at,omitempty"`
+	ReturnNote      string            `json:"return_note,omitempty"`
+	IsOverdue       bool              `json:"is_overdue"`
+	CreatedAt       time.Time         `json:"created_at"`
+	Items           []ReservationItem `json:"items"`
+}
+
+// ==========================
+// INVENTARIS
+// ==========================
+
+func Insert(db *sql.DB, e Equipment, userID string) (string, error) {
+	var id string
+	err := db.QueryRow(`
+		INSERT INTO equipment (name, description, total_quantity, home_facility_id, is_active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, e.Name, e.Description, e.TotalQuantity, e.HomeFacilityID, e.IsActive, userID).Scan(&id)
+	return id, err
+}
+
+func Update(db *sql.DB, id string, e Equipment, userID string) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE equipment
+		SET name = $1, description = $2, total_quantity = $3, home_facility_id = $4, is_active = $5,
+			updated_by = $6, updated_at = NOW()
+		WHERE id = $7 AND deleted_at IS NULL
+	`, e.Name, e.Description, e.TotalQuantity, e.HomeFacilityID, e.IsActive, userID, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// SoftDelete: data lama tetap dibutuhkan oleh riwayat peminjaman
+func SoftDelete(db *sql.DB, id string, userID string) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE equipment SET deleted_at = NOW(), is_active = false, updated_by = $2, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const equipmentSelect = `
+	SELECT e.id, e.name, COALESCE(e.description, ''), e.total_quantity, e.home_facility_id,
+		COALESCE(f.name, ''), e.is_active
+	FROM equipment e
+	LEFT JOIN facilities f ON e.home_facility_id = f.id`
+
+// FindAll: campusID kosong = semua kampus (peralatan tanpa fasilitas asal hanya muncul tanpa filter kampus)
+func FindAll(db *sql.DB, facilityID string, activeOnly bool, campusID string) ([]Equipment, error) {
+	rows, err := db.Query(equipmentSelect+`
+		WHERE e.deleted_at IS NULL
+		  AND ($1::text = '' OR e.home_facility_id::text = $1::text)
+		  AND ($2::bool = false OR e.is_active = true)
+		  AND ($3::text = '' OR f.campus_id::text = $3::text)
+		ORDER BY e.name
+	`, facilityID, activeOnly, campusID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []Equipment{}
+	for rows.Next() {
+		e, err := scanEquipment(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, e)
+	}
+	return items, rows.Err()
+}
+
+func FindByID(db *sql.DB, id string) (Equipment, error) {
+	return scanEquipment(db.QueryRow(equipmentSelect+` WHERE e.id = $1 AND e.deleted_at IS NULL`, id))
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEquipment(row rowScanner) (Equipment, error) {
+	var e Equipment
+	var homeID sql.NullString
+	err := row.Scan(&e.ID, &e.Name, &e.Description, &e.TotalQuantity, &homeID, &e.HomeFacilityName, &e.IsActive)
+	if homeID.Valid {
+		e.HomeFacilityID = &homeID.String
+	}
+	return e, err
+}
+
+// ==========================
+// KETERSEDIAAN (QUANTITY-AWARE)
+// ==========================
+
+// lockEquipment mengunci baris peralatan (urut berdasarkan id agar tidak deadlock) selama transaksi
+// reservasi, sehingga dua reservasi paralel tidak bisa sama-sama mengambil unit terakhir.
+func lockEquipment(tx *sql.Tx, ids []string) (map[string]Equipment, error) {
+	rows, err := tx.Query(`
+		SELECT id, name, total_quantity, is_active
+		FROM equipment
+		WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL
+		ORDER BY id
+		FOR UPDATE
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]Equipment)
+	for rows.Next() {
+		var e Equipment
+		if err := rows.Scan(&e.ID, &e.Name, &e.TotalQuantity, &e.IsActive); err != nil {
+			return nil, err
+		}
+		out[e.ID] = e
+	}
+	return out, rows.Err()
+}
+
+// ReservedQuantities menghitung unit yang sudah dipesan per peralatan pada rentang waktu tertentu.
+// Reservasi pending & approved dihitung; barang yang sudah diambil tapi belum kembali dianggap
+// masih terpakai sampai dikembalikan (meskipun jadwalnya sudah lewat).
+func ReservedQuantities(q database.Querier, ids []string, start, end time.Time, excludeReservationID string) (map[string]int, error) {
+	rows, err := q.Query(`
+		SELECT i.equipment_id, COALESCE(SUM(i.quantity), 0)::int
+		FROM equipment_reservation_items i
+		JOIN equipment_reservations r ON r.id = i.reservation_id
+		WHERE i.equipment_id = ANY($1::uuid[])
+		  AND r.id::text <> $4::text
+		  AND (
+			(r.status IN ('pending', 'approved')
+			  AND $2::timestamptz < CASE WHEN r.checked_out_at IS NOT NULL AND r.returned_at IS NULL
+			                            THEN GREATEST(r.end_time, NOW()) ELSE r.end_time END
+			  AND $3::timestamptz > r.start_time)
+		  )
+		GROUP BY i.equipment_id
+	`, pq.Array(ids), start, end, excludeReservationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]int)
+	for rows.Next() {
+		var id string
+		var qty int
+		if err := rows.Scan(&id, &qty); err != nil {
+			return nil, err
+		}
+		out[id] = qty
+	}
+	return out, rows.Err()
+}
+
+// ==========================
+// RESERVASI
+// ==========================
+
+func insertReservation(tx *sql.Tx, r Reservation) (string, error) {
+	var id string
+	err := tx.QueryRow(`
+		INSERT INTO equipment_reservations (booking_id, user_id, start_time, end_time, purpose, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, r.BookingID, r.UserID, r.StartTime, r.EndTime, r.Purpose, r.Status).Scan(&id)
+	return id, err
+}
+
+func insertReservationItem(tx *sql.Tx, reservationID string, item ReservationItem) error {
+	_, err := tx.Exec(`
+		INSERT INTO equipment_reservation_items (reservation_id, equipment_id, quantity)
+		VALUES ($1, $2, $3)
+	`, reservationID, item.EquipmentID, item.Quantity)
+	return err
+}
+
+const reservationSelect = `
+	SELECT r.id, r.booking_id, r.user_id, u.name, r.start_time, r.end_time, COALESCE(r.purpose, ''),
+		r.status, COALESCE(r.rejection_reason, ''), r.checked_out_at, r.returned_at, COALESCE(r.return_note, ''),
+		(r.checked_out_at IS NOT NULL AND r.returned_at IS NULL AND r.end_time < NOW()),
+		r.created_at
+	FROM equipment_reservations r
+	JOIN users u ON r.user_id = u.id`
+
+// ReservationFilter untuk daftar reservasi (admin melihat semua, user hanya miliknya)
+type ReservationFilter struct {
+	UserID    string
+	BookingID string
+	Status    string // pending | approved | ... | checked_out (sedang dipinjam) | overdue
+	CampusID  string // cakupan admin kampus (lihat reservationInCampus)
+}
+
+// reservationInCampus: reservasi milik kampus jika fasilitas booking-nya di kampus tersebut, atau (peminjaman
+// tanpa booking) salah satu peralatannya berasal dari fasilitas di kampus tersebut. param = placeholder kampus.
+func reservationInCampus(param string) string {
+	return `(
+		EXISTS (
+			SELECT 1 FROM bookings b JOIN facilities bf ON bf.id = b.facility_id
+			WHERE b.id = r.booking_id AND bf.campus_id::text = ` + param + `
+		)
+		OR (r.booking_id IS NULL AND EXISTS (
+			SELECT 1 FROM equipment_reservation_items ci
+			JOIN equipment ce ON ce.id = ci.equipment_id
+			JOIN facilities cf ON cf.id = ce.home_facility_id
+			WHERE ci.reservation_id = r.id AND cf.campus_id::text = ` + param + `
+		))
+	)`
+}
+
+func FindReservations(db *sql.DB, f ReservationFilter) ([]Reservation, error) {
+	rows, err := db.Query(reservationSelect+`
+		WHERE ($1::text = '' OR r.user_id::text = $1::text)
+		  AND ($2::text = '' OR r.booking_id::text = $2::text)
+		  AND (
+			$3::text = ''
+			OR ($3::text = 'checked_out' AND r.checked_out_at IS NOT NULL AND r.returned_at IS NULL)
+			OR ($3::text = 'overdue' AND r.checked_out_at IS NOT NULL AND r.returned_at IS NULL AND r.end_time < NOW())
+			OR r.status = $3::text
+		  )
+		  AND ($4::text = '' OR `+reservationInCampus("$4::text")+`)
+		ORDER BY r.start_time DESC
+		LIMIT 500
+	`, f.UserID, f.BookingID, f.Status, f.CampusID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reservations := []Reservation{}
+	for rows.Next() {
+		r, err := scanReservation(rows)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range reservations {
+		items, err := findReservationItems(db, reservations[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		reservations[i].Items = items
+	}
+	return reservations, nil
+}
+
+func FindReservationByID(db *sql.DB, id string) (Reservation, error) {
+	r, err := scanReservation(db.QueryRow(reservationSelect+` WHERE r.id = $1`, id))
+	if err != nil {
+		return r, err
+	}
+	r.Items, err = findReservationItems(db, id)
+	return r, err
+}
+
+func findReservationItems(q database.Querier, reservationID string) ([]ReservationItem, error) {
+	rows, err := q.Query(`
+		SELECT i.equipment_id, e.name, i.quantity, i.returned_quantity
+		FROM equipment_reservation_items i
+		JOIN equipment e ON e.id = i.equipment_id
+		WHERE i.reservation_id = $1
+		ORDER BY e.name
+	`, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []ReservationItem{}
+	for rows.Next() {
+		var it ReservationItem
+		var returned sql.NullInt64
+		if err := rows.Scan(&it.EquipmentID, &it.EquipmentName, &it.Quantity, &returned); err != nil {
+			return nil, err
+		}
+		if returned.Valid {
+			n := int(returned.Int64)
+			it.ReturnedQuantity = &n
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+func scanReservation(row rowScanner) (Reservation, error) {
+	var r Reservation
+	var bookingID sql.NullString
+	var checkedOutAt, returnedAt sql.NullTime
+
+	err := row.Scan(&r.ID, &bookingID, &r.UserID, &r.UserName, &r.StartTime, &r.EndTime, &r.Purpose,
+		&r.Status, &r.RejectionReason, &checkedOutAt, &returnedAt, &r.ReturnNote, &r.IsOverdue, &r.CreatedAt)
+	if err != nil {
+		return r, err
+	}
+
+	if bookingID.Valid {
+		r.BookingID = &bookingID.String
+	}
+	if checkedOutAt.Valid {
+		r.CheckedOutAt = &checkedOutAt.Time
+	}
+	if returnedAt.Valid {
+		r.ReturnedAt = &returnedAt.Time
+	}
+	return r, nil
+}
+
+// UpdateReservationStatus mengubah status hanya jika status saat ini sesuai (mencegah proses ganda)
+func UpdateReservationStatus(q database.Querier, id, fromStatus, toStatus, reason, actorID string) (int64, error) {
+	res, err := q.Exec(`
+		UPDATE equipment_reservations
+		SET status = $3, rejection_reason = NULLIF($4, ''), updated_by = NULLIF($5, '')::uuid, updated_at = NOW()
+		WHERE id = $1 AND status = $2
+	`, id, fromStatus, toStatus, reason, actorID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// UpdateStatusByBooking menyamakan status reservasi yang menempel pada booking (approve / reject / cancel)
+func UpdateStatusByBooking(q database.Querier, bookingID, fromStatus, toStatus, actorID string) error {
+	_, err := q.Exec(`
+		UPDATE equipment_reservations
+		SET status = $3, updated_by = NULLIF($4, '')::uuid, updated_at = NOW()
+		WHERE booking_id = $1 AND status = $2
+	`, bookingID, fromStatus, toStatus, actorID)
+	return err
+}
+
+// CancelByBooking membatalkan reservasi booking yang masih pending / approved dan barangnya belum diambil
+func CancelByBooking(q database.Querier, bookingID, actorID string) error {
+	_, err := q.Exec(`
+		UPDATE equipment_reservations
+		SET status = 'canceled', updated_by = NULLIF($2, '')::uuid, updated_at = NOW()
+		WHERE booking_id = $1 AND status IN ('pending', 'approved') AND checked_out_at IS NULL
+	`, bookingID, actorID)
+	return err
+}
+
+// MarkCheckedOut mencatat barang sudah diambil (hanya reservasi approved yang belum diambil)
+func MarkCheckedOut(q database.Querier, where string, arg string, actorID string) (int64, error) {
+	res, err := q.Exec(`
+		UPDATE equipment_reservations
+		SET checked_out_at = NOW(), checked_out_by = NULLIF($2, '')::uuid, updated_at = NOW()
+		WHERE `+where+` = $1 AND status = 'approved' AND checked_out_at IS NULL
+	`, arg, actorID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// MarkReturned mencatat barang sudah dikembalikan dan menutup reservasi
+func MarkReturned(q database.Querier, where string, arg string, actorID string, note string) ([]string, error) {
+	rows, err := q.Query(`
+		UPDATE equipment_reservations
+		SET returned_at = NOW(), returned_by = NULLIF($2, '')::uuid, return_note = NULLIF($3, ''),
+			status = 'completed', updated_at = NOW()
+		WHERE `+where+` = $1 AND status = 'approved' AND checked_out_at IS NOT NULL AND returned_at IS NULL
+		RETURNING id
+	`, arg, actorID, note)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// setReturnedQuantity: default seluruh unit kembali, kecuali dicatat lain oleh petugas
+func setReturnedQuantity(q database.Querier, reservationID, equipmentID string, qty *int) error {
+	_, err := q.Exec(`
+		UPDATE equipment_reservation_items
+		SET returned_quantity = COALESCE($3::int, quantity)
+		WHERE reservation_id = $1 AND ($2::text = '' OR equipment_id::text = $2::text)
+	`, reservationID, equipmentID, qty)
+	return err
+}
+
+// bookingWindow mengambil jadwal & pemilik booking untuk reservasi yang menempel ke booking
+func bookingWindow(q database.Querier, bookingID string) (userID, status string, start, end time.Time, err error) {
+	err = q.QueryRow(`
+		SELECT user_id, status::text, start_time, end_time
+		FROM bookings WHERE id = $1 AND deleted_at IS NULL
+	`, bookingID).Scan(&userID, &status, &start, &end)
+	return
+}
+
+// ==========================
+// CAKUPAN KAMPUS
+// ==========================
+
+// FindFacilityCampusID mengembalikan kampus pemilik fasilitas
+func FindFacilityCampusID(db *sql.DB, facilityID string) (string, error) {
+	var campusID string
+	err := db.QueryRow(`
+		SELECT COALESCE(campus_id::text, '') FROM facilities WHERE id::text = $1 AND deleted_at IS NULL
+	`, facilityID).Scan(&campusID)
+	return campusID, err
+}
+
+// FindEquipmentCampusID: kampus dari fasilitas asal peralatan (kosong jika tanpa fasilitas asal)
+func FindEquipmentCampusID(db *sql.DB, id string) (string, error) {
+	var campusID string
+	err := db.QueryRow(`
+		SELECT COALESCE(f.campus_id::text, '')
+		FROM equipment e
+		LEFT JOIN facilities f ON f.id = e.home_facility_id
+		WHERE e.id::text = $1 AND e.deleted_at IS NULL
+	`, id).Scan(&campusID)
+	return campusID, err
+}
+
+// ReservationInCampus memeriksa cakupan kampus satu reservasi
+func ReservationInCampus(db *sql.DB, id, campusID string) (bool, error) {
+	var ok bool
+	err := db.QueryRow(`
+		SELECT `+reservationInCampus("$2::text")+`
+		FROM equipment_reservations r
+		WHERE r.id::text = $1
+	`, id, campusID).Scan(&ok)
+	return ok, err
+}
+
+// FindBookingCampusID: kampus dari fasilitas booking
+func FindBookingCampusID(db *sql.DB, bookingID string) (string, error) {
+	var campusID string
+	err := db.QueryRow(`
+		SELECT COALESCE(f.campus_id::text, '')
+		FROM bookings b
+		JOIN facilities f ON f.id = b.facility_id
+		WHERE b.id::text = $1
+	`, bookingID).Scan(&campusID)
+	return campusID, err
+}
@@ -0,0 +1,476 @@
+package equipment
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"campus-reservation-backend/internal/database"
+)
+
+// ==========================
+// INVENTARIS (ADMIN)
+// ==========================
+
+func validateEquipment(e *Equipment) error {
+	e.Name = strings.TrimSpace(e.Name)
+	e.Description = strings.TrimSpace(e.Description)
+
+	if e.Name == "" {
+		return errors.New("nama peralatan wajib diisi")
+	}
+	if e.TotalQuantity < 0 || e.TotalQuantity > 10000 {
+		return errors.New("jumlah unit tidak valid")
+	}
+	if e.HomeFacilityID != nil && strings.TrimSpace(*e.HomeFacilityID) == "" {
+		e.HomeFacilityID = nil
+	}
+	return nil
+}
+
+func CreateEquipment(db *sql.DB, e Equipment, adminID string) (string, error) {
+	if err := validateEquipment(&e); err != nil {
+		return "", err
+	}
+	id, err := Insert(db, e, adminID)
+	if err != nil {
+		return "", mapEquipmentError(err)
+	}
+	return id, nil
+}
+
+func UpdateEquipment(db *sql.DB, id string, e Equipment, adminID string) error {
+	if err := validateEquipment(&e); err != nil {
+		return err
+	}
+	rows, err := Update(db, id, e, adminID)
+	if err != nil {
+		return mapEquipmentError(err)
+	}
+	if rows == 0 {
+		return errors.New("peralatan tidak ditemukan")
+	}
+	return nil
+}
+
+func DeleteEquipment(db *sql.DB, id string, adminID string) error {
+	rows, err := SoftDelete(db, id, adminID)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("peralatan tidak ditemukan")
+	}
+	return nil
+}
+
+func mapEquipmentError(err error) error {
+	if strings.Contains(err.Error(), "home_facility_id") {
+		return errors.New("fasilitas asal tidak ditemukan")
+	}
+	return err
+}
+
+// ListEquipment menampilkan inventaris. Jika rentang waktu diisi, sisa unit pada rentang tersebut ikut dihitung.
+func ListEquipment(db *sql.DB, facilityID string, activeOnly bool, campusID string, start, end *time.Time) ([]Equipment, error) {
+	items, err := FindAll(db, facilityID, activeOnly, campusID)
+	if err != nil {
+		return nil, err
+	}
+	if start == nil || end == nil || len(items) == 0 {
+		return items, nil
+	}
+	if !start.Before(*end) {
+		return nil, errors.New("waktu mulai harus sebelum waktu selesai")
+	}
+
+	ids := make([]string, len(items))
+	for i, e := range items {
+		ids[i] = e.ID
+	}
+	reserved, err := ReservedQuantities(db, ids, *start, *end, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		available := max(items[i].TotalQuantity-reserved[items[i].ID], 0)
+		items[i].Available = &available
+	}
+	return items, nil
+}
+
+// ==========================
+// RESERVASI
+// ==========================
+
+type ItemRequest struct {
+	EquipmentID string `json:"equipment_id"`
+	Quantity    int    `json:"quantity" example:"1"`
+}
+
+type CreateReservationRequest struct {
+	StartTime string        `json:"start_time"` // YYYY-MM-DDTHH:MM:SS
+	EndTime   string        `json:"end_time"`
+	Purpose   string        `json:"purpose"`
+	Items     []ItemRequest `json:"items"`
+}
+
+// normalizeItems menggabungkan peralatan yang sama dan memvalidasi jumlahnya
+func normalizeItems(items []ItemRequest) ([]ItemRequest, error) {
+	if len(items) == 0 {
+		return nil, errors.New("pilih minimal satu peralatan")
+	}
+
+	merged := make(map[string]int)
+	for _, it := range items {
+		id := strings.TrimSpace(it.EquipmentID)
+		if id == "" {
+			return nil, errors.New("peralatan tidak valid")
+		}
+		if it.Quantity <= 0 {
+			return nil, errors.New("jumlah peralatan minimal 1")
+		}
+		merged[id] += it.Quantity
+	}
+
+	out := make([]ItemRequest, 0, len(merged))
+	for id, qty := range merged {
+		out = append(out, ItemRequest{EquipmentID: id, Quantity: qty})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].EquipmentID < out[j].EquipmentID })
+	return out, nil
+}
+
+func itemIDs(items []ItemRequest) []string {
+	ids := make([]string, len(items))
+	for i, it := range items {
+		ids[i] = it.EquipmentID
+	}
+	return ids
+}
+
+// checkQuantities membandingkan permintaan dengan stok dan unit yang sudah dipesan di rentang waktu yang sama
+func checkQuantities(stock map[string]Equipment, reserved map[string]int, items []ItemRequest) error {
+	for _, it := range items {
+		e, ok := stock[it.EquipmentID]
+		if !ok {
+			return errors.New("peralatan tidak ditemukan")
+		}
+		if !e.IsActive {
+			return fmt.Errorf("peralatan %s sedang tidak tersedia", e.Name)
+		}
+		available := e.TotalQuantity - reserved[it.EquipmentID]
+		if it.Quantity > available {
+			return fmt.Errorf("Peralatan %s hanya tersedia %d unit pada waktu tersebut", e.Name, max(available, 0))
+		}
+	}
+	return nil
+}
+
+// CheckAvailability dipakai sebelum booking ruangan dibuat agar user tidak mendapat booking tanpa peralatan.
+// Pengecekan final tetap dilakukan di dalam transaksi saat reservasi disimpan.
+func CheckAvailability(db *sql.DB, items []ItemRequest, start, end time.Time) error {
+	items, err := normalizeItems(items)
+	if err != nil {
+		return err
+	}
+
+	stock := make(map[string]Equipment)
+	for _, it := range items {
+		e, err := FindByID(db, it.EquipmentID)
+		if err != nil {
+			return errors.New("peralatan tidak ditemukan")
+		}
+		stock[e.ID] = e
+	}
+
+	reserved, err := ReservedQuantities(db, itemIDs(items), start, end, "")
+	if err != nil {
+		return errors.New("gagal mengecek ketersediaan peralatan")
+	}
+	return checkQuantities(stock, reserved, items)
+}
+
+// reserve menyimpan reservasi dalam satu transaksi: baris peralatan dikunci, sisa unit dihitung ulang,
+// lalu reservasi & item disimpan.
+func reserve(db *sql.DB, r Reservation, items []ItemRequest) (string, error) {
+	items, err := normalizeItems(items)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	stock, err := lockEquipment(tx, itemIDs(items))
+	if err != nil {
+		return "", errors.New("gagal mengecek ketersediaan peralatan")
+	}
+	reserved, err := ReservedQuantities(tx, itemIDs(items), r.StartTime, r.EndTime, "")
+	if err != nil {
+		return "", errors.New("gagal mengecek ketersediaan peralatan")
+	}
+	if err := checkQuantities(stock, reserved, items); err != nil {
+		return "", err
+	}
+
+	id, err := insertReservation(tx, r)
+	if err != nil {
+		return "", err
+	}
+	for _, it := range items {
+		if err := insertReservationItem(tx, id, ReservationItem{EquipmentID: it.EquipmentID, Quantity: it.Quantity}); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// CreateReservation membuat peminjaman peralatan tanpa booking ruangan (menunggu persetujuan admin)
+func CreateReservation(db *sql.DB, userID string, start, end time.Time, purpose string, items []ItemRequest) (string, error) {
+	if userID == "" {
+		return "", errors.New("user tidak valid")
+	}
+	if !start.Before(end) {
+		return "", errors.New("waktu mulai harus sebelum waktu selesai")
+	}
+	if end.Before(time.Now()) {
+		return "", errors.New("waktu peminjaman sudah lewat")
+	}
+
+	return reserve(db, Reservation{
+		UserID:    userID,
+		StartTime: start,
+		EndTime:   end,
+		Purpose:   strings.TrimSpace(purpose),
+		Status:    "pending",
+	}, items)
+}
+
+// ReserveForBooking menempelkan peralatan ke booking ruangan. Jadwal mengikuti booking dan
+// status mengikuti booking (pending ikut disetujui bersama ruangan, approved langsung approved).
+func ReserveForBooking(db *sql.DB, bookingID string, userID string, isAdmin bool, items []ItemRequest) (string, error) {
+	ownerID, status, start, end, err := bookingWindow(db, bookingID)
+	if err != nil {
+		return "", errors.New("booking tidak ditemukan")
+	}
+	if !isAdmin && ownerID != userID {
+		return "", errors.New("tidak punya hak mengubah booking ini")
+	}
+	if status != "pending" && status != "approved" {
+		return "", errors.New("peralatan hanya bisa ditambahkan ke booking pending atau approved")
+	}
+	if end.Before(time.Now()) {
+		return "", errors.New("booking sudah selesai")
+	}
+
+	return reserve(db, Reservation{
+		BookingID: &bookingID,
+		UserID:    ownerID,
+		StartTime: start,
+		EndTime:   end,
+		Status:    status,
+	}, items)
+}
+
+func GetReservations(db *sql.DB, f ReservationFilter) ([]Reservation, error) {
+	return FindReservations(db, f)
+}
+
+func GetReservation(db *sql.DB, id string, userID string, isAdmin bool) (Reservation, error) {
+	r, err := FindReservationByID(db, id)
+	if err != nil {
+		return r, errors.New("reservasi peralatan tidak ditemukan")
+	}
+	if !isAdmin && r.UserID != userID {
+		return r, errors.New("tidak punya hak melihat reservasi ini")
+	}
+	return r, nil
+}
+
+// UpdateReservationStatusByAdmin menyetujui / menolak peminjaman berdiri sendiri.
+// Peralatan yang menempel ke booking disetujui bersamaan dengan ruangannya.
+func UpdateReservationStatusByAdmin(db *sql.DB, id string, newStatus string, reason string, adminID string) error {
+	if newStatus != "approved" && newStatus != "rejected" {
+		return errors.New("status tidak valid")
+	}
+
+	r, err := FindReservationByID(db, id)
+	if err != nil {
+		return errors.New("reservasi peralatan tidak ditemukan")
+	}
+	if r.BookingID != nil {
+		return errors.New("status peralatan mengikuti persetujuan booking ruangan")
+	}
+	if newStatus == "approved" {
+		reason = ""
+	}
+
+	rows, err := UpdateReservationStatus(db, id, "pending", newStatus, reason, adminID)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("status reservasi tidak bisa diubah karena sudah diproses")
+	}
+	return nil
+}
+
+// CancelReservation: pemilik boleh membatalkan selama barang belum diambil
+func CancelReservation(db *sql.DB, id string, userID string, isAdmin bool) error {
+	r, err := FindReservationByID(db, id)
+	if err != nil {
+		return errors.New("reservasi peralatan tidak ditemukan")
+	}
+	if !isAdmin && r.UserID != userID {
+		return errors.New("tidak punya hak membatalkan reservasi ini")
+	}
+	if r.CheckedOutAt != nil {
+		return errors.New("peralatan sudah diambil, lakukan pengembalian terlebih dahulu")
+	}
+	if r.Status != "pending" && r.Status != "approved" {
+		return errors.New("reservasi ini sudah tidak aktif")
+	}
+
+	rows, err := UpdateReservationStatus(db, id, r.Status, "canceled", "", userID)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("status reservasi sudah berubah, silakan muat ulang")
+	}
+	return nil
+}
+
+// ==========================
+// PENGAMBILAN & PENGEMBALIAN (PETUGAS)
+// ==========================
+
+type ReturnItem struct {
+	EquipmentID      string `json:"equipment_id"`
+	ReturnedQuantity int    `json:"returned_quantity" example:"1"`
+}
+
+type ReturnRequest struct {
+	// Opsional: isi jika jumlah yang kembali tidak lengkap; item yang tidak disebut dianggap kembali semua
+	Items []ReturnItem `json:"items"`
+	Note  string       `json:"note" example:"1 kabel HDMI rusak"`
+}
+
+func CheckoutReservation(db *sql.DB, id string, adminID string) error {
+	r, err := FindReservationByID(db, id)
+	if err != nil {
+		return errors.New("reservasi peralatan tidak ditemukan")
+	}
+	if r.Status != "approved" {
+		return errors.New("hanya reservasi yang sudah disetujui yang bisa diambil")
+	}
+	if r.CheckedOutAt != nil {
+		return errors.New("peralatan sudah diambil")
+	}
+
+	if _, err := MarkCheckedOut(db, "id", id, adminID); err != nil {
+		return errors.New("gagal mencatat pengambilan peralatan")
+	}
+	return nil
+}
+
+func ReturnReservation(db *sql.DB, id string, adminID string, req ReturnRequest) error {
+	r, err := FindReservationByID(db, id)
+	if err != nil {
+		return errors.New("reservasi peralatan tidak ditemukan")
+	}
+	if r.CheckedOutAt == nil {
+		return errors.New("peralatan belum diambil")
+	}
+	if r.ReturnedAt != nil {
+		return errors.New("peralatan sudah dikembalikan")
+	}
+
+	quantities := make(map[string]int)
+	for _, it := range r.Items {
+		quantities[it.EquipmentID] = it.Quantity
+	}
+	for _, it := range req.Items {
+		qty, ok := quantities[it.EquipmentID]
+		if !ok {
+			return errors.New("peralatan tidak termasuk dalam reservasi ini")
+		}
+		if it.ReturnedQuantity < 0 || it.ReturnedQuantity > qty {
+			return fmt.Errorf("jumlah kembali harus antara 0 dan %d", qty)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids, err := MarkReturned(tx, "id", id, adminID, strings.TrimSpace(req.Note))
+	if err != nil {
+		return errors.New("gagal mencatat pengembalian peralatan")
+	}
+	if len(ids) == 0 {
+		return errors.New("status reservasi sudah berubah, silakan muat ulang")
+	}
+
+	if err := setReturnedQuantity(tx, id, "", nil); err != nil {
+		return err
+	}
+	for _, it := range req.Items {
+		qty := it.ReturnedQuantity
+		if err := setReturnedQuantity(tx, id, it.EquipmentID, &qty); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ==========================
+// SINKRONISASI DENGAN BOOKING RUANGAN
+// ==========================
+// Dipanggil dari package booking. Kegagalan tidak membatalkan proses booking, hanya dicatat oleh pemanggil.
+
+// SyncBookingStatus: approve / reject booking ikut mengubah reservasi peralatan yang masih pending.
+// Cancel juga melepas reservasi yang sudah approved (booking approved bisa dibatalkan) selama barang belum diambil.
+func SyncBookingStatus(q database.Querier, bookingID string, newStatus string, actorID string) error {
+	switch newStatus {
+	case "approved", "rejected":
+		return UpdateStatusByBooking(q, bookingID, "pending", newStatus, actorID)
+	case "canceled":
+		return CancelByBooking(q, bookingID, actorID)
+	}
+	return nil
+}
+
+// CheckoutForBooking: check-in ruangan = peralatan diserahkan ke pemesan
+func CheckoutForBooking(q database.Querier, bookingID string, actorID string) error {
+	_, err := MarkCheckedOut(q, "booking_id", bookingID, actorID)
+	return err
+}
+
+// ReturnForBooking: check-out ruangan = peralatan dikembalikan lengkap
+func ReturnForBooking(q database.Querier, bookingID string, actorID string) error {
+	ids, err := MarkReturned(q, "booking_id", bookingID, actorID, "")
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := setReturnedQuantity(q, id, "", nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,10 +2,16 @@ package facility
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"path/filepath"
+	"log"
+	"slices"
 	"strconv"
-	"time"
+	"strings"
+
+	"campus-reservation-backend/internal/auth"
+	"campus-reservation-backend/internal/media"
+	"campus-reservation-backend/internal/storage"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -18,13 +24,20 @@ type ToggleStatusReq struct {
 	IsActive bool `json:"is_active" example:"true"`
 }
 
+type WalkUpPolicyReq struct {
+	AllowWalkUp      bool `json:"allow_walk_up" example:"true"`
+	WalkUpMaxMinutes int  `json:"walk_up_max_minutes" example:"60"`
+}
+
 // ==========================
 // HELPER: PROCESS UPLOADS
 // ==========================
-func processUploads(c *fiber.Ctx) []string {
+// processUploads memproses foto upload (maksimal 4) menjadi varian WebP; photo_url menyimpan URL varian large.
+// Jika satu foto ditolak, foto yang sudah tersimpan dari request yang sama ikut dibersihkan.
+func processUploads(db *sql.DB, c *fiber.Ctx) ([]string, error) {
 	form, err := c.MultipartForm()
 	if err != nil {
-		return []string{}
+		return []string{}, nil
 	}
 
 	files := form.File["photos"]
@@ -35,15 +48,154 @@ func processUploads(c *fiber.Ctx) []string {
 			break
 		}
 
-		filename := fmt.Sprintf("%d-%d-%s", time.Now().Unix(), i, file.Filename)
-		savePath := filepath.Join("./uploads", filename)
+		v, err := media.FacilityPhoto.SaveFile(file)
+		if err != nil {
+			releasePhotos(db, photoURLs, nil)
+			if media.IsInvalid(err) {
+				return nil, fmt.Errorf("foto %s: %w", file.Filename, err)
+			}
+			log.Printf("Gagal menyimpan foto fasilitas %s: %v\n", file.Filename, err)
+			return nil, errors.New("gagal menyimpan foto fasilitas")
+		}
+		photoURLs = append(photoURLs, v.Large)
+	}
+
+	return photoURLs, nil
+}
+
+// uploadStatus: foto yang ditolak = 400, kegagalan storage = 500
+func uploadStatus(err error) int {
+	if media.IsInvalid(err) {
+		return 400
+	}
+	return 500
+}
 
-		if err := c.SaveFile(file, savePath); err == nil {
-			photoURLs = append(photoURLs, fmt.Sprintf("/uploads/%s", filename))
+// releasePhotos menghapus foto yang tidak lagi dipakai: foto lama yang diganti, atau upload baru yang gagal disimpan
+func releasePhotos(db *sql.DB, photos, keep []string) {
+	var unused []string
+	for _, url := range photos {
+		if !slices.Contains(keep, url) {
+			unused = append(unused, url)
 		}
 	}
+	storage.Release(db, unused...)
+}
+
+// ==========================
+// HELPER: ATRIBUT DARI FORM
+// ==========================
 
-	return photoURLs
+// formValues mengambil semua nilai sebuah field (multipart maupun urlencoded)
+func formValues(c *fiber.Ctx, key string) ([]string, bool) {
+	if form, err := c.MultipartForm(); err == nil {
+		vals, ok := form.Value[key]
+		return vals, ok
+	}
+	args := c.Request().PostArgs()
+	if !args.Has(key) {
+		return nil, false
+	}
+	var vals []string
+	for _, v := range args.PeekMulti(key) {
+		vals = append(vals, string(v))
+	}
+	return vals, true
+}
+
+// applyAttributeForm mengisi kategori, amenities, aksesibilitas, gedung & lantai dari form.
+// Field yang tidak dikirim tidak diubah, sehingga form lama (tanpa field ini) tetap aman dipakai untuk PUT.
+func applyAttributeForm(c *fiber.Ctx, f *Facility) error {
+	if vals, ok := formValues(c, "category"); ok && len(vals) > 0 {
+		f.Category = vals[0]
+	}
+	if vals, ok := formValues(c, "amenities"); ok {
+		// Boleh dikirim sebagai beberapa field "amenities" atau satu field dipisah koma
+		f.Amenities = nil
+		for _, v := range vals {
+			f.Amenities = append(f.Amenities, strings.Split(v, ",")...)
+		}
+	}
+	if vals, ok := formValues(c, "floor_id"); ok && len(vals) > 0 {
+		// Kosong = lepas dari hierarki gedung
+		if id := strings.TrimSpace(vals[0]); id != "" {
+			f.FloorID = &id
+		} else {
+			f.FloorID = nil
+		}
+	}
+	if vals, ok := formValues(c, "building"); ok && len(vals) > 0 {
+		f.Building = vals[0]
+	}
+	if vals, ok := formValues(c, "floor"); ok && len(vals) > 0 {
+		if strings.TrimSpace(vals[0]) == "" {
+			f.Floor = nil
+		} else {
+			n, err := strconv.Atoi(strings.TrimSpace(vals[0]))
+			if err != nil {
+				return errors.New("lantai harus berupa angka")
+			}
+			f.Floor = &n
+		}
+	}
+
+	for key, dst := range map[string]*bool{
+		"wheelchair_accessible": &f.WheelchairAccessible,
+		"accessible_restroom":   &f.AccessibleRestroom,
+		"hearing_loop":          &f.HearingLoop,
+		"requires_payment":      &f.RequiresPayment,
+		"allow_guest_booking":   &f.AllowGuestBooking,
+	} {
+		if vals, ok := formValues(c, key); ok && len(vals) > 0 {
+			v, err := strconv.ParseBool(vals[0])
+			if err != nil {
+				return fmt.Errorf("%s harus bernilai true atau false", key)
+			}
+			*dst = v
+		}
+	}
+	return nil
+}
+
+// applyCampusForm menentukan kampus pemilik fasilitas.
+// Admin kampus selalu membuat / mengelola fasilitas di kampusnya sendiri; admin pusat boleh memilih lewat field campus_id.
+// Jika fasilitas ditempatkan di lantai, kampus mengikuti gedung lantai tersebut.
+func applyCampusForm(db *sql.DB, c *fiber.Ctx, f *Facility) error {
+	scope := auth.AdminCampusID(c)
+	if vals, ok := formValues(c, "campus_id"); ok && len(vals) > 0 {
+		id := strings.TrimSpace(vals[0])
+		if scope != "" && id != scope {
+			return errors.New("admin kampus hanya dapat mengelola fasilitas di kampusnya sendiri")
+		}
+		f.CampusID = id
+	}
+	if scope != "" {
+		f.CampusID = scope
+	}
+
+	if f.FloorID != nil {
+		floorCampus, err := FindFloorCampusID(db, *f.FloorID)
+		if err != nil {
+			return errors.New("lantai tidak ditemukan")
+		}
+		if scope != "" && floorCampus != scope {
+			return errors.New("lantai tersebut berada di kampus lain")
+		}
+		f.CampusID = floorCampus
+	}
+	return nil
+}
+
+// findScopedFacility mengambil fasilitas dan memastikan admin kampus tidak mengelola fasilitas kampus lain
+func findScopedFacility(db *sql.DB, c *fiber.Ctx, id string) (Facility, int, error) {
+	f, err := FindByID(db, id)
+	if err != nil {
+		return f, 404, errors.New("Fasilitas tidak ditemukan")
+	}
+	if !auth.CampusAllowed(c, f.CampusID) {
+		return f, 403, errors.New("Fasilitas ini bukan milik kampus Anda")
+	}
+	return f, 200, nil
 }
 
 // ==========================
@@ -61,6 +213,17 @@ func processUploads(c *fiber.Ctx) []string {
 // @Param        location     formData  string  true  "Lokasi"
 // @Param        capacity     formData  int     true  "Kapasitas"
 // @Param        price        formData  number  true  "Harga per Jam"
+// @Param        category     formData  string  false "Kategori (lab, auditorium, discussion_room, workshop, classroom, sport, other)"
+// @Param        amenities    formData  string  false "Amenities, dipisah koma (projector,whiteboard,ac)"
+// @Param        floor_id     formData  string  false "ID Lantai (hierarki kampus -> gedung -> lantai)"
+// @Param        campus_id    formData  string  false "ID Kampus (admin kampus otomatis memakai kampusnya sendiri)"
+// @Param        building     formData  string  false "Gedung"
+// @Param        floor        formData  int     false "Lantai"
+// @Param        wheelchair_accessible  formData  bool  false "Akses kursi roda"
+// @Param        accessible_restroom    formData  bool  false "Toilet difabel"
+// @Param        hearing_loop           formData  bool  false "Hearing loop"
+// @Param        requires_payment       formData  bool  false "Booking disetujui setelah invoice dibayar"
+// @Param        allow_guest_booking    formData  bool  false "Organisasi luar kampus boleh mengajukan booking tamu"
 // @Param        photos       formData  []file  false "Upload Foto (Max 4)" collectionFormat(multi)
 // @Success      201  {object}  map[string]string
 // @Failure      400  {object}  map[string]string
@@ -77,7 +240,10 @@ func CreateHandler(db *sql.DB) fiber.Handler {
 		capacity, _ := strconv.Atoi(c.FormValue("capacity"))
 		price, _ := strconv.ParseFloat(c.FormValue("price"), 64)
 
-		photoURLs := processUploads(c)
+		photoURLs, err := processUploads(db, c)
+		if err != nil {
+			return c.Status(uploadStatus(err)).JSON(fiber.Map{"error": err.Error()})
+		}
 
 		f := Facility{
 			Name:        name,
@@ -87,8 +253,15 @@ func CreateHandler(db *sql.DB) fiber.Handler {
 			Price:       price,
 			PhotoURL:    photoURLs,
 		}
+		if err := applyAttributeForm(c, &f); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err := applyCampusForm(db, c, &f); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
 
-		if err := Insert(db, f, userID); err != nil {
+		if err := CreateFacility(db, f, userID); err != nil {
+			releasePhotos(db, photoURLs, nil)
 			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 		}
 
@@ -101,24 +274,130 @@ func CreateHandler(db *sql.DB) fiber.Handler {
 // ==========================
 
 // @Summary      Lihat Semua Fasilitas
-// @Description  Menampilkan daftar semua fasilitas yang tersedia.
+// @Description  Menampilkan daftar fasilitas dengan filter, pencarian full-text, sort dan pagination.
+// @Description  Jika page / limit dikirim, total data ada di header X-Total-Count dan X-Total-Pages.
 // @Tags         Facilities
 // @Produce      json
 // @Security     BearerAuth
+// @Param        q                      query  string  false  "Kata kunci (nama, lokasi, deskripsi)"
+// @Param        category               query  string  false  "Kategori"
+// @Param        amenities              query  string  false  "Amenities wajib, dipisah koma (projector,ac)"
+// @Param        building               query  string  false  "Gedung"
+// @Param        floor                  query  int     false  "Lantai"
+// @Param        campus_id              query  string  false  "ID Kampus"
+// @Param        building_id            query  string  false  "ID Gedung"
+// @Param        floor_id               query  string  false  "ID Lantai"
+// @Param        min_capacity           query  int     false  "Kapasitas minimal"
+// @Param        max_price              query  number  false  "Harga maksimal"
+// @Param        wheelchair_accessible  query  bool    false  "Hanya yang ramah kursi roda"
+// @Param        accessible_restroom    query  bool    false  "Hanya yang memiliki toilet difabel"
+// @Param        hearing_loop           query  bool    false  "Hanya yang memiliki hearing loop"
+// @Param        active                 query  bool    false  "Hanya fasilitas aktif"
+// @Param        sort                   query  string  false  "name | capacity | price | created_at | relevance"
+// @Param        order                  query  string  false  "asc | desc"
+// @Param        page                   query  int     false  "Halaman (mulai 1)"
+// @Param        limit                  query  int     false  "Jumlah per halaman (default 20, maks 100)"
 // @Success      200  {array}   Facility
+// @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /facilities [get]
 func ListHandler(db *sql.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		data, err := FindAll(db)
+		filter := FacilityFilter{
+			Query:                c.Query("q"),
+			Category:             c.Query("category"),
+			Building:             c.Query("building"),
+			CampusID:             c.Query("campus_id"),
+			BuildingID:           c.Query("building_id"),
+			FloorID:              c.Query("floor_id"),
+			MinCapacity:          c.QueryInt("min_capacity", 0),
+			WheelchairAccessible: c.QueryBool("wheelchair_accessible", false),
+			AccessibleRestroom:   c.QueryBool("accessible_restroom", false),
+			HearingLoop:          c.QueryBool("hearing_loop", false),
+			ActiveOnly:           c.QueryBool("active", false),
+			Sort:                 c.Query("sort"),
+			Desc:                 strings.EqualFold(c.Query("order"), "desc"),
+		}
+		// User boleh melihat & booking lintas kampus; admin kampus hanya melihat fasilitas kampusnya
+		if role, _ := c.Locals("role").(string); role == "admin" {
+			filter.CampusID = auth.CampusFilter(c)
+		}
+		if amenities := c.Query("amenities"); amenities != "" {
+			filter.Amenities = strings.Split(amenities, ",")
+		}
+		if v := c.Query("floor"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "lantai harus berupa angka"})
+			}
+			filter.Floor = &n
+		}
+		if v := c.Query("max_price"); v != "" {
+			p, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "harga maksimal harus berupa angka"})
+			}
+			filter.MaxPrice = &p
+		}
+
+		// Pagination hanya aktif jika diminta, agar halaman lama yang memuat semua data tetap berfungsi
+		paginate := c.Query("page") != "" || c.Query("limit") != ""
+		page := c.QueryInt("page", 1)
+		if page < 1 {
+			page = 1
+		}
+		limit := c.QueryInt("limit", 20)
+		if limit <= 0 || limit > 100 {
+			limit = 20
+		}
+		if paginate {
+			filter.Limit = limit
+			filter.Offset = (page - 1) * limit
+		}
+
+		data, total, err := SearchFacilities(db, filter)
 		if err != nil {
+			if strings.Contains(err.Error(), "tidak valid") {
+				return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+			}
 			return c.Status(500).JSON(fiber.Map{"error": "Gagal ambil data"})
 		}
+
+		if paginate {
+			c.Set("X-Total-Count", strconv.Itoa(total))
+			c.Set("X-Total-Pages", strconv.Itoa((total+limit-1)/limit))
+			c.Set("X-Page", strconv.Itoa(page))
+		}
 		return c.JSON(data)
 	}
 }
 
+// ==========================
+// DAFTAR AMENITIES & KATEGORI
+// ==========================
+
+// @Summary      Pilihan Filter Fasilitas
+// @Description  Menampilkan kategori yang valid dan amenities yang dipakai beserta jumlah fasilitasnya.
+// @Tags         Facilities
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]string
+// @Router       /facilities/filters [get]
+func FiltersHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		amenities, err := FindAmenities(db)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Gagal ambil data"})
+		}
+		return c.JSON(fiber.Map{
+			"categories": Categories,
+			"amenities":  amenities,
+		})
+	}
+}
+
 // ==========================
 // GET ONE DETAIL
 // ==========================
@@ -164,6 +443,14 @@ func GetOneHandler(db *sql.DB) fiber.Handler {
 // @Param        location     formData  string  true  "Lokasi (Wajib)"
 // @Param        capacity     formData  int     true  "Kapasitas (Wajib)"
 // @Param        price        formData  number  true  "Harga (Wajib)"
+// @Param        category     formData  string  false "Kategori (jika tidak dikirim, nilai lama dipakai)"
+// @Param        amenities    formData  string  false "Amenities, dipisah koma (jika tidak dikirim, nilai lama dipakai)"
+// @Param        floor_id     formData  string  false "ID Lantai (kosongkan untuk melepas dari gedung)"
+// @Param        building     formData  string  false "Gedung"
+// @Param        floor        formData  int     false "Lantai"
+// @Param        wheelchair_accessible  formData  bool  false "Akses kursi roda"
+// @Param        accessible_restroom    formData  bool  false "Toilet difabel"
+// @Param        hearing_loop           formData  bool  false "Hearing loop"
 // @Param        photos       formData  []file  false "Foto Baru (Opsional, jika diisi akan menimpa yang lama)" collectionFormat(multi)
 // @Success      200  {object}  map[string]string
 // @Failure      400  {object}  map[string]string
@@ -174,9 +461,9 @@ func UpdateHandler(db *sql.DB) fiber.Handler {
 		id := c.Params("id")
 		userID := c.Locals("user_id").(string)
 
-		oldData, err := FindByID(db, id)
+		oldData, status, err := findScopedFacility(db, c, id)
 		if err != nil {
-			return c.Status(404).JSON(fiber.Map{"error": "Fasilitas tidak ditemukan"})
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
 		}
 
 		// Karena ini PUT, kita asumsikan semua data dikirim ulang.
@@ -187,7 +474,10 @@ func UpdateHandler(db *sql.DB) fiber.Handler {
 		capacity, _ := strconv.Atoi(c.FormValue("capacity"))
 		price, _ := strconv.ParseFloat(c.FormValue("price"), 64)
 
-		newPhotos := processUploads(c)
+		newPhotos, err := processUploads(db, c)
+		if err != nil {
+			return c.Status(uploadStatus(err)).JSON(fiber.Map{"error": err.Error()})
+		}
 
 		// Logika foto tetap sama (hanya ganti jika ada upload baru)
 		finalPhotos := oldData.PhotoURL
@@ -202,11 +492,32 @@ func UpdateHandler(db *sql.DB) fiber.Handler {
 			Capacity:    capacity,
 			Price:       price,
 			PhotoURL:    finalPhotos,
+
+			// Atribut yang tidak dikirim di form tetap memakai nilai lama
+			Category:             oldData.Category,
+			Amenities:            oldData.Amenities,
+			WheelchairAccessible: oldData.WheelchairAccessible,
+			AccessibleRestroom:   oldData.AccessibleRestroom,
+			HearingLoop:          oldData.HearingLoop,
+			Building:             oldData.Building,
+			Floor:                oldData.Floor,
+			FloorID:              oldData.FloorID,
+			CampusID:             oldData.CampusID,
+			RequiresPayment:      oldData.RequiresPayment,
+			AllowGuestBooking:    oldData.AllowGuestBooking,
+		}
+		if err := applyAttributeForm(c, &newData); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err := applyCampusForm(db, c, &newData); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 		}
 
-		if err := Update(db, id, newData, userID); err != nil {
+		if err := UpdateFacility(db, id, newData, userID); err != nil {
+			releasePhotos(db, newPhotos, oldData.PhotoURL)
 			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 		}
+		releasePhotos(db, oldData.PhotoURL, finalPhotos)
 
 		return c.JSON(fiber.Map{"message": "Fasilitas berhasil diperbarui"})
 	}
@@ -229,8 +540,11 @@ func UpdateHandler(db *sql.DB) fiber.Handler {
 func DeleteHandler(db *sql.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		id := c.Params("id")
+		if _, status, err := findScopedFacility(db, c, id); err != nil {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
 
-		if err := HardDelete(db, id); err != nil {
+		if err := DeleteFacility(db, id); err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.JSON(fiber.Map{"message": "Fasilitas dihapus permanen"})
@@ -265,7 +579,11 @@ func ToggleStatusHandler(db *sql.DB) fiber.Handler {
 			return c.Status(400).JSON(fiber.Map{"error": "Format JSON salah"})
 		}
 
-		if err := ToggleActive(db, id, req.IsActive, userID); err != nil {
+		if _, status, err := findScopedFacility(db, c, id); err != nil {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if err := ToggleFacilityStatus(db, id, req.IsActive, userID); err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 		}
 
@@ -277,3 +595,41 @@ func ToggleStatusHandler(db *sql.DB) fiber.Handler {
 		return c.JSON(fiber.Map{"message": msg})
 	}
 }
+
+// ==========================
+// KEBIJAKAN WALK-UP
+// ==========================
+
+// @Summary      Atur Kebijakan Walk-Up
+// @Description  Mengizinkan / melarang booking instan dari layar pintu dan mengatur durasi maksimalnya (30/60 menit). Hanya Admin.
+// @Tags         Facilities
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      string           true  "ID Fasilitas"
+// @Param        request  body      WalkUpPolicyReq  true  "Payload JSON"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Router       /facilities/{id}/walk-up-policy [patch]
+func UpdateWalkUpPolicyHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		userID := c.Locals("user_id").(string)
+
+		var req WalkUpPolicyReq
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Format JSON salah"})
+		}
+
+		if _, status, err := findScopedFacility(db, c, id); err != nil {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if err := SetWalkUpPolicy(db, id, req.AllowWalkUp, req.WalkUpMaxMinutes, userID); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"message": "Kebijakan walk-up berhasil diperbarui"})
+	}
+}
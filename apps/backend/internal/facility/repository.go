@@ -3,6 +3,9 @@ package facility
 import (
 	"database/sql"
 
+	"campus-reservation-backend/internal/database"
+	"campus-reservation-backend/internal/media"
+
 	"github.com/lib/pq" // WAJIB: Library untuk handle Array PostgreSQL
 )
 
@@ -10,101 +13,306 @@ import (
 // MODEL FACILITY
 // ==========================
 type Facility struct {
-	ID            string   `json:"id"`
-	Name          string   `json:"name"`
-	Description   string   `json:"description"`
-	Location      string   `json:"location"`
-	Capacity      int      `json:"capacity"`
-	Price         float64  `json:"price"`
-	PhotoURL      []string `json:"photo_url"` // UBAH: string -> []string (Array)
-	IsActive      bool     `json:"is_active"`
-	CreatedByName string   `json:"created_by_name"`
-	UpdatedByName string   `json:"updated_by_name"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Location    string   `json:"location"`
+	Capacity    int      `json:"capacity"`
+	Price       float64  `json:"price"`
+	PhotoURL    []string `json:"photo_url"` // UBAH: string -> []string (Array)
+	// URL thumb/medium/large per foto (urutan sama dengan photo_url), diturunkan dari photo_url
+	PhotoVariants    []media.Variants `json:"photo_variants"`
+	IsActive         bool             `json:"is_active"`
+	AllowWalkUp      bool             `json:"allow_walk_up"`       // Boleh booking instan dari layar pintu
+	WalkUpMaxMinutes int              `json:"walk_up_max_minutes"` // 30 atau 60
+	CreatedByName    string           `json:"created_by_name"`
+	UpdatedByName    string           `json:"updated_by_name"`
+
+	// Atribut terstruktur untuk pencarian & filter
+	Category             string   `json:"category"`  // lihat Categories
+	Amenities            []string `json:"amenities"` // tag: projector, whiteboard, ac, ...
+	WheelchairAccessible bool     `json:"wheelchair_accessible"`
+	AccessibleRestroom   bool     `json:"accessible_restroom"`
+	HearingLoop          bool     `json:"hearing_loop"`
+	Building             string   `json:"building"` // nama gedung (dari hierarki jika floor_id diisi)
+	Floor                *int     `json:"floor"`
+
+	// Posisi di hierarki kampus -> gedung -> lantai
+	FloorID    *string `json:"floor_id"`
+	BuildingID string  `json:"building_id"`
+	CampusID   string  `json:"campus_id"` // tenant pemilik fasilitas (mengikuti gedung jika floor_id diisi)
+	CampusName string  `json:"campus_name"`
+
+	// true = booking baru bisa disetujui setelah invoice dibayar
+	RequiresPayment bool `json:"requires_payment"`
+	// true = organisasi luar kampus boleh mengajukan booking tamu
+	AllowGuestBooking bool `json:"allow_guest_booking"`
+}
+
+// Categories adalah kategori fasilitas yang valid (sama dengan CHECK di database)
+var Categories = []string{"lab", "auditorium", "discussion_room", "workshop", "classroom", "sport", "other"}
+
+// FacilityFilter adalah parameter pencarian daftar fasilitas
+type FacilityFilter struct {
+	Query                string // full-text search nama, lokasi & deskripsi
+	Category             string
+	Amenities            []string // semua tag harus dimiliki
+	Building             string
+	Floor                *int
+	CampusID             string
+	BuildingID           string
+	FloorID              string
+	MinCapacity          int
+	MaxPrice             *float64
+	WheelchairAccessible bool // true = hanya yang memenuhi
+	AccessibleRestroom   bool
+	HearingLoop          bool
+	ActiveOnly           bool
+	Sort                 string // name | capacity | price | created_at | relevance
+	Desc                 bool
+	Limit                int // 0 = tanpa batas
+	Offset               int
 }
 
 // ==========================
 // INSERT
 // ==========================
-func Insert(db *sql.DB, f Facility, userID string) error {
+func Insert(q database.Querier, f Facility, userID string) (string, error) {
 	// Gunakan pq.Array() untuk menyimpan slice Go ke kolom text[] PostgreSQL
-	_, err := db.Exec(`
-		INSERT INTO facilities (name, description, location, capacity, price, photo_url, created_by)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`, f.Name, f.Description, f.Location, f.Capacity, f.Price, pq.Array(f.PhotoURL), userID)
-	return err
+	var id string
+	err := q.QueryRow(`
+		INSERT INTO facilities (name, description, location, capacity, price, photo_url, created_by,
+			category, amenities, wheelchair_accessible, accessible_restroom, hearing_loop, building, floor, floor_id, campus_id,
+			requires_payment, allow_guest_booking)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), $14, $15, NULLIF($16, '')::uuid, $17, $18)
+		RETURNING id
+	`, f.Name, f.Description, f.Location, f.Capacity, f.Price, pq.Array(f.PhotoURL), userID,
+		f.Category, pq.Array(nonNilStrings(f.Amenities)), f.WheelchairAccessible, f.AccessibleRestroom, f.HearingLoop, f.Building, f.Floor,
+		f.FloorID, f.CampusID, f.RequiresPayment, f.AllowGuestBooking).Scan(&id)
+	return id, err
 }
 
 // ==========================
 // GET ALL (JOIN USERS)
 // ==========================
 func FindAll(db *sql.DB) ([]Facility, error) {
+	facilities, _, err := Search(db, FacilityFilter{})
+	return facilities, err
+}
+
+// ==========================
+// SEARCH (FILTER, FULL-TEXT, SORT, PAGINATION)
+// ==========================
+// Mengembalikan satu halaman data beserta total seluruh data yang cocok (untuk pagination)
+func Search(db *sql.DB, f FacilityFilter) ([]Facility, int, error) {
+	// Kolom sort dipilih dari whitelist, tidak pernah dari input langsung
+	orderBy := "f.created_at DESC"
+	dir := "ASC"
+	if f.Desc {
+		dir = "DESC"
+	}
+	switch f.Sort {
+	case "name":
+		orderBy = "LOWER(f.name) " + dir
+	case "capacity":
+		orderBy = "f.capacity " + dir + ", LOWER(f.name)"
+	case "price":
+		orderBy = "COALESCE(f.price, 0) " + dir + ", LOWER(f.name)"
+	case "created_at":
+		orderBy = "f.created_at " + dir
+	case "relevance":
+		if f.Query != "" {
+			orderBy = "ts_rank(f.search_vector, websearch_to_tsquery('simple', $1)) DESC, LOWER(f.name)"
+		}
+	}
+
+	limit := sql.NullInt64{Int64: int64(f.Limit), Valid: f.Limit > 0}
+
+	// FROM + WHERE dipakai bersama oleh query data & query total
+	from := `
+		FROM facilities f
+		LEFT JOIN users u_cre ON f.created_by = u_cre.id
+		LEFT JOIN users u_upd ON f.updated_by = u_upd.id
+		LEFT JOIN floors fl ON f.floor_id = fl.id
+		LEFT JOIN buildings bld ON fl.building_id = bld.id
+		LEFT JOIN campuses cmp ON f.campus_id = cmp.id
+		WHERE f.deleted_at IS NULL
+		  AND ($1::text = '' OR f.search_vector @@ websearch_to_tsquery('simple', $1::text))
+		  AND ($2::text = '' OR f.category = $2::text)
+		  AND f.amenities @> $3::text[]
+		  AND ($4::text = '' OR LOWER(COALESCE(bld.name, f.building)) = LOWER($4::text))
+		  AND ($5::int IS NULL OR COALESCE(fl.level, f.floor) = $5::int)
+		  AND f.capacity >= $6::int
+		  AND ($7::numeric IS NULL OR COALESCE(f.price, 0) <= $7::numeric)
+		  AND ($8::bool = false OR f.wheelchair_accessible)
+		  AND ($9::bool = false OR f.accessible_restroom)
+		  AND ($10::bool = false OR f.hearing_loop)
+		  AND ($11::bool = false OR f.is_active)
+		  AND ($12::text = '' OR f.campus_id::text = $12::text)
+		  AND ($13::text = '' OR bld.id::text = $13::text)
+		  AND ($14::text = '' OR f.floor_id::text = $14::text)`
+	args := []interface{}{f.Query, f.Category, pq.Array(nonNilStrings(f.Amenities)), f.Building, f.Floor, f.MinCapacity, f.MaxPrice,
+		f.WheelchairAccessible, f.AccessibleRestroom, f.HearingLoop, f.ActiveOnly, f.CampusID, f.BuildingID, f.FloorID}
+
+	// Total dihitung terpisah agar tetap benar walau halaman yang diminta melewati data terakhir
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*)`+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
 	// Tambahkan COALESCE(..., '{}') agar jika NULL, terbaca sebagai array kosong
 	rows, err := db.Query(`
 		SELECT 
 			f.id, f.name, COALESCE(f.description, ''), COALESCE(f.location, ''), 
 			f.capacity, COALESCE(f.price, 0), 
 			COALESCE(f.photo_url, '{}'), 
-			f.is_active,
+			f.is_active, f.allow_walk_up, f.walk_up_max_minutes,
 			COALESCE(u_cre.name, '-'), 
-			COALESCE(u_upd.name, '')
-		FROM facilities f
-		LEFT JOIN users u_cre ON f.created_by = u_cre.id
-		LEFT JOIN users u_upd ON f.updated_by = u_upd.id
-		WHERE f.deleted_at IS NULL
-		ORDER BY f.created_at DESC
-	`)
+			COALESCE(u_upd.name, ''),
+			f.category, f.amenities, f.wheelchair_accessible, f.accessible_restroom, f.hearing_loop,
+			COALESCE(bld.name, f.building, ''), COALESCE(fl.level, f.floor),
+			f.floor_id, COALESCE(bld.id::text, ''), COALESCE(cmp.id::text, ''), COALESCE(cmp.name, ''),
+			f.requires_payment, f.allow_guest_booking`+from+`
+		ORDER BY `+orderBy+`
+		LIMIT $15 OFFSET $16
+	`, append(args, limit, f.Offset)...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
 	var facilities []Facility
 	for rows.Next() {
 		var f Facility
+		var floor sql.NullInt64
+		var floorID sql.NullString
 		// Gunakan pq.Array(&f.PhotoURL) untuk scan array DB ke slice Go
 		if err := rows.Scan(
 			&f.ID, &f.Name, &f.Description, &f.Location,
-			&f.Capacity, &f.Price, pq.Array(&f.PhotoURL), &f.IsActive,
+			&f.Capacity, &f.Price, pq.Array(&f.PhotoURL), &f.IsActive, &f.AllowWalkUp, &f.WalkUpMaxMinutes,
 			&f.CreatedByName, &f.UpdatedByName,
+			&f.Category, pq.Array(&f.Amenities), &f.WheelchairAccessible, &f.AccessibleRestroom, &f.HearingLoop,
+			&f.Building, &floor, &floorID, &f.BuildingID, &f.CampusID, &f.CampusName, &f.RequiresPayment, &f.AllowGuestBooking,
 		); err != nil {
-			return nil, err
+			return nil, 0, err
+		}
+		if floor.Valid {
+			n := int(floor.Int64)
+			f.Floor = &n
+		}
+		if floorID.Valid {
+			f.FloorID = &floorID.String
 		}
+		f.PhotoVariants = media.VariantsOfAll(f.PhotoURL)
 		facilities = append(facilities, f)
 	}
-	return facilities, nil
+	return facilities, total, rows.Err()
+}
+
+// nonNilStrings: pq.Array(nil) dikirim sebagai NULL, sedangkan filter amenities butuh array kosong
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// AmenityCount dipakai frontend untuk menampilkan pilihan filter amenities
+type AmenityCount struct {
+	Amenity string `json:"amenity"`
+	Count   int    `json:"count"`
+}
+
+func FindAmenities(db *sql.DB) ([]AmenityCount, error) {
+	rows, err := db.Query(`
+		SELECT a, COUNT(*)::int
+		FROM facilities, UNNEST(amenities) AS a
+		WHERE deleted_at IS NULL
+		GROUP BY a
+		ORDER BY COUNT(*) DESC, a
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	amenities := []AmenityCount{}
+	for rows.Next() {
+		var a AmenityCount
+		if err := rows.Scan(&a.Amenity, &a.Count); err != nil {
+			return nil, err
+		}
+		amenities = append(amenities, a)
+	}
+	return amenities, rows.Err()
 }
 
 // ==========================
 // GET ONE BY ID
 // ==========================
-func FindByID(db *sql.DB, id string) (Facility, error) {
+func FindByID(q database.Querier, id string) (Facility, error) {
 	var f Facility
 	// Query ini tidak perlu join user karena hanya untuk mengisi form edit
-	err := db.QueryRow(`
-		SELECT id, name, COALESCE(description, ''), COALESCE(location, ''), capacity, COALESCE(price, 0), 
-		COALESCE(photo_url, '{}'), is_active
-		FROM facilities WHERE id = $1 AND deleted_at IS NULL
-	`, id).Scan(&f.ID, &f.Name, &f.Description, &f.Location, &f.Capacity, &f.Price, pq.Array(&f.PhotoURL), &f.IsActive)
+	var floor sql.NullInt64
+	var floorID sql.NullString
+	err := q.QueryRow(`
+		SELECT f.id, f.name, COALESCE(f.description, ''), COALESCE(f.location, ''), f.capacity, COALESCE(f.price, 0), 
+		COALESCE(f.photo_url, '{}'), f.is_active, f.allow_walk_up, f.walk_up_max_minutes,
+		f.category, f.amenities, f.wheelchair_accessible, f.accessible_restroom, f.hearing_loop,
+		COALESCE(bld.name, f.building, ''), COALESCE(fl.level, f.floor),
+		f.floor_id, COALESCE(bld.id::text, ''), COALESCE(cmp.id::text, ''), COALESCE(cmp.name, ''),
+		f.requires_payment, f.allow_guest_booking
+		FROM facilities f
+		LEFT JOIN floors fl ON f.floor_id = fl.id
+		LEFT JOIN buildings bld ON fl.building_id = bld.id
+		LEFT JOIN campuses cmp ON f.campus_id = cmp.id
+		WHERE f.id = $1 AND f.deleted_at IS NULL
+	`, id).Scan(&f.ID, &f.Name, &f.Description, &f.Location, &f.Capacity, &f.Price, pq.Array(&f.PhotoURL), &f.IsActive,
+		&f.AllowWalkUp, &f.WalkUpMaxMinutes,
+		&f.Category, pq.Array(&f.Amenities), &f.WheelchairAccessible, &f.AccessibleRestroom, &f.HearingLoop, &f.Building, &floor,
+		&floorID, &f.BuildingID, &f.CampusID, &f.CampusName, &f.RequiresPayment, &f.AllowGuestBooking)
+	if floor.Valid {
+		n := int(floor.Int64)
+		f.Floor = &n
+	}
+	if floorID.Valid {
+		f.FloorID = &floorID.String
+	}
+	f.PhotoVariants = media.VariantsOfAll(f.PhotoURL)
 	return f, err
 }
 
 // ==========================
 // UPDATE
 // ==========================
-func Update(db *sql.DB, id string, f Facility, userID string) error {
-	_, err := db.Exec(`
+func Update(q database.Querier, id string, f Facility, userID string) error {
+	_, err := q.Exec(`
 		UPDATE facilities
-		SET name = $1, description = $2, location = $3, capacity = $4, price = $5, photo_url = $6, updated_at = now(), updated_by = $7
+		SET name = $1, description = $2, location = $3, capacity = $4, price = $5, photo_url = $6, updated_at = now(), updated_by = $7,
+			category = $9, amenities = $10, wheelchair_accessible = $11, accessible_restroom = $12, hearing_loop = $13,
+			building = NULLIF($14, ''), floor = $15, floor_id = $16, campus_id = NULLIF($17, '')::uuid,
+			requires_payment = $18, allow_guest_booking = $19
 		WHERE id = $8 AND deleted_at IS NULL
-	`, f.Name, f.Description, f.Location, f.Capacity, f.Price, pq.Array(f.PhotoURL), userID, id)
+	`, f.Name, f.Description, f.Location, f.Capacity, f.Price, pq.Array(f.PhotoURL), userID, id,
+		f.Category, pq.Array(nonNilStrings(f.Amenities)), f.WheelchairAccessible, f.AccessibleRestroom, f.HearingLoop, f.Building, f.Floor,
+		f.FloorID, f.CampusID, f.RequiresPayment, f.AllowGuestBooking)
 	return err
 }
 
+// FindFloorCampusID mengembalikan kampus tempat sebuah lantai berada
+func FindFloorCampusID(db *sql.DB, floorID string) (string, error) {
+	var campusID string
+	err := db.QueryRow(`
+		SELECT b.campus_id::text FROM floors fl JOIN buildings b ON b.id = fl.building_id WHERE fl.id::text = $1
+	`, floorID).Scan(&campusID)
+	return campusID, err
+}
+
 // ==========================
 // TOGGLE ACTIVE
 // ==========================
-func ToggleActive(db *sql.DB, id string, status bool, userID string) error {
-	_, err := db.Exec(`
+func ToggleActive(q database.Querier, id string, status bool, userID string) error {
+	_, err := q.Exec(`
 		UPDATE facilities 
 		SET is_active = $1, updated_at = now(), updated_by = $2
 		WHERE id = $3 AND deleted_at IS NULL
@@ -112,10 +320,25 @@ func ToggleActive(db *sql.DB, id string, status bool, userID string) error {
 	return err
 }
 
+// ==========================
+// KEBIJAKAN WALK-UP
+// ==========================
+func UpdateWalkUpPolicy(q database.Querier, id string, allow bool, maxMinutes int, userID string) (int64, error) {
+	res, err := q.Exec(`
+		UPDATE facilities
+		SET allow_walk_up = $1, walk_up_max_minutes = $2, updated_at = now(), updated_by = $3
+		WHERE id = $4 AND deleted_at IS NULL
+	`, allow, maxMinutes, userID, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 // ==========================
 // DELETE PERMANEN
 // ==========================
-func HardDelete(db *sql.DB, id string) error {
-	_, err := db.Exec("DELETE FROM facilities WHERE id = $1", id)
+func HardDelete(q database.Querier, id string) error {
+	_, err := q.Exec("DELETE FROM facilities WHERE id = $1", id)
 	return err
 }
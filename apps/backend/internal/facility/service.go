@@ -3,6 +3,12 @@ package facility
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"campus-reservation-backend/internal/storage"
+	"campus-reservation-backend/internal/webhook"
 )
 
 // ==========================
@@ -27,9 +33,74 @@ func CreateFacility(db *sql.DB, f Facility, userID string) error {
 	if f.Price < 0 {
 		return errors.New("harga tidak boleh negatif")
 	}
+	if err := normalizeAttributes(&f); err != nil {
+		return err
+	}
 
-	// 2. Panggil repository
-	return Insert(db, f, userID)
+	// 2. Panggil repository (event webhook di-commit bersama datanya)
+	err := webhook.WithTx(db, func(tx *sql.Tx) error {
+		id, err := Insert(tx, f, userID)
+		if err != nil {
+			return err
+		}
+		publishFacilityEvent(tx, webhook.EventFacilityCreated, id)
+		return nil
+	})
+	if err != nil {
+		return mapFacilityError(err)
+	}
+	return nil
+}
+
+// ==========================
+// ATRIBUT TERSTRUKTUR (KATEGORI, AMENITIES, AKSESIBILITAS, GEDUNG)
+// ==========================
+const maxAmenities = 30
+
+func normalizeAttributes(f *Facility) error {
+	f.Category = strings.ToLower(strings.TrimSpace(f.Category))
+	if f.Category == "" {
+		f.Category = "other"
+	}
+	if !slices.Contains(Categories, f.Category) {
+		return fmt.Errorf("kategori tidak valid, pilih salah satu: %s", strings.Join(Categories, ", "))
+	}
+
+	f.Amenities = NormalizeAmenities(f.Amenities)
+	if len(f.Amenities) > maxAmenities {
+		return fmt.Errorf("maksimal %d amenities per fasilitas", maxAmenities)
+	}
+
+	f.Building = strings.TrimSpace(f.Building)
+	if len(f.Building) > 100 {
+		return errors.New("nama gedung maksimal 100 karakter")
+	}
+	if f.Floor != nil && (*f.Floor < -10 || *f.Floor > 200) {
+		return errors.New("lantai tidak valid")
+	}
+	return nil
+}
+
+// mapFacilityError menerjemahkan pelanggaran foreign key lantai menjadi pesan yang jelas
+func mapFacilityError(err error) error {
+	if strings.Contains(err.Error(), "floor_id") || strings.Contains(err.Error(), "invalid input syntax for type uuid") {
+		return errors.New("lantai tidak ditemukan")
+	}
+	return err
+}
+
+// NormalizeAmenities menyeragamkan tag: huruf kecil, spasi jadi underscore, tanpa duplikat.
+// "Proyektor", " proyektor " dan "PROYEKTOR" dianggap tag yang sama.
+func NormalizeAmenities(tags []string) []string {
+	out := []string{}
+	for _, t := range tags {
+		t = strings.Join(strings.Fields(strings.ToLower(t)), "_")
+		if t == "" || len(t) > 40 || slices.Contains(out, t) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
 }
 
 // ==========================
@@ -40,6 +111,29 @@ func GetAllFacilities(db *sql.DB) ([]Facility, error) {
 	return FindAll(db)
 }
 
+// SearchFacilities dipakai halaman daftar fasilitas (filter, pencarian, sort & pagination)
+func SearchFacilities(db *sql.DB, f FacilityFilter) ([]Facility, int, error) {
+	f.Query = strings.TrimSpace(f.Query)
+	f.Category = strings.ToLower(strings.TrimSpace(f.Category))
+	if f.Category != "" && !slices.Contains(Categories, f.Category) {
+		return nil, 0, fmt.Errorf("kategori tidak valid, pilih salah satu: %s", strings.Join(Categories, ", "))
+	}
+	f.Amenities = NormalizeAmenities(f.Amenities)
+	f.Building = strings.TrimSpace(f.Building)
+
+	switch f.Sort {
+	case "", "name", "capacity", "price", "created_at", "relevance":
+	default:
+		return nil, 0, errors.New("sort tidak valid (name, capacity, price, created_at, relevance)")
+	}
+	// Tanpa sort eksplisit, hasil pencarian diurutkan berdasarkan relevansi
+	if f.Sort == "" && f.Query != "" {
+		f.Sort = "relevance"
+	}
+
+	return Search(db, f)
+}
+
 // ==========================
 // UPDATE FASILITAS (LOGIKA)
 // ==========================
@@ -65,9 +159,22 @@ func UpdateFacility(db *sql.DB, id string, f Facility, userID string) error {
 	if f.Price < 0 {
 		return errors.New("harga tidak boleh negatif")
 	}
+	if err := normalizeAttributes(&f); err != nil {
+		return err
+	}
 
 	// 3. Panggil repository
-	return Update(db, id, f, userID)
+	err := webhook.WithTx(db, func(tx *sql.Tx) error {
+		if err := Update(tx, id, f, userID); err != nil {
+			return err
+		}
+		publishFacilityEvent(tx, webhook.EventFacilityUpdated, id)
+		return nil
+	})
+	if err != nil {
+		return mapFacilityError(err)
+	}
+	return nil
 }
 
 // ==========================
@@ -79,8 +186,27 @@ func DeleteFacility(db *sql.DB, id string) error {
 		return errors.New("id fasilitas tidak valid")
 	}
 
+	// Data diambil sebelum dihapus untuk payload webhook
+	existing, findErr := FindByID(db, id)
+
 	// PERBAIKAN: Menggunakan HardDelete (bukan SoftDelete)
-	return HardDelete(db, id)
+	err := webhook.WithTx(db, func(tx *sql.Tx) error {
+		if err := HardDelete(tx, id); err != nil {
+			return err
+		}
+		if findErr == nil {
+			webhook.Publish(tx, webhook.EventFacilityDeleted, existing)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if findErr == nil {
+		storage.Release(db, existing.PhotoURL...)
+	}
+	return nil
 }
 
 // ==========================
@@ -92,5 +218,51 @@ func ToggleFacilityStatus(db *sql.DB, id string, isActive bool, userID string) e
 		return errors.New("id fasilitas tidak valid")
 	}
 
-	return ToggleActive(db, id, isActive, userID)
+	return webhook.WithTx(db, func(tx *sql.Tx) error {
+		if err := ToggleActive(tx, id, isActive, userID); err != nil {
+			return err
+		}
+		publishFacilityEvent(tx, webhook.EventFacilityStatusChanged, id)
+		return nil
+	})
+}
+
+// ==========================
+// KEBIJAKAN WALK-UP (LOGIKA)
+// ==========================
+func SetWalkUpPolicy(db *sql.DB, id string, allow bool, maxMinutes int, userID string) error {
+	if maxMinutes == 0 {
+		maxMinutes = 60
+	}
+	if maxMinutes != 30 && maxMinutes != 60 {
+		return errors.New("durasi maksimal walk-up hanya boleh 30 atau 60 menit")
+	}
+
+	var n int64
+	err := webhook.WithTx(db, func(tx *sql.Tx) error {
+		var err error
+		if n, err = UpdateWalkUpPolicy(tx, id, allow, maxMinutes, userID); err != nil || n == 0 {
+			return err
+		}
+		publishFacilityEvent(tx, webhook.EventFacilityUpdated, id)
+		return nil
+	})
+	if err != nil {
+		return errors.New("gagal menyimpan kebijakan walk-up")
+	}
+	if n == 0 {
+		return errors.New("fasilitas tidak ditemukan")
+	}
+	return nil
+}
+
+// publishFacilityEvent memasukkan data fasilitas terbaru ke antrian webhook (di transaksi perubahannya)
+func publishFacilityEvent(tx *sql.Tx, event string, id string) {
+	webhook.PublishLoaded(tx, event, func() (interface{}, error) {
+		f, err := FindByID(tx, id)
+		if err != nil {
+			return nil, fmt.Errorf("fasilitas %s: %w", id, err)
+		}
+		return f, nil
+	})
 }
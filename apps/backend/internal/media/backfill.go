@@ -0,0 +1,146 @@
+package media
+
+import (
+	"database/sql"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"campus-reservation-backend/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// ==========================
+// KONVERSI FOTO LAMA
+// ==========================
+// Foto fasilitas & avatar yang diupload sebelum ada pipeline gambar (URL /uploads/... atau key storage tunggal)
+// dikonversi ke varian WebP sekali jalan saat startup. Aman dijalankan berulang: URL yang sudah berupa varian dilewati.
+
+const legacyUploadPrefix = "/uploads/"
+
+// processed: URL sudah mengikuti pola grup varian
+func processed(url string) bool {
+	return url == "" || strings.HasSuffix(url, "/large.webp")
+}
+
+// readStored membaca isi file dari URL lama /uploads/... atau URL publik storage
+func readStored(url string) ([]byte, error) {
+	if strings.HasPrefix(url, legacyUploadPrefix) {
+		return os.ReadFile(filepath.Join("./uploads", filepath.Base(url)))
+	}
+	key, ok := storage.KeyFromURL(url)
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	r, err := storage.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// convert mengembalikan URL varian large untuk url; jika gagal URL lama tetap dipakai
+func (p Preset) convert(url string) (string, bool) {
+	if processed(url) {
+		return url, false
+	}
+	data, err := readStored(url)
+	if err != nil {
+		log.Printf("Konversi gambar dilewati, file %s tidak terbaca: %v\n", url, err)
+		return url, false
+	}
+	v, err := p.Save(data)
+	if err != nil {
+		log.Printf("Konversi gambar %s gagal: %v\n", url, err)
+		return url, false
+	}
+	return v.Large, true
+}
+
+// BackfillVariants mengonversi foto fasilitas & avatar lama. Mengembalikan jumlah gambar yang dikonversi.
+func BackfillVariants(db *sql.DB) (int, error) {
+	converted := 0
+
+	rows, err := db.Query(`
+		SELECT id, photo_url FROM facilities
+		WHERE EXISTS (SELECT 1 FROM unnest(photo_url) AS u WHERE u NOT LIKE '%/large.webp')
+	`)
+	if err != nil {
+		return 0, err
+	}
+	type facilityPhotos struct {
+		id     string
+		photos []string
+	}
+	var facilities []facilityPhotos
+	for rows.Next() {
+		var f facilityPhotos
+		if err := rows.Scan(&f.id, pq.Array(&f.photos)); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		facilities = append(facilities, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, f := range facilities {
+		updated := make([]string, len(f.photos))
+		var old []string
+		for i, url := range f.photos {
+			var ok bool
+			updated[i], ok = FacilityPhoto.convert(url)
+			if ok {
+				old = append(old, url)
+			}
+		}
+		if len(old) == 0 {
+			continue
+		}
+		if _, err := db.Exec(`UPDATE facilities SET photo_url = $1 WHERE id = $2`, pq.Array(updated), f.id); err != nil {
+			return converted, err
+		}
+		converted += len(old)
+		storage.Release(db, old...)
+	}
+
+	rows, err = db.Query(`
+		SELECT user_id, avatar_url FROM profiles
+		WHERE COALESCE(avatar_url, '') <> '' AND avatar_url NOT LIKE '%/large.webp'
+	`)
+	if err != nil {
+		return converted, err
+	}
+	avatars := map[string]string{}
+	for rows.Next() {
+		var userID, url string
+		if err := rows.Scan(&userID, &url); err != nil {
+			rows.Close()
+			return converted, err
+		}
+		avatars[userID] = url
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return converted, err
+	}
+
+	for userID, url := range avatars {
+		updated, ok := Avatar.convert(url)
+		if !ok {
+			continue
+		}
+		if _, err := db.Exec(`UPDATE profiles SET avatar_url = $1 WHERE user_id = $2`, updated, userID); err != nil {
+			return converted, err
+		}
+		converted++
+		storage.Release(db, url)
+	}
+	return converted, nil
+}
@@ -0,0 +1,18 @@
+//go:build cgo
+
+package media
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+// webpQuality: kualitas WebP lossy (0-100)
+const webpQuality = 80
+
+// encodeWebP memakai libwebp (lossy) jika build dengan cgo
+func encodeWebP(w io.Writer, img image.Image) error {
+	return webp.Encode(w, img, &webp.Options{Quality: webpQuality})
+}
@@ -0,0 +1,16 @@
+//go:build !cgo
+
+package media
+
+import (
+	"image"
+	"io"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+// encodeWebP untuk build tanpa cgo (CGO_ENABLED=0): encoder Go murni, hanya mendukung WebP lossless
+// sehingga file lebih besar dibanding build cgo
+func encodeWebP(w io.Writer, img image.Image) error {
+	return nativewebp.Encode(w, img, nil)
+}
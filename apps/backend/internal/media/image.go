@@ -0,0 +1,196 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"campus-reservation-backend/internal/storage"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp" // decoder WebP Go murni (input upload)
+)
+
+// ========================================================
+// PIPELINE GAMBAR (FOTO FASILITAS & AVATAR)
+// ========================================================
+// Upload gambar tidak pernah disimpan apa adanya: jenis file dicek dari isinya (bukan ekstensi / header dari klien),
+// gambar di-decode dengan orientasi EXIF diterapkan, lalu di-encode ulang ke WebP dalam beberapa ukuran.
+// Encode ulang sekaligus membuang seluruh metadata (EXIF, lokasi GPS, info kamera).
+// Encoder WebP dipilih saat build: libwebp lossy jika cgo aktif, encoder Go murni (lossless) jika CGO_ENABLED=0.
+//
+// Semua varian satu upload disimpan dalam satu grup storage: <prefix>/<sha256[:2]>/<sha256>/{thumb,medium,large}.webp
+// (hash dari file asli). Kolom photo_url / avatar_url tetap menyimpan satu URL (varian large), URL varian lain
+// diturunkan dari URL tersebut lewat VariantsOf.
+
+// maxPixels: batas resolusi sebelum decode (mencegah "decompression bomb")
+const maxPixels = 40_000_000
+
+var (
+	ErrUnsupportedType = errors.New("file harus berupa gambar JPG, PNG atau WebP")
+	ErrTooLarge        = errors.New("resolusi gambar terlalu besar (maksimal 40 megapiksel)")
+	ErrCorrupt         = errors.New("gambar rusak atau tidak bisa dibaca")
+)
+
+// allowedTypes: hasil http.DetectContentType yang diterima
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// IsInvalid: error karena isi file (ditampilkan ke user sebagai 400), bukan kegagalan server
+func IsInvalid(err error) bool {
+	return errors.Is(err, ErrUnsupportedType) || errors.Is(err, ErrTooLarge) || errors.Is(err, ErrCorrupt)
+}
+
+// Variants adalah URL setiap ukuran sebuah gambar
+type Variants struct {
+	Thumb  string `json:"thumb"`  // kartu daftar / avatar kecil
+	Medium string `json:"medium"` // tampilan detail di layar kecil
+	Large  string `json:"large"`  // tampilan penuh (sama dengan photo_url / avatar_url)
+}
+
+// Preset menentukan ukuran (sisi terpanjang, piksel) varian untuk satu jenis gambar
+type Preset struct {
+	Prefix string
+	Thumb  int
+	Medium int
+	Large  int
+	// Square: dipotong persegi di tengah (avatar)
+	Square bool
+}
+
+var (
+	FacilityPhoto = Preset{Prefix: storage.PrefixFacilityPhotos, Thumb: 320, Medium: 800, Large: 1600}
+	Avatar        = Preset{Prefix: storage.PrefixAvatars, Thumb: 64, Medium: 256, Large: 512, Square: true}
+)
+
+// variantNames: urutan nama file varian di dalam grup
+var variantNames = []string{"thumb", "medium", "large"}
+
+func (p Preset) size(name string) int {
+	switch name {
+	case "thumb":
+		return p.Thumb
+	case "medium":
+		return p.Medium
+	}
+	return p.Large
+}
+
+// ==========================
+// PROSES GAMBAR
+// ==========================
+
+// decode memvalidasi jenis & resolusi lalu men-decode gambar (orientasi EXIF diterapkan)
+func decode(data []byte) (image.Image, error) {
+	if !allowedTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedType
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrCorrupt
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	return img, nil
+}
+
+// resize tidak pernah memperbesar gambar kecil
+func (p Preset) resize(img image.Image, size int) image.Image {
+	if !p.Square {
+		return imaging.Fit(img, size, size, imaging.Lanczos)
+	}
+	b := img.Bounds()
+	size = min(size, b.Dx(), b.Dy())
+	return imaging.Fill(img, size, size, imaging.Center, imaging.Lanczos)
+}
+
+// Process membuat semua varian WebP dari data gambar (nama varian -> isi file)
+func (p Preset) Process(data []byte) (map[string][]byte, error) {
+	img, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte, len(variantNames))
+	for _, name := range variantNames {
+		var buf bytes.Buffer
+		if err := encodeWebP(&buf, p.resize(img, p.size(name))); err != nil {
+			return nil, fmt.Errorf("gagal mengonversi gambar: %w", err)
+		}
+		files[name] = buf.Bytes()
+	}
+	return files, nil
+}
+
+// Save memproses gambar lalu menyimpan semua variannya ke storage
+func (p Preset) Save(data []byte) (Variants, error) {
+	files, err := p.Process(data)
+	if err != nil {
+		return Variants{}, err
+	}
+
+	group := storage.GroupKey(p.Prefix, data)
+	urls := make(map[string]string, len(files))
+	for _, name := range variantNames {
+		obj, err := storage.SaveKey(group+"/"+name+".webp", files[name], "image/webp")
+		if err != nil {
+			return Variants{}, fmt.Errorf("gagal menyimpan gambar: %w", err)
+		}
+		urls[name] = obj.URL
+	}
+	return Variants{Thumb: urls["thumb"], Medium: urls["medium"], Large: urls["large"]}, nil
+}
+
+// SaveFile memproses file upload multipart
+func (p Preset) SaveFile(file *multipart.FileHeader) (Variants, error) {
+	f, err := file.Open()
+	if err != nil {
+		return Variants{}, ErrCorrupt
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return Variants{}, ErrCorrupt
+	}
+	return p.Save(data)
+}
+
+// ==========================
+// URL VARIAN
+// ==========================
+
+// VariantsOf menurunkan URL varian dari URL yang tersimpan (varian large).
+// URL lama yang belum diproses (misal /uploads/...) dipakai untuk semua ukuran.
+func VariantsOf(url string) Variants {
+	base, ok := strings.CutSuffix(url, "/large.webp")
+	if !ok {
+		return Variants{Thumb: url, Medium: url, Large: url}
+	}
+	return Variants{Thumb: base + "/thumb.webp", Medium: base + "/medium.webp", Large: url}
+}
+
+// VariantsOfAll: VariantsOf untuk setiap URL (urutan sama)
+func VariantsOfAll(urls []string) []Variants {
+	list := make([]Variants, 0, len(urls))
+	for _, url := range urls {
+		list = append(list, VariantsOf(url))
+	}
+	return list
+}
@@ -0,0 +1,184 @@
+package payment
+
+import (
+	"bytes"
+	"database/sql"
+	"html/template"
+	"strings"
+
+	"campus-reservation-backend/internal/auth"
+	"campus-reservation-backend/internal/billing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func errorStatus(err error) int {
+	msg := err.Error()
+	switch {
+	case err == errInvalidSignature:
+		return 401
+	case strings.Contains(msg, "tidak ditemukan"), strings.Contains(msg, "tidak dikenal"):
+		return 404
+	case strings.Contains(msg, "sudah lunas"), strings.Contains(msg, "sudah dibatalkan"),
+		strings.Contains(msg, "jatuh tempo"), strings.Contains(msg, "sudah diproses"):
+		return 409
+	case err == errProviderDisabled:
+		return 503
+	case strings.Contains(msg, "link pembayaran"):
+		return 502
+	}
+	return 400
+}
+
+func respondError(c *fiber.Ctx, err error) error {
+	return c.Status(errorStatus(err)).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// canView: pemilik invoice atau admin (admin kampus hanya kampusnya)
+func canView(c *fiber.Ctx, userID, campusID string) bool {
+	if role, _ := c.Locals("role").(string); role == "admin" {
+		return auth.CampusAllowed(c, campusID)
+	}
+	me, _ := c.Locals("user_id").(string)
+	return userID == me
+}
+
+// ========================================================
+// HANDLER: PEMBAYARAN INVOICE
+// ========================================================
+
+// CreateLinkHandler: POST /invoices/:id/payments -> link pembayaran (payment_url) untuk invoice milik user
+func CreateLinkHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(string)
+
+		p, err := CreateLink(db, c.Params("id"), userID)
+		if err != nil {
+			return respondError(c, err)
+		}
+		return c.Status(201).JSON(p)
+	}
+}
+
+// ListInvoicePaymentsHandler: GET /invoices/:id/payments -> riwayat percobaan pembayaran
+func ListInvoicePaymentsHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		inv, err := billing.FindInvoiceByID(db, c.Params("id"))
+		if err != nil || !canView(c, inv.UserID, inv.CampusID) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Invoice tidak ditemukan",
+			})
+		}
+
+		payments, err := FindByInvoice(db, inv.ID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal memuat riwayat pembayaran",
+			})
+		}
+		return c.JSON(payments)
+	}
+}
+
+// GetPaymentHandler: GET /payments/:id. Status pending dicek langsung ke provider (polling dari frontend).
+func GetPaymentHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p, err := FindByID(db, c.Params("id"))
+		if err != nil || !canView(c, p.UserID, p.CampusID) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Pembayaran tidak ditemukan",
+			})
+		}
+
+		if p.Status == StatusPending {
+			if refreshed, err := RefreshStatus(db, p); err == nil {
+				p = refreshed
+			}
+		}
+		return c.JSON(p)
+	}
+}
+
+// CallbackHandler: POST /payments/callback/:provider (publik, diverifikasi lewat signature provider)
+func CallbackHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := func(key string) string { return c.Get(key) }
+		if _, err := HandleCallback(db, c.Params("provider"), header, c.Body()); err != nil {
+			return respondError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"message": "OK",
+		})
+	}
+}
+
+// ========================================================
+// HANDLER: HALAMAN SIMULATOR
+// ========================================================
+
+var simulatorPage = template.Must(template.New("simulator").Parse(`<!doctype html>
+<html><head><title>Simulator Pembayaran</title></head>
+<body style="font-family:sans-serif;max-width:420px;margin:40px auto">
+<h2>Simulator Pembayaran</h2>
+{{if .Message}}<p><b>{{.Message}}</b></p>
+<p><a href="{{.FinishURL}}">Kembali ke aplikasi</a></p>
+{{else}}<p>{{.Order.Description}}</p>
+<p>Pelanggan: {{.Order.Customer}}<br>Order: {{.Order.OrderID}}<br>Total: <b>Rp {{.Amount}}</b><br>Berlaku sampai: {{.Order.ExpiresAt.Format "02 Jan 2006 15:04"}}</p>
+<form method="post">
+<button name="result" value="paid">Bayar</button>
+<button name="result" value="failed">Gagal</button>
+<button name="result" value="expired">Kedaluwarsa</button>
+</form>
+{{end}}
+</body></html>`))
+
+func renderSimulator(c *fiber.Ctx, status int, order simOrder, message string) error {
+	var buf bytes.Buffer
+	err := simulatorPage.Execute(&buf, fiber.Map{
+		"Order":     order,
+		"Amount":    billing.FormatRupiah(order.Amount),
+		"Message":   message,
+		"FinishURL": finishURL(),
+	})
+	if err != nil {
+		return c.Status(500).SendString("gagal menampilkan halaman simulator")
+	}
+	c.Type("html", "utf-8")
+	return c.Status(status).Send(buf.Bytes())
+}
+
+// SimulatorPageHandler: GET /payments/simulator/:order_id (halaman "bayar" pengganti halaman gateway)
+func SimulatorPageHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		order, ok := findSimOrder(c.Params("order_id"))
+		if !ok {
+			return renderSimulator(c, 404, order, "Transaksi tidak ditemukan")
+		}
+		if order.Status != StatusPending {
+			return renderSimulator(c, 200, order, "Transaksi sudah "+order.Status)
+		}
+		return renderSimulator(c, 200, order, "")
+	}
+}
+
+// SimulatorActionHandler: POST /payments/simulator/:order_id (result=paid|failed|expired) -> kirim callback
+func SimulatorActionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		orderID := c.Params("order_id")
+		sim, err := newSimulator()
+		if err != nil {
+			return renderSimulator(c, 503, simOrder{}, "Gagal: "+err.Error())
+		}
+		if err := sim.completeSimOrder(orderID, c.FormValue("result")); err != nil {
+			return renderSimulator(c, errorStatus(err), simOrder{}, "Gagal: "+err.Error())
+		}
+
+		order, _ := findSimOrder(orderID)
+		if order.Status == StatusPaid {
+			return renderSimulator(c, 200, order, "Pembayaran berhasil")
+		}
+		return renderSimulator(c, 200, order, "Transaksi "+order.Status)
+	}
+}
@@ -0,0 +1,229 @@
+package payment
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========================================================
+// MIDTRANS (SNAP PAYMENT LINK + CORE API)
+// ========================================================
+// Link: POST /snap/v1/transactions -> redirect_url
+// Callback: HTTP notification, signature_key = SHA512(order_id + status_code + gross_amount + server_key)
+// Status & refund: GET /v2/{order_id}/status, POST /v2/{order_id}/refund
+
+type midtrans struct {
+	serverKey string
+	snapURL   string
+	apiURL    string
+	client    *http.Client
+}
+
+func newMidtrans() (*midtrans, error) {
+	key := os.Getenv("MIDTRANS_SERVER_KEY")
+	if key == "" {
+		return nil, errors.New("MIDTRANS_SERVER_KEY belum diset di .env")
+	}
+	m := &midtrans{
+		serverKey: key,
+		snapURL:   "https://app.sandbox.midtrans.com/snap/v1/transactions",
+		apiURL:    "https://api.sandbox.midtrans.com/v2",
+		client:    &http.Client{Timeout: providerTimeout},
+	}
+	if os.Getenv("MIDTRANS_PRODUCTION") == "true" {
+		m.snapURL = "https://app.midtrans.com/snap/v1/transactions"
+		m.apiURL = "https://api.midtrans.com/v2"
+	}
+	return m, nil
+}
+
+func (m *midtrans) Name() string { return "midtrans" }
+
+// midtransStatus adalah bentuk body callback sekaligus respon cek status
+type midtransStatus struct {
+	OrderID           string `json:"order_id"`
+	StatusCode        string `json:"status_code"`
+	GrossAmount       string `json:"gross_amount"`
+	SignatureKey      string `json:"signature_key"`
+	TransactionID     string `json:"transaction_id"`
+	TransactionStatus string `json:"transaction_status"`
+	FraudStatus       string `json:"fraud_status"`
+	StatusMessage     string `json:"status_message"`
+}
+
+func (s midtransStatus) toNotification(payload []byte) Notification {
+	amount, _ := strconv.ParseFloat(s.GrossAmount, 64)
+	return Notification{
+		OrderID:     s.OrderID,
+		Status:      mapMidtransStatus(s.TransactionStatus, s.FraudStatus),
+		Amount:      amount,
+		ProviderRef: s.TransactionID,
+		Payload:     string(payload),
+	}
+}
+
+func mapMidtransStatus(status, fraud string) string {
+	switch status {
+	case "settlement":
+		return StatusPaid
+	case "capture":
+		// Kartu kredit: "challenge" masih menunggu review fraud
+		if fraud == "" || fraud == "accept" {
+			return StatusPaid
+		}
+		return StatusPending
+	case "deny", "cancel", "failure":
+		return StatusFailed
+	case "expire":
+		return StatusExpired
+	case "refund", "partial_refund":
+		return StatusRefunded
+	}
+	return StatusPending
+}
+
+func (m *midtrans) do(method, url string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(m.serverKey, "")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("gagal menghubungi midtrans: %v", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode == 404 {
+		return errOrderNotFound
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("midtrans merespon dengan status %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func (m *midtrans) CreateLink(req LinkRequest) (Link, error) {
+	name := req.Description
+	if len(name) > 50 {
+		name = name[:50]
+	}
+	amount := int64(math.Round(req.Amount))
+	minutes := max(1, int(time.Until(req.ExpiresAt).Minutes()))
+
+	body := map[string]any{
+		"transaction_details": map[string]any{
+			"order_id":     req.OrderID,
+			"gross_amount": amount,
+		},
+		"item_details": []map[string]any{{
+			"id":       req.OrderID,
+			"name":     name,
+			"price":    amount,
+			"quantity": 1,
+		}},
+		"customer_details": map[string]any{
+			"first_name": req.CustomerName,
+			"email":      req.CustomerEmail,
+		},
+		"expiry": map[string]any{
+			"start_time": time.Now().Format("2006-01-02 15:04:05 -0700"),
+			"unit":       "minute",
+			"duration":   minutes,
+		},
+		"callbacks": map[string]any{
+			"finish": finishURL(),
+		},
+	}
+
+	var out struct {
+		Token       string `json:"token"`
+		RedirectURL string `json:"redirect_url"`
+	}
+	if err := m.do("POST", m.snapURL, body, &out); err != nil {
+		return Link{}, err
+	}
+	if out.RedirectURL == "" {
+		return Link{}, errors.New("midtrans tidak mengembalikan link pembayaran")
+	}
+	return Link{URL: out.RedirectURL, ProviderRef: out.Token}, nil
+}
+
+func (m *midtrans) signature(s midtransStatus) string {
+	sum := sha512.Sum512([]byte(s.OrderID + s.StatusCode + s.GrossAmount + m.serverKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *midtrans) ParseCallback(_ func(string) string, body []byte) (Notification, error) {
+	var s midtransStatus
+	if err := json.Unmarshal(body, &s); err != nil || s.OrderID == "" {
+		return Notification{}, errors.New("payload callback tidak valid")
+	}
+	if subtle.ConstantTimeCompare([]byte(m.signature(s)), []byte(strings.ToLower(s.SignatureKey))) != 1 {
+		return Notification{}, errInvalidSignature
+	}
+	return s.toNotification(body), nil
+}
+
+func (m *midtrans) GetStatus(orderID string) (Notification, error) {
+	var s midtransStatus
+	if err := m.do("GET", m.apiURL+"/"+orderID+"/status", nil, &s); err != nil {
+		return Notification{}, err
+	}
+	// Transaksi yang belum pernah dibuka di halaman Snap dijawab "404" di dalam body
+	if s.StatusCode == "404" {
+		return Notification{}, errOrderNotFound
+	}
+	raw, _ := json.Marshal(s)
+	return s.toNotification(raw), nil
+}
+
+func (m *midtrans) Refund(orderID string, amount float64, reason string) (string, error) {
+	refundKey := orderID + "-refund"
+	body := map[string]any{
+		"refund_key": refundKey,
+		"amount":     int64(math.Round(amount)),
+		"reason":     reason,
+	}
+
+	var out struct {
+		StatusCode    string `json:"status_code"`
+		StatusMessage string `json:"status_message"`
+		RefundKey     string `json:"refund_key"`
+	}
+	if err := m.do("POST", m.apiURL+"/"+orderID+"/refund", body, &out); err != nil {
+		return "", err
+	}
+	if out.StatusCode != "200" {
+		return "", fmt.Errorf("refund ditolak midtrans: %s", out.StatusMessage)
+	}
+	if out.RefundKey != "" {
+		refundKey = out.RefundKey
+	}
+	return refundKey, nil
+}
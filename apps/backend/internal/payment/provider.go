@@ -0,0 +1,112 @@
+package payment
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// ========================================================
+// ABSTRAKSI PAYMENT GATEWAY
+// ========================================================
+// Alur: buat link pembayaran -> user bayar di halaman provider -> provider mengirim callback bertanda tangan
+// (atau status diambil lewat polling) -> invoice ditandai lunas. Refund dipanggil saat booking batal / ditolak.
+
+// Status pembayaran internal (provider memetakan status miliknya ke salah satu nilai ini)
+const (
+	StatusPending  = "pending"
+	StatusPaid     = "paid"
+	StatusFailed   = "failed"
+	StatusExpired  = "expired"
+	StatusRefunded = "refunded"
+)
+
+var (
+	errProviderDisabled = errors.New("pembayaran online belum diaktifkan")
+	errInvalidSignature = errors.New("signature callback tidak valid")
+	errOrderNotFound    = errors.New("transaksi tidak ditemukan di provider")
+)
+
+const providerTimeout = 15 * time.Second
+
+// LinkRequest adalah data transaksi yang dikirim ke provider saat membuat link pembayaran
+type LinkRequest struct {
+	OrderID       string
+	Amount        float64
+	Description   string
+	CustomerName  string
+	CustomerEmail string
+	ExpiresAt     time.Time
+}
+
+type Link struct {
+	URL         string
+	ProviderRef string
+}
+
+// Notification adalah status transaksi dari callback maupun hasil cek status ke provider
+type Notification struct {
+	OrderID     string
+	Status      string
+	Amount      float64
+	ProviderRef string
+	Payload     string // payload mentah, disimpan di payment_events
+}
+
+// Provider diimplementasikan oleh setiap payment gateway
+type Provider interface {
+	Name() string
+	CreateLink(req LinkRequest) (Link, error)
+	// ParseCallback memverifikasi tanda tangan callback lalu membaca statusnya. header(key) membaca header request.
+	ParseCallback(header func(string) string, body []byte) (Notification, error)
+	GetStatus(orderID string) (Notification, error)
+	// Refund mengembalikan dana penuh, mengembalikan nomor referensi refund
+	Refund(orderID string, amount float64, reason string) (string, error)
+}
+
+// ActiveProvider: provider yang dipakai untuk link pembayaran baru (PAYMENT_PROVIDER=midtrans|simulator)
+func ActiveProvider() (Provider, error) {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv("PAYMENT_PROVIDER")))
+	if name == "" {
+		return nil, errProviderDisabled
+	}
+	return providerByName(name)
+}
+
+// SimulatorEnabled: simulator (halaman bayar & callback palsu) hanya aktif jika PAYMENT_PROVIDER=simulator
+func SimulatorEnabled() bool {
+	return strings.ToLower(strings.TrimSpace(os.Getenv("PAYMENT_PROVIDER"))) == "simulator"
+}
+
+// providerByName dipakai callback, polling & refund agar transaksi lama tetap diproses
+// oleh provider yang membuatnya walau PAYMENT_PROVIDER sudah diganti (kecuali simulator)
+func providerByName(name string) (Provider, error) {
+	switch name {
+	case "midtrans":
+		return newMidtrans()
+	case "simulator":
+		if !SimulatorEnabled() {
+			return nil, errors.New("provider pembayaran simulator tidak aktif")
+		}
+		return newSimulator()
+	}
+	return nil, errors.New("provider pembayaran tidak dikenal: " + name)
+}
+
+// publicBaseURL adalah URL backend yang bisa diakses dari luar (untuk halaman & callback simulator)
+func publicBaseURL() string {
+	if u := os.Getenv("PAYMENT_PUBLIC_URL"); u != "" {
+		return strings.TrimRight(u, "/")
+	}
+	return "http://localhost:3000"
+}
+
+// finishURL: halaman frontend setelah user selesai di halaman pembayaran
+func finishURL() string {
+	base := "http://localhost:3001"
+	if u := os.Getenv("FRONTEND_URL"); u != "" {
+		base = strings.TrimRight(u, "/")
+	}
+	return base + "/invoices"
+}
@@ -0,0 +1,160 @@
+package payment
+
+import (
+	"database/sql"
+	"time"
+
+	"campus-reservation-backend/internal/database"
+)
+
+type Payment struct {
+	ID            string     `json:"id"`
+	InvoiceID     string     `json:"invoice_id"`
+	InvoiceNumber string     `json:"invoice_number"`
+	BookingID     *string    `json:"booking_id"`
+	UserID        string     `json:"user_id"`
+	CampusID      string     `json:"campus_id,omitempty"`
+	Provider      string     `json:"provider"`
+	OrderID       string     `json:"order_id"`
+	Amount        float64    `json:"amount"`
+	Status        string     `json:"status"` // pending | paid | failed | expired | refunded
+	PaymentURL    string     `json:"payment_url"`
+	ProviderRef   string     `json:"provider_ref,omitempty"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	PaidAt        *time.Time `json:"paid_at"`
+	RefundedAt    *time.Time `json:"refunded_at,omitempty"`
+	RefundRef     string     `json:"refund_ref,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func insertPayment(db *sql.DB, p *Payment, userID string) error {
+	return db.QueryRow(`
+		INSERT INTO payments (invoice_id, provider, order_id, amount, status, payment_url, provider_ref, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, 'pending', $5, NULLIF($6, ''), $7, $8)
+		RETURNING id, created_at
+	`, p.InvoiceID, p.Provider, p.OrderID, p.Amount, p.PaymentURL, p.ProviderRef, p.ExpiresAt, userID).
+		Scan(&p.ID, &p.CreatedAt)
+}
+
+const paymentSelect = `
+	SELECT p.id, p.invoice_id, i.number, i.booking_id::text, COALESCE(i.user_id::text, ''), COALESCE(i.campus_id::text, ''),
+		p.provider, p.order_id, p.amount::float8, p.status, p.payment_url, COALESCE(p.provider_ref, ''),
+		p.expires_at, p.paid_at, p.refunded_at, COALESCE(p.refund_ref, ''), p.created_at
+	FROM payments p
+	JOIN invoices i ON i.id = p.invoice_id`
+
+func scanPayment(row rowScanner) (Payment, error) {
+	var p Payment
+	var bookingID sql.NullString
+	var paidAt, refundedAt sql.NullTime
+	err := row.Scan(&p.ID, &p.InvoiceID, &p.InvoiceNumber, &bookingID, &p.UserID, &p.CampusID,
+		&p.Provider, &p.OrderID, &p.Amount, &p.Status, &p.PaymentURL, &p.ProviderRef,
+		&p.ExpiresAt, &paidAt, &refundedAt, &p.RefundRef, &p.CreatedAt)
+	if bookingID.Valid {
+		p.BookingID = &bookingID.String
+	}
+	if paidAt.Valid {
+		p.PaidAt = &paidAt.Time
+	}
+	if refundedAt.Valid {
+		p.RefundedAt = &refundedAt.Time
+	}
+	return p, err
+}
+
+func queryPayments(db *sql.DB, query string, args ...any) ([]Payment, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []Payment{}
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+func FindByID(db *sql.DB, id string) (Payment, error) {
+	return scanPayment(db.QueryRow(paymentSelect+` WHERE p.id::text = $1`, id))
+}
+
+// findByOrderIDForUpdate mengunci baris payment selama callback / polling diproses
+func findByOrderIDForUpdate(tx *sql.Tx, orderID string) (Payment, error) {
+	return scanPayment(tx.QueryRow(paymentSelect+` WHERE p.order_id = $1 FOR UPDATE OF p`, orderID))
+}
+
+func FindByInvoice(db *sql.DB, invoiceID string) ([]Payment, error) {
+	return queryPayments(db, paymentSelect+` WHERE p.invoice_id::text = $1 ORDER BY p.created_at DESC`, invoiceID)
+}
+
+// findActiveForInvoice: link yang masih bisa dipakai (pending & belum kedaluwarsa)
+func findActiveForInvoice(db *sql.DB, invoiceID string) (Payment, error) {
+	return scanPayment(db.QueryRow(paymentSelect+`
+		WHERE p.invoice_id::text = $1 AND p.status = 'pending' AND p.expires_at > NOW() + INTERVAL '1 minute'
+		ORDER BY p.created_at DESC
+		LIMIT 1
+	`, invoiceID))
+}
+
+// findPaidForBooking: pembayaran online yang sudah lunas untuk invoice booking
+func findPaidForBooking(db *sql.DB, bookingID string) (Payment, error) {
+	return scanPayment(db.QueryRow(paymentSelect+`
+		WHERE i.booking_id::text = $1 AND p.status = 'paid'
+		ORDER BY p.paid_at DESC
+		LIMIT 1
+	`, bookingID))
+}
+
+// findPending: transaksi yang belum ada kabarnya, dicek ulang oleh worker polling
+func findPending(db *sql.DB, olderThan time.Duration, limit int) ([]Payment, error) {
+	return queryPayments(db, paymentSelect+`
+		WHERE p.status = 'pending' AND p.updated_at < NOW() - make_interval(secs => $1)
+		ORDER BY p.updated_at
+		LIMIT $2
+	`, olderThan.Seconds(), limit)
+}
+
+func updateStatus(q database.Querier, id, status, providerRef string) error {
+	_, err := q.Exec(`
+		UPDATE payments
+		SET status = $2,
+			provider_ref = COALESCE(NULLIF($3, ''), provider_ref),
+			paid_at = CASE WHEN $2::text = 'paid' THEN NOW() ELSE paid_at END,
+			updated_at = NOW()
+		WHERE id::text = $1
+	`, id, status, providerRef)
+	return err
+}
+
+// touch menggeser updated_at agar payment yang sama tidak terus-menerus dipolling
+func touch(db *sql.DB, id string) error {
+	_, err := db.Exec(`UPDATE payments SET updated_at = NOW() WHERE id::text = $1`, id)
+	return err
+}
+
+func markRefunded(q database.Querier, id, refundRef string) error {
+	_, err := q.Exec(`
+		UPDATE payments
+		SET status = 'refunded', refunded_at = NOW(), refund_ref = NULLIF($2, ''), updated_at = NOW()
+		WHERE id::text = $1
+	`, id, refundRef)
+	return err
+}
+
+func insertEvent(q database.Querier, paymentID, source, status, payload string) error {
+	_, err := q.Exec(`
+		INSERT INTO payment_events (payment_id, source, status, payload)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+	`, paymentID, source, status, payload)
+	return err
+}
@@ -0,0 +1,292 @@
+package payment
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"campus-reservation-backend/internal/billing"
+
+	"github.com/google/uuid"
+)
+
+// linkTTL: masa berlaku link pembayaran (PAYMENT_LINK_TTL_MINUTES, default 60 menit)
+func linkTTL() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("PAYMENT_LINK_TTL_MINUTES")); err == nil && v > 0 {
+		return time.Duration(v) * time.Minute
+	}
+	return 60 * time.Minute
+}
+
+// ==========================
+// LINK PEMBAYARAN
+// ==========================
+
+// CreateLink membuat link pembayaran untuk invoice milik user. Link yang masih aktif dipakai ulang.
+func CreateLink(db *sql.DB, invoiceID, userID string) (Payment, error) {
+	inv, err := billing.FindInvoiceByID(db, invoiceID)
+	if err != nil || inv.UserID != userID {
+		return Payment{}, errors.New("invoice tidak ditemukan")
+	}
+	switch inv.Status {
+	case "paid":
+		return Payment{}, errors.New("invoice sudah lunas")
+	case "void", "refunded":
+		return Payment{}, errors.New("invoice sudah dibatalkan")
+	}
+
+	if active, err := findActiveForInvoice(db, inv.ID); err == nil {
+		return active, nil
+	}
+
+	provider, err := ActiveProvider()
+	if err != nil {
+		return Payment{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(linkTTL())
+	if inv.DueAt != nil {
+		if !inv.DueAt.After(now) {
+			return Payment{}, errors.New("invoice sudah melewati jatuh tempo")
+		}
+		if inv.DueAt.Before(expiresAt) {
+			expiresAt = *inv.DueAt
+		}
+	}
+
+	// INV/2026/000001 -> INV-2026-000001-a1b2c3 (unik per percobaan bayar)
+	orderID := strings.ReplaceAll(inv.Number, "/", "-") + "-" + uuid.NewString()[:6]
+
+	link, err := provider.CreateLink(LinkRequest{
+		OrderID:       orderID,
+		Amount:        inv.Amount,
+		Description:   inv.Description,
+		CustomerName:  inv.CustomerName,
+		CustomerEmail: inv.CustomerEmail,
+		ExpiresAt:     expiresAt,
+	})
+	if err != nil {
+		log.Printf("Gagal membuat link pembayaran %s (%s): %v\n", orderID, provider.Name(), err)
+		return Payment{}, errors.New("gagal membuat link pembayaran, coba lagi nanti")
+	}
+
+	p := Payment{
+		InvoiceID:   inv.ID,
+		Provider:    provider.Name(),
+		OrderID:     orderID,
+		Amount:      inv.Amount,
+		PaymentURL:  link.URL,
+		ProviderRef: link.ProviderRef,
+		ExpiresAt:   expiresAt,
+	}
+	if err := insertPayment(db, &p, userID); err != nil {
+		return Payment{}, err
+	}
+	return FindByID(db, p.ID)
+}
+
+// ==========================
+// CALLBACK & POLLING
+// ==========================
+
+// transitionAllowed: pembayaran yang telat masuk (setelah gagal / kedaluwarsa) tetap diterima
+func transitionAllowed(from, to string) bool {
+	switch from {
+	case StatusPending:
+		return to != StatusPending
+	case StatusFailed, StatusExpired:
+		return to == StatusPaid
+	case StatusPaid:
+		return to == StatusRefunded
+	}
+	return false
+}
+
+// applyNotification menerapkan status dari provider ke payment & invoice. Aman dipanggil berulang.
+// providerName: provider yang memverifikasi notifikasi, harus sama dengan provider yang membuat transaksi.
+func applyNotification(db *sql.DB, providerName string, n Notification, source string) (Payment, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Payment{}, err
+	}
+	defer tx.Rollback()
+
+	p, err := findByOrderIDForUpdate(tx, n.OrderID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Payment{}, errors.New("pembayaran tidak ditemukan")
+		}
+		return Payment{}, err
+	}
+	// Callback bertanda tangan dari provider lain (misal simulator) tidak boleh mengubah transaksi Midtrans
+	if p.Provider != providerName {
+		return Payment{}, errors.New("pembayaran tidak ditemukan")
+	}
+
+	if n.Status == StatusPaid && math.Abs(n.Amount-p.Amount) >= 1 {
+		return Payment{}, errors.New("nominal pembayaran tidak sesuai dengan invoice")
+	}
+
+	if err := insertEvent(tx, p.ID, source, n.Status, n.Payload); err != nil {
+		return Payment{}, err
+	}
+
+	refundNeeded := false
+	if n.Status != p.Status && transitionAllowed(p.Status, n.Status) {
+		if err := updateStatus(tx, p.ID, n.Status, n.ProviderRef); err != nil {
+			return Payment{}, err
+		}
+
+		switch n.Status {
+		case StatusPaid:
+			ref := p.Provider + ":" + n.ProviderRef
+			rows, err := billing.MarkPaid(tx, p.InvoiceID, "", ref)
+			if err != nil {
+				return Payment{}, err
+			}
+			// Invoice sudah lunas lewat transaksi lain atau sudah dibatalkan: dana dikembalikan
+			refundNeeded = rows == 0
+		case StatusRefunded:
+			// Refund yang dilakukan langsung dari dashboard provider (invoice hanya ikut jika dilunasi transaksi ini)
+			inv, err := billing.FindInvoiceByID(tx, p.InvoiceID)
+			if err != nil {
+				return Payment{}, err
+			}
+			if inv.PaymentRef == p.Provider+":"+p.ProviderRef {
+				if _, err := billing.MarkRefunded(tx, p.InvoiceID); err != nil {
+					return Payment{}, err
+				}
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Payment{}, err
+	}
+
+	if refundNeeded {
+		p.Status = StatusPaid
+		if err := refundPayment(db, p, "invoice sudah lunas / dibatalkan", false); err != nil {
+			log.Printf("Gagal refund pembayaran ganda %s: %v\n", p.OrderID, err)
+		}
+	}
+	return FindByID(db, p.ID)
+}
+
+// HandleCallback memverifikasi & memproses callback dari provider
+func HandleCallback(db *sql.DB, providerName string, header func(string) string, body []byte) (Payment, error) {
+	provider, err := providerByName(providerName)
+	if err != nil {
+		return Payment{}, err
+	}
+	n, err := provider.ParseCallback(header, body)
+	if err != nil {
+		return Payment{}, err
+	}
+	return applyNotification(db, provider.Name(), n, "callback")
+}
+
+// RefreshStatus menanyakan status transaksi pending ke provider (polling, untuk callback yang tidak sampai)
+func RefreshStatus(db *sql.DB, p Payment) (Payment, error) {
+	if p.Status != StatusPending {
+		return p, nil
+	}
+	provider, err := providerByName(p.Provider)
+	if err != nil {
+		return p, err
+	}
+
+	n, err := provider.GetStatus(p.OrderID)
+	switch {
+	case err == errOrderNotFound && time.Now().After(p.ExpiresAt):
+		// Link tidak pernah dibuka sampai kedaluwarsa
+		return applyNotification(db, p.Provider, Notification{OrderID: p.OrderID, Status: StatusExpired}, "expire")
+	case err != nil:
+		touch(db, p.ID)
+		if err == errOrderNotFound {
+			return p, nil
+		}
+		return p, err
+	case n.Status == StatusPending:
+		touch(db, p.ID)
+		return p, nil
+	}
+	return applyNotification(db, p.Provider, n, "poll")
+}
+
+// PollPending dijalankan worker: cek ulang transaksi pending yang belum mendapat callback
+func PollPending(db *sql.DB) error {
+	pending, err := findPending(db, time.Minute, 50)
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		if _, err := RefreshStatus(db, p); err != nil {
+			log.Printf("Gagal cek status pembayaran %s: %v\n", p.OrderID, err)
+		}
+	}
+	return nil
+}
+
+// ==========================
+// REFUND
+// ==========================
+
+// refundPayment mengembalikan dana lewat provider. withInvoice=false untuk pembayaran ganda (invoice tidak diubah).
+func refundPayment(db *sql.DB, p Payment, reason string, withInvoice bool) error {
+	provider, err := providerByName(p.Provider)
+	if err != nil {
+		return err
+	}
+
+	ref, err := provider.Refund(p.OrderID, p.Amount, reason)
+	if err != nil {
+		insertEvent(db, p.ID, "refund", "failed", err.Error())
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := markRefunded(tx, p.ID, ref); err != nil {
+		return err
+	}
+	if withInvoice {
+		if _, err := billing.MarkRefunded(tx, p.InvoiceID); err != nil {
+			return err
+		}
+	}
+	if err := insertEvent(tx, p.ID, "refund", StatusRefunded, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RefundForBooking dipanggil saat booking dibatalkan / ditolak setelah invoice dibayar online
+func RefundForBooking(db *sql.DB, bookingID, reason string) {
+	p, err := findPaidForBooking(db, bookingID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Gagal memeriksa pembayaran booking %s: %v\n", bookingID, err)
+			return
+		}
+		// Dibayar tunai / transfer yang dicek admin: dana dikembalikan manual
+		if inv, err := billing.FindInvoiceByBooking(db, bookingID); err == nil && inv.Status == "paid" {
+			log.Printf("Invoice %s dibayar manual, refund harus diproses manual oleh admin\n", inv.Number)
+		}
+		return
+	}
+
+	if err := refundPayment(db, p, reason, true); err != nil {
+		log.Printf("Gagal refund pembayaran %s untuk booking %s: %v\n", p.OrderID, bookingID, err)
+	}
+}
@@ -0,0 +1,217 @@
+package payment
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ========================================================
+// SIMULATOR PAYMENT GATEWAY (PENGEMBANGAN LOKAL)
+// ========================================================
+// Link mengarah ke halaman /payments/simulator/:order_id di backend ini. Tombol "Bayar" / "Gagal" / "Kedaluwarsa"
+// mengubah status lalu mengirim callback bertanda tangan ke /payments/callback/simulator, sama seperti gateway asli:
+// X-Simulator-Signature: sha256=HEX(HMAC_SHA256(secret, X-Simulator-Timestamp + "." + body)).
+// Status disimpan di memori, sehingga hilang saat server restart.
+
+const simulatorMaxSkew = 5 * time.Minute
+
+type simOrder struct {
+	OrderID     string
+	Amount      float64
+	Description string
+	Customer    string
+	Status      string
+	Ref         string
+	ExpiresAt   time.Time
+}
+
+var simStore = struct {
+	sync.Mutex
+	orders map[string]*simOrder
+}{orders: map[string]*simOrder{}}
+
+type simulator struct {
+	secret string
+}
+
+func newSimulator() (*simulator, error) {
+	secret := os.Getenv("PAYMENT_SIMULATOR_SECRET")
+	if secret == "" {
+		return nil, errors.New("PAYMENT_SIMULATOR_SECRET belum diset di .env")
+	}
+	return &simulator{secret: secret}, nil
+}
+
+func (s *simulator) Name() string { return "simulator" }
+
+// simPayload adalah body callback simulator
+type simPayload struct {
+	OrderID string  `json:"order_id"`
+	Status  string  `json:"status"`
+	Amount  float64 `json:"amount"`
+	Ref     string  `json:"ref"`
+}
+
+func (o *simOrder) notification() Notification {
+	raw, _ := json.Marshal(simPayload{OrderID: o.OrderID, Status: o.Status, Amount: o.Amount, Ref: o.Ref})
+	return Notification{
+		OrderID:     o.OrderID,
+		Status:      o.Status,
+		Amount:      o.Amount,
+		ProviderRef: o.Ref,
+		Payload:     string(raw),
+	}
+}
+
+func (s *simulator) CreateLink(req LinkRequest) (Link, error) {
+	o := &simOrder{
+		OrderID:     req.OrderID,
+		Amount:      req.Amount,
+		Description: req.Description,
+		Customer:    req.CustomerName,
+		Status:      StatusPending,
+		Ref:         "SIM-" + uuid.NewString()[:8],
+		ExpiresAt:   req.ExpiresAt,
+	}
+
+	simStore.Lock()
+	simStore.orders[req.OrderID] = o
+	simStore.Unlock()
+
+	return Link{URL: publicBaseURL() + "/payments/simulator/" + req.OrderID, ProviderRef: o.Ref}, nil
+}
+
+func (s *simulator) sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(s.secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *simulator) ParseCallback(header func(string) string, body []byte) (Notification, error) {
+	ts := header("X-Simulator-Timestamp")
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return Notification{}, errInvalidSignature
+	}
+	if skew := time.Since(time.Unix(unix, 0)); skew > simulatorMaxSkew || skew < -simulatorMaxSkew {
+		return Notification{}, errInvalidSignature
+	}
+	if !hmac.Equal([]byte(s.sign(ts, body)), []byte(header("X-Simulator-Signature"))) {
+		return Notification{}, errInvalidSignature
+	}
+
+	var p simPayload
+	if err := json.Unmarshal(body, &p); err != nil || p.OrderID == "" {
+		return Notification{}, errors.New("payload callback tidak valid")
+	}
+	return Notification{
+		OrderID:     p.OrderID,
+		Status:      p.Status,
+		Amount:      p.Amount,
+		ProviderRef: p.Ref,
+		Payload:     string(body),
+	}, nil
+}
+
+func (s *simulator) GetStatus(orderID string) (Notification, error) {
+	simStore.Lock()
+	defer simStore.Unlock()
+
+	o, ok := simStore.orders[orderID]
+	if !ok {
+		return Notification{}, errOrderNotFound
+	}
+	if o.Status == StatusPending && time.Now().After(o.ExpiresAt) {
+		o.Status = StatusExpired
+	}
+	return o.notification(), nil
+}
+
+func (s *simulator) Refund(orderID string, amount float64, reason string) (string, error) {
+	simStore.Lock()
+	defer simStore.Unlock()
+
+	o, ok := simStore.orders[orderID]
+	if !ok {
+		return "", errOrderNotFound
+	}
+	if o.Status != StatusPaid {
+		return "", errors.New("transaksi belum dibayar, tidak bisa di-refund")
+	}
+	o.Status = StatusRefunded
+	return "SIMREF-" + uuid.NewString()[:8], nil
+}
+
+// findSimOrder mengembalikan salinan order simulator untuk halaman pembayaran
+func findSimOrder(orderID string) (simOrder, bool) {
+	simStore.Lock()
+	defer simStore.Unlock()
+
+	o, ok := simStore.orders[orderID]
+	if !ok {
+		return simOrder{}, false
+	}
+	return *o, true
+}
+
+// completeSimOrder dipanggil dari halaman simulator: ubah status lalu kirim callback ke backend
+func (s *simulator) completeSimOrder(orderID, result string) error {
+	simStore.Lock()
+	o, ok := simStore.orders[orderID]
+	if !ok {
+		simStore.Unlock()
+		return errOrderNotFound
+	}
+	if o.Status != StatusPending {
+		simStore.Unlock()
+		return errors.New("transaksi sudah diproses")
+	}
+	switch result {
+	case StatusPaid, StatusFailed, StatusExpired:
+		o.Status = result
+	default:
+		simStore.Unlock()
+		return errors.New("hasil simulasi tidak valid")
+	}
+	n := o.notification()
+	simStore.Unlock()
+
+	return s.sendCallback([]byte(n.Payload))
+}
+
+func (s *simulator) sendCallback(body []byte) error {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest("POST", publicBaseURL()+"/payments/callback/simulator", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Simulator-Timestamp", ts)
+	req.Header.Set("X-Simulator-Signature", s.sign(ts, body))
+
+	client := &http.Client{Timeout: providerTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("gagal mengirim callback: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("callback ditolak backend dengan status %d", resp.StatusCode)
+	}
+	return nil
+}
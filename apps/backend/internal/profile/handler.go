@@ -2,9 +2,9 @@ package profile
 
 import (
 	"database/sql"
-	"fmt"
-	"path/filepath"
-	"time"
+
+	"campus-reservation-backend/internal/media"
+	"campus-reservation-backend/internal/storage"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -76,10 +76,16 @@ func UpdateHandler(db *sql.DB) fiber.Handler {
 			Position:       req.Position,
 		}
 
+		// Avatar lama dihapus dari storage setelah diganti (jika tidak dipakai data lain)
+		old, oldErr := GetByUserID(db, userID)
+
 		if err := Upsert(db, userID, profile); err != nil {
 			return c.Status(500).
 				JSON(fiber.Map{"error": "gagal menyimpan profil"})
 		}
+		if oldErr == nil && old.AvatarURL != profile.AvatarURL {
+			storage.Release(db, old.AvatarURL)
+		}
 
 		return c.JSON(fiber.Map{
 			"message": "profil berhasil disimpan",
@@ -102,21 +108,20 @@ func UploadAvatarHandler(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Ukuran file maksimal 2MB"})
 	}
 
-	// 3. Buat nama file unik
-	timestamp := time.Now().Unix()
-	ext := filepath.Ext(file.Filename)
-	filename := fmt.Sprintf("%d-%s%s", timestamp, "avatar", ext)
-
-	// 4. Simpan ke folder uploads
-	// Pastikan folder 'uploads' sudah ada di root project backend
-	if err := c.SaveFile(file, fmt.Sprintf("./uploads/%s", filename)); err != nil {
+	// 3. Validasi isi gambar, buang EXIF & buat varian WebP (nama file = hash isi, bukan nama asli)
+	v, err := media.Avatar.SaveFile(file)
+	if err != nil {
+		if media.IsInvalid(err) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "Gagal menyimpan file ke server"})
 	}
 
-	// 5. Return URL gambar
-	// URL ini akan dikirim frontend ke endpoint UpdateProfile
-	avatarURL := fmt.Sprintf("/uploads/%s", filename)
+	// 4. Return URL gambar
+	// URL ini (varian large) akan dikirim frontend ke endpoint UpdateProfile.
+	// Avatar yang tidak jadi disimpan ke profil dibersihkan worker file yatim.
 	return c.JSON(fiber.Map{
-		"url": avatarURL,
+		"url":      v.Large,
+		"variants": v,
 	})
 }
@@ -3,21 +3,24 @@ package profile
 import (
 	"database/sql"
 	"time"
+
+	"campus-reservation-backend/internal/media"
 )
 
 // Struct Profile sesuai database
 type Profile struct {
-	ID             string    `json:"id"`
-	UserID         string    `json:"user_id"`
-	FullName       string    `json:"full_name"`
-	PhoneNumber    string    `json:"phone_number"`
-	Address        string    `json:"address"`
-	AvatarURL      string    `json:"avatar_url"`
-	Gender         string    `json:"gender"`          // 'L' atau 'P'
-	IdentityNumber string    `json:"identity_number"` // NIM / NIP / NIDN
-	Department     string    `json:"department"`      // Jurusan / Unit
-	Position       string    `json:"position"`        // Semester / Jabatan
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID             string         `json:"id"`
+	UserID         string         `json:"user_id"`
+	FullName       string         `json:"full_name"`
+	PhoneNumber    string         `json:"phone_number"`
+	Address        string         `json:"address"`
+	AvatarURL      string         `json:"avatar_url"`
+	AvatarVariants media.Variants `json:"avatar_variants"`
+	Gender         string         `json:"gender"`          // 'L' atau 'P'
+	IdentityNumber string         `json:"identity_number"` // NIM / NIP / NIDN
+	Department     string         `json:"department"`      // Jurusan / Unit
+	Position       string         `json:"position"`        // Semester / Jabatan
+	UpdatedAt      time.Time      `json:"updated_at"`
 }
 
 // ==========================================
@@ -50,6 +53,7 @@ func GetByUserID(db *sql.DB, userID string) (*Profile, error) {
 	if err != nil {
 		return nil, err
 	}
+	p.AvatarVariants = media.VariantsOf(p.AvatarURL)
 	return &p, nil
 }
 
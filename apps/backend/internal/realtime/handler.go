@@ -0,0 +1,163 @@
+package realtime
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"campus-reservation-backend/internal/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ========================================================
+// HANDLER: STREAM SERVER-SENT EVENTS
+// ========================================================
+// Event yang dikirim:
+// - "ready"   : koneksi berhasil dibuka
+// - "booking" : ada booking yang dibuat / berubah status / check-in / dihapus
+// - "resync"  : koneksi LISTEN ke database sempat terputus, client sebaiknya memuat ulang data lengkap
+// Komentar ": ping" dikirim berkala agar koneksi tidak diputus proxy.
+
+const heartbeatInterval = 20 * time.Second
+
+// FacilityStreamHandler mengirim perubahan booking untuk satu fasilitas (halaman jadwal & kiosk)
+func FacilityStreamHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		facilityID := c.Params("id")
+
+		if !auth.APIKeyFacilityAllowed(c, facilityID) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "API key tidak diizinkan mengakses fasilitas ini",
+			})
+		}
+
+		return stream(c, TopicFacility(facilityID))
+	}
+}
+
+// AdminStreamHandler mengirim perubahan booking ke halaman approval admin.
+// Admin pusat menerima semua kampus, admin kampus hanya kampusnya sendiri.
+func AdminStreamHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if campusID := auth.AdminCampusID(c); campusID != "" {
+			return stream(c, TopicCampus(campusID))
+		}
+		return stream(c, TopicAdmin)
+	}
+}
+
+func stream(c *fiber.Ctx, topics ...string) error {
+	setStreamHeaders(c)
+
+	events, cancel := Subscribe(topics...)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		// retry: jeda reconnect otomatis EventSource (ms)
+		fmt.Fprintf(w, "retry: 3000\n")
+		if !writeEvent(w, Message{Event: "ready", Data: []byte(`{}`)}) {
+			return
+		}
+
+		for {
+			select {
+			case msg := <-events:
+				if !writeEvent(w, msg) {
+					return
+				}
+
+			case <-heartbeat.C:
+				fmt.Fprintf(w, ": ping\n\n")
+				// Flush gagal berarti client sudah menutup koneksi
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	})
+
+	return nil
+}
+
+func writeEvent(w *bufio.Writer, msg Message) bool {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Event, msg.Data)
+	return w.Flush() == nil
+}
+
+// StreamSnapshots dipakai layar yang selalu menampilkan data utuh (misal layar pintu ruangan).
+// Setiap ada perubahan di topik, koneksi DB tersambung ulang, atau interval refresh lewat,
+// load dipanggil ulang dan hasilnya dikirim sebagai satu event.
+func StreamSnapshots(c *fiber.Ctx, topic string, event string, refresh time.Duration, load func() (interface{}, error)) error {
+	setStreamHeaders(c)
+
+	events, cancel := Subscribe(topic)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		ticker := time.NewTicker(refresh)
+		defer ticker.Stop()
+
+		send := func() bool {
+			data, err := load()
+			if err != nil {
+				log.Printf("Realtime: gagal memuat snapshot %s: %v\n", topic, err)
+				// Tetap kirim ping agar koneksi terdeteksi masih hidup
+				fmt.Fprintf(w, ": ping\n\n")
+				return w.Flush() == nil
+			}
+			payload, err := json.Marshal(data)
+			if err != nil {
+				return false
+			}
+			return writeEvent(w, Message{Event: event, Data: payload})
+		}
+
+		fmt.Fprintf(w, "retry: 3000\n")
+		if !send() {
+			return
+		}
+
+		for {
+			select {
+			case <-events:
+				// Beberapa perubahan beruntun cukup dikirim sekali
+				drain(events)
+				if !send() {
+					return
+				}
+
+			case <-ticker.C:
+				if !send() {
+					return
+				}
+			}
+		}
+	})
+
+	return nil
+}
+
+func drain(events <-chan Message) {
+	for {
+		select {
+		case <-events:
+		default:
+			return
+		}
+	}
+}
+
+func setStreamHeaders(c *fiber.Ctx) {
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Matikan buffering di Nginx
+}
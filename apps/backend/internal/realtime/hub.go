@@ -0,0 +1,186 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ==========================
+// HUB (PUB/SUB DALAM PROSES)
+// ==========================
+// Setiap instance backend LISTEN ke channel Postgres "booking_changes" (diisi oleh trigger di tabel bookings),
+// lalu meneruskan perubahan ke client SSE yang berlangganan di instance tersebut.
+
+const (
+	channelBookingChanges = "booking_changes"
+
+	TopicAdmin = "admin"
+)
+
+// TopicFacility adalah topik untuk perubahan booking di satu fasilitas
+func TopicFacility(facilityID string) string {
+	return "facility:" + facilityID
+}
+
+// TopicCampus adalah topik untuk perubahan booking di satu kampus (stream admin kampus)
+func TopicCampus(campusID string) string {
+	return "campus:" + campusID
+}
+
+// Message adalah satu event SSE
+type Message struct {
+	Event string
+	Data  []byte
+}
+
+// BookingChange adalah isi NOTIFY dari trigger notify_booking_change()
+type BookingChange struct {
+	Op               string     `json:"op"`
+	ID               string     `json:"id"`
+	FacilityID       string     `json:"facility_id"`
+	CampusID         string     `json:"campus_id,omitempty"`
+	UserID           string     `json:"user_id,omitempty"`
+	Status           string     `json:"status"`
+	StartTime        time.Time  `json:"start_time"`
+	EndTime          time.Time  `json:"end_time"`
+	ActualEndTime    *time.Time `json:"actual_end_time"`
+	AttendanceStatus *string    `json:"attendance_status"`
+	IsCheckedIn      bool       `json:"is_checked_in"`
+	IsCheckedOut     bool       `json:"is_checked_out"`
+	Deleted          bool       `json:"deleted"`
+}
+
+type hub struct {
+	mu   sync.RWMutex
+	subs map[string]map[chan Message]struct{}
+}
+
+var defaultHub = &hub{subs: make(map[string]map[chan Message]struct{})}
+
+// Subscribe mendaftarkan client ke satu atau beberapa topik. Panggil fungsi cancel saat koneksi ditutup.
+func Subscribe(topics ...string) (<-chan Message, func()) {
+	ch := make(chan Message, 32)
+
+	defaultHub.mu.Lock()
+	for _, t := range topics {
+		if defaultHub.subs[t] == nil {
+			defaultHub.subs[t] = make(map[chan Message]struct{})
+		}
+		defaultHub.subs[t][ch] = struct{}{}
+	}
+	defaultHub.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			defaultHub.mu.Lock()
+			for _, t := range topics {
+				delete(defaultHub.subs[t], ch)
+				if len(defaultHub.subs[t]) == 0 {
+					delete(defaultHub.subs, t)
+				}
+			}
+			defaultHub.mu.Unlock()
+		})
+	}
+	return ch, cancel
+}
+
+// broadcast mengirim ke semua subscriber topik. Client yang lambat (buffer penuh) dilewati
+// agar tidak menahan client lain; client tersebut akan menerima event "resync" berikutnya.
+func broadcast(topic string, msg Message) {
+	defaultHub.mu.RLock()
+	defer defaultHub.mu.RUnlock()
+
+	for ch := range defaultHub.subs[topic] {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// broadcastAll dipakai untuk event yang berlaku ke semua client (misal "resync" setelah koneksi DB putus)
+func broadcastAll(msg Message) {
+	defaultHub.mu.RLock()
+	defer defaultHub.mu.RUnlock()
+
+	sent := make(map[chan Message]bool)
+	for _, subs := range defaultHub.subs {
+		for ch := range subs {
+			if sent[ch] {
+				continue
+			}
+			sent[ch] = true
+			select {
+			case ch <- msg:
+			default:
+			}
+		}
+	}
+}
+
+// ==========================
+// LISTENER POSTGRES
+// ==========================
+
+// Start menjalankan LISTEN di koneksi terpisah (pq.Listener otomatis reconnect).
+// dsn sama dengan DATABASE_URL yang dipakai database.Connect.
+func Start(dsn string) {
+	listener := pq.NewListener(dsn, 2*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventConnectionAttemptFailed, pq.ListenerEventDisconnected:
+			log.Printf("Realtime: koneksi LISTEN terputus: %v\n", err)
+		case pq.ListenerEventReconnected:
+			log.Println("Realtime: koneksi LISTEN tersambung kembali")
+		}
+	})
+
+	if err := listener.Listen(channelBookingChanges); err != nil {
+		log.Printf("Realtime: gagal LISTEN %s: %v\n", channelBookingChanges, err)
+	}
+
+	go func() {
+		log.Println("Realtime Listener Started...")
+
+		for {
+			select {
+			case n := <-listener.Notify:
+				// n == nil berarti koneksi baru tersambung ulang; notifikasi di antaranya mungkin hilang
+				if n == nil {
+					broadcastAll(Message{Event: "resync", Data: []byte(`{}`)})
+					continue
+				}
+				dispatch([]byte(n.Extra))
+
+			case <-time.After(90 * time.Second):
+				// Cek koneksi secara berkala
+				go func() { _ = listener.Ping() }()
+			}
+		}
+	}()
+}
+
+func dispatch(payload []byte) {
+	var change BookingChange
+	if err := json.Unmarshal(payload, &change); err != nil {
+		log.Printf("Realtime: payload NOTIFY tidak valid: %v\n", err)
+		return
+	}
+
+	broadcast(TopicAdmin, Message{Event: "booking", Data: payload})
+	if change.CampusID != "" {
+		broadcast(TopicCampus(change.CampusID), Message{Event: "booking", Data: payload})
+	}
+
+	// Stream fasilitas bisa diakses semua user login & kiosk, jadi user_id pemesan tidak ikut dikirim
+	if change.FacilityID != "" {
+		change.UserID = ""
+		public, _ := json.Marshal(change)
+		broadcast(TopicFacility(change.FacilityID), Message{Event: "booking", Data: public})
+	}
+}
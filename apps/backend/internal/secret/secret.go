@@ -0,0 +1,29 @@
+package secret
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"os"
+)
+
+// ==========================
+// KUNCI TURUNAN JWT_SECRET
+// ==========================
+// Fitur yang butuh kunci HMAC sendiri (signed URL storage, verifikasi tiket, dll) boleh memakai kunci khusus dari
+// .env. Jika tidak diset, kunci diturunkan dari JWT_SECRET per tujuan sehingga tidak pernah sama dengan kunci token
+// login maupun kunci fitur lain. Package ini tidak mengimpor package internal lain agar bisa dipakai di mana saja.
+
+// DeriveKey: HMAC-SHA256(JWT_SECRET, purpose)
+func DeriveKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// Key: nilai env jika diset, selain itu DeriveKey(purpose)
+func Key(env, purpose string) []byte {
+	if s := os.Getenv(env); s != "" {
+		return []byte(s)
+	}
+	return DeriveKey(purpose)
+}
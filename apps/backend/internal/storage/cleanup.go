@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ==========================
+// PEMBERSIHAN FILE YATIM
+// ==========================
+// Karena key content-addressed, satu file bisa dipakai beberapa data sekaligus (misal foto yang sama untuk dua
+// fasilitas). File hanya dihapus jika sudah tidak direferensikan di tabel mana pun.
+
+// OrphanMinAge: file yang lebih muda dari ini tidak disapu (upload avatar yang belum disimpan ke profil, dsb)
+const OrphanMinAge = 24 * time.Hour
+
+// legacyUploadPrefix: URL file lama sebelum ada storage (disajikan app.Static dari ./uploads)
+const legacyUploadPrefix = "/uploads/"
+
+// referenced memeriksa semua kolom yang menyimpan URL publik / key file. Pencocokan memakai awalan agar satu
+// referensi ke salah satu file grup (misal URL varian large) menjaga seluruh grup.
+func referenced(db *sql.DB, key, url string) (bool, error) {
+	var used bool
+	err := db.QueryRow(`
+		SELECT ($2::text <> '' AND (
+				EXISTS (SELECT 1 FROM facilities, unnest(photo_url) AS u WHERE starts_with(u, $2::text))
+				OR EXISTS (SELECT 1 FROM profiles WHERE starts_with(avatar_url, $2::text))
+			))
+			OR ($1::text <> '' AND (
+				EXISTS (SELECT 1 FROM booking_documents WHERE starts_with(file_path, $1::text))
+				OR EXISTS (SELECT 1 FROM guest_booking_requests WHERE starts_with(document_path, $1::text))
+			))
+	`, key, url).Scan(&used)
+	return used, err
+}
+
+// groupReferenced memeriksa pemakaian grup tempat key berada
+func groupReferenced(db *sql.DB, b Backend, key string) (bool, error) {
+	group, url := groupOf(key), ""
+	if IsPublic(group) {
+		url = b.PublicURL(group)
+	}
+	return referenced(db, group, url)
+}
+
+// Release menghapus file (URL publik, key, atau URL lama /uploads/...) yang sudah tidak dipakai.
+// Untuk file di dalam grup, seluruh isi grup ikut dihapus.
+// Dipanggil setelah data yang mereferensikannya diganti / dihapus; kegagalan hanya dicatat di log.
+func Release(db *sql.DB, refs ...string) {
+	b, err := Active()
+	if err != nil {
+		return
+	}
+	for _, ref := range refs {
+		if ref == "" {
+			continue
+		}
+
+		if strings.HasPrefix(ref, legacyUploadPrefix) {
+			if used, err := referenced(db, "", ref); err != nil || used {
+				continue
+			}
+			name := filepath.Base(ref)
+			if err := os.Remove(filepath.Join("./uploads", name)); err != nil && !os.IsNotExist(err) {
+				log.Printf("Gagal menghapus file lama %s: %v\n", name, err)
+			}
+			continue
+		}
+
+		key := ref
+		if k, ok := KeyFromURL(ref); ok {
+			key = k
+		} else if !validKey(ref) {
+			continue
+		}
+
+		used, err := groupReferenced(db, b, key)
+		if err != nil {
+			log.Printf("Gagal memeriksa pemakaian file %s: %v\n", key, err)
+			continue
+		}
+		if used {
+			continue
+		}
+
+		keys := []string{key}
+		if group := groupOf(key); group != key {
+			objects, err := b.List(group)
+			if err != nil {
+				log.Printf("Gagal membaca isi grup file %s: %v\n", group, err)
+				continue
+			}
+			keys = keys[:0]
+			for _, o := range objects {
+				keys = append(keys, o.Key)
+			}
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				log.Printf("Gagal menghapus file %s: %v\n", k, err)
+			}
+		}
+	}
+}
+
+// CleanupOrphans menyapu file yang tidak direferensikan & lebih tua dari OrphanMinAge
+// (upload yang tidak jadi dipakai, dokumen booking yang ikut terhapus, dsb). Mengembalikan jumlah file yang dihapus.
+func CleanupOrphans(db *sql.DB) (int, error) {
+	b, err := Active()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	checked := map[string]bool{} // grup -> masih dipakai
+	for _, prefix := range []string{"public/", "private/"} {
+		objects, err := b.List(prefix)
+		if err != nil {
+			return removed, err
+		}
+		for _, o := range objects {
+			if time.Since(o.ModTime) < OrphanMinAge {
+				continue
+			}
+			group := groupOf(o.Key)
+			used, ok := checked[group]
+			if !ok {
+				used, err = groupReferenced(db, b, o.Key)
+				if err != nil {
+					return removed, err
+				}
+				checked[group] = used
+			}
+			if used {
+				continue
+			}
+			if err := b.Delete(o.Key); err != nil {
+				log.Printf("Gagal menghapus file yatim %s: %v\n", o.Key, err)
+				continue
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ========================================================
+// HANDLER: SAJIKAN FILE (BACKEND LOKAL)
+// ========================================================
+
+// FileHandler: GET /files/* untuk backend lokal. File publik disajikan langsung (cache panjang karena key
+// content-addressed tidak pernah berubah isinya), file privat wajib membawa signed URL yang masih berlaku.
+func FileHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		b, err := Active()
+		l, ok := b.(*local)
+		key := c.Params("*")
+		if err != nil || !ok || !validKey(key) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "File tidak ditemukan",
+			})
+		}
+
+		name := c.Query("name")
+		if IsPublic(key) {
+			c.Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			if !verifySignedURL(key, c.Query("expires"), name, c.Query("signature")) {
+				return c.Status(403).JSON(fiber.Map{
+					"error": "Link file tidak valid atau sudah kedaluwarsa",
+				})
+			}
+			c.Set("Cache-Control", "private, no-store")
+		}
+
+		path := l.path(key)
+		if _, err := os.Stat(path); err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "File tidak ditemukan",
+			})
+		}
+		if name != "" {
+			c.Attachment(name)
+		}
+		return c.SendFile(path)
+	}
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========================================================
+// PENYIMPANAN LOKAL (DISK)
+// ========================================================
+// File disimpan di STORAGE_LOCAL_DIR (default ./storage/files) dan disajikan lewat GET /files/<key>.
+// File privat hanya bisa diunduh dengan signed URL: HMAC-SHA256(key + expires + name) memakai STORAGE_SIGNING_SECRET.
+// Cocok untuk development / satu instance; untuk beberapa instance gunakan STORAGE_DRIVER=s3.
+
+type local struct {
+	root string
+}
+
+func newLocal() *local {
+	root := os.Getenv("STORAGE_LOCAL_DIR")
+	if root == "" {
+		root = "./storage/files"
+	}
+	return &local{root: root}
+}
+
+func (l *local) Name() string { return "local" }
+
+func (l *local) path(key string) string {
+	return filepath.Join(l.root, filepath.FromSlash(key))
+}
+
+// Put menulis ke file sementara lalu rename agar pembaca tidak pernah melihat file setengah jadi
+func (l *local) Put(key string, data []byte, contentType string) error {
+	dest := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+func (l *local) Open(key string) (io.ReadCloser, error) {
+	f, err := os.Open(l.path(key))
+	if os.IsNotExist(err) {
+		return nil, errNotFound
+	}
+	return f, err
+}
+
+func (l *local) Delete(key string) error {
+	if err := os.Remove(l.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (l *local) List(prefix string) ([]ObjectInfo, error) {
+	var list []ObjectInfo
+	err := filepath.WalkDir(l.path(prefix), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(l.root, path)
+		if err != nil {
+			return err
+		}
+		list = append(list, ObjectInfo{Key: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return list, err
+}
+
+func (l *local) PublicURL(key string) string {
+	return "/files/" + key
+}
+
+func localSignature(key, expires, name string) string {
+	mac := hmac.New(sha256.New, signingSecret())
+	mac.Write([]byte(key + "\n" + expires + "\n" + name))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *local) SignedURL(key string, ttl time.Duration, downloadName string) (string, error) {
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	if downloadName != "" {
+		q.Set("name", downloadName)
+	}
+	q.Set("signature", localSignature(key, expires, downloadName))
+	return l.PublicURL(key) + "?" + q.Encode(), nil
+}
+
+// verifySignedURL memeriksa parameter signed URL lokal
+func verifySignedURL(key, expires, name, signature string) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(localSignature(key, expires, name)))
+}
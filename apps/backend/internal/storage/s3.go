@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========================================================
+// PENYIMPANAN S3-COMPATIBLE (AWS S3 / MINIO)
+// ========================================================
+// Request ditandatangani AWS Signature V4 dengan path-style URL ({endpoint}/{bucket}/{key}) agar bisa dipakai MinIO.
+// File publik diakses lewat S3_PUBLIC_URL (misal CDN, atau bucket policy public-read untuk prefix public/),
+// file privat lewat presigned URL.
+
+const s3Algorithm = "AWS4-HMAC-SHA256"
+
+type s3 struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	publicURL string
+	client    *http.Client
+}
+
+func newS3() (*s3, error) {
+	endpoint := os.Getenv("S3_ENDPOINT")
+	bucket := os.Getenv("S3_BUCKET")
+	accessKey := os.Getenv("S3_ACCESS_KEY")
+	secretKey := os.Getenv("S3_SECRET_KEY")
+	if endpoint == "" || bucket == "" || accessKey == "" || secretKey == "" {
+		return nil, errors.New("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY dan S3_SECRET_KEY wajib diset untuk STORAGE_DRIVER=s3")
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, errors.New("S3_ENDPOINT tidak valid")
+	}
+
+	s := &s3{
+		endpoint:  u,
+		bucket:    bucket,
+		region:    os.Getenv("S3_REGION"),
+		accessKey: accessKey,
+		secretKey: secretKey,
+		publicURL: strings.TrimRight(os.Getenv("S3_PUBLIC_URL"), "/"),
+		client:    &http.Client{Timeout: backendTimeout},
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+	if s.publicURL == "" {
+		s.publicURL = u.String() + "/" + bucket
+	}
+	return s, nil
+}
+
+func (s *s3) Name() string { return "s3" }
+
+// objectPath: path-style, sudah di-encode sesuai aturan SigV4
+func (s *s3) objectPath(key string) string {
+	return awsEscapePath(s.endpoint.Path + "/" + s.bucket + "/" + key)
+}
+
+// ==========================
+// SIGNATURE V4
+// ==========================
+
+// awsEscape: URI encode ala AWS (hanya A-Z a-z 0-9 - _ . ~ yang tidak di-encode, spasi = %20)
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func awsEscapePath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		parts[i] = awsEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			pairs = append(pairs, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *s3) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+}
+
+// sign menghitung signature dari canonical request
+func (s *s3) sign(t time.Time, canonicalRequest string) string {
+	stringToSign := s3Algorithm + "\n" + t.Format("20060102T150405Z") + "\n" + s.scope(t) + "\n" +
+		sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// do mengirim request bertanda tangan (header Authorization) ke S3
+func (s *s3) do(method, path string, query url.Values, body []byte, contentType string) (*http.Response, error) {
+	t := time.Now().UTC()
+	payloadHash := sha256Hex(body)
+	amzDate := t.Format("20060102T150405Z")
+	rawQuery := canonicalQuery(query)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		method,
+		path,
+		rawQuery,
+		"host:" + s.endpoint.Host + "\n" + "x-amz-content-sha256:" + payloadHash + "\n" + "x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	target := s.endpoint.Scheme + "://" + s.endpoint.Host + path
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.accessKey, s.scope(t), signedHeaders, s.sign(t, canonical)))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return s.client.Do(req)
+}
+
+func s3Error(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 %s gagal (HTTP %d): %s", op, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// ==========================
+// OPERASI FILE
+// ==========================
+
+func (s *s3) Put(key string, data []byte, contentType string) error {
+	resp, err := s.do(http.MethodPut, s.objectPath(key), nil, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return s3Error("put", resp)
+	}
+	return nil
+}
+
+func (s *s3) Open(key string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, s.objectPath(key), nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, errNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, s3Error("get", resp)
+	}
+	return resp.Body, nil
+}
+
+func (s *s3) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, s.objectPath(key), nil, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return s3Error("delete", resp)
+	}
+	return nil
+}
+
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// List memakai ListObjectsV2 (per halaman 1000 object)
+func (s *s3) List(prefix string) ([]ObjectInfo, error) {
+	var list []ObjectInfo
+	token := ""
+	for {
+		q := url.Values{}
+		q.Set("list-type", "2")
+		q.Set("prefix", prefix)
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(http.MethodGet, awsEscapePath(s.endpoint.Path+"/"+s.bucket), q, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode/100 != 2 {
+			err := s3Error("list", resp)
+			resp.Body.Close()
+			return nil, err
+		}
+		var res s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range res.Contents {
+			list = append(list, ObjectInfo{Key: c.Key, Size: c.Size, ModTime: c.LastModified})
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return list, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+func (s *s3) PublicURL(key string) string {
+	return s.publicURL + "/" + key
+}
+
+// SignedURL membuat presigned GET (maksimal 7 hari sesuai batas SigV4)
+func (s *s3) SignedURL(key string, ttl time.Duration, downloadName string) (string, error) {
+	if ttl > 7*24*time.Hour {
+		ttl = 7 * 24 * time.Hour
+	}
+	return s.presign(s.endpoint.Scheme, s.endpoint.Host, s.objectPath(key), ttl, downloadName, time.Now().UTC()), nil
+}
+
+func (s *s3) presign(scheme, host, path string, ttl time.Duration, downloadName string, t time.Time) string {
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", s3Algorithm)
+	q.Set("X-Amz-Credential", s.accessKey+"/"+s.scope(t))
+	q.Set("X-Amz-Date", t.Format("20060102T150405Z"))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(ttl.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+	if downloadName != "" {
+		q.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": downloadName}))
+	}
+
+	rawQuery := canonicalQuery(q)
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		path,
+		rawQuery,
+		"host:" + host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	return scheme + "://" + host + path + "?" + rawQuery + "&X-Amz-Signature=" + s.sign(t, canonical)
+}
@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"campus-reservation-backend/internal/secret"
+)
+
+// ========================================================
+// ABSTRAKSI PENYIMPANAN FILE
+// ========================================================
+// Semua upload (foto fasilitas, avatar, dokumen booking) disimpan lewat Backend yang aktif (STORAGE_DRIVER=local|s3),
+// sehingga beberapa instance backend bisa berbagi file yang sama lewat S3 / MinIO.
+//
+// Key bersifat content-addressed: <prefix>/<sha256[:2]>/<sha256><ext>. File yang sama selalu mendapat key yang sama,
+// nama file asli (spasi, karakter aneh, tabrakan nama) tidak pernah dipakai di path. Turunan sebuah file (varian
+// gambar) disimpan sebagai grup: <prefix>/<sha256[:2]>/<sha256>/<nama>.
+// Prefix public/... boleh diakses langsung lewat URL publik, private/... hanya lewat signed URL berbatas waktu.
+
+// Prefix key per jenis file
+const (
+	PrefixFacilityPhotos   = "public/facilities"
+	PrefixAvatars          = "public/avatars"
+	PrefixBookingDocuments = "private/booking-documents"
+	PrefixGuestDocuments   = "private/guest-documents"
+)
+
+var errNotFound = errors.New("file tidak ditemukan")
+
+const backendTimeout = 30 * time.Second
+
+// ObjectInfo dipakai saat membersihkan file yatim
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Backend diimplementasikan oleh setiap tempat penyimpanan
+type Backend interface {
+	Name() string
+	Put(key string, data []byte, contentType string) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	List(prefix string) ([]ObjectInfo, error)
+	// PublicURL: URL permanen untuk key public/... (PublicURL("") = awalan semua URL publik)
+	PublicURL(key string) string
+	// SignedURL: URL sementara untuk key private/...; downloadName (opsional) menjadi nama file saat diunduh
+	SignedURL(key string, ttl time.Duration, downloadName string) (string, error)
+}
+
+// Object adalah hasil penyimpanan sebuah file
+type Object struct {
+	Key         string `json:"key"`
+	URL         string `json:"url,omitempty"` // hanya untuk file publik
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+var (
+	activeOnce    sync.Once
+	activeBackend Backend
+	activeErr     error
+)
+
+// Active: backend yang dipakai (STORAGE_DRIVER=local|s3, default local). Konfigurasi dibaca sekali saat pertama dipakai.
+func Active() (Backend, error) {
+	activeOnce.Do(func() {
+		switch driver := strings.ToLower(strings.TrimSpace(os.Getenv("STORAGE_DRIVER"))); driver {
+		case "", "local":
+			activeBackend = newLocal()
+		case "s3":
+			activeBackend, activeErr = newS3()
+		default:
+			activeErr = errors.New("STORAGE_DRIVER tidak dikenal: " + driver)
+		}
+	})
+	return activeBackend, activeErr
+}
+
+// IsPublic: file dengan key ini boleh diakses tanpa signed URL
+func IsPublic(key string) bool {
+	return strings.HasPrefix(key, "public/")
+}
+
+// validKey menolak key yang bisa keluar dari folder penyimpanan (../, path absolut)
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// cleanExt menormalkan ekstensi nama file asli: huruf kecil, hanya huruf & angka
+func cleanExt(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if len(ext) < 2 || len(ext) > 6 {
+		return ""
+	}
+	for _, r := range ext[1:] {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+// ContentKey membuat key content-addressed untuk data di bawah prefix
+func ContentKey(prefix string, data []byte, ext string) string {
+	return GroupKey(prefix, data) + ext
+}
+
+// GroupKey: folder content-addressed <prefix>/<sha256[:2]>/<sha256> untuk beberapa turunan satu file
+// (misal varian ukuran gambar). Semua file di dalamnya diperlakukan sebagai satu kesatuan saat pembersihan.
+func GroupKey(prefix string, data []byte) string {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	return prefix + "/" + hash[:2] + "/" + hash
+}
+
+// groupOf: awalan key yang mewakili pemakaian sebuah file, yaitu folder grup jika file ada di dalam GroupKey,
+// atau key itu sendiri untuk file tunggal
+func groupOf(key string) string {
+	dir := path.Dir(key)
+	if isHash(path.Base(dir)) {
+		return dir + "/"
+	}
+	return key
+}
+
+func isHash(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// Save menyimpan data di bawah prefix. File yang isinya sama ditulis ulang ke key yang sama (idempotent).
+func Save(prefix string, data []byte, ext, contentType string) (Object, error) {
+	return SaveKey(ContentKey(prefix, data, ext), data, contentType)
+}
+
+// SaveKey menyimpan data ke key yang sudah ditentukan (dipakai untuk file di dalam GroupKey)
+func SaveKey(key string, data []byte, contentType string) (Object, error) {
+	b, err := Active()
+	if err != nil {
+		return Object{}, err
+	}
+	if !validKey(key) {
+		return Object{}, errors.New("key file tidak valid")
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+
+	if err := b.Put(key, data, contentType); err != nil {
+		return Object{}, err
+	}
+
+	obj := Object{Key: key, ContentType: contentType, Size: int64(len(data))}
+	if IsPublic(key) {
+		obj.URL = b.PublicURL(key)
+	}
+	return obj, nil
+}
+
+// SaveFile menyimpan file upload multipart (pengganti c.SaveFile ke ./uploads)
+func SaveFile(file *multipart.FileHeader, prefix string) (Object, error) {
+	f, err := file.Open()
+	if err != nil {
+		return Object{}, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return Object{}, err
+	}
+	return Save(prefix, data, cleanExt(file.Filename), "")
+}
+
+// Open membuka isi file (dipakai endpoint unduh yang sudah memeriksa hak akses)
+func Open(key string) (io.ReadCloser, error) {
+	b, err := Active()
+	if err != nil {
+		return nil, err
+	}
+	if !validKey(key) {
+		return nil, errNotFound
+	}
+	return b.Open(key)
+}
+
+// SignedURL membuat link unduh sementara untuk file privat
+func SignedURL(key string, ttl time.Duration, downloadName string) (string, error) {
+	b, err := Active()
+	if err != nil {
+		return "", err
+	}
+	if !validKey(key) {
+		return "", errNotFound
+	}
+	return b.SignedURL(key, ttl, downloadName)
+}
+
+// KeyFromURL mengembalikan key dari URL publik yang dibuat backend aktif
+func KeyFromURL(url string) (string, bool) {
+	b, err := Active()
+	if err != nil {
+		return "", false
+	}
+	base := b.PublicURL("")
+	if !strings.HasPrefix(url, base) {
+		return "", false
+	}
+	key := strings.TrimPrefix(url, base)
+	return key, validKey(key)
+}
+
+// signingSecret untuk signed URL backend lokal: STORAGE_SIGNING_SECRET atau kunci turunan JWT_SECRET
+func signingSecret() []byte {
+	return secret.Key("STORAGE_SIGNING_SECRET", "storage-url")
+}
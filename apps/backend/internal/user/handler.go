@@ -2,7 +2,10 @@ package user
 
 import (
 	"database/sql"
+	"slices"
+	"strings"
 
+	"campus-reservation-backend/internal/billing"
 	"campus-reservation-backend/internal/booking" // [FIX] Import ini penting untuk cancel booking
 
 	"github.com/gofiber/fiber/v2"
@@ -57,6 +60,8 @@ func GetOneHandler(db *sql.DB) fiber.Handler {
 			"email":      user.Email,
 			"role":       user.Role,
 			"created_at": user.CreatedAt,
+			"campus_id":  user.AdminCampusID,
+			"user_type":  user.UserType,
 			"profile":    user.Profile, // Data profile (alamat, hp, dll)
 			"stats":      stats,        // Data statistik (on_time, late, no_show)
 		})
@@ -93,6 +98,70 @@ func UpdateRoleHandler(db *sql.DB) fiber.Handler {
 	}
 }
 
+// ==========================
+// UPDATE KAMPUS ADMIN HANDLER
+// ==========================
+// Body: { "campus_id": "..." } atau { "campus_id": "" } untuk menjadikan admin pusat
+func UpdateAdminCampusHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+
+		var req struct {
+			CampusID string `json:"campus_id"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Format data salah"})
+		}
+
+		n, err := UpdateAdminCampus(db, id, req.CampusID)
+		if err != nil {
+			if strings.Contains(err.Error(), "admin_campus_id") || strings.Contains(err.Error(), "uuid") {
+				return c.Status(400).JSON(fiber.Map{"error": "Kampus tidak ditemukan"})
+			}
+			return c.Status(500).JSON(fiber.Map{"error": "Gagal update kampus admin"})
+		}
+		if n == 0 {
+			return c.Status(404).JSON(fiber.Map{"error": "Admin tidak ditemukan"})
+		}
+
+		if req.CampusID == "" {
+			return c.JSON(fiber.Map{"message": "Admin sekarang menjadi admin pusat"})
+		}
+		return c.JSON(fiber.Map{"message": "Kampus admin berhasil diperbarui"})
+	}
+}
+
+// ==========================
+// UPDATE JENIS USER HANDLER
+// ==========================
+// Body: { "user_type": "student" | "staff" | "external" } -> menentukan tarif booking
+func UpdateUserTypeHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+
+		var req struct {
+			UserType string `json:"user_type"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Format data salah"})
+		}
+
+		if !slices.Contains(billing.UserTypes, req.UserType) {
+			return c.Status(400).JSON(fiber.Map{"error": "Jenis user tidak valid (student, staff, external)"})
+		}
+
+		n, err := UpdateUserType(db, id, req.UserType)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Gagal update jenis user"})
+		}
+		if n == 0 {
+			return c.Status(404).JSON(fiber.Map{"error": "User tidak ditemukan"})
+		}
+
+		return c.JSON(fiber.Map{"message": "Jenis user berhasil diubah menjadi " + req.UserType})
+	}
+}
+
 // ==========================
 // DELETE USER HANDLER (FIXED: Transaction & Arguments)
 // ==========================
@@ -122,7 +191,8 @@ func DeleteUserHandler(db *sql.DB) fiber.Handler {
 
 		// 2. Batalkan Booking Masa Depan User Tersebut
 		//    (Agar ruangan kosong kembali dan bisa dipesan orang lain)
-		if err := booking.CancelFutureBookingsTx(tx, id, adminID); err != nil {
+		canceledIDs, err := booking.CancelFutureBookingsTx(tx, id, adminID)
+		if err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": "Gagal membatalkan booking user"})
 		}
 
@@ -137,6 +207,9 @@ func DeleteUserHandler(db *sql.DB) fiber.Handler {
 			return c.Status(500).JSON(fiber.Map{"error": "Gagal menyimpan perubahan"})
 		}
 
+		// 5. Batalkan invoice & refund pembayaran booking yang dibatalkan
+		booking.SettleCanceledBookings(db, canceledIDs, "akun pemesan dihapus")
+
 		return c.JSON(fiber.Map{"message": "User berhasil dihapus, email dibebaskan, dan jadwal mendatang dibatalkan"})
 	}
 }
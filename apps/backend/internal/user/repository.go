@@ -29,6 +29,10 @@ type User struct {
 	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
 	Profile   Profile   `json:"profile"` // Data profile terlampir
+	// Kampus yang dikelola (khusus admin). Kosong = admin pusat
+	AdminCampusID string `json:"admin_campus_id,omitempty"`
+	// Jenis user untuk tarif booking: student | staff | external
+	UserType string `json:"user_type"`
 }
 
 // ==========================
@@ -48,7 +52,9 @@ func GetAllUsers(db *sql.DB) ([]User, error) {
 			COALESCE(p.gender, ''),
 			COALESCE(p.identity_number, ''),
 			COALESCE(p.department, ''),
-			COALESCE(p.position, '')
+			COALESCE(p.position, ''),
+			COALESCE(u.admin_campus_id::text, ''),
+			u.user_type
 		FROM users u
 		LEFT JOIN profiles p ON u.id = p.user_id
 		WHERE u.deleted_at IS NULL
@@ -74,6 +80,8 @@ func GetAllUsers(db *sql.DB) ([]User, error) {
 			&u.Profile.IdentityNumber,
 			&u.Profile.Department,
 			&u.Profile.Position,
+			&u.AdminCampusID,
+			&u.UserType,
 		); err != nil {
 			return nil, err
 		}
@@ -88,12 +96,44 @@ func GetAllUsers(db *sql.DB) ([]User, error) {
 func UpdateUserRole(db *sql.DB, userID string, newRole string) error {
 	_, err := db.Exec(`
 		UPDATE users 
-		SET role = $1, updated_at = NOW()
+		SET role = $1,
+		    admin_campus_id = CASE WHEN $1 = 'admin' THEN admin_campus_id END, -- turun jadi user = lepas kampus
+		    updated_at = NOW()
 		WHERE id = $2 AND deleted_at IS NULL
 	`, newRole, userID)
 	return err
 }
 
+// ==========================
+// UPDATE KAMPUS ADMIN
+// ==========================
+// campusID kosong = admin pusat (mengelola semua kampus)
+func UpdateAdminCampus(db *sql.DB, userID string, campusID string) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE users
+		SET admin_campus_id = NULLIF($1, '')::uuid, updated_at = NOW()
+		WHERE id = $2 AND role = 'admin' AND deleted_at IS NULL
+	`, campusID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ==========================
+// UPDATE JENIS USER (TARIF)
+// ==========================
+func UpdateUserType(db *sql.DB, userID string, userType string) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE users SET user_type = $1, updated_at = NOW()
+		WHERE id = $2 AND deleted_at IS NULL
+	`, userType, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 // ==========================
 // DELETE USER (SOFT DELETE + RENAME EMAIL)
 // ==========================
@@ -148,7 +188,9 @@ func GetUserByID(db *sql.DB, id string) (*User, error) {
 			COALESCE(p.gender, ''),
 			COALESCE(p.identity_number, ''),
 			COALESCE(p.department, ''),
-			COALESCE(p.position, '')
+			COALESCE(p.position, ''),
+			COALESCE(u.admin_campus_id::text, ''),
+			u.user_type
 		FROM users u
 		LEFT JOIN profiles p ON u.id = p.user_id
 		WHERE u.id = $1 AND u.deleted_at IS NULL
@@ -164,6 +206,8 @@ func GetUserByID(db *sql.DB, id string) (*User, error) {
 		&u.Profile.IdentityNumber,
 		&u.Profile.Department,
 		&u.Profile.Position,
+		&u.AdminCampusID,
+		&u.UserType,
 	)
 
 	if err != nil {
@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ========================================================
+// HANDLER: LANGGANAN WEBHOOK (ADMIN)
+// ========================================================
+
+func CreateHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID, _ := c.Locals("user_id").(string)
+
+		var req SubscriptionRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		res, err := CreateSubscription(db, adminID, req)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(201).JSON(res)
+	}
+}
+
+func ListHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		subs, err := FindAllSubscriptions(db)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal memuat daftar webhook",
+			})
+		}
+		return c.JSON(subs)
+	}
+}
+
+func UpdateHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req SubscriptionRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Format request tidak valid",
+			})
+		}
+
+		sub, err := UpdateSubscriptionByID(db, c.Params("id"), req)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(sub)
+	}
+}
+
+func DeleteHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := RemoveSubscription(db, c.Params("id")); err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"message": "Webhook berhasil dihapus",
+		})
+	}
+}
+
+func RotateSecretHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		secret, err := RotateSecret(db, c.Params("id"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"secret": secret,
+		})
+	}
+}
+
+func PingHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		deliveryID, err := SendPing(db, c.Params("id"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(202).JSON(fiber.Map{
+			"message":     "Ping dimasukkan ke antrian",
+			"delivery_id": deliveryID,
+		})
+	}
+}
+
+// ========================================================
+// HANDLER: LOG PENGIRIMAN (ADMIN)
+// ========================================================
+
+func ListDeliveriesHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := c.QueryInt("limit", 50)
+		if limit <= 0 || limit > 200 {
+			limit = 50
+		}
+
+		deliveries, err := FindDeliveries(db, c.Params("id"), c.Query("status"), limit)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Gagal memuat log pengiriman",
+			})
+		}
+		return c.JSON(deliveries)
+	}
+}
+
+func GetDeliveryHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		d, err := FindDeliveryByID(db, c.Params("id"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Data pengiriman tidak ditemukan",
+			})
+		}
+		return c.JSON(d)
+	}
+}
+
+func RedeliverHandler(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := Redeliver(db, c.Params("id")); err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(202).JSON(fiber.Map{
+			"message": "Pengiriman dijadwalkan ulang",
+		})
+	}
+}
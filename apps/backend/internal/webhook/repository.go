@@ -0,0 +1,357 @@
+package webhook
+
+import (
+	"database/sql"
+	"time"
+
+	"campus-reservation-backend/internal/database"
+
+	"github.com/lib/pq"
+)
+
+// ==========================
+// MODEL
+// ==========================
+
+type Subscription struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	Events      []string  `json:"events"`
+	IsActive    bool      `json:"is_active"`
+	CreatedBy   *string   `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Delivery struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Payload        RawJSON    `json:"payload,omitempty"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code"`
+	LastError      *string    `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Logs           []Attempt  `json:"logs,omitempty"`
+}
+
+type Attempt struct {
+	Attempt      int       `json:"attempt"`
+	StatusCode   *int      `json:"status_code"`
+	Error        *string   `json:"error"`
+	ResponseBody *string   `json:"response_body"`
+	DurationMS   int       `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// RawJSON agar payload JSONB tampil apa adanya di response API (bukan string ter-escape)
+type RawJSON []byte
+
+func (r RawJSON) MarshalJSON() ([]byte, error) {
+	if len(r) == 0 {
+		return []byte("null"), nil
+	}
+	return r, nil
+}
+
+// ==========================
+// SUBSCRIPTIONS
+// ==========================
+
+func InsertSubscription(db *sql.DB, s Subscription, secret string, adminID string) (string, error) {
+	var id string
+	err := db.QueryRow(`
+		INSERT INTO webhook_subscriptions (url, description, secret, events, is_active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, s.URL, s.Description, secret, pq.Array(s.Events), s.IsActive, adminID).Scan(&id)
+	return id, err
+}
+
+func UpdateSubscription(db *sql.DB, id string, s Subscription) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE webhook_subscriptions
+		SET url = $2, description = $3, events = $4, is_active = $5, updated_at = NOW()
+		WHERE id = $1
+	`, id, s.URL, s.Description, pq.Array(s.Events), s.IsActive)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func UpdateSecret(db *sql.DB, id string, secret string) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE webhook_subscriptions SET secret = $2, updated_at = NOW() WHERE id = $1
+	`, id, secret)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func DeleteSubscription(db *sql.DB, id string) (int64, error) {
+	res, err := db.Exec(`DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const subscriptionSelect = `
+	SELECT id, url, COALESCE(description, ''), events, is_active, created_by, created_at, updated_at
+	FROM webhook_subscriptions`
+
+func FindAllSubscriptions(db *sql.DB) ([]Subscription, error) {
+	rows, err := db.Query(subscriptionSelect + ` ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []Subscription{}
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, nil
+}
+
+func FindSubscriptionByID(db *sql.DB, id string) (Subscription, error) {
+	return scanSubscription(db.QueryRow(subscriptionSelect+` WHERE id = $1`, id))
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSubscription(row rowScanner) (Subscription, error) {
+	var s Subscription
+	var createdBy sql.NullString
+	err := row.Scan(&s.ID, &s.URL, &s.Description, pq.Array(&s.Events), &s.IsActive, &createdBy, &s.CreatedAt, &s.UpdatedAt)
+	if createdBy.Valid {
+		s.CreatedBy = &createdBy.String
+	}
+	return s, err
+}
+
+// ==========================
+// DELIVERIES (ANTRIAN)
+// ==========================
+
+// InsertDeliveries memasukkan satu event ke antrian untuk setiap langganan aktif yang cocok
+func InsertDeliveries(q database.Querier, eventID, eventType string, payload []byte) error {
+	_, err := q.Exec(`
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT id, $1::uuid, $2::varchar, $3::jsonb
+		FROM webhook_subscriptions
+		WHERE is_active = true AND ($2::text = ANY(events) OR '*' = ANY(events))
+	`, eventID, eventType, string(payload))
+	return err
+}
+
+// InsertDeliveryFor memasukkan event hanya untuk satu langganan (dipakai untuk event "ping")
+func InsertDeliveryFor(db *sql.DB, subscriptionID, eventID, eventType string, payload []byte) (string, error) {
+	var id string
+	err := db.QueryRow(`
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, subscriptionID, eventID, eventType, string(payload)).Scan(&id)
+	return id, err
+}
+
+// dueDelivery adalah delivery yang sudah diklaim worker beserta data langganannya
+type dueDelivery struct {
+	ID       string
+	EventID  string
+	Type     string
+	Payload  []byte
+	Attempts int
+	URL      string
+	Secret   string
+}
+
+// ClaimDueDeliveries mengambil delivery yang jatuh tempo. next_attempt_at digeser sebagai "lease"
+// sehingga instance lain tidak memproses delivery yang sama (aman untuk multi instance).
+func ClaimDueDeliveries(db *sql.DB, limit int, lease time.Duration) ([]dueDelivery, error) {
+	rows, err := db.Query(`
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2::float8)
+		FROM webhook_subscriptions s
+		WHERE d.subscription_id = s.id
+		  AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		  )
+		RETURNING d.id, d.event_id, d.event_type, d.payload, d.attempts, s.url, s.secret
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []dueDelivery
+	for rows.Next() {
+		var d dueDelivery
+		if err := rows.Scan(&d.ID, &d.EventID, &d.Type, &d.Payload, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// RecordAttempt menyimpan log percobaan dan memperbarui status delivery
+func RecordAttempt(db *sql.DB, deliveryID string, attempt int, statusCode int, errMsg, body string, duration time.Duration, status string, nextAttempt time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var code interface{}
+	if statusCode > 0 {
+		code = statusCode
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, response_body, duration_ms)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6)
+	`, deliveryID, attempt, code, errMsg, body, duration.Milliseconds())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, last_status_code = $4, last_error = NULLIF($5, ''),
+			next_attempt_at = $6,
+			delivered_at = CASE WHEN $2::text = 'success' THEN NOW() ELSE delivered_at END
+		WHERE id = $1
+	`, deliveryID, status, attempt, code, errMsg, nextAttempt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ResetDelivery menjadwalkan ulang delivery (redelivery manual) dengan jatah percobaan baru
+func ResetDelivery(db *sql.DB, deliveryID string) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE id = $1
+	`, deliveryID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const deliverySelect = `
+	SELECT id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+		last_status_code, last_error, delivered_at, created_at
+	FROM webhook_deliveries`
+
+func FindDeliveries(db *sql.DB, subscriptionID, status string, limit int) ([]Delivery, error) {
+	rows, err := db.Query(deliverySelect+`
+		WHERE subscription_id = $1 AND ($2::text = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, subscriptionID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = nil // Payload lengkap hanya ditampilkan di detail
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+func FindDeliveryByID(db *sql.DB, id string) (Delivery, error) {
+	d, err := scanDelivery(db.QueryRow(deliverySelect+` WHERE id = $1`, id))
+	if err != nil {
+		return d, err
+	}
+
+	rows, err := db.Query(`
+		SELECT attempt, status_code, error, response_body, duration_ms, created_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY id
+	`, id)
+	if err != nil {
+		return d, err
+	}
+	defer rows.Close()
+
+	d.Logs = []Attempt{}
+	for rows.Next() {
+		var a Attempt
+		var code sql.NullInt64
+		var errMsg, body sql.NullString
+		if err := rows.Scan(&a.Attempt, &code, &errMsg, &body, &a.DurationMS, &a.CreatedAt); err != nil {
+			return d, err
+		}
+		if code.Valid {
+			c := int(code.Int64)
+			a.StatusCode = &c
+		}
+		if errMsg.Valid {
+			a.Error = &errMsg.String
+		}
+		if body.Valid {
+			a.ResponseBody = &body.String
+		}
+		d.Logs = append(d.Logs, a)
+	}
+	return d, nil
+}
+
+func scanDelivery(row rowScanner) (Delivery, error) {
+	var d Delivery
+	var code sql.NullInt64
+	var lastErr sql.NullString
+	var deliveredAt sql.NullTime
+	var payload []byte
+
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &code, &lastErr, &deliveredAt, &d.CreatedAt)
+	if err != nil {
+		return d, err
+	}
+
+	d.Payload = payload
+	if code.Valid {
+		c := int(code.Int64)
+		d.LastStatusCode = &c
+	}
+	if lastErr.Valid {
+		d.LastError = &lastErr.String
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return d, nil
+}
@@ -0,0 +1,398 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"campus-reservation-backend/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// ==========================
+// DAFTAR EVENT
+// ==========================
+const (
+	EventBookingCreated    = "booking.created"
+	EventBookingApproved   = "booking.approved"
+	EventBookingRejected   = "booking.rejected"
+	EventBookingCanceled   = "booking.canceled"
+	EventBookingCheckedIn  = "booking.checked_in"
+	EventBookingCheckedOut = "booking.checked_out"
+	EventBookingNoShow     = "booking.no_show"
+
+	EventFacilityCreated       = "facility.created"
+	EventFacilityUpdated       = "facility.updated"
+	EventFacilityStatusChanged = "facility.status_changed"
+	EventFacilityDeleted       = "facility.deleted"
+
+	EventPing = "ping"
+)
+
+var knownEvents = map[string]bool{
+	EventBookingCreated: true, EventBookingApproved: true, EventBookingRejected: true,
+	EventBookingCanceled: true, EventBookingCheckedIn: true, EventBookingCheckedOut: true,
+	EventBookingNoShow: true, EventFacilityCreated: true, EventFacilityUpdated: true,
+	EventFacilityStatusChanged: true, EventFacilityDeleted: true, "*": true,
+}
+
+const (
+	maxAttempts     = 8
+	baseRetryDelay  = 30 * time.Second
+	maxRetryDelay   = 6 * time.Hour
+	claimBatchSize  = 20
+	claimLease      = 2 * time.Minute
+	maxResponseBody = 2048
+)
+
+var httpClient = &http.Client{
+	Timeout: 10 * time.Second,
+	// Redirect tidak diikuti agar payload bertanda tangan tidak terkirim ke host lain
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// Event adalah isi body JSON yang dikirim ke penerima
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// ==========================
+// PUBLISH (DIPANGGIL MODUL LAIN)
+// ==========================
+
+// Publish memasukkan event ke antrian untuk semua langganan yang cocok.
+// Gagal publish hanya dicatat di log agar tidak menggagalkan proses bisnis utamanya (lihat PublishLoaded).
+func Publish(q database.Querier, eventType string, data interface{}) {
+	PublishLoaded(q, eventType, func() (interface{}, error) { return data, nil })
+}
+
+// PublishLoaded seperti Publish, data event dimuat oleh load (biasanya query di transaksi yang sama).
+// Di dalam transaksi, load & insert antrian dijalankan di SAVEPOINT: query yang gagal hanya membatalkan
+// event ini, sedangkan perubahan data utamanya tetap bisa di-commit.
+func PublishLoaded(q database.Querier, eventType string, load func() (interface{}, error)) {
+	err := savepoint(q, func() error {
+		data, err := load()
+		if err != nil {
+			return fmt.Errorf("gagal memuat data: %w", err)
+		}
+
+		ev := Event{
+			ID:        uuid.New().String(),
+			Type:      eventType,
+			CreatedAt: time.Now(),
+			Data:      data,
+		}
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("gagal encode event: %w", err)
+		}
+		return InsertDeliveries(q, ev.ID, eventType, payload)
+	})
+	if err != nil {
+		log.Printf("Webhook: gagal memasukkan event %s ke antrian: %v\n", eventType, err)
+	}
+}
+
+// savepoint menjalankan fn di dalam SAVEPOINT jika q adalah transaksi, agar error di fn tidak membuat
+// seluruh transaksi aborted. Di luar transaksi fn dijalankan langsung.
+func savepoint(q database.Querier, fn func() error) error {
+	tx, ok := q.(*sql.Tx)
+	if !ok {
+		return fn()
+	}
+
+	if _, err := tx.Exec("SAVEPOINT webhook_publish"); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if _, rbErr := tx.Exec("ROLLBACK TO SAVEPOINT webhook_publish"); rbErr != nil {
+			return fmt.Errorf("%v (rollback savepoint: %v)", err, rbErr)
+		}
+		return err
+	}
+	_, err := tx.Exec("RELEASE SAVEPOINT webhook_publish")
+	return err
+}
+
+// WithTx menjalankan perubahan data beserta Publish-nya dalam satu transaksi: event hanya masuk antrian jika
+// perubahannya ter-commit, dan tidak hilang jika proses berhenti tepat setelah commit. Gagal publish tidak
+// membatalkan perubahan (lihat PublishLoaded).
+func WithTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ==========================
+// REQUEST OBJECTS
+// ==========================
+
+type SubscriptionRequest struct {
+	URL         string   `json:"url" example:"https://portal.kampus.ac.id/hooks/unispace"`
+	Description string   `json:"description"`
+	Events      []string `json:"events" example:"booking.approved"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+type CreateSubscriptionResponse struct {
+	Subscription
+	Secret string `json:"secret"` // Hanya ditampilkan saat dibuat / di-rotate
+}
+
+// ==========================
+// ADMIN: KELOLA LANGGANAN
+// ==========================
+
+func CreateSubscription(db *sql.DB, adminID string, req SubscriptionRequest) (CreateSubscriptionResponse, error) {
+	sub, err := validateSubscription(req)
+	if err != nil {
+		return CreateSubscriptionResponse{}, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return CreateSubscriptionResponse{}, errors.New("gagal membuat secret webhook")
+	}
+
+	id, err := InsertSubscription(db, sub, secret, adminID)
+	if err != nil {
+		return CreateSubscriptionResponse{}, errors.New("gagal menyimpan webhook")
+	}
+
+	created, err := FindSubscriptionByID(db, id)
+	if err != nil {
+		return CreateSubscriptionResponse{}, errors.New("webhook tidak ditemukan")
+	}
+	return CreateSubscriptionResponse{Subscription: created, Secret: secret}, nil
+}
+
+func UpdateSubscriptionByID(db *sql.DB, id string, req SubscriptionRequest) (Subscription, error) {
+	sub, err := validateSubscription(req)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	n, err := UpdateSubscription(db, id, sub)
+	if err != nil {
+		return Subscription{}, errors.New("gagal memperbarui webhook")
+	}
+	if n == 0 {
+		return Subscription{}, errors.New("webhook tidak ditemukan")
+	}
+	return FindSubscriptionByID(db, id)
+}
+
+func RotateSecret(db *sql.DB, id string) (string, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return "", errors.New("gagal membuat secret webhook")
+	}
+	n, err := UpdateSecret(db, id, secret)
+	if err != nil {
+		return "", errors.New("gagal menyimpan secret webhook")
+	}
+	if n == 0 {
+		return "", errors.New("webhook tidak ditemukan")
+	}
+	return secret, nil
+}
+
+func RemoveSubscription(db *sql.DB, id string) error {
+	n, err := DeleteSubscription(db, id)
+	if err != nil {
+		return errors.New("gagal menghapus webhook")
+	}
+	if n == 0 {
+		return errors.New("webhook tidak ditemukan")
+	}
+	return nil
+}
+
+// SendPing mengirim event "ping" ke satu langganan untuk menguji endpoint penerima
+func SendPing(db *sql.DB, id string) (string, error) {
+	if _, err := FindSubscriptionByID(db, id); err != nil {
+		return "", errors.New("webhook tidak ditemukan")
+	}
+
+	ev := Event{
+		ID:        uuid.New().String(),
+		Type:      EventPing,
+		CreatedAt: time.Now(),
+		Data:      map[string]string{"message": "Webhook UniSpace berhasil terhubung"},
+	}
+	payload, _ := json.Marshal(ev)
+
+	deliveryID, err := InsertDeliveryFor(db, id, ev.ID, EventPing, payload)
+	if err != nil {
+		return "", errors.New("gagal memasukkan ping ke antrian")
+	}
+	return deliveryID, nil
+}
+
+// Redeliver menjadwalkan ulang pengiriman (misal setelah endpoint penerima diperbaiki)
+func Redeliver(db *sql.DB, deliveryID string) error {
+	n, err := ResetDelivery(db, deliveryID)
+	if err != nil {
+		return errors.New("gagal menjadwalkan ulang pengiriman")
+	}
+	if n == 0 {
+		return errors.New("data pengiriman tidak ditemukan")
+	}
+	return nil
+}
+
+func validateSubscription(req SubscriptionRequest) (Subscription, error) {
+	req.URL = strings.TrimSpace(req.URL)
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return Subscription{}, errors.New("URL webhook tidak valid (harus http/https)")
+	}
+
+	if len(req.Events) == 0 {
+		return Subscription{}, errors.New("minimal satu event wajib dipilih")
+	}
+	for _, e := range req.Events {
+		if !knownEvents[e] {
+			return Subscription{}, errors.New("event tidak dikenal: " + e)
+		}
+	}
+
+	active := true
+	if req.IsActive != nil {
+		active = *req.IsActive
+	}
+
+	return Subscription{
+		URL:         req.URL,
+		Description: strings.TrimSpace(req.Description),
+		Events:      req.Events,
+		IsActive:    active,
+	}, nil
+}
+
+// ==========================
+// WORKER PENGIRIMAN
+// ==========================
+
+// ProcessQueue mengirim delivery yang jatuh tempo. Dipanggil berkala oleh worker di main.go.
+func ProcessQueue(db *sql.DB) error {
+	due, err := ClaimDueDeliveries(db, claimBatchSize, claimLease)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range due {
+		deliver(db, d)
+	}
+	return nil
+}
+
+func deliver(db *sql.DB, d dueDelivery) {
+	attempt := d.Attempts + 1
+	started := time.Now()
+
+	statusCode, body, sendErr := send(d)
+	duration := time.Since(started)
+
+	status := "success"
+	errMsg := ""
+	nextAttempt := time.Now()
+
+	if sendErr != nil || statusCode < 200 || statusCode >= 300 {
+		if sendErr != nil {
+			errMsg = sendErr.Error()
+		} else {
+			errMsg = fmt.Sprintf("HTTP %d", statusCode)
+		}
+
+		if attempt >= maxAttempts {
+			status = "failed"
+		} else {
+			status = "pending"
+			nextAttempt = time.Now().Add(retryDelay(attempt))
+		}
+	}
+
+	if err := RecordAttempt(db, d.ID, attempt, statusCode, errMsg, body, duration, status, nextAttempt); err != nil {
+		log.Printf("Webhook: gagal mencatat pengiriman %s: %v\n", d.ID, err)
+	}
+}
+
+func send(d dueDelivery) (int, string, error) {
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "UniSpace-Webhook/1.0")
+	req.Header.Set("X-UniSpace-Event", d.Type)
+	req.Header.Set("X-UniSpace-Delivery", d.ID)
+	req.Header.Set("X-UniSpace-Event-ID", d.EventID)
+	req.Header.Set("X-UniSpace-Timestamp", timestamp)
+	req.Header.Set("X-UniSpace-Signature", "sha256="+Sign(d.Secret, timestamp, d.Payload))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, string(body), nil
+}
+
+// Sign menghitung HMAC-SHA256 dari "<timestamp>.<body>".
+// Penerima memverifikasi dengan menghitung ulang nilai ini dari header X-UniSpace-Timestamp dan body mentah,
+// lalu menolak timestamp yang terlalu lama untuk mencegah replay.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryDelay: 30 detik, 1 menit, 2 menit, ... maksimal 6 jam
+func retryDelay(attempt int) time.Duration {
+	d := baseRetryDelay
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return d
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
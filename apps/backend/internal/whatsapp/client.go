@@ -32,6 +32,15 @@ type UserCheckResponse struct {
 // ============================================================================
 
 func SendOTP(phone string, code string) error {
+	msg := fmt.Sprintf("Kode OTP Kampus Reservation Anda adalah: *%s*\n\nJangan berikan kode ini kepada siapapun. Berlaku selama 5 menit.", code)
+	return SendMessage(phone, msg)
+}
+
+// ============================================================================
+// 1.1 SEND MESSAGE (PESAN BEBAS, MISAL NOTIFIKASI KEAMANAN)
+// ============================================================================
+
+func SendMessage(phone string, msg string) error {
 	gatewayURL := os.Getenv("WA_GATEWAY_URL")
 	if gatewayURL == "" {
 		return errors.New("WA_GATEWAY_URL belum diset di .env")
@@ -40,8 +49,6 @@ func SendOTP(phone string, code string) error {
 	// Format ke JID untuk pengiriman pesan (biasanya butuh @s.whatsapp.net)
 	formattedPhone := FormatPhoneToJID(phone)
 
-	msg := fmt.Sprintf("Kode OTP Kampus Reservation Anda adalah: *%s*\n\nJangan berikan kode ini kepada siapapun. Berlaku selama 5 menit.", code)
-
 	payload := SendMessageRequest{
 		Phone:   formattedPhone,
 		Message: msg,
@@ -68,7 +75,7 @@ func SendOTP(phone string, code string) error {
 
 	if resp.StatusCode != 200 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		fmt.Printf("[WA-ERROR] Send OTP Status: %d, Body: %s\n", resp.StatusCode, string(bodyBytes))
+		fmt.Printf("[WA-ERROR] Send Message Status: %d, Body: %s\n", resp.StatusCode, string(bodyBytes))
 		return fmt.Errorf("WA gateway merespon dengan status: %d", resp.StatusCode)
 	}
 